@@ -25,6 +25,7 @@ func Run() {
 
 	cmd.AddCommand(
 		convertCommand(),
+		debugCommand(),
 		fmtCommand(),
 		runCommand(),
 		toolsCommand(),
@@ -0,0 +1,231 @@
+package alloycli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/common/config"
+	"github.com/spf13/cobra"
+)
+
+func debugCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Live debugging utilities",
+		Long:  `The debug command contains utilities to stream and replay live debugging data.`,
+	}
+
+	cmd.AddCommand(
+		debugTailCommand(),
+		debugReplayCommand(),
+	)
+
+	return cmd
+}
+
+func debugTailCommand() *cobra.Command {
+	t := &alloyDebugTail{
+		serverAddr: "http://127.0.0.1:12345",
+		uiPrefix:   "/",
+		sampleProb: 1,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "tail [flags] component_id",
+		Short: "Stream the live debugging data of a component",
+		Long: `The tail command streams the live debugging data of a component from a running
+Alloy instance to the terminal, and optionally records it to a file.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer cancel()
+			return t.Run(ctx, args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&t.serverAddr, "server.http.address", t.serverAddr, "Address of the Alloy HTTP server")
+	cmd.Flags().StringVar(&t.uiPrefix, "server.http.ui-path-prefix", t.uiPrefix, "Prefix the Alloy UI is served at")
+	cmd.Flags().StringVar(&t.username, "basic-auth.username", t.username, "Username used to authenticate to the Alloy HTTP server")
+	cmd.Flags().StringVar(&t.passwordFile, "basic-auth.password-file", t.passwordFile, "File containing the password used to authenticate to the Alloy HTTP server")
+	cmd.Flags().StringVar(&t.bearerTokenFile, "bearer-token-file", t.bearerTokenFile, "File containing the bearer token used to authenticate to the Alloy HTTP server")
+
+	cmd.Flags().StringVar(&t.filter, "filter", t.filter, `Only stream data with labels matching the selector, for example {job="api"}`)
+	cmd.Flags().Float64Var(&t.sampleProb, "sample-prob", t.sampleProb, "Probability, between 0 and 1, to stream each data")
+	cmd.Flags().Float64Var(&t.rate, "rate", t.rate, "Maximum number of data streamed per second. 0 means unlimited")
+	cmd.Flags().DurationVar(&t.duration, "duration", t.duration, "Stop streaming after this duration. 0 means until interrupted")
+	cmd.Flags().BoolVar(&t.targetDiff, "target-diff", t.targetDiff, "Only stream the targets added, removed or changed between updates of discovery components")
+
+	cmd.Flags().StringVar(&t.outputFile, "output", t.outputFile, "Record the streamed data to this NDJSON file")
+	cmd.Flags().BoolVar(&t.json, "json", t.json, "Print the streamed data as NDJSON records")
+
+	return cmd
+}
+
+type alloyDebugTail struct {
+	serverAddr      string
+	uiPrefix        string
+	username        string
+	passwordFile    string
+	bearerTokenFile string
+
+	filter     string
+	sampleProb float64
+	rate       float64
+	duration   time.Duration
+	targetDiff bool
+
+	outputFile string
+	json       bool
+}
+
+func (t *alloyDebugTail) Run(ctx context.Context, componentID string, out io.Writer) error {
+	if t.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.duration)
+		defer cancel()
+	}
+
+	u, err := url.Parse(t.serverAddr)
+	if err != nil {
+		return fmt.Errorf("invalid server address: %w", err)
+	}
+	u = u.JoinPath(path.Join(t.uiPrefix, "/api/v0/web/debug", componentID))
+
+	query := url.Values{}
+	query.Set("format", "ndjson")
+	query.Set("sampleProb", strconv.FormatFloat(t.sampleProb, 'f', -1, 64))
+	if t.filter != "" {
+		query.Set("filter", t.filter)
+	}
+	if t.rate > 0 {
+		query.Set("rate", strconv.FormatFloat(t.rate, 'f', -1, 64))
+	}
+	if t.targetDiff {
+		query.Set("targetDiff", "true")
+	}
+	u.RawQuery = query.Encode()
+
+	clientConfig := config.HTTPClientConfig{BearerTokenFile: t.bearerTokenFile}
+	if t.username != "" {
+		clientConfig.BasicAuth = &config.BasicAuth{Username: t.username, PasswordFile: t.passwordFile}
+	}
+	if err := clientConfig.Validate(); err != nil {
+		return err
+	}
+	client, err := config.NewClientFromConfig(clientConfig, "alloy-debug-tail")
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to stream live debugging data, status code: %d, reason: %s", resp.StatusCode, body)
+	}
+
+	var recorder *json.Encoder
+	if t.outputFile != "" {
+		f, err := os.Create(t.outputFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		recorder = json.NewEncoder(f)
+	}
+
+	err = livedebugging.ReadRecords(resp.Body, func(rec livedebugging.Record) error {
+		if recorder != nil {
+			if err := recorder.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return printRecord(out, rec, t.json)
+	})
+	// The stream only ends when it is interrupted or the duration elapsed.
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func debugReplayCommand() *cobra.Command {
+	r := &alloyDebugReplay{}
+
+	cmd := &cobra.Command{
+		Use:   "replay [flags] file",
+		Short: "Print live debugging data recorded to a file",
+		Long: `The replay command prints the live debugging data recorded to an NDJSON file,
+either by the tail command or by downloading it from the Alloy UI.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer cancel()
+			return r.Run(ctx, args[0], cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&r.realtime, "realtime", r.realtime, "Print the data at the pace it was recorded")
+	cmd.Flags().BoolVar(&r.json, "json", r.json, "Print the data as NDJSON records")
+
+	return cmd
+}
+
+type alloyDebugReplay struct {
+	realtime bool
+	json     bool
+}
+
+func (r *alloyDebugReplay) Run(ctx context.Context, file string, out io.Writer) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var last time.Time
+	err = livedebugging.ReadRecords(f, func(rec livedebugging.Record) error {
+		if r.realtime && !last.IsZero() && rec.Timestamp.After(last) {
+			select {
+			case <-time.After(rec.Timestamp.Sub(last)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		last = rec.Timestamp
+		return printRecord(out, rec, r.json)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func printRecord(out io.Writer, rec livedebugging.Record, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(out).Encode(rec)
+	}
+	_, err := fmt.Fprintf(out, "%s %s (%s): %s\n", rec.Timestamp.Format(time.RFC3339Nano), rec.ComponentID, rec.Type, rec.Data)
+	return err
+}
@@ -0,0 +1,81 @@
+package alloycli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugTailAndReplay(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []livedebugging.Record{
+		{Timestamp: ts, ComponentID: "loki.process.default", Type: livedebugging.LokiLog, Count: 1, Data: "first"},
+		{Timestamp: ts.Add(time.Millisecond), ComponentID: "loki.process.default", Type: livedebugging.LokiLog, Count: 1, Data: "second"},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ui/api/v0/web/debug/loki.process.default", r.URL.Path)
+		require.Equal(t, "ndjson", r.URL.Query().Get("format"))
+		require.Equal(t, `{job="api"}`, r.URL.Query().Get("filter"))
+		require.Equal(t, "10", r.URL.Query().Get("rate"))
+		require.Equal(t, "true", r.URL.Query().Get("targetDiff"))
+
+		enc := json.NewEncoder(w)
+		for _, rec := range records {
+			require.NoError(t, enc.Encode(rec))
+		}
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "session.ndjson")
+	tail := &alloyDebugTail{
+		serverAddr: srv.URL,
+		uiPrefix:   "/ui",
+		filter:     `{job="api"}`,
+		sampleProb: 1,
+		rate:       10,
+		targetDiff: true,
+		outputFile: output,
+	}
+
+	var tailOut bytes.Buffer
+	require.NoError(t, tail.Run(context.Background(), "loki.process.default", &tailOut))
+	expected := "2024-01-01T00:00:00Z loki.process.default (loki_log): first\n" +
+		"2024-01-01T00:00:00.001Z loki.process.default (loki_log): second\n"
+	require.Equal(t, expected, tailOut.String())
+
+	var replayed []livedebugging.Record
+	f, err := os.Open(output)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, livedebugging.ReadRecords(f, func(rec livedebugging.Record) error {
+		replayed = append(replayed, rec)
+		return nil
+	}))
+	require.Equal(t, records, replayed)
+
+	replay := &alloyDebugReplay{realtime: true}
+	var replayOut bytes.Buffer
+	require.NoError(t, replay.Run(context.Background(), output, &replayOut))
+	require.Equal(t, expected, replayOut.String())
+}
+
+func TestDebugTailError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid filter", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	tail := &alloyDebugTail{serverAddr: srv.URL, uiPrefix: "/", sampleProb: 1}
+	err := tail.Run(context.Background(), "loki.process.default", &bytes.Buffer{})
+	require.ErrorContains(t, err, "status code: 400, reason: invalid filter")
+}
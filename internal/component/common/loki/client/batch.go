@@ -33,6 +33,8 @@ type SentDataMarkerHandler interface {
 // streams for each tenant are stored in a dedicated batch.
 type batch struct {
 	streams map[string]*logproto.Stream
+	// streamLabels holds the label set of each stream, keyed like streams.
+	streamLabels map[string]model.LabelSet
 	// totalBytes holds the total amounts of bytes, across the log lines in this batch.
 	totalBytes int
 	createdAt  time.Time
@@ -46,6 +48,7 @@ type batch struct {
 func newBatch(maxStreams int, entries ...loki.Entry) *batch {
 	b := &batch{
 		streams:        map[string]*logproto.Stream{},
+		streamLabels:   map[string]model.LabelSet{},
 		totalBytes:     0,
 		createdAt:      time.Now(),
 		maxStreams:     maxStreams,
@@ -81,6 +84,7 @@ func (b *batch) add(entry loki.Entry) error {
 		Labels:  labels,
 		Entries: []logproto.Entry{entry.Entry},
 	}
+	b.streamLabels[labels] = entry.Labels
 	return nil
 }
 
@@ -107,6 +111,7 @@ func (b *batch) addFromWAL(lbs model.LabelSet, entry logproto.Entry, segmentNum
 		Labels:  labels,
 		Entries: []logproto.Entry{entry},
 	}
+	b.streamLabels[labels] = lbs
 	b.countForSegment(segmentNum)
 
 	return nil
@@ -340,7 +340,7 @@ func batchIsRateLimited(status int) bool {
 }
 
 func (c *client) sendBatch(tenantID string, batch *batch) {
-	buf, entriesCount, err := batch.encode()
+	buf, entriesCount, err := encodeBatch(batch, c.cfg.Protocol)
 	if err != nil {
 		level.Error(c.logger).Log("msg", "error encoding batch", "error", err)
 		return
@@ -407,7 +407,7 @@ func (c *client) send(ctx context.Context, tenantID string, buf []byte) (int, er
 	if err != nil {
 		return -1, err
 	}
-	req.Header.Set("Content-Type", contentType)
+	setContentHeaders(req.Header, c.cfg.Protocol)
 	req.Header.Set("User-Agent", userAgent)
 
 	// If the tenant ID is not empty promtail is running in multi-tenant mode, so
@@ -21,6 +21,15 @@ const (
 	Timeout        = 10 * time.Second
 )
 
+// Protocols supported to push batches to an endpoint.
+const (
+	// ProtocolLoki pushes batches as snappy-compressed Loki push requests.
+	ProtocolLoki = "loki"
+	// ProtocolOTLP pushes batches as gzip-compressed OTLP/HTTP logs export
+	// requests.
+	ProtocolOTLP = "otlp"
+)
+
 // Config describes configuration for an HTTP pusher client.
 type Config struct {
 	Name      string `yaml:"name,omitempty"`
@@ -47,6 +56,10 @@ type Config struct {
 
 	// Queue controls configuration parameters specific to the queue client
 	Queue QueueConfig
+
+	// Protocol used to push batches to the endpoint. An empty string means
+	// ProtocolLoki.
+	Protocol string `yaml:"-"`
 }
 
 // QueueConfig holds configurations for the queue-based remote-write client.
@@ -61,6 +74,13 @@ type QueueConfig struct {
 
 	// DrainTimeout controls the maximum time that draining the send queue can take.
 	DrainTimeout time.Duration
+
+	// TenantRateLimit is the maximum number of bytes per second sent for each tenant. Zero means no limit.
+	TenantRateLimit int
+
+	// TenantRateBurst is the maximum number of bytes sent at once for each tenant when TenantRateLimit is set. Zero
+	// means BatchSize.
+	TenantRateBurst int
 }
 
 // RegisterFlags with prefix registers flags where every name is prefixed by
@@ -6,7 +6,9 @@ import (
 )
 
 type QueueClientMetrics struct {
-	lastReadTimestamp *prometheus.GaugeVec
+	lastReadTimestamp   *prometheus.GaugeVec
+	tenantQueuedBatches *prometheus.GaugeVec
+	tenantRateLimited   *prometheus.CounterVec
 }
 
 func NewQueueClientMetrics(reg prometheus.Registerer) *QueueClientMetrics {
@@ -19,19 +21,40 @@ func NewQueueClientMetrics(reg prometheus.Registerer) *QueueClientMetrics {
 			},
 			[]string{"id"},
 		),
+		tenantQueuedBatches: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Name:      "tenant_queued_batches",
+				Help:      "Number of batches of a tenant waiting to be sent",
+			},
+			[]string{"id", TenantLabel},
+		),
+		tenantRateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Name:      "tenant_rate_limited_total",
+				Help:      "Number of times sending a batch of a tenant was delayed by the tenant rate limit",
+			},
+			[]string{"id", TenantLabel},
+		),
 	}
 
 	if reg != nil {
 		m.lastReadTimestamp = util.MustRegisterOrGet(reg, m.lastReadTimestamp).(*prometheus.GaugeVec)
+		m.tenantQueuedBatches = util.MustRegisterOrGet(reg, m.tenantQueuedBatches).(*prometheus.GaugeVec)
+		m.tenantRateLimited = util.MustRegisterOrGet(reg, m.tenantRateLimited).(*prometheus.CounterVec)
 	}
 
 	return m
 }
 
 func (m *QueueClientMetrics) CurryWithId(id string) *QueueClientMetrics {
+	labels := map[string]string{
+		"id": id,
+	}
 	return &QueueClientMetrics{
-		lastReadTimestamp: m.lastReadTimestamp.MustCurryWith(map[string]string{
-			"id": id,
-		}),
+		lastReadTimestamp:   m.lastReadTimestamp.MustCurryWith(labels),
+		tenantQueuedBatches: m.tenantQueuedBatches.MustCurryWith(labels),
+		tenantRateLimited:   m.tenantRateLimited.MustCurryWith(labels),
 	}
 }
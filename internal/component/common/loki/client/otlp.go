@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+)
+
+// encodeBatch encodes the batch for the push protocol of an endpoint, and
+// returns the encoded bytes and the number of encoded entries.
+func encodeBatch(b *batch, protocol string) ([]byte, int, error) {
+	if protocol == ProtocolOTLP {
+		return b.encodeOTLP()
+	}
+	return b.encode()
+}
+
+// setContentHeaders sets the headers describing a batch encoded for the push
+// protocol of an endpoint.
+func setContentHeaders(header http.Header, protocol string) {
+	header.Set("Content-Type", contentType)
+	if protocol == ProtocolOTLP {
+		header.Set("Content-Encoding", "gzip")
+	}
+}
+
+// encodeOTLP encodes the batch as a gzip-compressed OTLP/HTTP logs export
+// request, and returns the encoded bytes and the number of encoded entries.
+// Each stream becomes a resource whose attributes are the labels of the
+// stream, and the structured metadata of each entry becomes the attributes of
+// its log record.
+func (b *batch) encodeOTLP() ([]byte, int, error) {
+	logs, entriesCount := b.createLogs()
+	buf, err := plogotlp.NewExportRequestFromLogs(logs).MarshalProto()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(buf); err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return compressed.Bytes(), entriesCount, nil
+}
+
+// createLogs converts the batch to OTLP logs, and returns them together with
+// the number of entries.
+func (b *batch) createLogs() (plog.Logs, int) {
+	logs := plog.NewLogs()
+	logs.ResourceLogs().EnsureCapacity(len(b.streams))
+
+	entriesCount := 0
+	for key, stream := range b.streams {
+		rl := logs.ResourceLogs().AppendEmpty()
+		attrs := rl.Resource().Attributes()
+		for name, value := range b.streamLabels[key] {
+			if name == ReservedLabelTenantID {
+				continue
+			}
+			attrs.PutStr(string(name), string(value))
+		}
+
+		records := rl.ScopeLogs().AppendEmpty().LogRecords()
+		records.EnsureCapacity(len(stream.Entries))
+		for _, entry := range stream.Entries {
+			record := records.AppendEmpty()
+			record.SetTimestamp(pcommon.NewTimestampFromTime(entry.Timestamp))
+			record.Body().SetStr(entry.Line)
+			for _, md := range entry.StructuredMetadata {
+				record.Attributes().PutStr(md.Name, md.Value)
+			}
+		}
+		entriesCount += len(stream.Entries)
+	}
+	return logs, entriesCount
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/grafana/loki/v3/pkg/logproto"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+func TestBatch_encodeOTLP(t *testing.T) {
+	t.Parallel()
+
+	b := newBatch(0,
+		loki.Entry{Labels: model.LabelSet{"app": "a", ReservedLabelTenantID: "tenant-1"}, Entry: logEntries[0].Entry},
+		loki.Entry{Labels: model.LabelSet{"app": "a", ReservedLabelTenantID: "tenant-1"}, Entry: logEntries[7].Entry},
+	)
+	require.NoError(t, b.addFromWAL(model.LabelSet{"app": "b"}, logEntries[1].Entry, 1))
+
+	buf, entriesCount, err := b.encodeOTLP()
+	require.NoError(t, err)
+	assert.Equal(t, 3, entriesCount)
+
+	streams := decodeOTLP(t, buf)
+	assert.Equal(t, map[string][]otlpRecord{
+		`{"app":"a"}`: {
+			{timestamp: time.Unix(1, 0).UTC(), body: "line1", attributes: map[string]any{}},
+			{timestamp: time.Unix(7, 0).UTC(), body: "line7", attributes: map[string]any{"trace_id": "12345"}},
+		},
+		`{"app":"b"}`: {
+			{timestamp: time.Unix(2, 0).UTC(), body: "line2", attributes: map[string]any{}},
+		},
+	}, streams)
+}
+
+func TestClient_OTLP(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		received <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	serverURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	c, err := New(NewMetrics(prometheus.NewRegistry()), Config{
+		URL:           flagext.URLValue{URL: serverURL},
+		BatchWait:     10 * time.Millisecond,
+		BatchSize:     BatchSize,
+		BackoffConfig: backoff.Config{MinBackoff: MinBackoff, MaxBackoff: MaxBackoff, MaxRetries: 1},
+		Timeout:       Timeout,
+		TenantID:      "tenant-1",
+		Protocol:      ProtocolOTLP,
+	}, 0, 0, false, log.NewNopLogger())
+	require.NoError(t, err)
+	defer c.Stop()
+
+	c.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"app": "a"},
+		Entry: logproto.Entry{
+			Timestamp:          time.Unix(1, 0).UTC(),
+			Line:               "hello",
+			StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "abc"}},
+		},
+	}
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "tenant-1", r.Header.Get("X-Scope-OrgID"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request received")
+	}
+	assert.Equal(t, map[string][]otlpRecord{
+		`{"app":"a"}`: {
+			{timestamp: time.Unix(1, 0).UTC(), body: "hello", attributes: map[string]any{"trace_id": "abc"}},
+		},
+	}, decodeOTLP(t, <-bodies))
+}
+
+// otlpRecord holds the fields of an OTLP log record set by the encoder.
+type otlpRecord struct {
+	timestamp  time.Time
+	body       string
+	attributes map[string]any
+}
+
+// decodeOTLP decodes a gzip-compressed OTLP logs export request, and returns
+// its log records keyed by the attributes of their resource.
+func decodeOTLP(t *testing.T, buf []byte) map[string][]otlpRecord {
+	gz, err := gzip.NewReader(bytes.NewReader(buf))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	req := plogotlp.NewExportRequest()
+	require.NoError(t, req.UnmarshalProto(raw))
+
+	res := map[string][]otlpRecord{}
+	resourceLogs := req.Logs().ResourceLogs()
+	for i := 0; i < resourceLogs.Len(); i++ {
+		rl := resourceLogs.At(i)
+		key, err := json.Marshal(rl.Resource().Attributes().AsRaw())
+		require.NoError(t, err)
+
+		records := rl.ScopeLogs().At(0).LogRecords()
+		for j := 0; j < records.Len(); j++ {
+			record := records.At(j)
+			res[string(key)] = append(res[string(key)], otlpRecord{
+				timestamp:  record.Timestamp().AsTime(),
+				body:       record.Body().Str(),
+				attributes: record.Attributes().AsRaw(),
+			})
+		}
+	}
+	return res
+}
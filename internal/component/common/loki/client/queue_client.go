@@ -19,6 +19,7 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/tsdb/chunks"
 	"github.com/prometheus/prometheus/tsdb/record"
+	"golang.org/x/time/rate"
 
 	alloyWal "github.com/grafana/alloy/internal/component/common/loki/wal"
 	"github.com/grafana/alloy/internal/useragent"
@@ -47,23 +48,62 @@ type MarkerHandler interface {
 type queuedBatch struct {
 	TenantID string
 	Batch    *batch
+
+	// encoded holds the encoded batch, along with the number of encoded entries, once the batch has been attempted.
+	encoded      []byte
+	entriesCount int
+	// backoff tracks the retries of the batch, and is created after the first failed attempt.
+	backoff *backoff.Backoff
+}
+
+// tenantIdleTimeout is how long the sub-queue of a tenant is kept once it's empty, before being evicted along with the
+// metrics of the tenant.
+const tenantIdleTimeout = 5 * time.Minute
+
+// tenantQueue holds the batches of a tenant waiting to be sent.
+type tenantQueue struct {
+	id      string
+	batches []*queuedBatch
+	// sending is set while a batch of the tenant is being sent, and lastActive is the last time a batch of the tenant
+	// was enqueued or sent.
+	sending    bool
+	lastActive time.Time
+	// readyAt is the earliest time the next batch of the tenant can be sent, either because the tenant is rate limited
+	// or because its last attempt failed and is backing off.
+	readyAt time.Time
+	// limiter is nil if tenants aren't rate limited.
+	limiter *rate.Limiter
 }
 
-// queue wraps a buffered channel and a routine that reads from it, sending batches of entries.
+// queue holds a sub-queue for each tenant, and a routine that sends batches from them. Tenants are served in a
+// round-robin fashion, and a tenant that's rate limited or backing off after a failed attempt doesn't delay the
+// batches of the other tenants.
 type queue struct {
-	client *queueClient
-	q      chan queuedBatch
-	quit   chan struct{}
-	wg     sync.WaitGroup
-	logger log.Logger
+	client   *queueClient
+	capacity int
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	logger   log.Logger
+
+	mtx     sync.Mutex
+	tenants map[string]*tenantQueue
+	order   []*tenantQueue
+	next    int
+	size    int
+	// notify is signaled when a batch is enqueued, and freed is closed, then renewed, when a batch is dequeued.
+	notify chan struct{}
+	freed  chan struct{}
 }
 
 func newQueue(client *queueClient, size int, logger log.Logger) *queue {
 	q := queue{
-		client: client,
-		q:      make(chan queuedBatch, size),
-		quit:   make(chan struct{}),
-		logger: logger,
+		client:   client,
+		capacity: size,
+		quit:     make(chan struct{}),
+		logger:   logger,
+		tenants:  make(map[string]*tenantQueue),
+		notify:   make(chan struct{}, 1),
+		freed:    make(chan struct{}),
 	}
 
 	q.wg.Add(1)
@@ -72,80 +112,242 @@ func newQueue(client *queueClient, size int, logger log.Logger) *queue {
 	return &q
 }
 
-// enqueue adds to the send queue a batch ready to be sent. Note that if the backing queue is has no
-// remaining capacity to enqueue the batch, calling enqueue might block.
+// enqueue adds to the send queue a batch ready to be sent. Note that if the queue has no remaining capacity to enqueue
+// the batch, calling enqueue might block.
 func (q *queue) enqueue(qb queuedBatch) {
-	q.q <- qb
+	q.enqueueWithCancel(context.Background(), qb)
 }
 
 // enqueueWithCancel tries to enqueue a batch, giving up if the supplied context times deadlines
 // times out. If the batch is successfully enqueued, it returns true.
+//
+// The capacity of the queue is shared by all tenants, but a tenant with no batch waiting to be sent can always enqueue
+// one, so that a tenant that fills the queue while it's throttled can't block the others.
 func (q *queue) enqueueWithCancel(ctx context.Context, qb queuedBatch) bool {
-	select {
-	case <-ctx.Done():
-		return false
-	case q.q <- qb:
+	for {
+		q.mtx.Lock()
+		tq := q.tenant(qb.TenantID)
+		if q.size < q.capacity || len(tq.batches) == 0 {
+			tq.batches = append(tq.batches, &qb)
+			tq.lastActive = time.Now()
+			q.size++
+			q.client.qcMetrics.tenantQueuedBatches.WithLabelValues(tq.id).Set(float64(len(tq.batches)))
+			q.mtx.Unlock()
+
+			select {
+			case q.notify <- struct{}{}:
+			default:
+			}
+			return true
+		}
+		freed := q.freed
+		q.mtx.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-freed:
+		}
+	}
+}
+
+// tenant returns the sub-queue of a tenant, creating it if needed. It must be called with mtx held.
+func (q *queue) tenant(tenantID string) *tenantQueue {
+	if tq, ok := q.tenants[tenantID]; ok {
+		return tq
+	}
+
+	tq := &tenantQueue{id: tenantID}
+	if limit := q.client.cfg.Queue.TenantRateLimit; limit > 0 {
+		tq.limiter = rate.NewLimiter(rate.Limit(limit), q.client.tenantRateBurst())
+	}
+	q.tenants[tenantID] = tq
+	q.order = append(q.order, tq)
+	return tq
+}
+
+// dequeue takes the next batch to send, picking the tenants in turns. If no batch can be sent right now, it returns a
+// nil batch, along with the time to wait before one can be sent, or a negative duration if the queue is empty.
+func (q *queue) dequeue(now time.Time) (*tenantQueue, *queuedBatch, time.Duration) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	wait := time.Duration(-1)
+	for i := range q.order {
+		tq := q.order[(q.next+i)%len(q.order)]
+		if len(tq.batches) == 0 {
+			continue
+		}
+
+		if tq.readyAt.After(now) {
+			if d := tq.readyAt.Sub(now); wait < 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		qb := tq.batches[0]
+		// The rate limit is only applied once per batch, before its first attempt.
+		if tq.limiter != nil && qb.encoded == nil {
+			r := tq.limiter.ReserveN(now, min(qb.Batch.sizeBytes(), tq.limiter.Burst()))
+			if d := r.DelayFrom(now); d > 0 {
+				r.CancelAt(now)
+				tq.readyAt = now.Add(d)
+				q.client.qcMetrics.tenantRateLimited.WithLabelValues(tq.id).Inc()
+				if wait < 0 || d < wait {
+					wait = d
+				}
+				continue
+			}
+		}
+
+		tq.batches[0] = nil
+		tq.batches = tq.batches[1:]
+		tq.sending = true
+		q.size--
+		q.next = (q.next + i + 1) % len(q.order)
+		q.client.qcMetrics.tenantQueuedBatches.WithLabelValues(tq.id).Set(float64(len(tq.batches)))
+
+		close(q.freed)
+		q.freed = make(chan struct{})
+		return tq, qb, 0
+	}
+	return nil, nil, wait
+}
+
+// retry puts a batch back at the front of the sub-queue of its tenant, which won't be served before the delay.
+func (q *queue) retry(tq *tenantQueue, qb *queuedBatch, delay time.Duration) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	tq.batches = append([]*queuedBatch{qb}, tq.batches...)
+	tq.readyAt = time.Now().Add(delay)
+	tq.sending = false
+	tq.lastActive = time.Now()
+	q.size++
+	q.client.qcMetrics.tenantQueuedBatches.WithLabelValues(tq.id).Set(float64(len(tq.batches)))
+}
+
+// sent marks that the batch of a tenant which was being sent is done.
+func (q *queue) sent(tq *tenantQueue) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	tq.sending = false
+	tq.lastActive = time.Now()
+}
+
+// evictIdleTenants removes the sub-queues of the tenants which had no batch to send for tenantIdleTimeout, along with
+// their metrics, so that the queue doesn't grow with every tenant ever seen.
+func (q *queue) evictIdleTenants(now time.Time) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	order := q.order[:0]
+	for i, tq := range q.order {
+		if len(tq.batches) > 0 || tq.sending || tq.readyAt.After(now) || now.Sub(tq.lastActive) < tenantIdleTimeout {
+			order = append(order, tq)
+			continue
+		}
+		if i < q.next {
+			q.next--
+		}
+		delete(q.tenants, tq.id)
+		q.client.qcMetrics.tenantQueuedBatches.DeleteLabelValues(tq.id)
+		q.client.qcMetrics.tenantRateLimited.DeleteLabelValues(tq.id)
+	}
+	clear(q.order[len(order):])
+	q.order = order
+	if q.next >= len(q.order) {
+		q.next = 0
 	}
-	return true
 }
 
 func (q *queue) run() {
 	defer q.wg.Done()
 
+	timer := time.NewTimer(0)
+	timer.Stop()
+	defer timer.Stop()
+	evict := time.NewTicker(tenantIdleTimeout)
+	defer evict.Stop()
+
 	for {
+		tq, qb, wait := q.dequeue(time.Now())
+		if qb != nil {
+			// Since inside the actual send operation a context with time out is used, we should exceed that timeout
+			// instead of cancelling this send operation, since that batch has been taken out of the queue.
+			q.sendAndReport(context.Background(), tq, qb)
+			continue
+		}
+
+		// Wait for a new batch, or for a tenant to be ready again.
+		var ready <-chan time.Time
+		if wait >= 0 {
+			timer.Reset(wait)
+			ready = timer.C
+		}
 		select {
 		case <-q.quit:
 			return
-		case qb := <-q.q:
-			// Since inside the actual send operation a context with time out is used, we should exceed that timeout
-			// instead of cancelling this send operation, since that batch has been taken out of the queue.
-			q.sendAndReport(context.Background(), qb.TenantID, qb.Batch)
+		case <-q.notify:
+		case <-ready:
+		case now := <-evict.C:
+			q.evictIdleTenants(now)
 		}
+		timer.Stop()
 	}
 }
 
 // closeAndDrain stops gracefully the queue. The process first stops the main routine that reads batches to be sent,
 // to instead drain the queue and send those batches from this thread, exiting if the supplied context deadline
-// is exceeded. Also, if the underlying buffered channel is fully drain, this will exit promptly.
+// is exceeded. Also, if the queue is fully drained, this will exit promptly.
 func (q *queue) closeAndDrain(ctx context.Context) {
-	// defer main channel closing
-	defer close(q.q)
-
 	// first stop main routine, and wait for it to signal
 	close(q.quit)
 	q.wg.Wait()
 
-	// keep reading messages from sendQueue until all have been consumed, or timeout is exceeded
+	// keep sending batches until all have been consumed, or timeout is exceeded
 	for {
-		select {
-		case qb := <-q.q:
+		tq, qb, wait := q.dequeue(time.Now())
+		if qb != nil {
 			// drain uses the same timeout, so if a timeout was applied to the parent context, it can cancel the underlying
 			// send operation preemptively.
-			q.sendAndReport(ctx, qb.TenantID, qb.Batch)
+			q.sendAndReport(ctx, tq, qb)
+			continue
+		}
+		if wait < 0 {
+			level.Debug(q.logger).Log("msg", "drain queue exited because there were no batches left to send")
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
 		case <-ctx.Done():
+			timer.Stop()
 			level.Warn(q.logger).Log("msg", "timeout exceeded while draining send queue")
 			return
-		default:
-			level.Debug(q.logger).Log("msg", "drain queue exited because there were no batches left to send")
-			return
-			// if default clause is taken, it means there's nothing left in the send queue
+		case <-timer.C:
 		}
 	}
 }
 
-// sendAndReport attempts to send the batch for the given tenant, and either way that operation succeeds or fails, reports
-// the data as sent.
-func (q *queue) sendAndReport(ctx context.Context, tenantId string, b *batch) {
-	q.client.sendBatch(ctx, tenantId, b)
+// sendAndReport attempts to send the batch for the given tenant. If the attempt failed and should be retried, the batch
+// is put back in the queue. Otherwise, either way that operation succeeded or failed, the data is reported as sent.
+func (q *queue) sendAndReport(ctx context.Context, tq *tenantQueue, qb *queuedBatch) {
+	if delay, retry := q.client.sendBatch(ctx, qb); retry {
+		q.retry(tq, qb, delay)
+		return
+	}
+	q.sent(tq)
 	// mark segment data for that batch as sent, even if the send operation failed
-	b.reportAsSentData(q.client.markerHandler)
+	qb.Batch.reportAsSentData(q.client.markerHandler)
 }
 
 // closeNow closes the queue, without draining batches that might be buffered to be sent.
 func (q *queue) closeNow() {
 	close(q.quit)
 	q.wg.Wait()
-	close(q.q)
 }
 
 // queueClient is a WAL-specific remote write client implementation. This client attests to the wal.WriteTo interface,
@@ -443,65 +645,75 @@ func (c *queueClient) enqueuePendingBatches(ctx context.Context) {
 	}
 }
 
-func (c *queueClient) sendBatch(ctx context.Context, tenantID string, batch *batch) {
-	buf, entriesCount, err := batch.encode()
-	if err != nil {
-		level.Error(c.logger).Log("msg", "error encoding batch", "error", err)
-		return
+// sendBatch makes an attempt at sending the batch. If the attempt failed and the batch should be retried, it returns
+// true along with the delay to wait before the next attempt.
+func (c *queueClient) sendBatch(ctx context.Context, qb *queuedBatch) (time.Duration, bool) {
+	tenantID := qb.TenantID
+	if qb.encoded == nil {
+		buf, entriesCount, err := encodeBatch(qb.Batch, c.cfg.Protocol)
+		if err != nil {
+			level.Error(c.logger).Log("msg", "error encoding batch", "error", err)
+			return 0, false
+		}
+		qb.encoded, qb.entriesCount = buf, entriesCount
+		c.metrics.encodedBytes.WithLabelValues(c.cfg.URL.Host, tenantID).Add(float64(len(buf)))
 	}
-	bufBytes := float64(len(buf))
-	c.metrics.encodedBytes.WithLabelValues(c.cfg.URL.Host, tenantID).Add(bufBytes)
+	bufBytes := float64(len(qb.encoded))
+	entriesCount := qb.entriesCount
 
-	backoff := backoff.New(c.ctx, c.cfg.BackoffConfig)
-	var status int
-	for {
-		start := time.Now()
-		// send uses `timeout` internally, so `context.Background` is good enough.
-		status, err = c.send(ctx, tenantID, buf)
+	start := time.Now()
+	status, err := c.send(ctx, tenantID, qb.encoded)
 
-		c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host, tenantID).Observe(time.Since(start).Seconds())
+	c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host, tenantID).Observe(time.Since(start).Seconds())
 
-		// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
-		if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
-			level.Warn(c.logger).Log("msg", "dropping batch due to rate limiting applied at ingester")
-			c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(bufBytes)
-			c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(float64(entriesCount))
-			return
-		}
+	// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
+	if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
+		level.Warn(c.logger).Log("msg", "dropping batch due to rate limiting applied at ingester")
+		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(bufBytes)
+		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(float64(entriesCount))
+		return 0, false
+	}
 
-		if err == nil {
-			c.metrics.sentBytes.WithLabelValues(c.cfg.URL.Host, tenantID).Add(bufBytes)
-			c.metrics.sentEntries.WithLabelValues(c.cfg.URL.Host, tenantID).Add(float64(entriesCount))
+	if err == nil {
+		c.metrics.sentBytes.WithLabelValues(c.cfg.URL.Host, tenantID).Add(bufBytes)
+		c.metrics.sentEntries.WithLabelValues(c.cfg.URL.Host, tenantID).Add(float64(entriesCount))
 
-			return
-		}
+		return 0, false
+	}
 
-		// Only retry 429s, 500s and connection-level errors.
-		if status > 0 && !batchIsRateLimited(status) && status/100 != 5 {
-			break
+	// Only retry 429s, 500s and connection-level errors.
+	if status <= 0 || batchIsRateLimited(status) || status/100 == 5 {
+		if qb.backoff == nil {
+			qb.backoff = backoff.New(c.ctx, c.cfg.BackoffConfig)
 		}
-
-		level.Warn(c.logger).Log("msg", "error sending batch, will retry", "status", status, "tenant", tenantID, "error", err)
-		c.metrics.batchRetries.WithLabelValues(c.cfg.URL.Host, tenantID).Inc()
-		backoff.Wait()
+		delay := qb.backoff.NextDelay()
 
 		// Make sure it sends at least once before checking for retry.
-		if !backoff.Ongoing() {
-			break
+		if qb.backoff.Ongoing() {
+			level.Warn(c.logger).Log("msg", "error sending batch, will retry", "status", status, "tenant", tenantID, "error", err)
+			c.metrics.batchRetries.WithLabelValues(c.cfg.URL.Host, tenantID).Inc()
+			return delay, true
 		}
 	}
 
-	if err != nil {
-		level.Error(c.logger).Log("msg", "final error sending batch", "status", status, "tenant", tenantID, "error", err)
-		// If the reason for the last retry error was rate limiting, count the drops as such, even if the previous errors
-		// were for a different reason
-		dropReason := ReasonGeneric
-		if batchIsRateLimited(status) {
-			dropReason = ReasonRateLimited
-		}
-		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(bufBytes)
-		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(float64(entriesCount))
+	level.Error(c.logger).Log("msg", "final error sending batch", "status", status, "tenant", tenantID, "error", err)
+	// If the reason for the last retry error was rate limiting, count the drops as such, even if the previous errors
+	// were for a different reason
+	dropReason := ReasonGeneric
+	if batchIsRateLimited(status) {
+		dropReason = ReasonRateLimited
+	}
+	c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(bufBytes)
+	c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(float64(entriesCount))
+	return 0, false
+}
+
+// tenantRateBurst returns the number of bytes a tenant can send at once when tenants are rate limited.
+func (c *queueClient) tenantRateBurst() int {
+	if c.cfg.Queue.TenantRateBurst > 0 {
+		return c.cfg.Queue.TenantRateBurst
 	}
+	return c.cfg.BatchSize
 }
 
 func (c *queueClient) send(ctx context.Context, tenantID string, buf []byte) (int, error) {
@@ -512,7 +724,7 @@ func (c *queueClient) send(ctx context.Context, tenantID string, buf []byte) (in
 		return -1, err
 	}
 	req = req.WithContext(ctx)
-	req.Header.Set("Content-Type", contentType)
+	setContentHeaders(req.Header, c.cfg.Protocol)
 	req.Header.Set("User-Agent", userAgent)
 
 	// If the tenant ID is not empty promtail is running in multi-tenant mode, so
@@ -1,7 +1,10 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -11,6 +14,7 @@ import (
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
@@ -182,6 +186,207 @@ func TestQueueClient(t *testing.T) {
 	}
 }
 
+func TestQueueClient_ThrottledTenantDoesNotBlockOthers(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	var noisyAttempts atomic.Int64
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		tenantID := req.Header.Get("X-Scope-OrgID")
+		if tenantID == "noisy" {
+			noisyAttempts.Inc()
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		received <- tenantID
+	}))
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	cfg := Config{
+		URL:       serverURL,
+		BatchWait: 10 * time.Millisecond,
+		BatchSize: 10,
+		// Throttled batches are retried much later than the test lasts.
+		BackoffConfig: backoff.Config{MinBackoff: time.Hour, MaxBackoff: 2 * time.Hour, MaxRetries: 3},
+		Timeout:       time.Second,
+		Queue: QueueConfig{
+			Capacity:     100,
+			DrainTimeout: time.Second,
+		},
+	}
+
+	qcMetrics := NewQueueClientMetrics(reg).CurryWithId("test")
+	qc, err := newQueueClient(NewMetrics(reg), qcMetrics, cfg, 0, 0, false, log.NewNopLogger(), nilMarkerHandler{})
+	require.NoError(t, err)
+	defer qc.StopNow()
+
+	appendTenantEntries(qc, "noisy", 0, "noisy 1", "noisy 2", "noisy 3")
+	require.Eventually(t, func() bool {
+		return noisyAttempts.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	appendTenantEntries(qc, "quiet", 1, "quiet 1")
+	select {
+	case tenantID := <-received:
+		require.Equal(t, "quiet", tenantID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch of the quiet tenant was blocked by the throttled tenant")
+	}
+
+	// The noisy tenant is only attempted once until its backoff expires.
+	require.Equal(t, int64(1), noisyAttempts.Load())
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(qcMetrics.tenantQueuedBatches.WithLabelValues("noisy")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestQueueClient_TenantRateLimit(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	receivedReqs := utils.NewSyncSlice[string]()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		receivedReqs.Append(req.Header.Get("X-Scope-OrgID"))
+	}))
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	cfg := Config{
+		URL:           serverURL,
+		BatchWait:     10 * time.Millisecond,
+		BatchSize:     10,
+		BackoffConfig: backoff.Config{MinBackoff: time.Second, MaxBackoff: time.Second, MaxRetries: 1},
+		Timeout:       time.Second,
+		Queue: QueueConfig{
+			Capacity:        100,
+			DrainTimeout:    time.Second,
+			TenantRateLimit: 10,
+		},
+	}
+
+	qcMetrics := NewQueueClientMetrics(reg).CurryWithId("test")
+	qc, err := newQueueClient(NewMetrics(reg), qcMetrics, cfg, 0, 0, false, log.NewNopLogger(), nilMarkerHandler{})
+	require.NoError(t, err)
+	defer qc.StopNow()
+
+	// Each line is a batch of 7 bytes, so the tenant can only send a batch every 0.7s once it used its burst.
+	appendTenantEntries(qc, "a", 0, "limit 1", "limit 2", "limit 3")
+	appendTenantEntries(qc, "b", 1, "other 1")
+
+	require.Eventually(t, func() bool {
+		return receivedReqs.Length() == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	tenants := receivedReqs.StartIterate()
+	require.Equal(t, "b", tenants[1], "tenant b should not wait for the rate limit of tenant a")
+	receivedReqs.DoneIterate()
+	require.Positive(t, testutil.ToFloat64(qcMetrics.tenantRateLimited.WithLabelValues("a")))
+	require.Zero(t, testutil.ToFloat64(qcMetrics.tenantRateLimited.WithLabelValues("b")))
+}
+
+func TestQueue_RoundRobin(t *testing.T) {
+	q := &queue{
+		client: &queueClient{
+			qcMetrics: NewQueueClientMetrics(nil).CurryWithId("test"),
+		},
+		capacity: 10,
+		quit:     make(chan struct{}),
+		tenants:  make(map[string]*tenantQueue),
+		notify:   make(chan struct{}, 1),
+		freed:    make(chan struct{}),
+	}
+	for _, tenantID := range []string{"a", "a", "a", "b", "c"} {
+		require.True(t, q.enqueueWithCancel(context.Background(), queuedBatch{TenantID: tenantID, Batch: newBatch(0)}))
+	}
+
+	var order []string
+	for {
+		tq, qb, _ := q.dequeue(time.Now())
+		if qb == nil {
+			break
+		}
+		order = append(order, tq.id)
+	}
+	require.Equal(t, []string{"a", "b", "c", "a", "a"}, order)
+
+	// A batch that's retried isn't sent before its delay.
+	require.True(t, q.enqueueWithCancel(context.Background(), queuedBatch{TenantID: "a", Batch: newBatch(0)}))
+	tq, qb, _ := q.dequeue(time.Now())
+	q.retry(tq, qb, time.Hour)
+	require.True(t, q.enqueueWithCancel(context.Background(), queuedBatch{TenantID: "b", Batch: newBatch(0)}))
+
+	tq, qb, _ = q.dequeue(time.Now())
+	require.NotNil(t, qb)
+	require.Equal(t, "b", tq.id)
+	_, qb, wait := q.dequeue(time.Now())
+	require.Nil(t, qb)
+	require.InDelta(t, time.Hour, wait, float64(time.Minute))
+}
+
+func TestQueue_EvictIdleTenants(t *testing.T) {
+	q := &queue{
+		client: &queueClient{
+			qcMetrics: NewQueueClientMetrics(nil).CurryWithId("test"),
+		},
+		capacity: 10,
+		quit:     make(chan struct{}),
+		tenants:  make(map[string]*tenantQueue),
+		notify:   make(chan struct{}, 1),
+		freed:    make(chan struct{}),
+	}
+	for _, tenantID := range []string{"a", "b", "c", "c"} {
+		require.True(t, q.enqueueWithCancel(context.Background(), queuedBatch{TenantID: tenantID, Batch: newBatch(0)}))
+	}
+	tqA, _, _ := q.dequeue(time.Now())
+	q.sent(tqA)
+	tqB, _, _ := q.dequeue(time.Now())
+	require.Equal(t, "b", tqB.id)
+	q.client.qcMetrics.tenantRateLimited.WithLabelValues("a").Inc()
+
+	// Tenants which were recently active are kept.
+	q.evictIdleTenants(time.Now())
+	require.Len(t, q.tenants, 3)
+
+	// Idle tenants are evicted along with their metrics, but not the tenants with batches to send or being sent.
+	q.evictIdleTenants(time.Now().Add(tenantIdleTimeout))
+	require.Len(t, q.order, 2)
+	require.NotContains(t, q.tenants, "a")
+	require.Equal(t, 2, testutil.CollectAndCount(q.client.qcMetrics.tenantQueuedBatches))
+	require.Zero(t, testutil.CollectAndCount(q.client.qcMetrics.tenantRateLimited))
+
+	tq, qb, _ := q.dequeue(time.Now())
+	require.NotNil(t, qb)
+	require.Equal(t, "c", tq.id)
+	q.sent(tqB)
+	q.sent(tq)
+
+	// Evicted tenants are added back when they have new batches.
+	require.True(t, q.enqueueWithCancel(context.Background(), queuedBatch{TenantID: "a", Batch: newBatch(0)}))
+	tq, qb, _ = q.dequeue(time.Now())
+	require.NotNil(t, qb)
+	require.Equal(t, "c", tq.id)
+	tq, qb, _ = q.dequeue(time.Now())
+	require.NotNil(t, qb)
+	require.Equal(t, "a", tq.id)
+}
+
+// appendTenantEntries appends entries to the queue client, in a series of the given tenant.
+func appendTenantEntries(qc *queueClient, tenantID string, ref chunks.HeadSeriesRef, lines ...string) {
+	qc.StoreSeries([]record.RefSeries{{
+		Labels: labels.FromStrings("app", "test", ReservedLabelTenantID, tenantID),
+		Ref:    ref,
+	}}, 0)
+	for _, line := range lines {
+		_ = qc.AppendEntries(wal.RefEntries{
+			Ref:     ref,
+			Entries: []logproto.Entry{{Timestamp: time.Now(), Line: line}},
+		}, 0)
+	}
+}
+
 func BenchmarkClientImplementations(b *testing.B) {
 	for name, bc := range map[string]testCase{
 		"100 entries, single series, no batching": {
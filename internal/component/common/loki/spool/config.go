@@ -0,0 +1,49 @@
+package spool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alecthomas/units"
+)
+
+// Modes of a spool once it reaches its maximum size.
+const (
+	// ModeBlock blocks the source until space is freed.
+	ModeBlock = "block"
+	// ModeDropOldest drops the oldest entries of the spool to make room for
+	// new ones.
+	ModeDropOldest = "drop_oldest"
+	// ModeDropNewest drops new entries.
+	ModeDropNewest = "drop_newest"
+)
+
+// DefaultArguments holds the default settings of a spool.
+var DefaultArguments = Arguments{
+	MaxSize: units.GiB,
+	Mode:    ModeBlock,
+}
+
+// Arguments configures the spool of a component.
+type Arguments struct {
+	MaxSize units.Base2Bytes `alloy:"max_size,attr,optional"`
+	Mode    string           `alloy:"mode,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.MaxSize <= 0 {
+		return errors.New("max_size must be greater than 0")
+	}
+	switch a.Mode {
+	case ModeBlock, ModeDropOldest, ModeDropNewest:
+	default:
+		return fmt.Errorf("unsupported mode %q, must be one of %q, %q or %q", a.Mode, ModeBlock, ModeDropOldest, ModeDropNewest)
+	}
+	return nil
+}
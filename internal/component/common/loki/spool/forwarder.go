@@ -0,0 +1,117 @@
+package spool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Forwarder forwards the entries of a source to its receivers, through a
+// spool when one is configured.
+type Forwarder struct {
+	logger  log.Logger
+	metrics *Metrics
+	dir     string
+
+	mut       sync.RWMutex
+	receivers []loki.LogsReceiver
+	enabled   bool
+	spool     *Spool
+	// opened is closed once the spool is opened.
+	opened chan struct{}
+}
+
+// NewForwarder creates a Forwarder which stores its spool in dir.
+func NewForwarder(logger log.Logger, reg prometheus.Registerer, dir string) *Forwarder {
+	return &Forwarder{
+		logger:  logger,
+		metrics: NewMetrics(reg),
+		dir:     dir,
+		opened:  make(chan struct{}),
+	}
+}
+
+// Update sets the receivers of the forwarder and the arguments of its spool.
+// The spool is opened the first time it is enabled. When it is disabled by
+// a nil args, new entries are forwarded directly, while the entries still in
+// the spool keep being forwarded from it.
+func (f *Forwarder) Update(receivers []loki.LogsReceiver, args *Arguments) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.receivers = receivers
+	f.enabled = args != nil
+	if args == nil {
+		return nil
+	}
+	if f.spool != nil {
+		f.spool.SetArguments(*args)
+		return nil
+	}
+
+	s, err := Open(f.logger, f.metrics, f.dir, *args)
+	if err != nil {
+		f.enabled = false
+		return err
+	}
+	f.spool = s
+	close(f.opened)
+	return nil
+}
+
+// Forward forwards the entry, writing it to the spool when it is enabled.
+// It returns an error only when ctx is canceled.
+func (f *Forwarder) Forward(ctx context.Context, e loki.Entry) error {
+	f.mut.RLock()
+	s, enabled := f.spool, f.enabled
+	f.mut.RUnlock()
+
+	if enabled {
+		err := s.Append(ctx, e)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		level.Warn(f.logger).Log("msg", "failed to write entry to spool, forwarding it directly", "err", err)
+	}
+	return f.send(ctx, e)
+}
+
+// send sends the entry to all the receivers.
+func (f *Forwarder) send(ctx context.Context, e loki.Entry) error {
+	f.mut.RLock()
+	receivers := f.receivers
+	f.mut.RUnlock()
+
+	for _, receiver := range receivers {
+		select {
+		case receiver.Chan() <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Run forwards the entries of the spool until ctx is canceled, and closes
+// the spool when it returns.
+func (f *Forwarder) Run(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-f.opened:
+	}
+
+	f.mut.RLock()
+	s := f.spool
+	f.mut.RUnlock()
+
+	s.Run(ctx, f.send)
+	if err := s.Close(); err != nil {
+		level.Warn(f.logger).Log("msg", "failed to close spool", "err", err)
+	}
+}
@@ -0,0 +1,72 @@
+package spool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+func TestForwarder(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	receiver := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, 10))
+	f := NewForwarder(log.NewNopLogger(), prometheus.NewRegistry(), dir)
+
+	// Without a spool, entries are sent directly.
+	require.NoError(t, f.Update([]loki.LogsReceiver{receiver}, nil))
+	require.NoError(t, f.Forward(ctx, testEntry(0)))
+	require.Equal(t, "line 00", (<-receiver.Chan()).Line)
+
+	// With a spool, entries are sent by Run.
+	require.NoError(t, f.Update([]loki.LogsReceiver{receiver}, &Arguments{MaxSize: units.MiB, Mode: ModeBlock}))
+	require.NoError(t, f.Forward(ctx, testEntry(1)))
+	require.NoError(t, f.Forward(ctx, testEntry(2)))
+	select {
+	case <-receiver.Chan():
+		t.Fatal("entry sent before Run")
+	default:
+	}
+
+	runCtx, stopRun := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Run(runCtx)
+	}()
+	require.Equal(t, "line 01", receiveLine(t, receiver))
+	require.Equal(t, "line 02", receiveLine(t, receiver))
+
+	// Entries which couldn't be sent before stopping are replayed by the
+	// next forwarder using the same directory.
+	blocked := loki.NewLogsReceiver()
+	require.NoError(t, f.Update([]loki.LogsReceiver{blocked}, &Arguments{MaxSize: units.MiB, Mode: ModeBlock}))
+	require.NoError(t, f.Forward(ctx, testEntry(3)))
+	stopRun()
+	<-done
+
+	f = NewForwarder(log.NewNopLogger(), prometheus.NewRegistry(), dir)
+	require.NoError(t, f.Update([]loki.LogsReceiver{receiver}, &Arguments{MaxSize: units.MiB, Mode: ModeBlock}))
+	go f.Run(ctx)
+	require.Equal(t, "line 03", receiveLine(t, receiver))
+}
+
+func receiveLine(t *testing.T, receiver loki.LogsReceiver) string {
+	select {
+	case e := <-receiver.Chan():
+		return e.Line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry received")
+		return ""
+	}
+}
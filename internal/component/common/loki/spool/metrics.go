@@ -0,0 +1,58 @@
+package spool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/util"
+)
+
+// Reasons for dropping entries.
+const (
+	reasonDropOldest = "drop_oldest"
+	reasonDropNewest = "drop_newest"
+	reasonTooLarge   = "too_large"
+	reasonCorrupted  = "corrupted"
+)
+
+// Metrics holds the metrics of a spool.
+type Metrics struct {
+	sizeBytes      prometheus.Gauge
+	entriesWritten prometheus.Counter
+	entriesSent    prometheus.Counter
+	entriesDropped *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics of a spool and registers them with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		sizeBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "loki_source_spool",
+			Name:      "size_bytes",
+			Help:      "Size of the entries in the spool which have not been forwarded yet",
+		}),
+		entriesWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "loki_source_spool",
+			Name:      "entries_written_total",
+			Help:      "Number of entries written to the spool",
+		}),
+		entriesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "loki_source_spool",
+			Name:      "entries_sent_total",
+			Help:      "Number of entries of the spool forwarded to the receivers",
+		}),
+		entriesDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "loki_source_spool",
+			Name:      "entries_dropped_total",
+			Help:      "Number of entries dropped by the spool",
+		}, []string{"reason"}),
+	}
+
+	if reg != nil {
+		m.sizeBytes = util.MustRegisterOrGet(reg, m.sizeBytes).(prometheus.Gauge)
+		m.entriesWritten = util.MustRegisterOrGet(reg, m.entriesWritten).(prometheus.Counter)
+		m.entriesSent = util.MustRegisterOrGet(reg, m.entriesSent).(prometheus.Counter)
+		m.entriesDropped = util.MustRegisterOrGet(reg, m.entriesDropped).(*prometheus.CounterVec)
+	}
+
+	return m
+}
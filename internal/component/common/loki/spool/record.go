@@ -0,0 +1,134 @@
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/grafana/loki/v3/pkg/logproto"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+// A record is made of a header holding the length and the CRC32 checksum of
+// its payload, followed by the payload which holds the encoded entry.
+const recordHeaderSize = 8
+
+var (
+	castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptedRecord = errors.New("corrupted spool record")
+)
+
+// encodeRecord encodes the entry as a record.
+func encodeRecord(e loki.Entry) []byte {
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+64+len(e.Line))
+	buf = binary.AppendUvarint(buf, uint64(len(e.Labels)))
+	for name, value := range e.Labels {
+		buf = appendString(buf, string(name))
+		buf = appendString(buf, string(value))
+	}
+	buf = binary.AppendVarint(buf, e.Timestamp.UnixNano())
+	buf = appendString(buf, e.Line)
+	buf = binary.AppendUvarint(buf, uint64(len(e.StructuredMetadata)))
+	for _, md := range e.StructuredMetadata {
+		buf = appendString(buf, md.Name)
+		buf = appendString(buf, md.Value)
+	}
+
+	payload := buf[recordHeaderSize:]
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, castagnoliTable))
+	return buf
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// decodeRecordHeader returns the length of the payload and its checksum.
+func decodeRecordHeader(header []byte) (int, uint32) {
+	return int(binary.BigEndian.Uint32(header[0:4])), binary.BigEndian.Uint32(header[4:8])
+}
+
+// decodePayload verifies the payload against its checksum and decodes the
+// entry it holds.
+func decodePayload(payload []byte, checksum uint32) (loki.Entry, error) {
+	if crc32.Checksum(payload, castagnoliTable) != checksum {
+		return loki.Entry{}, errCorruptedRecord
+	}
+
+	d := decoder{buf: payload}
+	var e loki.Entry
+	if n := d.uvarint(); n > 0 && d.err == nil {
+		e.Labels = make(model.LabelSet, min(n, uint64(len(payload))))
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			name := d.string()
+			e.Labels[model.LabelName(name)] = model.LabelValue(d.string())
+		}
+	}
+	e.Timestamp = time.Unix(0, d.varint())
+	e.Line = d.string()
+	if n := d.uvarint(); n > 0 && d.err == nil {
+		e.StructuredMetadata = make(push.LabelsAdapter, 0, min(n, uint64(len(payload))))
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			name := d.string()
+			e.StructuredMetadata = append(e.StructuredMetadata, logproto.LabelAdapter{Name: name, Value: d.string()})
+		}
+	}
+	if d.err != nil || len(d.buf) > 0 {
+		return loki.Entry{}, errCorruptedRecord
+	}
+	return e, nil
+}
+
+// decoder reads the fields of a payload, and remembers the first error.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errCorruptedRecord
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errCorruptedRecord
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	l := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if l > uint64(len(d.buf)) {
+		d.err = errCorruptedRecord
+		return ""
+	}
+	s := string(d.buf[:l])
+	d.buf = d.buf[l:]
+	return s
+}
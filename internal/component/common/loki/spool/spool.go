@@ -0,0 +1,646 @@
+package spool
+
+// The spool package implements a disk-backed buffer between a source of log
+// entries and the receivers it forwards them to. Entries are appended to
+// segment files in the data directory of the component, and forwarded in
+// order by a single reader. The position of the first entry which has not
+// been forwarded yet is persisted periodically, so that the remaining entries
+// are replayed after a restart. Entries are forwarded at least once: those
+// forwarded since the last persisted position are forwarded again.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	// defaultSegmentSize is the size at which a segment is closed and a new
+	// one is started. Smaller segments are used for small spools, so that
+	// forwarded entries are removed from disk in a timely manner.
+	defaultSegmentSize = 8 << 20
+
+	positionFileName = "position"
+	segmentFileMode  = 0600
+	syncPeriod       = time.Second
+	readRetryPeriod  = time.Second
+)
+
+var errClosed = errors.New("spool is closed")
+
+// position is the position of a record in the spool.
+type position struct {
+	segment int
+	offset  int64
+}
+
+// Spool is a disk-backed FIFO of log entries.
+type Spool struct {
+	logger  log.Logger
+	metrics *Metrics
+	dir     string
+
+	mut         sync.Mutex
+	args        Arguments
+	segmentSize int64
+	closed      bool
+
+	// first is the first segment which may still be on disk.
+	first  int
+	writer *os.File
+	write  position
+	reader *os.File
+	// read is the position of the next record to read, and ack the position
+	// of the first record which has not been forwarded yet.
+	read position
+	ack  position
+	// dirty is set when ack changed since the position was last persisted.
+	dirty bool
+	// inflight is the size of the record being forwarded, and size the size
+	// of all the records which have not been forwarded yet.
+	inflight int64
+	size     int64
+
+	// notify is signalled when a record is written, and freed is closed and
+	// renewed when records are removed from the spool.
+	notify chan struct{}
+	freed  chan struct{}
+}
+
+// Open opens the spool stored in dir, creating it if it doesn't exist.
+// Entries left in the spool by a previous run are forwarded first.
+func Open(logger log.Logger, metrics *Metrics, dir string, args Arguments) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		logger:  logger,
+		metrics: metrics,
+		dir:     dir,
+		notify:  make(chan struct{}, 1),
+		freed:   make(chan struct{}),
+	}
+	s.setArguments(args)
+	if err := s.recover(); err != nil {
+		s.closeFiles()
+		return nil, err
+	}
+	s.metrics.sizeBytes.Set(float64(s.size))
+	return s, nil
+}
+
+// recover restores the state of the spool from the files in its directory.
+func (s *Spool) recover() error {
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return err
+	}
+	ack, ok, err := readPosition(s.dir)
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to read spool position, replaying all the entries", "err", err)
+	}
+	if !ok && len(segments) > 0 {
+		ack = position{segment: segments[0]}
+	}
+
+	// Segments before the position have been forwarded already.
+	remaining := segments[:0]
+	for _, segment := range segments {
+		if segment < ack.segment {
+			if err := os.Remove(segmentPath(s.dir, segment)); err != nil {
+				return err
+			}
+			continue
+		}
+		remaining = append(remaining, segment)
+	}
+	if len(remaining) == 0 {
+		ack = position{segment: ack.segment}
+		remaining = append(remaining, ack.segment)
+	} else if remaining[0] != ack.segment {
+		ack = position{segment: remaining[0]}
+	}
+
+	for _, segment := range remaining[:len(remaining)-1] {
+		fi, err := os.Stat(segmentPath(s.dir, segment))
+		if err != nil {
+			return err
+		}
+		s.size += fi.Size()
+	}
+
+	// A crash may have left a partially written record at the end of the last
+	// segment, which is truncated before appending to it.
+	last := remaining[len(remaining)-1]
+	end, err := repairSegment(segmentPath(s.dir, last))
+	if err != nil {
+		return err
+	}
+	s.size += end
+	if ack.segment == last && ack.offset > end {
+		ack.offset = end
+	}
+	s.size -= ack.offset
+
+	s.writer, err = os.OpenFile(segmentPath(s.dir, last), os.O_CREATE|os.O_WRONLY|os.O_APPEND, segmentFileMode)
+	if err != nil {
+		return err
+	}
+	s.reader, err = os.Open(segmentPath(s.dir, ack.segment))
+	if err != nil {
+		return err
+	}
+	s.first = ack.segment
+	s.write = position{segment: last, offset: end}
+	s.read = ack
+	s.ack = ack
+	return nil
+}
+
+// SetArguments updates the arguments of the spool.
+func (s *Spool) SetArguments(args Arguments) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.setArguments(args)
+	// Wake up the writers blocked on the previous arguments.
+	s.signalFreed()
+}
+
+func (s *Spool) setArguments(args Arguments) {
+	s.args = args
+	s.segmentSize = min(defaultSegmentSize, int64(args.MaxSize)/4)
+}
+
+// Append writes the entry to the spool. When the spool is full, Append
+// blocks, drops the oldest entries of the spool, or drops the entry,
+// depending on the mode of the spool.
+func (s *Spool) Append(ctx context.Context, e loki.Entry) error {
+	rec := encodeRecord(e)
+	n := int64(len(rec))
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for {
+		if s.closed {
+			return errClosed
+		}
+		maxSize := int64(s.args.MaxSize)
+		if n > maxSize {
+			level.Warn(s.logger).Log("msg", "dropping entry larger than the spool", "size", n, "max_size", maxSize)
+			s.metrics.entriesDropped.WithLabelValues(reasonTooLarge).Inc()
+			return nil
+		}
+		if s.size+n <= maxSize {
+			break
+		}
+
+		switch s.args.Mode {
+		case ModeDropOldest:
+			dropped, err := s.dropOldest(s.size + n - maxSize)
+			if err != nil {
+				return err
+			}
+			if dropped {
+				continue
+			}
+			// Only the entry being forwarded is left, so there is no room for
+			// the new entry.
+			s.metrics.entriesDropped.WithLabelValues(reasonDropNewest).Inc()
+			return nil
+		case ModeDropNewest:
+			s.metrics.entriesDropped.WithLabelValues(reasonDropNewest).Inc()
+			return nil
+		default:
+			freed := s.freed
+			s.mut.Unlock()
+			select {
+			case <-freed:
+			case <-ctx.Done():
+				s.mut.Lock()
+				return ctx.Err()
+			}
+			s.mut.Lock()
+		}
+	}
+
+	if s.write.offset > 0 && s.write.offset >= s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(rec); err != nil {
+		// Don't leave a partial record behind, the next one would be
+		// appended after it.
+		_ = s.writer.Truncate(s.write.offset)
+		return err
+	}
+	s.write.offset += n
+	s.size += n
+	s.metrics.entriesWritten.Inc()
+	s.metrics.sizeBytes.Set(float64(s.size))
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// rotate closes the current segment and starts a new one.
+func (s *Spool) rotate() error {
+	next := s.write.segment + 1
+	f, err := os.OpenFile(segmentPath(s.dir, next), os.O_CREATE|os.O_WRONLY|os.O_APPEND, segmentFileMode)
+	if err != nil {
+		return err
+	}
+	if err := s.writer.Sync(); err != nil {
+		level.Warn(s.logger).Log("msg", "failed to sync spool segment", "segment", s.write.segment, "err", err)
+	}
+	s.writer.Close()
+	s.writer = f
+	s.write = position{segment: next}
+	return nil
+}
+
+// dropOldest drops records which haven't been read yet until at least need
+// bytes are freed, and returns whether any record was dropped.
+func (s *Spool) dropOldest(need int64) (bool, error) {
+	dropped := false
+	for need > 0 {
+		_, _, n, ok, err := s.readRecord(false)
+		if err != nil {
+			return dropped, err
+		}
+		if !ok {
+			break
+		}
+		s.size -= n
+		need -= n
+		dropped = true
+		s.metrics.entriesDropped.WithLabelValues(reasonDropOldest).Inc()
+	}
+	if dropped && s.inflight == 0 {
+		s.setAck(s.read)
+	}
+	s.metrics.sizeBytes.Set(float64(s.size))
+	return dropped, nil
+}
+
+// readRecord reads the next record of the spool, and returns its payload
+// when withPayload is set, its checksum and its size. ok is false when there
+// is no record to read.
+func (s *Spool) readRecord(withPayload bool) (payload []byte, checksum uint32, n int64, ok bool, err error) {
+	for {
+		end, err := s.segmentEnd()
+		if err != nil {
+			return nil, 0, 0, false, err
+		}
+		if s.read.segment == s.write.segment && s.read.offset >= end {
+			return nil, 0, 0, false, nil
+		}
+
+		var header [recordHeaderSize]byte
+		if s.read.offset+recordHeaderSize > end {
+			if err := s.skipSegment(end); err != nil {
+				return nil, 0, 0, false, err
+			}
+			continue
+		}
+		if _, err := s.reader.ReadAt(header[:], s.read.offset); err != nil {
+			return nil, 0, 0, false, err
+		}
+		l, checksum := decodeRecordHeader(header[:])
+		n := int64(recordHeaderSize + l)
+		if s.read.offset+n > end {
+			if err := s.skipSegment(end); err != nil {
+				return nil, 0, 0, false, err
+			}
+			continue
+		}
+		if withPayload {
+			payload = make([]byte, l)
+			if _, err := s.reader.ReadAt(payload, s.read.offset+recordHeaderSize); err != nil {
+				return nil, 0, 0, false, err
+			}
+		}
+		s.read.offset += n
+		return payload, checksum, n, true, nil
+	}
+}
+
+// segmentEnd returns the size of the segment being read.
+func (s *Spool) segmentEnd() (int64, error) {
+	if s.read.segment == s.write.segment {
+		return s.write.offset, nil
+	}
+	fi, err := s.reader.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// skipSegment skips the rest of the segment being read, which ends at end,
+// and moves to the next segment. A partial record at the end of a segment is
+// dropped.
+func (s *Spool) skipSegment(end int64) error {
+	if end > s.read.offset {
+		level.Warn(s.logger).Log("msg", "dropping corrupted end of spool segment", "segment", s.read.segment, "offset", s.read.offset)
+		s.metrics.entriesDropped.WithLabelValues(reasonCorrupted).Inc()
+		s.size -= end - s.read.offset
+	}
+	if s.read.segment == s.write.segment {
+		s.read.offset = end
+		return nil
+	}
+
+	for next := s.read.segment + 1; next <= s.write.segment; next++ {
+		f, err := os.Open(segmentPath(s.dir, next))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.reader.Close()
+		s.reader = f
+		s.read = position{segment: next}
+		return nil
+	}
+	return fmt.Errorf("spool segment %d is missing", s.write.segment)
+}
+
+// setAck sets the position of the first record which hasn't been forwarded
+// yet, and removes the segments before it.
+func (s *Spool) setAck(pos position) {
+	s.ack = pos
+	s.dirty = true
+	for ; s.first < s.ack.segment; s.first++ {
+		if err := os.Remove(segmentPath(s.dir, s.first)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			level.Warn(s.logger).Log("msg", "failed to remove spool segment", "segment", s.first, "err", err)
+		}
+	}
+}
+
+func (s *Spool) signalFreed() {
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
+
+// next returns the next entry to forward, waiting for one to be written if
+// the spool is empty.
+func (s *Spool) next(ctx context.Context) (loki.Entry, error) {
+	for {
+		s.mut.Lock()
+		if s.closed {
+			s.mut.Unlock()
+			return loki.Entry{}, errClosed
+		}
+		payload, checksum, n, ok, err := s.readRecord(true)
+		if err != nil {
+			s.mut.Unlock()
+			return loki.Entry{}, err
+		}
+		if ok {
+			e, err := decodePayload(payload, checksum)
+			if err == nil {
+				s.inflight = n
+				s.mut.Unlock()
+				return e, nil
+			}
+			level.Warn(s.logger).Log("msg", "dropping corrupted spool record", "segment", s.read.segment, "err", err)
+			s.metrics.entriesDropped.WithLabelValues(reasonCorrupted).Inc()
+			s.size -= n
+			s.setAck(s.read)
+			s.mut.Unlock()
+			continue
+		}
+		s.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return loki.Entry{}, ctx.Err()
+		case <-s.notify:
+		}
+	}
+}
+
+// forwarded marks the entry returned by next as forwarded.
+func (s *Spool) forwarded() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.size -= s.inflight
+	s.inflight = 0
+	s.setAck(s.read)
+	s.metrics.entriesSent.Inc()
+	s.metrics.sizeBytes.Set(float64(s.size))
+	s.signalFreed()
+}
+
+// Run forwards the entries of the spool in order with forward, until ctx is
+// canceled or the spool is closed. An entry is removed from the spool once
+// forward returns without an error. Run must be called only once.
+func (s *Spool) Run(ctx context.Context, forward func(context.Context, loki.Entry) error) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(syncPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.sync(); err != nil && !errors.Is(err, errClosed) {
+					level.Warn(s.logger).Log("msg", "failed to sync spool", "err", err)
+				}
+			}
+		}
+	}()
+
+	for {
+		e, err := s.next(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, errClosed) {
+				return
+			}
+			level.Error(s.logger).Log("msg", "failed to read from spool", "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(readRetryPeriod):
+			}
+			continue
+		}
+		if err := forward(ctx, e); err != nil {
+			// The entry stays in the spool, and is forwarded again after a
+			// restart.
+			return
+		}
+		s.forwarded()
+	}
+}
+
+// sync flushes the current segment to disk and persists the position of the
+// first entry which hasn't been forwarded yet.
+func (s *Spool) sync() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	return s.syncLocked()
+}
+
+func (s *Spool) syncLocked() error {
+	if err := s.writer.Sync(); err != nil {
+		return err
+	}
+	if !s.dirty {
+		return nil
+	}
+	if err := writePosition(s.dir, s.ack); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Close syncs and closes the spool. Entries which haven't been forwarded yet
+// are kept on disk.
+func (s *Spool) Close() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.syncLocked()
+	s.closeFiles()
+
+	// Wake up the blocked writers and the reader.
+	s.signalFreed()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+func (s *Spool) closeFiles() {
+	if s.writer != nil {
+		s.writer.Close()
+	}
+	if s.reader != nil {
+		s.reader.Close()
+	}
+}
+
+// Size returns the size of the entries which haven't been forwarded yet.
+func (s *Spool) Size() int64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.size
+}
+
+func segmentPath(dir string, segment int) string {
+	return filepath.Join(dir, fmt.Sprintf("%08d", segment))
+}
+
+// listSegments returns the segments in dir in ascending order.
+func listSegments(dir string) ([]int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []int
+	for _, f := range files {
+		segment, err := strconv.Atoi(f.Name())
+		if err != nil || f.IsDir() {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	slices.Sort(segments)
+	return segments, nil
+}
+
+// repairSegment truncates the segment after its last complete record, and
+// returns its size.
+func repairSegment(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, segmentFileMode)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		offset int64
+		header [recordHeaderSize]byte
+	)
+	for offset+recordHeaderSize <= fi.Size() {
+		if _, err := f.ReadAt(header[:], offset); err != nil {
+			return 0, err
+		}
+		l, _ := decodeRecordHeader(header[:])
+		if offset+recordHeaderSize+int64(l) > fi.Size() {
+			break
+		}
+		offset += recordHeaderSize + int64(l)
+	}
+	if offset < fi.Size() {
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
+
+func readPosition(dir string) (position, bool, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, positionFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return position{}, false, nil
+	}
+	if err != nil {
+		return position{}, false, err
+	}
+	var pos position
+	if _, err := fmt.Sscanf(string(buf), "%d %d", &pos.segment, &pos.offset); err != nil {
+		return position{}, false, err
+	}
+	if pos.segment < 0 || pos.offset < 0 {
+		return position{}, false, fmt.Errorf("invalid spool position %q", buf)
+	}
+	return pos, true, nil
+}
+
+// writePosition atomically replaces the position file.
+func writePosition(dir string, pos position) error {
+	path := filepath.Join(dir, positionFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(fmt.Sprintf("%d %d\n", pos.segment, pos.offset)), segmentFileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
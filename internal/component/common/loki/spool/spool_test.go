@@ -0,0 +1,228 @@
+package spool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/grafana/loki/v3/pkg/logproto"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	e := loki.Entry{
+		Labels: model.LabelSet{"app": "a", "env": "prod"},
+		Entry: logproto.Entry{
+			Timestamp:          time.Unix(1, 42).UTC(),
+			Line:               "hello",
+			StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "abc"}},
+		},
+	}
+	rec := encodeRecord(e)
+	l, checksum := decodeRecordHeader(rec)
+	require.Equal(t, len(rec)-recordHeaderSize, l)
+
+	decoded, err := decodePayload(rec[recordHeaderSize:], checksum)
+	require.NoError(t, err)
+	decoded.Timestamp = decoded.Timestamp.UTC()
+	assert.Equal(t, e, decoded)
+
+	rec[len(rec)-1]++
+	_, err = decodePayload(rec[recordHeaderSize:], checksum)
+	require.ErrorIs(t, err, errCorruptedRecord)
+}
+
+func TestSpool_ReplayAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	args := Arguments{MaxSize: 2 * units.KiB, Mode: ModeBlock}
+	s := openSpool(t, dir, args)
+	for i := 0; i < 40; i++ {
+		require.NoError(t, s.Append(context.Background(), testEntry(i)))
+	}
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+
+	// Forward half of the entries, then stop.
+	received := forwardN(t, s, 20)
+	require.Equal(t, testLines(0, 20), received)
+	require.NoError(t, s.Close())
+
+	// The forwarded segments are removed, and the remaining entries are
+	// replayed after a restart.
+	s = openSpool(t, dir, args)
+	segmentsAfter, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Less(t, len(segmentsAfter), len(segments))
+
+	require.NoError(t, s.Append(context.Background(), testEntry(40)))
+	received = forwardN(t, s, 21)
+	require.Equal(t, testLines(20, 41), received)
+	require.Zero(t, s.Size())
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_TornRecord(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	args := Arguments{MaxSize: units.MiB, Mode: ModeBlock}
+	s := openSpool(t, dir, args)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Append(context.Background(), testEntry(i)))
+	}
+	require.NoError(t, s.Close())
+
+	// Simulate a crash while writing a record.
+	f, err := os.OpenFile(segmentPath(dir, 0), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write(encodeRecord(testEntry(99))[:12])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s = openSpool(t, dir, args)
+	require.NoError(t, s.Append(context.Background(), testEntry(3)))
+	require.Equal(t, testLines(0, 4), forwardN(t, s, 4))
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_CorruptedPosition(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	args := Arguments{MaxSize: units.MiB, Mode: ModeBlock}
+	s := openSpool(t, dir, args)
+	require.NoError(t, s.Append(context.Background(), testEntry(0)))
+	require.NoError(t, s.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, positionFileName), []byte("garbage"), 0600))
+
+	// All the entries are replayed.
+	s = openSpool(t, dir, args)
+	require.Equal(t, testLines(0, 1), forwardN(t, s, 1))
+	require.NoError(t, s.Close())
+}
+
+func TestSpool_Modes(t *testing.T) {
+	t.Parallel()
+
+	recordSize := units.Base2Bytes(len(encodeRecord(testEntry(0))))
+
+	t.Run("block", func(t *testing.T) {
+		t.Parallel()
+		s := openSpool(t, t.TempDir(), Arguments{MaxSize: 3 * recordSize, Mode: ModeBlock})
+		for i := 0; i < 3; i++ {
+			require.NoError(t, s.Append(context.Background(), testEntry(i)))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, s.Append(ctx, testEntry(3)), context.DeadlineExceeded)
+
+		done := make(chan error)
+		go func() {
+			done <- s.Append(context.Background(), testEntry(3))
+		}()
+		require.Equal(t, testLines(0, 1), forwardN(t, s, 1))
+		require.NoError(t, <-done)
+		require.Equal(t, testLines(1, 4), forwardN(t, s, 3))
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("drop_newest", func(t *testing.T) {
+		t.Parallel()
+		s := openSpool(t, t.TempDir(), Arguments{MaxSize: 3 * recordSize, Mode: ModeDropNewest})
+		for i := 0; i < 5; i++ {
+			require.NoError(t, s.Append(context.Background(), testEntry(i)))
+		}
+		require.Equal(t, 2.0, testutil.ToFloat64(s.metrics.entriesDropped.WithLabelValues(reasonDropNewest)))
+		require.Equal(t, testLines(0, 3), forwardN(t, s, 3))
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		t.Parallel()
+		s := openSpool(t, t.TempDir(), Arguments{MaxSize: 3 * recordSize, Mode: ModeDropOldest})
+		for i := 0; i < 5; i++ {
+			require.NoError(t, s.Append(context.Background(), testEntry(i)))
+		}
+		require.Equal(t, 2.0, testutil.ToFloat64(s.metrics.entriesDropped.WithLabelValues(reasonDropOldest)))
+		require.Equal(t, testLines(2, 5), forwardN(t, s, 3))
+		require.NoError(t, s.Close())
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		t.Parallel()
+		s := openSpool(t, t.TempDir(), Arguments{MaxSize: recordSize - 1, Mode: ModeBlock})
+		require.NoError(t, s.Append(context.Background(), testEntry(0)))
+		require.Equal(t, 1.0, testutil.ToFloat64(s.metrics.entriesDropped.WithLabelValues(reasonTooLarge)))
+		require.Zero(t, s.Size())
+		require.NoError(t, s.Close())
+	})
+}
+
+func TestArguments_Validate(t *testing.T) {
+	t.Parallel()
+
+	args := DefaultArguments
+	require.NoError(t, args.Validate())
+
+	args.Mode = "drop"
+	require.ErrorContains(t, args.Validate(), `unsupported mode "drop"`)
+
+	args = DefaultArguments
+	args.MaxSize = 0
+	require.ErrorContains(t, args.Validate(), "max_size must be greater than 0")
+}
+
+func openSpool(t *testing.T, dir string, args Arguments) *Spool {
+	s, err := Open(log.NewNopLogger(), NewMetrics(prometheus.NewRegistry()), dir, args)
+	require.NoError(t, err)
+	return s
+}
+
+func testEntry(i int) loki.Entry {
+	return loki.Entry{
+		Labels: model.LabelSet{"app": "test"},
+		Entry:  logproto.Entry{Timestamp: time.Unix(1700000000+int64(i), 0), Line: fmt.Sprintf("line %02d", i)},
+	}
+}
+
+func testLines(from, to int) []string {
+	var lines []string
+	for i := from; i < to; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d", i))
+	}
+	return lines
+}
+
+// forwardN forwards n entries of the spool, and returns their lines.
+func forwardN(t *testing.T, s *Spool, n int) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var lines []string
+	for len(lines) < n {
+		e, err := s.next(ctx)
+		require.NoError(t, err)
+		lines = append(lines, e.Line)
+		s.forwarded()
+	}
+	return lines
+}
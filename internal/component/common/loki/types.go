@@ -11,6 +11,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/grafana/loki/v3/pkg/logproto"
 )
@@ -61,6 +62,23 @@ func (e *Entry) Clone() Entry {
 	}
 }
 
+// LabelsWithMetadata returns the labels of the entry merged with its
+// structured metadata. Labels take precedence over structured metadata with
+// the same name.
+func (e *Entry) LabelsWithMetadata() labels.Labels {
+	b := labels.NewScratchBuilder(len(e.Labels) + len(e.StructuredMetadata))
+	for _, m := range e.StructuredMetadata {
+		if _, ok := e.Labels[model.LabelName(m.Name)]; !ok {
+			b.Add(m.Name, m.Value)
+		}
+	}
+	for name, value := range e.Labels {
+		b.Add(string(name), string(value))
+	}
+	b.Sort()
+	return b.Labels()
+}
+
 // InstrumentedEntryHandler ...
 type InstrumentedEntryHandler interface {
 	EntryHandler
@@ -8,6 +8,7 @@ import (
 
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
@@ -187,6 +188,8 @@ func (c *Component) runDiscovery(ctx context.Context, d DiscovererWithMetrics) {
 			livedebugging.Target,
 			uint64(len(allTargets)),
 			func() string { return fmt.Sprintf("%s", allTargets) },
+			livedebugging.WithLabels(func() []labels.Labels { return TargetsLabels(allTargets) }),
+			livedebugging.AsSnapshot(),
 		))
 		c.opts.OnStateChange(Exports{Targets: allTargets})
 	}
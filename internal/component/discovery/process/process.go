@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/alloy/internal/component/discovery"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 func init() {
@@ -74,6 +75,8 @@ func (c *Component) Run(ctx context.Context) error {
 			livedebugging.Target,
 			uint64(len(c.processes)),
 			func() string { return fmt.Sprintf("%s", c.processes) },
+			livedebugging.WithLabels(func() []labels.Labels { return discovery.TargetsLabels(c.processes) }),
+			livedebugging.AsSnapshot(),
 		))
 
 		return nil
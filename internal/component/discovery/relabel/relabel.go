@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/alloy/internal/component/discovery"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 func init() {
@@ -87,24 +88,22 @@ func (c *Component) Update(args component.Arguments) error {
 	targets := make([]discovery.Target, 0, len(newArgs.Targets))
 
 	for _, t := range newArgs.Targets {
-		var (
-			relabelled discovery.Target
-			builder    = discovery.NewTargetBuilderFrom(t)
-			keep       = alloy_relabel.ProcessBuilder(builder, newArgs.RelabelConfigs...)
-		)
-		if keep {
-			relabelled = builder.Target()
-			targets = append(targets, relabelled)
+		builder := discovery.NewTargetBuilderFrom(t)
+		if alloy_relabel.ProcessBuilder(builder, newArgs.RelabelConfigs...) {
+			targets = append(targets, builder.Target())
 		}
-		componentID := livedebugging.ComponentID(c.opts.ID)
-		c.debugDataPublisher.PublishIfActive(livedebugging.NewData(
-			componentID,
-			livedebugging.Target,
-			1,
-			func() string { return fmt.Sprintf("%s => %s", t, relabelled) },
-		))
 	}
 
+	componentID := livedebugging.ComponentID(c.opts.ID)
+	c.debugDataPublisher.PublishIfActive(livedebugging.NewData(
+		componentID,
+		livedebugging.Target,
+		uint64(len(targets)),
+		func() string { return fmt.Sprintf("%s", targets) },
+		livedebugging.WithLabels(func() []labels.Labels { return discovery.TargetsLabels(targets) }),
+		livedebugging.AsSnapshot(),
+	))
+
 	c.opts.OnStateChange(Exports{
 		Output: targets,
 		Rules:  newArgs.RelabelConfigs,
@@ -99,6 +99,16 @@ func (t Target) PromLabels() modellabels.Labels {
 	return lb
 }
 
+// TargetsLabels converts targets into prometheus/prometheus/model/labels.Labels. Like PromLabels, it is not efficient
+// and should be avoided on a hot path.
+func TargetsLabels(targets []Target) []modellabels.Labels {
+	res := make([]modellabels.Labels, 0, len(targets))
+	for _, t := range targets {
+		res = append(res, t.PromLabels())
+	}
+	return res
+}
+
 func (t Target) NonReservedLabelSet() commonlabels.LabelSet {
 	// This may not be the most optimal way, but this method is NOT a known hot spot at the time of this comment.
 	result := make(commonlabels.LabelSet, t.Len())
@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 // TODO(thampiotr): We should reconsider which parts of this component should be exported and which should
@@ -49,8 +50,9 @@ type Exports struct {
 }
 
 var (
-	_ component.Component     = (*Component)(nil)
-	_ component.LiveDebugging = (*Component)(nil)
+	_ component.Component      = (*Component)(nil)
+	_ component.DebugComponent = (*Component)(nil)
+	_ component.LiveDebugging  = (*Component)(nil)
 )
 
 // Component implements the loki.process component.
@@ -62,6 +64,7 @@ type Component struct {
 	processIn    chan<- loki.Entry
 	processOut   chan loki.Entry
 	entryHandler loki.EntryHandler
+	pipeline     *stages.Pipeline
 	stages       []stages.StageConfig
 
 	fanoutMut sync.RWMutex
@@ -149,6 +152,7 @@ func (c *Component) Update(args component.Arguments) error {
 		entryHandler := loki.NewEntryHandler(c.processOut, func() { pipeline.Cleanup() })
 		c.entryHandler = pipeline.Wrap(entryHandler)
 		c.processIn = c.entryHandler.Chan()
+		c.pipeline = pipeline
 		c.stages = newArgs.Stages
 	}
 
@@ -176,6 +180,7 @@ func (c *Component) handleIn(ctx context.Context, wg *sync.WaitGroup) {
 					}
 					return fmt.Sprintf("[IN]: timestamp: %s, entry: %s, labels: %s, structured_metadata: %s", entry.Timestamp.Format(time.RFC3339Nano), entry.Line, entry.Labels.String(), string(structured_metadata))
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{entry.LabelsWithMetadata()} }),
 			))
 			select {
 			case <-ctx.Done():
@@ -217,6 +222,7 @@ func (c *Component) handleOut(shutdownCh chan struct{}, wg *sync.WaitGroup) {
 					}
 					return fmt.Sprintf("[OUT]: timestamp: %s, entry: %s, labels: %s, structured_metadata: %s", entry.Timestamp.Format(time.RFC3339Nano), entry.Line, entry.Labels.String(), string(structured_metadata))
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{entry.LabelsWithMetadata()} }),
 			))
 
 			for _, f := range fanout {
@@ -243,3 +249,24 @@ func stagesChanged(prev, next []stages.StageConfig) bool {
 }
 
 func (c *Component) LiveDebugging() {}
+
+// PatternsStatus reports the most frequent patterns of each stream found by the
+// patterns stages of the pipeline.
+type PatternsStatus struct {
+	Streams []stages.StreamPatterns `alloy:"stream,block,optional"`
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.pipeline == nil {
+		return nil
+	}
+	streams := c.pipeline.Patterns()
+	if len(streams) == 0 {
+		return nil
+	}
+	return PatternsStatus{Streams: streams}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/grafana/alloy/internal/component/discovery"
 	"github.com/grafana/alloy/internal/component/loki/process/stages"
 	lsf "github.com/grafana/alloy/internal/component/loki/source/file"
+	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/componenttest"
 	"github.com/grafana/alloy/internal/service/livedebugging"
 	"github.com/grafana/alloy/internal/util"
@@ -906,3 +907,52 @@ func (t *tester) updateAndTest(numLogsToSend int, cfg, expectedMetricsBeforeSend
 		require.NoError(t.t, err)
 	}
 }
+
+func TestPatternsDebugInfo(t *testing.T) {
+	stg := `
+stage.patterns {
+    top_patterns = 1
+}`
+	type cfg struct {
+		Stages []stages.StageConfig `alloy:"stage,enum"`
+	}
+	var stagesCfg cfg
+	require.NoError(t, syntax.Unmarshal([]byte(stg), &stagesCfg))
+
+	ch := loki.NewLogsReceiver()
+	opts := component.Options{
+		Logger:         util.TestAlloyLogger(t),
+		Registerer:     prometheus.NewRegistry(),
+		OnStateChange:  func(e component.Exports) {},
+		GetServiceData: getServiceData,
+		MinStability:   featuregate.StabilityExperimental,
+	}
+	c, err := New(opts, Arguments{ForwardTo: []loki.LogsReceiver{ch}, Stages: stagesCfg.Stages})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go c.Run(ctx)
+
+	// There is no debug info until a line was processed.
+	require.Nil(t, c.DebugInfo())
+
+	for _, line := range []string{"user 1 logged in", "user 2 logged in", "disk full"} {
+		c.receiver.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"app": "a"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		}
+		select {
+		case <-ch.Chan():
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log line")
+		}
+	}
+
+	info, ok := c.DebugInfo().(PatternsStatus)
+	require.True(t, ok)
+	require.Len(t, info.Streams, 1)
+	require.Equal(t, map[string]string{"app": "a"}, info.Streams[0].Labels)
+	require.Len(t, info.Streams[0].Patterns, 1)
+	require.Equal(t, "user <_> logged in", info.Streams[0].Patterns[0].Pattern)
+	require.Equal(t, uint64(2), info.Streams[0].Patterns[0].Count)
+}
@@ -0,0 +1,271 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors
+var (
+	ErrAccessLogFormatRequired   = errors.New("exactly one of format or log_format is required")
+	ErrAccessLogUnknownFormat    = errors.New("unknown access log format")
+	ErrInvalidAccessLogFormat    = errors.New("invalid log_format")
+	ErrEmptyAccessLogStageSource = errors.New("empty source")
+)
+
+// accessLogFormats are the named presets of the access_log stage, written
+// with the syntax of the NGINX log_format directive.
+var accessLogFormats = map[string]string{
+	// The Common Log Format of Apache and NGINX.
+	"common": `$remote_addr $remote_ident $remote_user [$time_local] "$request" $status $body_bytes_sent`,
+	// The combined format of Apache and NGINX.
+	"combined": `$remote_addr $remote_ident $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+	// The vhost_combined format of Apache.
+	"vhost_combined": `$server_name:$server_port $remote_addr $remote_ident $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+	// The Common Log Format of Traefik, which extends the combined format.
+	"traefik": `$remote_addr $remote_ident $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" $request_count "$router_name" "$service_url" ${request_duration}ms`,
+}
+
+// AccessLogConfig represents an access_log Stage configuration
+type AccessLogConfig struct {
+	Format    string  `alloy:"format,attr,optional"`
+	LogFormat string  `alloy:"log_format,attr,optional"`
+	Source    *string `alloy:"source,attr,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (c *AccessLogConfig) Validate() error {
+	_, err := validateAccessLogConfig(c)
+	return err
+}
+
+// validateAccessLogConfig validates an access_log stage config and returns
+// its compiled format.
+func validateAccessLogConfig(c *AccessLogConfig) (accessLogFormat, error) {
+	if (c.Format == "") == (c.LogFormat == "") {
+		return nil, ErrAccessLogFormatRequired
+	}
+	if c.Source != nil && *c.Source == "" {
+		return nil, ErrEmptyAccessLogStageSource
+	}
+
+	format := c.LogFormat
+	if c.Format != "" {
+		var ok bool
+		if format, ok = accessLogFormats[c.Format]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrAccessLogUnknownFormat, c.Format)
+		}
+	}
+	f, err := compileAccessLogFormat(format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAccessLogFormat, err)
+	}
+	return f, nil
+}
+
+// accessLogStage sets extracted data from access logs of web servers.
+type accessLogStage struct {
+	cfg    *AccessLogConfig
+	format accessLogFormat
+	logger log.Logger
+}
+
+// newAccessLogStage creates a new access_log pipeline stage from a config.
+func newAccessLogStage(logger log.Logger, cfg AccessLogConfig) (Stage, error) {
+	format, err := validateAccessLogConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return toStage(&accessLogStage{
+		cfg:    &cfg,
+		format: format,
+		logger: log.With(logger, "component", "stage", "type", StageTypeAccessLog),
+	}), nil
+}
+
+// Process implements Stage
+func (a *accessLogStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	input, ok := getSourceInput(a.logger, extracted, a.cfg.Source, entry)
+	if !ok {
+		return
+	}
+
+	if !a.format.parse(input, extracted) {
+		if Debug {
+			level.Debug(a.logger).Log("msg", "access log format did not match", "input", input)
+		}
+		return
+	}
+	if Debug {
+		level.Debug(a.logger).Log("msg", "extracted data debug in access_log stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+}
+
+// Name implements Stage
+func (a *accessLogStage) Name() string {
+	return StageTypeAccessLog
+}
+
+// accessLogFormat is a compiled access log format. Its segments alternate
+// between literal text and variables.
+type accessLogFormat []accessLogSegment
+
+type accessLogSegment struct {
+	literal  string
+	variable string
+}
+
+// compileAccessLogFormat compiles a format written with the syntax of the
+// NGINX log_format directive, where variables are written as $name or
+// ${name}.
+func compileAccessLogFormat(s string) (accessLogFormat, error) {
+	var (
+		f       accessLogFormat
+		literal strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			literal.WriteByte(s[i])
+			continue
+		}
+
+		var name string
+		if strings.HasPrefix(s[i+1:], "{") {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated variable at offset %d", i)
+			}
+			name = s[i+2 : i+end]
+			i += end
+		} else {
+			end := i + 1
+			for end < len(s) && isAccessLogVariableChar(s[end]) {
+				end++
+			}
+			name = s[i+1 : end]
+			i = end - 1
+		}
+		if name == "" || strings.IndexFunc(name, func(r rune) bool { return r > 0x7f || !isAccessLogVariableChar(byte(r)) }) >= 0 {
+			return nil, fmt.Errorf("invalid variable name %q", name)
+		}
+
+		if literal.Len() > 0 {
+			f = append(f, accessLogSegment{literal: literal.String()})
+			literal.Reset()
+		} else if len(f) > 0 {
+			return nil, fmt.Errorf("variables %q and %q must be separated by text", f[len(f)-1].variable, name)
+		}
+		if slices.ContainsFunc(f, func(seg accessLogSegment) bool { return seg.variable == name }) {
+			return nil, fmt.Errorf("duplicate variable %q", name)
+		}
+		f = append(f, accessLogSegment{variable: name})
+	}
+	if literal.Len() > 0 {
+		f = append(f, accessLogSegment{literal: literal.String()})
+	}
+
+	if !slices.ContainsFunc(f, func(seg accessLogSegment) bool { return seg.variable != "" }) {
+		return nil, errors.New("format has no variables")
+	}
+	return f, nil
+}
+
+func isAccessLogVariableChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_'
+}
+
+// parse matches input against f and sets the values of its variables in
+// extracted. Text after the end of the format is ignored. Nothing is set if
+// input doesn't match.
+//
+// The request variable is also split into request_method, request_uri and
+// server_protocol.
+func (f accessLogFormat) parse(input string, extracted map[string]interface{}) bool {
+	values := make([]string, len(f))
+
+	pos := 0
+	for i, seg := range f {
+		if seg.variable == "" {
+			if !strings.HasPrefix(input[pos:], seg.literal) {
+				return false
+			}
+			pos += len(seg.literal)
+			continue
+		}
+
+		// The last variable of a format takes the rest of the input.
+		if i == len(f)-1 {
+			values[i] = input[pos:]
+			break
+		}
+
+		next := f[i+1].literal
+		quoted := i > 0 && strings.HasSuffix(f[i-1].literal, `"`) && strings.HasPrefix(next, `"`)
+		end := indexUnescaped(input[pos:], next, quoted)
+		if end < 0 {
+			return false
+		}
+		values[i] = input[pos : pos+end]
+		pos += end
+	}
+
+	for i, seg := range f {
+		if seg.variable != "" {
+			extracted[seg.variable] = values[i]
+		}
+	}
+	if i := slices.IndexFunc(f, func(seg accessLogSegment) bool { return seg.variable == "request" }); i >= 0 {
+		splitRequest(values[i], extracted)
+	}
+	return true
+}
+
+// indexUnescaped returns the index of the first instance of substr in s. If
+// escaped is set, instances preceded by a backslash are skipped, like escaped
+// quotes in quoted values.
+func indexUnescaped(s, substr string, escaped bool) int {
+	offset := 0
+	for {
+		i := strings.Index(s[offset:], substr)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if !escaped || !isEscaped(s, i) {
+			return i
+		}
+		offset = i + 1
+	}
+}
+
+// isEscaped returns whether the character at i in s is preceded by an odd
+// number of backslashes.
+func isEscaped(s string, i int) bool {
+	n := 0
+	for i > 0 && s[i-1] == '\\' {
+		n++
+		i--
+	}
+	return n%2 == 1
+}
+
+// splitRequest splits the first line of an HTTP request into its method, URI
+// and protocol.
+func splitRequest(request string, extracted map[string]interface{}) {
+	parts := strings.Split(request, " ")
+	if len(parts) < 2 || len(parts) > 3 {
+		return
+	}
+	extracted["request_method"] = parts[0]
+	extracted["request_uri"] = parts[1]
+	if len(parts) == 3 {
+		extracted["server_protocol"] = parts[2]
+	}
+}
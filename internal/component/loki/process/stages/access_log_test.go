@@ -0,0 +1,238 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config          string
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"combined": {
+			`
+			stage.access_log {
+				format = "combined"
+			}`,
+			regexLogFixture,
+			map[string]interface{}{
+				"remote_addr":     "11.11.11.11",
+				"remote_ident":    "-",
+				"remote_user":     "frank",
+				"time_local":      "25/Jan/2000:14:00:01 -0500",
+				"request":         "GET /1986.js HTTP/1.1",
+				"request_method":  "GET",
+				"request_uri":     "/1986.js",
+				"server_protocol": "HTTP/1.1",
+				"status":          "200",
+				"body_bytes_sent": "932",
+				"http_referer":    "-",
+				"http_user_agent": "Mozilla/5.0 (Windows; U; Windows NT 5.1; de; rv:1.9.1.7) Gecko/20091221 Firefox/3.5.7 GTB6",
+			},
+		},
+		"combined with escaped quotes and extra fields": {
+			`
+			stage.access_log {
+				format = "combined"
+			}`,
+			`10.0.0.1 - - [02/May/2024:10:00:00 +0000] "GET /search?q=\"alloy\" HTTP/2.0" 404 0 "-" "curl/8.5.0" 0.003`,
+			map[string]interface{}{
+				"remote_addr":     "10.0.0.1",
+				"remote_ident":    "-",
+				"remote_user":     "-",
+				"time_local":      "02/May/2024:10:00:00 +0000",
+				"request":         `GET /search?q=\"alloy\" HTTP/2.0`,
+				"request_method":  "GET",
+				"request_uri":     `/search?q=\"alloy\"`,
+				"server_protocol": "HTTP/2.0",
+				"status":          "404",
+				"body_bytes_sent": "0",
+				"http_referer":    "-",
+				"http_user_agent": "curl/8.5.0",
+			},
+		},
+		"common": {
+			`
+			stage.access_log {
+				format = "common"
+			}`,
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			map[string]interface{}{
+				"remote_addr":     "127.0.0.1",
+				"remote_ident":    "-",
+				"remote_user":     "frank",
+				"time_local":      "10/Oct/2000:13:55:36 -0700",
+				"request":         "GET /apache_pb.gif HTTP/1.0",
+				"request_method":  "GET",
+				"request_uri":     "/apache_pb.gif",
+				"server_protocol": "HTTP/1.0",
+				"status":          "200",
+				"body_bytes_sent": "2326",
+			},
+		},
+		"vhost_combined": {
+			`
+			stage.access_log {
+				format = "vhost_combined"
+			}`,
+			`www.example.com:443 10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 301 0 "-" "Go-http-client/1.1"`,
+			map[string]interface{}{
+				"server_name":     "www.example.com",
+				"server_port":     "443",
+				"remote_addr":     "10.0.0.1",
+				"remote_ident":    "-",
+				"remote_user":     "-",
+				"time_local":      "10/Oct/2000:13:55:36 -0700",
+				"request":         "GET / HTTP/1.1",
+				"request_method":  "GET",
+				"request_uri":     "/",
+				"server_protocol": "HTTP/1.1",
+				"status":          "301",
+				"body_bytes_sent": "0",
+				"http_referer":    "-",
+				"http_user_agent": "Go-http-client/1.1",
+			},
+		},
+		"traefik": {
+			`
+			stage.access_log {
+				format = "traefik"
+			}`,
+			`192.168.1.1 - - [02/May/2024:10:00:00 +0000] "POST /api HTTP/1.1" 201 17 "-" "python-requests/2.31" 42 "api@docker" "http://172.17.0.3:8080" 12ms`,
+			map[string]interface{}{
+				"remote_addr":      "192.168.1.1",
+				"remote_ident":     "-",
+				"remote_user":      "-",
+				"time_local":       "02/May/2024:10:00:00 +0000",
+				"request":          "POST /api HTTP/1.1",
+				"request_method":   "POST",
+				"request_uri":      "/api",
+				"server_protocol":  "HTTP/1.1",
+				"status":           "201",
+				"body_bytes_sent":  "17",
+				"http_referer":     "-",
+				"http_user_agent":  "python-requests/2.31",
+				"request_count":    "42",
+				"router_name":      "api@docker",
+				"service_url":      "http://172.17.0.3:8080",
+				"request_duration": "12",
+			},
+		},
+		"custom log_format": {
+			`
+			stage.access_log {
+				log_format = "$remote_addr [$time_local] \"$request\" $status rt=$request_time uct=\"${upstream_connect_time}\""
+			}`,
+			`10.1.2.3 [02/May/2024:10:00:00 +0000] "GET /health HTTP/1.1" 200 rt=0.001 uct="0.000"`,
+			map[string]interface{}{
+				"remote_addr":           "10.1.2.3",
+				"time_local":            "02/May/2024:10:00:00 +0000",
+				"request":               "GET /health HTTP/1.1",
+				"request_method":        "GET",
+				"request_uri":           "/health",
+				"server_protocol":       "HTTP/1.1",
+				"status":                "200",
+				"request_time":          "0.001",
+				"upstream_connect_time": "0.000",
+			},
+		},
+		"no match": {
+			`
+			stage.access_log {
+				format = "combined"
+			}`,
+			`level=info msg="not an access log"`,
+			map[string]interface{}{},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(nil, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func TestAccessLogConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config AccessLogConfig
+		err    error
+	}{
+		"missing format": {
+			AccessLogConfig{},
+			ErrAccessLogFormatRequired,
+		},
+		"both formats": {
+			AccessLogConfig{Format: "combined", LogFormat: "$remote_addr"},
+			ErrAccessLogFormatRequired,
+		},
+		"unknown format": {
+			AccessLogConfig{Format: "iis"},
+			ErrAccessLogUnknownFormat,
+		},
+		"adjacent variables": {
+			AccessLogConfig{LogFormat: "$remote_addr$status"},
+			ErrInvalidAccessLogFormat,
+		},
+		"duplicate variables": {
+			AccessLogConfig{LogFormat: "$status $status"},
+			ErrInvalidAccessLogFormat,
+		},
+		"no variables": {
+			AccessLogConfig{LogFormat: "plain text"},
+			ErrInvalidAccessLogFormat,
+		},
+		"unterminated variable": {
+			AccessLogConfig{LogFormat: "${status"},
+			ErrInvalidAccessLogFormat,
+		},
+		"empty variable": {
+			AccessLogConfig{LogFormat: "$ $status"},
+			ErrInvalidAccessLogFormat,
+		},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+			require.ErrorIs(t, testData.config.Validate(), testData.err)
+		})
+	}
+
+	for name := range accessLogFormats {
+		cfg := AccessLogConfig{Format: name}
+		require.NoError(t, cfg.Validate(), name)
+	}
+}
+
+func FuzzAccessLogStage(f *testing.F) {
+	fuzzStage(f, StageConfig{AccessLogConfig: &AccessLogConfig{Format: "combined"}},
+		regexLogFixture, `- - - [] "" - - "" ""`, `a b c [d] "e\" f g "h" "i\\"`)
+}
+
+func BenchmarkAccessLogStage(b *testing.B) {
+	benchmarkStages(b, regexLogFixture, map[string]StageConfig{
+		"access_log": {AccessLogConfig: &AccessLogConfig{Format: "combined"}},
+		"regex": {RegexConfig: &RegexConfig{
+			Expression: `^(?P<remote_addr>\S+) (?P<remote_ident>\S+) (?P<remote_user>\S+) \[(?P<time_local>[^\]]+)\] "(?P<request_method>\S+) (?P<request_uri>\S+) (?P<server_protocol>[^"]+)" (?P<status>\d{3}) (?P<body_bytes_sent>\d+|-) "(?P<http_referer>[^"]*)" "(?P<http_user_agent>[^"]*)"`,
+		}},
+	})
+}
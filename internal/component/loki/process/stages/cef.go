@@ -0,0 +1,193 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors
+var (
+	ErrEmptyCEFStageSource = errors.New("empty source")
+)
+
+// cefHeaderFields are the names under which the fields of the CEF header are
+// extracted, in order.
+var cefHeaderFields = []string{
+	"cef_version",
+	"device_vendor",
+	"device_product",
+	"device_version",
+	"device_event_class_id",
+	"name",
+	"severity",
+}
+
+// CEFConfig represents a cef Stage configuration
+type CEFConfig struct {
+	Source *string `alloy:"source,attr,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (c *CEFConfig) Validate() error {
+	if c.Source != nil && *c.Source == "" {
+		return ErrEmptyCEFStageSource
+	}
+	return nil
+}
+
+// cefStage sets extracted data from ArcSight Common Event Format (CEF)
+// events.
+type cefStage struct {
+	cfg    *CEFConfig
+	logger log.Logger
+}
+
+// newCEFStage creates a new cef pipeline stage from a config.
+func newCEFStage(logger log.Logger, cfg CEFConfig) (Stage, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return toStage(&cefStage{
+		cfg:    &cfg,
+		logger: log.With(logger, "component", "stage", "type", StageTypeCEF),
+	}), nil
+}
+
+// Process implements Stage
+func (c *cefStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	input, ok := getSourceInput(c.logger, extracted, c.cfg.Source, entry)
+	if !ok {
+		return
+	}
+
+	if err := parseCEF(input, extracted); err != nil {
+		if Debug {
+			level.Debug(c.logger).Log("msg", "failed to parse cef event", "err", err)
+		}
+		return
+	}
+	if Debug {
+		level.Debug(c.logger).Log("msg", "extracted data debug in cef stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+}
+
+// Name implements Stage
+func (c *cefStage) Name() string {
+	return StageTypeCEF
+}
+
+// parseCEF parses a CEF event into extracted. The event may be prefixed, for
+// example by a syslog header, and looks like:
+//
+//	CEF:0|Vendor|Product|1.0|100|Name|10|src=10.0.0.1 msg=Some message
+func parseCEF(input string, extracted map[string]interface{}) error {
+	i := strings.Index(input, "CEF:")
+	if i < 0 {
+		return errors.New("missing CEF: prefix")
+	}
+
+	header, extension, err := splitHeader(input[i+len("CEF:"):], len(cefHeaderFields))
+	if err != nil {
+		return err
+	}
+	for i, name := range cefHeaderFields {
+		extracted[name] = header[i]
+	}
+
+	for _, kv := range splitCEFExtension(extension) {
+		extracted[kv[0]] = unescapeCEFValue(kv[1])
+	}
+	return nil
+}
+
+// splitHeader splits the first n fields of the header of a CEF or LEEF event,
+// which are separated by pipes, from the rest of the event. Pipes and
+// backslashes in fields are escaped with a backslash.
+func splitHeader(s string, n int) ([]string, string, error) {
+	fields := make([]string, 0, n)
+
+	var (
+		sb      strings.Builder
+		escaped bool
+	)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case escaped:
+			if ch != '|' && ch != '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(ch)
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == '|':
+			fields = append(fields, strings.TrimSpace(sb.String()))
+			sb.Reset()
+			if len(fields) == n {
+				return fields, s[i+1:], nil
+			}
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return nil, "", fmt.Errorf("expected %d header fields, got %d", n, len(fields))
+}
+
+// splitCEFExtension splits the extension of a CEF event into key-value pairs.
+// Keys are separated from values by an unescaped equal sign, and pairs are
+// separated by spaces, which may also appear in values.
+func splitCEFExtension(s string) [][2]string {
+	type key struct {
+		name       string
+		start, end int // Positions of the key and its equal sign.
+	}
+
+	var keys []key
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // Skip the escaped character.
+		case '=':
+			start := i
+			for start > 0 && isCEFKeyChar(s[start-1]) {
+				start--
+			}
+			// Keys start the extension or follow a space, otherwise the equal
+			// sign is part of a value.
+			if start == i || (start > 0 && s[start-1] != ' ') {
+				continue
+			}
+			keys = append(keys, key{name: s[start:i], start: start, end: i})
+		}
+	}
+
+	pairs := make([][2]string, 0, len(keys))
+	for i, k := range keys {
+		valueEnd := len(s)
+		if i+1 < len(keys) {
+			valueEnd = keys[i+1].start
+		}
+		pairs = append(pairs, [2]string{k.name, strings.TrimRight(s[k.end+1:valueEnd], " ")})
+	}
+	return pairs
+}
+
+func isCEFKeyChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '.' || ch == '-'
+}
+
+var cefValueUnescaper = strings.NewReplacer(`\\`, `\`, `\=`, `=`, `\n`, "\n", `\r`, "\r")
+
+func unescapeCEFValue(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return cefValueUnescaper.Replace(s)
+}
@@ -0,0 +1,134 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+var testCEFLogLine = `Sep 19 08:26:10 host CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat. No action needed cs1Label=rule cs1=a\=b\\c`
+
+func TestCEF(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config          string
+		extracted       map[string]interface{}
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"header and extension": {
+			`stage.cef {}`,
+			nil,
+			testCEFLogLine,
+			map[string]interface{}{
+				"cef_version":           "0",
+				"device_vendor":         "Security",
+				"device_product":        "threatmanager",
+				"device_version":        "1.0",
+				"device_event_class_id": "100",
+				"name":                  "worm successfully stopped",
+				"severity":              "10",
+				"src":                   "10.0.0.1",
+				"dst":                   "2.1.2.2",
+				"spt":                   "1232",
+				"msg":                   "Detected a threat. No action needed",
+				"cs1Label":              "rule",
+				"cs1":                   `a=b\c`,
+			},
+		},
+		"escaped pipes in header and empty extension": {
+			`stage.cef {}`,
+			nil,
+			`CEF:1|Vendor\|Inc|Product|2.0|signature|Name with \\ backslash|Low|`,
+			map[string]interface{}{
+				"cef_version":           "1",
+				"device_vendor":         "Vendor|Inc",
+				"device_product":        "Product",
+				"device_version":        "2.0",
+				"device_event_class_id": "signature",
+				"name":                  `Name with \ backslash`,
+				"severity":              "Low",
+			},
+		},
+		"unescaped equal sign in value": {
+			`stage.cef {}`,
+			nil,
+			`CEF:0|V|P|1|1|N|1|request=https://example.com/?a=b&c=d act=blocked`,
+			map[string]interface{}{
+				"cef_version":           "0",
+				"device_vendor":         "V",
+				"device_product":        "P",
+				"device_version":        "1",
+				"device_event_class_id": "1",
+				"name":                  "N",
+				"severity":              "1",
+				"request":               "https://example.com/?a=b&c=d",
+				"act":                   "blocked",
+			},
+		},
+		"from source": {
+			`
+			stage.cef {
+				source = "event"
+			}`,
+			map[string]interface{}{"event": `CEF:0|V|P|1|1|N|1|src=10.0.0.1`},
+			"ignored",
+			map[string]interface{}{
+				"event":                 `CEF:0|V|P|1|1|N|1|src=10.0.0.1`,
+				"cef_version":           "0",
+				"device_vendor":         "V",
+				"device_product":        "P",
+				"device_version":        "1",
+				"device_event_class_id": "1",
+				"name":                  "N",
+				"severity":              "1",
+				"src":                   "10.0.0.1",
+			},
+		},
+		"incomplete header is ignored": {
+			`stage.cef {}`,
+			nil,
+			`CEF:0|Vendor|Product`,
+			map[string]interface{}{},
+		},
+		"not a CEF event": {
+			`stage.cef {}`,
+			nil,
+			`level=info msg="hello"`,
+			map[string]interface{}{},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(testData.extracted, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func FuzzCEFStage(f *testing.F) {
+	fuzzStage(f, StageConfig{CEFConfig: &CEFConfig{}},
+		testCEFLogLine, `CEF:0|a|b|c|d|e|f|`, `CEF:0|a|b|c|d|e|f|=x =y k=\`, `CEF:\|\\|`)
+}
+
+func BenchmarkCEFStage(b *testing.B) {
+	benchmarkStages(b, testCEFLogLine, map[string]StageConfig{
+		"cef": {CEFConfig: &CEFConfig{}},
+		"regex": {RegexConfig: &RegexConfig{
+			Expression: `CEF:(?P<cef_version>[^|]*)\|(?P<device_vendor>[^|]*)\|(?P<device_product>[^|]*)\|(?P<device_version>[^|]*)\|(?P<device_event_class_id>[^|]*)\|(?P<name>[^|]*)\|(?P<severity>[^|]*)\|src=(?P<src>\S+) dst=(?P<dst>\S+) spt=(?P<spt>\S+) msg=(?P<msg>.*?) cs1Label=(?P<cs1Label>\S+) cs1=(?P<cs1>.*)$`,
+		}},
+	})
+}
@@ -0,0 +1,134 @@
+package stages
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors
+var (
+	ErrCSVColumnsRequired  = errors.New("csv columns are required")
+	ErrCSVInvalidDelimiter = errors.New("csv delimiter must be a single character other than a quote, carriage return or line feed")
+	ErrEmptyCSVStageSource = errors.New("empty source")
+)
+
+const defaultCSVDelimiter = ","
+
+// CSVConfig represents a csv Stage configuration
+type CSVConfig struct {
+	Columns    []string `alloy:"columns,attr"`
+	Delimiter  string   `alloy:"delimiter,attr,optional"`
+	LazyQuotes bool     `alloy:"lazy_quotes,attr,optional"`
+	TrimSpace  bool     `alloy:"trim_space,attr,optional"`
+	Source     *string  `alloy:"source,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *CSVConfig) SetToDefault() {
+	*c = CSVConfig{Delimiter: defaultCSVDelimiter}
+}
+
+// Validate implements syntax.Validator.
+func (c *CSVConfig) Validate() error {
+	_, err := validateCSVConfig(c)
+	return err
+}
+
+// validateCSVConfig validates a csv stage config and returns its delimiter.
+func validateCSVConfig(c *CSVConfig) (rune, error) {
+	if len(c.Columns) == 0 {
+		return 0, ErrCSVColumnsRequired
+	}
+	if c.Source != nil && *c.Source == "" {
+		return 0, ErrEmptyCSVStageSource
+	}
+
+	delimiter := c.Delimiter
+	if delimiter == "" {
+		delimiter = defaultCSVDelimiter
+	}
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, ErrCSVInvalidDelimiter
+	}
+	return r, nil
+}
+
+// csvStage sets extracted data from the columns of a CSV record.
+type csvStage struct {
+	cfg       *CSVConfig
+	delimiter rune
+	logger    log.Logger
+}
+
+// newCSVStage creates a new csv pipeline stage from a config.
+func newCSVStage(logger log.Logger, cfg CSVConfig) (Stage, error) {
+	delimiter, err := validateCSVConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return toStage(&csvStage{
+		cfg:       &cfg,
+		delimiter: delimiter,
+		logger:    log.With(logger, "component", "stage", "type", StageTypeCSV),
+	}), nil
+}
+
+// Process implements Stage
+func (c *csvStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	input, ok := getSourceInput(c.logger, extracted, c.cfg.Source, entry)
+	if !ok {
+		return
+	}
+
+	record, err := c.parse(input)
+	if err != nil {
+		if Debug {
+			level.Debug(c.logger).Log("msg", "failed to parse csv record", "err", err)
+		}
+		return
+	}
+
+	if len(record) != len(c.cfg.Columns) && Debug {
+		level.Debug(c.logger).Log("msg", fmt.Sprintf("found %d fields for %d configured columns in csv stage", len(record), len(c.cfg.Columns)))
+	}
+
+	for i, column := range c.cfg.Columns {
+		// Columns without a name are skipped.
+		if column == "" || i >= len(record) {
+			continue
+		}
+		value := record[i]
+		if c.cfg.TrimSpace {
+			value = strings.TrimSpace(value)
+		}
+		extracted[column] = value
+	}
+	if Debug {
+		level.Debug(c.logger).Log("msg", "extracted data debug in csv stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+}
+
+// parse parses the first record of input.
+func (c *csvStage) parse(input string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(input))
+	r.Comma = c.delimiter
+	r.LazyQuotes = c.cfg.LazyQuotes
+	r.TrimLeadingSpace = c.cfg.TrimSpace
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	return r.Read()
+}
+
+// Name implements Stage
+func (c *csvStage) Name() string {
+	return StageTypeCSV
+}
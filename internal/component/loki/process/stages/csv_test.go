@@ -0,0 +1,184 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+var testCSVLogLine = `2024-05-02T10:00:00Z,web-1,"GET /index.html",200,"Mozilla/5.0 (X11; Linux x86_64)"`
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config          string
+		extracted       map[string]interface{}
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"extract all columns": {
+			`
+			stage.csv {
+				columns = ["time", "host", "request", "status", "user_agent"]
+			}`,
+			nil,
+			testCSVLogLine,
+			map[string]interface{}{
+				"time":       "2024-05-02T10:00:00Z",
+				"host":       "web-1",
+				"request":    "GET /index.html",
+				"status":     "200",
+				"user_agent": "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+		},
+		"skip unnamed and missing columns": {
+			`
+			stage.csv {
+				columns = ["", "host", "", "status", "", "extra"]
+			}`,
+			nil,
+			testCSVLogLine,
+			map[string]interface{}{
+				"host":   "web-1",
+				"status": "200",
+			},
+		},
+		"custom delimiter and trimmed values": {
+			`
+			stage.csv {
+				columns    = ["level", "component", "msg"]
+				delimiter  = ";"
+				trim_space = true
+			}`,
+			nil,
+			`info ; ingester ; "flushed chunk; size=12kB"`,
+			map[string]interface{}{
+				"level":     "info",
+				"component": "ingester",
+				"msg":       "flushed chunk; size=12kB",
+			},
+		},
+		"tab delimiter from source": {
+			`
+			stage.csv {
+				columns   = ["user", "action"]
+				delimiter = "\t"
+				source    = "fields"
+			}`,
+			map[string]interface{}{"fields": "alice\tlogin"},
+			"ignored",
+			map[string]interface{}{
+				"fields": "alice\tlogin",
+				"user":   "alice",
+				"action": "login",
+			},
+		},
+		"malformed record is ignored": {
+			`
+			stage.csv {
+				columns = ["a", "b"]
+			}`,
+			nil,
+			`a,"b`,
+			map[string]interface{}{},
+		},
+		"lazy quotes": {
+			`
+			stage.csv {
+				columns     = ["a", "b"]
+				lazy_quotes = true
+			}`,
+			nil,
+			`x"y,z`,
+			map[string]interface{}{
+				"a": `x"y`,
+				"b": "z",
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(testData.extracted, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func TestCSVConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	emptySource := ""
+	tests := map[string]struct {
+		config CSVConfig
+		err    error
+	}{
+		"missing columns": {
+			CSVConfig{Delimiter: ","},
+			ErrCSVColumnsRequired,
+		},
+		"empty source": {
+			CSVConfig{Columns: []string{"a"}, Source: &emptySource},
+			ErrEmptyCSVStageSource,
+		},
+		"multi-character delimiter": {
+			CSVConfig{Columns: []string{"a"}, Delimiter: "||"},
+			ErrCSVInvalidDelimiter,
+		},
+		"quote delimiter": {
+			CSVConfig{Columns: []string{"a"}, Delimiter: `"`},
+			ErrCSVInvalidDelimiter,
+		},
+		"valid": {
+			CSVConfig{Columns: []string{"a"}, Delimiter: "|"},
+			nil,
+		},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+			err := testData.config.Validate()
+			if testData.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, testData.err)
+		})
+	}
+}
+
+func TestCSVStability(t *testing.T) {
+	_, err := NewPipeline(util_log.Logger, loadConfig(`stage.csv { columns = ["a"] }`), nil, prometheus.DefaultRegisterer, featuregate.StabilityGenerallyAvailable)
+	require.ErrorContains(t, err, `stage "csv" is at stability level "experimental"`)
+}
+
+func FuzzCSVStage(f *testing.F) {
+	fuzzStage(f, StageConfig{CSVConfig: &CSVConfig{
+		Columns:   []string{"time", "host", "request", "status", "user_agent"},
+		Delimiter: ",",
+	}}, testCSVLogLine, `a,"b`, `"",,"x""y"`)
+}
+
+func BenchmarkCSVStage(b *testing.B) {
+	benchmarkStages(b, testCSVLogLine, map[string]StageConfig{
+		"csv": {CSVConfig: &CSVConfig{
+			Columns:   []string{"time", "host", "request", "status", "user_agent"},
+			Delimiter: ",",
+		}},
+		"regex": {RegexConfig: &RegexConfig{
+			Expression: `^(?P<time>[^,]*),(?P<host>[^,]*),"(?P<request>[^"]*)",(?P<status>[^,]*),"(?P<user_agent>[^"]*)"$`,
+		}},
+	})
+}
@@ -0,0 +1,203 @@
+package stages
+
+import (
+	"container/list"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrDedupInvalidWindow     = errors.New("dedup stage window must be greater than 0")
+	ErrDedupInvalidMaxEntries = errors.New("dedup stage max_entries must be greater than 0")
+)
+
+const (
+	// dedupRepeatCountKey is the structured metadata key holding the number of
+	// times a line was seen in a window.
+	dedupRepeatCountKey = "repeat_count"
+)
+
+// dedupFingerprintRegex matches the variable parts of a line which are
+// ignored when lines are compared by fingerprint: UUIDs, hexadecimal values
+// and numbers.
+var dedupFingerprintRegex = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|0[xX][0-9a-fA-F]+|\b[0-9a-fA-F]{8,}\b|[0-9]+`)
+
+// DedupConfig contains the configuration for a dedupStage.
+type DedupConfig struct {
+	Window      time.Duration `alloy:"window,attr,optional"`
+	Fingerprint bool          `alloy:"fingerprint,attr,optional"`
+	MaxEntries  int           `alloy:"max_entries,attr,optional"`
+	DropReason  string        `alloy:"drop_counter_reason,attr,optional"`
+}
+
+// DefaultDedupConfig applies the default values on
+var DefaultDedupConfig = DedupConfig{
+	Window:     10 * time.Second,
+	MaxEntries: 10000,
+	DropReason: "dedup_stage",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *DedupConfig) SetToDefault() {
+	*args = DefaultDedupConfig
+}
+
+// Validate implements syntax.Validator.
+func (args *DedupConfig) Validate() error {
+	if args.Window <= 0 {
+		return ErrDedupInvalidWindow
+	}
+	if args.MaxEntries <= 0 {
+		return ErrDedupInvalidMaxEntries
+	}
+	return nil
+}
+
+// dedupStage collapses repeated lines of a stream within a time window.
+type dedupStage struct {
+	logger    log.Logger
+	cfg       DedupConfig
+	dropCount *prometheus.CounterVec
+}
+
+// dedupKey identifies the lines of a stream which are collapsed together.
+type dedupKey struct {
+	stream model.Fingerprint
+	line   string
+}
+
+// dedupEntry is the first entry seen for a key in the current window.
+type dedupEntry struct {
+	key      dedupKey
+	entry    Entry
+	count    uint64
+	deadline time.Time
+}
+
+// dedupState captures the internal state of a running dedup stage. All
+// windows have the same length, so the pending entries are kept in the order
+// in which their windows close.
+type dedupState struct {
+	entries map[dedupKey]*list.Element
+	pending *list.List
+}
+
+// newDedupStage creates a DedupStage from config
+func newDedupStage(logger log.Logger, config DedupConfig, registerer prometheus.Registerer) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &dedupStage{
+		logger:    log.With(logger, "component", "stage", "type", StageTypeDedup),
+		cfg:       config,
+		dropCount: getDropCountMetric(registerer),
+	}, nil
+}
+
+func (d *dedupStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+
+		state := &dedupState{
+			entries: make(map[dedupKey]*list.Element),
+			pending: list.New(),
+		}
+
+		timer := time.NewTimer(d.cfg.Window)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case e, ok := <-in:
+				if !ok {
+					level.Debug(d.logger).Log("msg", "flush pending lines because inbound closed", "lines", state.pending.Len())
+					for state.pending.Len() > 0 {
+						d.flushOldest(out, state)
+					}
+					return
+				}
+				d.add(out, state, e)
+			case <-timer.C:
+				now := time.Now()
+				for state.pending.Len() > 0 && !now.Before(state.pending.Front().Value.(*dedupEntry).deadline) {
+					d.flushOldest(out, state)
+				}
+			}
+
+			if front := state.pending.Front(); front != nil {
+				timer.Reset(front.Value.(*dedupEntry).deadline.Sub(time.Now()))
+			} else {
+				timer.Stop()
+			}
+		}
+	}()
+	return out
+}
+
+// add counts e against the pending entry of its key, or starts a new window
+// for it.
+func (d *dedupStage) add(out chan Entry, state *dedupState, e Entry) {
+	key := dedupKey{stream: e.Labels.Fingerprint(), line: e.Line}
+	if d.cfg.Fingerprint {
+		key.line = dedupFingerprintRegex.ReplaceAllLiteralString(e.Line, "<_>")
+	}
+
+	if el, ok := state.entries[key]; ok {
+		el.Value.(*dedupEntry).count++
+		d.dropCount.WithLabelValues(d.cfg.DropReason).Inc()
+		return
+	}
+
+	// Keep the memory bounded by closing the oldest windows early.
+	for state.pending.Len() >= d.cfg.MaxEntries {
+		level.Debug(d.logger).Log("msg", "flush oldest line because max_entries was reached", "max_entries", d.cfg.MaxEntries)
+		d.flushOldest(out, state)
+	}
+
+	state.entries[key] = state.pending.PushBack(&dedupEntry{
+		key:      key,
+		entry:    e,
+		count:    1,
+		deadline: time.Now().Add(d.cfg.Window),
+	})
+}
+
+// flushOldest sends the pending entry whose window closes first.
+func (d *dedupStage) flushOldest(out chan Entry, state *dedupState) {
+	pending := state.pending.Remove(state.pending.Front()).(*dedupEntry)
+	delete(state.entries, pending.key)
+
+	e := pending.entry
+	if pending.count > 1 {
+		metadata := make([]logproto.LabelAdapter, 0, len(e.StructuredMetadata)+1)
+		metadata = append(metadata, e.StructuredMetadata...)
+		e.StructuredMetadata = append(metadata, logproto.LabelAdapter{
+			Name:  dedupRepeatCountKey,
+			Value: strconv.FormatUint(pending.count, 10),
+		})
+	}
+	out <- e
+}
+
+// Name implements Stage
+func (d *dedupStage) Name() string {
+	return StageTypeDedup
+}
+
+// Cleanup implements Stage.
+func (*dedupStage) Cleanup() {
+	// no-op
+}
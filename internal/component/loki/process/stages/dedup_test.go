@@ -0,0 +1,185 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+func TestDedupStage(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Now()
+	streamA := model.LabelSet{"app": "a"}
+	streamB := model.LabelSet{"app": "b"}
+	repeated := func(n string) []logproto.LabelAdapter {
+		return []logproto.LabelAdapter{{Name: dedupRepeatCountKey, Value: n}}
+	}
+
+	tests := map[string]struct {
+		config   DedupConfig
+		entries  []Entry
+		expected []Entry
+	}{
+		"identical lines per stream": {
+			DefaultDedupConfig,
+			[]Entry{
+				newEntry(nil, streamA, "connection refused", ts),
+				newEntry(nil, streamA, "connection refused", ts.Add(time.Second)),
+				newEntry(nil, streamB, "connection refused", ts.Add(time.Second)),
+				newEntry(nil, streamA, "retrying", ts.Add(2*time.Second)),
+				newEntry(nil, streamA, "connection refused", ts.Add(3*time.Second)),
+			},
+			[]Entry{
+				withStructuredMetadata(newEntry(nil, streamA, "connection refused", ts), repeated("3")),
+				newEntry(nil, streamB, "connection refused", ts.Add(time.Second)),
+				newEntry(nil, streamA, "retrying", ts.Add(2*time.Second)),
+			},
+		},
+		"similar lines are kept without fingerprint": {
+			DefaultDedupConfig,
+			[]Entry{
+				newEntry(nil, streamA, "request 1 failed", ts),
+				newEntry(nil, streamA, "request 2 failed", ts),
+			},
+			[]Entry{
+				newEntry(nil, streamA, "request 1 failed", ts),
+				newEntry(nil, streamA, "request 2 failed", ts),
+			},
+		},
+		"fingerprint": {
+			func() DedupConfig {
+				cfg := DefaultDedupConfig
+				cfg.Fingerprint = true
+				return cfg
+			}(),
+			[]Entry{
+				newEntry(nil, streamA, "request 1 to 10.0.0.1 failed, trace_id=4bf92f3577b34da6a3ce929d0e0e4736 id=5b1f5c0e-9f6c-4a4e-8d1a-3c6f0f7e2b11 addr=0xc000123", ts),
+				newEntry(nil, streamA, "request 22 to 10.0.0.2 failed, trace_id=00f067aa0ba902b7a3ce929d0e0e4736 id=0d9d5c0e-1f6c-4a4e-8d1a-3c6f0f7e2b12 addr=0xc000456", ts),
+				newEntry(nil, streamA, "request 3 to 10.0.0.3 succeeded", ts),
+			},
+			[]Entry{
+				withStructuredMetadata(newEntry(nil, streamA, "request 1 to 10.0.0.1 failed, trace_id=4bf92f3577b34da6a3ce929d0e0e4736 id=5b1f5c0e-9f6c-4a4e-8d1a-3c6f0f7e2b11 addr=0xc000123", ts), repeated("2")),
+				newEntry(nil, streamA, "request 3 to 10.0.0.3 succeeded", ts),
+			},
+		},
+		"max entries": {
+			func() DedupConfig {
+				cfg := DefaultDedupConfig
+				cfg.MaxEntries = 1
+				return cfg
+			}(),
+			[]Entry{
+				newEntry(nil, streamA, "a", ts),
+				newEntry(nil, streamA, "a", ts),
+				newEntry(nil, streamA, "b", ts),
+				newEntry(nil, streamA, "a", ts),
+			},
+			[]Entry{
+				withStructuredMetadata(newEntry(nil, streamA, "a", ts), repeated("2")),
+				newEntry(nil, streamA, "b", ts),
+				newEntry(nil, streamA, "a", ts),
+			},
+		},
+		"existing structured metadata": {
+			DefaultDedupConfig,
+			[]Entry{
+				withStructuredMetadata(newEntry(nil, streamA, "a", ts), []logproto.LabelAdapter{{Name: "pod", Value: "p-1"}}),
+				withStructuredMetadata(newEntry(nil, streamA, "a", ts), []logproto.LabelAdapter{{Name: "pod", Value: "p-1"}}),
+			},
+			[]Entry{
+				withStructuredMetadata(newEntry(nil, streamA, "a", ts), []logproto.LabelAdapter{{Name: "pod", Value: "p-1"}, {Name: dedupRepeatCountKey, Value: "2"}}),
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := newDedupStage(util_log.Logger, testData.config, prometheus.NewRegistry())
+			require.NoError(t, err)
+			assert.Equal(t, testData.expected, processEntries(s, testData.entries...))
+		})
+	}
+}
+
+func TestDedupStageWindow(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	cfg := DefaultDedupConfig
+	cfg.Window = 50 * time.Millisecond
+	s, err := newDedupStage(util_log.Logger, cfg, registry)
+	require.NoError(t, err)
+
+	in := make(chan Entry)
+	out := s.Run(in)
+	defer func() {
+		close(in)
+		for range out {
+		}
+	}()
+
+	ts := time.Now()
+	for i := 0; i < 3; i++ {
+		in <- newEntry(nil, nil, "connection refused", ts)
+	}
+
+	select {
+	case e := <-out:
+		assert.Equal(t, "connection refused", e.Line)
+		assert.Equal(t, []logproto.LabelAdapter{{Name: dedupRepeatCountKey, Value: "3"}}, []logproto.LabelAdapter(e.StructuredMetadata))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the window was never flushed")
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(s.(*dedupStage).dropCount.WithLabelValues("dedup_stage")))
+
+	// A new window starts once the previous one is closed.
+	in <- newEntry(nil, nil, "connection refused", ts)
+	select {
+	case e := <-out:
+		assert.Empty(t, e.StructuredMetadata)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the window was never flushed")
+	}
+}
+
+func TestDedupConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultDedupConfig
+	cfg.Window = 0
+	require.ErrorIs(t, cfg.Validate(), ErrDedupInvalidWindow)
+
+	cfg = DefaultDedupConfig
+	cfg.MaxEntries = 0
+	require.ErrorIs(t, cfg.Validate(), ErrDedupInvalidMaxEntries)
+
+	_, err := NewPipeline(util_log.Logger, loadConfig(`stage.dedup {}`), nil, prometheus.NewRegistry(), featuregate.StabilityGenerallyAvailable)
+	require.ErrorContains(t, err, `stage "dedup" is at stability level "experimental"`)
+
+	pl, err := NewPipeline(util_log.Logger, loadConfig(`
+	stage.dedup {
+		window      = "1m"
+		fingerprint = true
+	}`), nil, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+	require.NoError(t, err)
+	out := processEntries(pl, newEntry(nil, nil, "took 10ms", time.Now()), newEntry(nil, nil, "took 12ms", time.Now()))
+	require.Len(t, out, 1)
+}
+
+func withStructuredMetadata(e Entry, metadata []logproto.LabelAdapter) Entry {
+	e.StructuredMetadata = metadata
+	return e
+}
@@ -0,0 +1,385 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Config Errors
+var (
+	ErrExprRequired        = errors.New("expression is required")
+	ErrExprInvalidMemory   = errors.New("memory_budget must be greater than zero")
+	ErrExprNegativeTimeout = errors.New("timeout must not be negative")
+	ErrCouldNotCompileExpr = errors.New("could not compile expression")
+)
+
+var (
+	errExprTimeout           = errors.New("expression timed out")
+	errExprSaturated         = errors.New("too many expressions still running after they timed out")
+	errExprInvalidLabelName  = errors.New("invalid label name")
+	errExprInvalidLabelValue = errors.New("invalid label value")
+)
+
+const (
+	defaultExprDropReason   = "expr_stage"
+	defaultExprMemoryBudget = uint(1e6)
+
+	// maxRunningExprs is the maximum number of evaluations of the expression
+	// of a stage running at once, including the ones which timed out but
+	// haven't completed yet.
+	maxRunningExprs = 8
+)
+
+// exprStageVariables are the variables of the environment built from an
+// entry.
+var exprStageVariables = []string{"line", "labels", "structured_metadata", "timestamp", "extracted"}
+
+// ExprConfig represents an expr Stage configuration
+type ExprConfig struct {
+	Expression   string        `alloy:"expression,attr"`
+	Timeout      time.Duration `alloy:"timeout,attr,optional"`
+	MemoryBudget uint          `alloy:"memory_budget,attr,optional"`
+	DropReason   string        `alloy:"drop_counter_reason,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *ExprConfig) SetToDefault() {
+	*c = ExprConfig{
+		MemoryBudget: defaultExprMemoryBudget,
+		DropReason:   defaultExprDropReason,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (c *ExprConfig) Validate() error {
+	if c.Expression == "" {
+		return ErrExprRequired
+	}
+	if c.MemoryBudget == 0 {
+		return ErrExprInvalidMemory
+	}
+	if c.Timeout < 0 {
+		return ErrExprNegativeTimeout
+	}
+	return nil
+}
+
+// exprEnv is the environment an expression runs in. The variables hold the
+// entry as it was before the expression ran, while the functions record the
+// changes to apply to the entry once the expression succeeds.
+type exprEnv struct {
+	Line               string            `expr:"line"`
+	Labels             map[string]string `expr:"labels"`
+	StructuredMetadata map[string]string `expr:"structured_metadata"`
+	Timestamp          time.Time         `expr:"timestamp"`
+	Extracted          map[string]any    `expr:"extracted"`
+
+	SetLine                  func(string) bool                  `expr:"set_line"`
+	SetTimestamp             func(time.Time) bool               `expr:"set_timestamp"`
+	SetLabel                 func(string, string) (bool, error) `expr:"set_label"`
+	DeleteLabel              func(string) bool                  `expr:"delete_label"`
+	SetStructuredMetadata    func(string, string) bool          `expr:"set_structured_metadata"`
+	DeleteStructuredMetadata func(string) bool                  `expr:"delete_structured_metadata"`
+	SetExtracted             func(string, any) bool             `expr:"set_extracted"`
+	DeleteExtracted          func(string) bool                  `expr:"delete_extracted"`
+	Drop                     func() bool                        `expr:"drop"`
+
+	changes []exprChange
+	drop    bool
+}
+
+type exprChangeKind int
+
+const (
+	exprSetLine exprChangeKind = iota
+	exprSetTimestamp
+	exprSetLabel
+	exprDeleteLabel
+	exprSetStructuredMetadata
+	exprDeleteStructuredMetadata
+	exprSetExtracted
+	exprDeleteExtracted
+)
+
+// exprChange is a single change to an entry made by an expression.
+type exprChange struct {
+	kind      exprChangeKind
+	name      string
+	value     string
+	extracted any
+	timestamp time.Time
+}
+
+func newExprEnv() *exprEnv {
+	env := &exprEnv{}
+	record := func(c exprChange) bool {
+		env.changes = append(env.changes, c)
+		return true
+	}
+	env.SetLine = func(line string) bool {
+		return record(exprChange{kind: exprSetLine, value: line})
+	}
+	env.SetTimestamp = func(t time.Time) bool {
+		return record(exprChange{kind: exprSetTimestamp, timestamp: t})
+	}
+	env.SetLabel = func(name, value string) (bool, error) {
+		if !model.LabelName(name).IsValid() {
+			return false, fmt.Errorf("%w %q", errExprInvalidLabelName, name)
+		}
+		if !model.LabelValue(value).IsValid() {
+			return false, fmt.Errorf("%w %q", errExprInvalidLabelValue, value)
+		}
+		return record(exprChange{kind: exprSetLabel, name: name, value: value}), nil
+	}
+	env.DeleteLabel = func(name string) bool {
+		return record(exprChange{kind: exprDeleteLabel, name: name})
+	}
+	env.SetStructuredMetadata = func(name, value string) bool {
+		return record(exprChange{kind: exprSetStructuredMetadata, name: name, value: value})
+	}
+	env.DeleteStructuredMetadata = func(name string) bool {
+		return record(exprChange{kind: exprDeleteStructuredMetadata, name: name})
+	}
+	env.SetExtracted = func(name string, value any) bool {
+		return record(exprChange{kind: exprSetExtracted, name: name, extracted: value})
+	}
+	env.DeleteExtracted = func(name string) bool {
+		return record(exprChange{kind: exprDeleteExtracted, name: name})
+	}
+	env.Drop = func() bool {
+		env.drop = true
+		return true
+	}
+	return env
+}
+
+// exprStage runs an expression against every entry.
+type exprStage struct {
+	cfg       ExprConfig
+	program   *vm.Program
+	uses      map[string]struct{}
+	logger    log.Logger
+	dropCount *prometheus.CounterVec
+
+	// running holds a slot for every evaluation running with a timeout.
+	running chan struct{}
+}
+
+// newExprStage creates a new expr pipeline stage from a config. The
+// expression is compiled once, when the stage is created.
+func newExprStage(logger log.Logger, cfg ExprConfig, registerer prometheus.Registerer) (Stage, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	program, err := expr.Compile(cfg.Expression, expr.Env(exprEnv{}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCouldNotCompileExpr, err)
+	}
+
+	return &exprStage{
+		cfg:       cfg,
+		program:   program,
+		uses:      exprVariables(program),
+		logger:    log.With(logger, "component", "stage", "type", StageTypeExpr),
+		dropCount: getDropCountMetric(registerer),
+		running:   make(chan struct{}, maxRunningExprs),
+	}, nil
+}
+
+// exprVariables returns the variables of the environment an expression
+// reads, so that the others don't have to be built for every entry.
+func exprVariables(program *vm.Program) map[string]struct{} {
+	v := &exprIdentifierVisitor{identifiers: map[string]struct{}{}}
+	node := program.Node()
+	ast.Walk(&node, v)
+
+	uses := map[string]struct{}{}
+	for _, name := range exprStageVariables {
+		if _, ok := v.identifiers[name]; ok {
+			uses[name] = struct{}{}
+		}
+	}
+	return uses
+}
+
+type exprIdentifierVisitor struct {
+	identifiers map[string]struct{}
+}
+
+// Visit implements ast.Visitor.
+func (v *exprIdentifierVisitor) Visit(node *ast.Node) {
+	if n, ok := (*node).(*ast.IdentifierNode); ok {
+		v.identifiers[n.Value] = struct{}{}
+	}
+}
+
+// Run implements Stage
+func (s *exprStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		for e := range in {
+			if s.process(&e) {
+				s.dropCount.WithLabelValues(s.cfg.DropReason).Inc()
+				continue
+			}
+			out <- e
+		}
+	}()
+	return out
+}
+
+// process runs the expression against an entry and applies its changes. It
+// returns whether the entry must be dropped. The entry is left unchanged if
+// the expression fails.
+func (s *exprStage) process(e *Entry) bool {
+	env := s.newEnv(e)
+	if err := s.eval(env); err != nil {
+		if Debug {
+			level.Debug(s.logger).Log("msg", "failed to evaluate expression", "err", err)
+		}
+		return false
+	}
+	if env.drop {
+		return true
+	}
+	applyExprChanges(e, env.changes)
+	return false
+}
+
+func (s *exprStage) newEnv(e *Entry) *exprEnv {
+	env := newExprEnv()
+	if _, ok := s.uses["line"]; ok {
+		env.Line = e.Line
+	}
+	if _, ok := s.uses["timestamp"]; ok {
+		env.Timestamp = e.Timestamp
+	}
+	if _, ok := s.uses["labels"]; ok {
+		env.Labels = make(map[string]string, len(e.Labels))
+		for name, value := range e.Labels {
+			env.Labels[string(name)] = string(value)
+		}
+	}
+	if _, ok := s.uses["structured_metadata"]; ok {
+		env.StructuredMetadata = make(map[string]string, len(e.StructuredMetadata))
+		for _, l := range e.StructuredMetadata {
+			env.StructuredMetadata[l.Name] = l.Value
+		}
+	}
+	if _, ok := s.uses["extracted"]; ok {
+		// The expression may outlive the entry when it times out, so it has to
+		// read from its own copy of the extracted map.
+		if s.cfg.Timeout > 0 {
+			env.Extracted = make(map[string]any, len(e.Extracted))
+			for k, v := range e.Extracted {
+				env.Extracted[k] = v
+			}
+		} else {
+			env.Extracted = e.Extracted
+		}
+	}
+	return env
+}
+
+// eval runs the expression within the limits of the stage.
+func (s *exprStage) eval(env *exprEnv) error {
+	run := func() error {
+		machine := vm.VM{MemoryBudget: s.cfg.MemoryBudget}
+		_, err := machine.Run(s.program, env)
+		return err
+	}
+	if s.cfg.Timeout <= 0 {
+		return run()
+	}
+
+	// The virtual machine can't be interrupted, but the memory budget bounds
+	// how long an expression can keep running after it timed out. Entries
+	// fail without running the expression while too many of them are still
+	// running, so that they can't pile up.
+	select {
+	case s.running <- struct{}{}:
+	default:
+		return errExprSaturated
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-s.running }()
+		done <- run()
+	}()
+
+	timer := time.NewTimer(s.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errExprTimeout
+	}
+}
+
+// applyExprChanges applies the changes made by an expression to an entry, in
+// the order they were made.
+func applyExprChanges(e *Entry, changes []exprChange) {
+	for _, c := range changes {
+		switch c.kind {
+		case exprSetLine:
+			e.Line = c.value
+		case exprSetTimestamp:
+			e.Timestamp = c.timestamp
+		case exprSetLabel:
+			if e.Labels == nil {
+				e.Labels = model.LabelSet{}
+			}
+			e.Labels[model.LabelName(c.name)] = model.LabelValue(c.value)
+		case exprDeleteLabel:
+			delete(e.Labels, model.LabelName(c.name))
+		case exprSetStructuredMetadata:
+			e.StructuredMetadata = removeStructuredMetadata(e.StructuredMetadata, c.name)
+			e.StructuredMetadata = append(e.StructuredMetadata, logproto.LabelAdapter{Name: c.name, Value: c.value})
+		case exprDeleteStructuredMetadata:
+			e.StructuredMetadata = removeStructuredMetadata(e.StructuredMetadata, c.name)
+		case exprSetExtracted:
+			if e.Extracted == nil {
+				e.Extracted = map[string]interface{}{}
+			}
+			e.Extracted[c.name] = c.extracted
+		case exprDeleteExtracted:
+			delete(e.Extracted, c.name)
+		}
+	}
+}
+
+// removeStructuredMetadata returns a copy of metadata without name, as the
+// slice may be shared with other entries.
+func removeStructuredMetadata(metadata []logproto.LabelAdapter, name string) []logproto.LabelAdapter {
+	out := make([]logproto.LabelAdapter, 0, len(metadata)+1)
+	for _, l := range metadata {
+		if l.Name != name {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Name implements Stage
+func (s *exprStage) Name() string {
+	return StageTypeExpr
+}
+
+// Cleanup implements Stage.
+func (*exprStage) Cleanup() {
+	// no-op
+}
@@ -0,0 +1,237 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+func TestExpr(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		config   string
+		entry    Entry
+		expected *Entry
+	}{
+		"rewrite line and labels": {
+			`
+			stage.logfmt {
+				mapping = { level = "", msg = "" }
+			}
+			stage.expr {
+				expression = "set_line(upper(extracted.level) + \": \" + extracted.msg); set_label(\"level\", extracted.level); delete_label(\"pod\")"
+			}`,
+			newEntry(nil, model.LabelSet{"pod": "p-1", "job": "app"}, `level=warn msg="disk almost full"`, ts),
+			&Entry{
+				Extracted: map[string]interface{}{"pod": "p-1", "job": "app", "level": "warn", "msg": "disk almost full"},
+				Entry:     newEntry(nil, model.LabelSet{"job": "app", "level": "warn"}, "WARN: disk almost full", ts).Entry,
+			},
+		},
+		"structured metadata and extracted": {
+			`
+			stage.expr {
+				expression = "labels.job == \"app\" ? set_structured_metadata(\"trace_id\", structured_metadata.span_id + \"-t\") && delete_structured_metadata(\"span_id\") && set_extracted(\"n\", len(line)) : false"
+			}`,
+			func() Entry {
+				e := newEntry(nil, model.LabelSet{"job": "app"}, "hello", ts)
+				e.StructuredMetadata = []logproto.LabelAdapter{{Name: "span_id", Value: "abc"}}
+				return e
+			}(),
+			func() *Entry {
+				e := newEntry(map[string]interface{}{"job": "app", "n": 5}, model.LabelSet{"job": "app"}, "hello", ts)
+				e.StructuredMetadata = []logproto.LabelAdapter{{Name: "trace_id", Value: "abc-t"}}
+				return &e
+			}(),
+		},
+		"set timestamp": {
+			`
+			stage.expr {
+				expression = "set_timestamp(timestamp.Add(duration(\"1h\")))"
+			}`,
+			newEntry(nil, nil, "hello", ts),
+			func() *Entry {
+				e := newEntry(nil, nil, "hello", ts.Add(time.Hour))
+				return &e
+			}(),
+		},
+		"drop": {
+			`
+			stage.expr {
+				expression = "line contains \"healthz\" && drop()"
+			}`,
+			newEntry(nil, nil, "GET /healthz 200", ts),
+			nil,
+		},
+		"changes are discarded when the expression fails": {
+			`
+			stage.expr {
+				expression = "set_line(\"changed\"); set_label(\"\", \"x\")"
+			}`,
+			newEntry(nil, nil, "hello", ts),
+			func() *Entry {
+				e := newEntry(nil, nil, "hello", ts)
+				return &e
+			}(),
+		},
+		"memory budget": {
+			`
+			stage.expr {
+				expression    = "set_line(string(len(filter(1..1000000, # % 2 == 0))))"
+				memory_budget = 1000
+			}`,
+			newEntry(nil, nil, "hello", ts),
+			func() *Entry {
+				e := newEntry(nil, nil, "hello", ts)
+				return &e
+			}(),
+		},
+		"timeout": {
+			`
+			stage.expr {
+				expression    = "set_line(string(len(filter(1..1000000, # % 2 == 0))))"
+				memory_budget = 10000000
+				timeout       = "1ns"
+			}`,
+			newEntry(nil, nil, "hello", ts),
+			func() *Entry {
+				e := newEntry(nil, nil, "hello", ts)
+				return &e
+			}(),
+		},
+		"within the timeout": {
+			`
+			stage.expr {
+				expression = "set_line(line + \"!\")"
+				timeout    = "1m"
+			}`,
+			newEntry(nil, nil, "hello", ts),
+			func() *Entry {
+				e := newEntry(nil, nil, "hello!", ts)
+				return &e
+			}(),
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, testData.entry)
+			if testData.expected == nil {
+				require.Empty(t, out)
+				return
+			}
+			require.Len(t, out, 1)
+			if len(testData.expected.Extracted) == 0 {
+				testData.expected.Extracted = out[0].Extracted
+			}
+			assert.Equal(t, *testData.expected, out[0])
+		})
+	}
+}
+
+func TestExprConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config ExprConfig
+		err    error
+	}{
+		"missing expression": {
+			ExprConfig{MemoryBudget: defaultExprMemoryBudget},
+			ErrExprRequired,
+		},
+		"zero memory budget": {
+			ExprConfig{Expression: "true"},
+			ErrExprInvalidMemory,
+		},
+		"negative timeout": {
+			ExprConfig{Expression: "true", MemoryBudget: 1, Timeout: -time.Second},
+			ErrExprNegativeTimeout,
+		},
+		"syntax error": {
+			ExprConfig{Expression: "set_line(", MemoryBudget: 1},
+			ErrCouldNotCompileExpr,
+		},
+		"unknown variable": {
+			ExprConfig{Expression: "set_line(message)", MemoryBudget: 1},
+			ErrCouldNotCompileExpr,
+		},
+		"wrong argument type": {
+			ExprConfig{Expression: "set_label(\"a\", 1)", MemoryBudget: 1},
+			ErrCouldNotCompileExpr,
+		},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+			_, err := newExprStage(util_log.Logger, testData.config, prometheus.NewRegistry())
+			require.ErrorIs(t, err, testData.err)
+		})
+	}
+}
+
+func TestExprStability(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewPipeline(util_log.Logger, loadConfig(`stage.expr { expression = "true" }`), nil, prometheus.NewRegistry(), featuregate.StabilityGenerallyAvailable)
+	require.ErrorContains(t, err, `stage "expr" is at stability level "experimental"`)
+}
+
+func TestExprVariables(t *testing.T) {
+	t.Parallel()
+
+	s, err := newExprStage(util_log.Logger, ExprConfig{
+		Expression:   `let line2 = line; labels.job == "a" && set_extracted("x", line2)`,
+		MemoryBudget: defaultExprMemoryBudget,
+	}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"line": {}, "labels": {}}, s.(*exprStage).uses)
+}
+
+func TestExprSaturated(t *testing.T) {
+	t.Parallel()
+
+	st, err := newExprStage(util_log.Logger, ExprConfig{
+		Expression:   `set_line(line + "!")`,
+		MemoryBudget: defaultExprMemoryBudget,
+		Timeout:      time.Minute,
+	}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	s := st.(*exprStage)
+
+	// Expressions which timed out and are still running hold their slot.
+	for range maxRunningExprs {
+		s.running <- struct{}{}
+	}
+	require.ErrorIs(t, s.eval(newExprEnv()), errExprSaturated)
+
+	<-s.running
+	require.NoError(t, s.eval(newExprEnv()))
+	require.Eventually(t, func() bool { return len(s.running) == maxRunningExprs-1 }, time.Second, time.Millisecond)
+}
+
+func BenchmarkExprStage(b *testing.B) {
+	benchmarkStages(b, regexLogFixture, map[string]StageConfig{
+		"expr": {ExprConfig: &ExprConfig{
+			Expression:   `line startsWith "11.11.11.11" ? set_extracted("status", split(line, " ")[8]) : false`,
+			MemoryBudget: defaultExprMemoryBudget,
+		}},
+		"regex": {RegexConfig: &RegexConfig{
+			Expression: `^11\.11\.11\.11 \S+ \S+ \[[^\]]+\] "[^"]*" (?P<status>\d{3})`,
+		}},
+	})
+}
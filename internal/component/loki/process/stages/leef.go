@@ -0,0 +1,151 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors
+var (
+	ErrEmptyLEEFStageSource = errors.New("empty source")
+)
+
+// leefHeaderFields are the names under which the fields of the LEEF header
+// are extracted, in order.
+var leefHeaderFields = []string{
+	"leef_version",
+	"vendor",
+	"product",
+	"product_version",
+	"event_id",
+}
+
+// LEEFConfig represents a leef Stage configuration
+type LEEFConfig struct {
+	Source *string `alloy:"source,attr,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (c *LEEFConfig) Validate() error {
+	if c.Source != nil && *c.Source == "" {
+		return ErrEmptyLEEFStageSource
+	}
+	return nil
+}
+
+// leefStage sets extracted data from IBM QRadar Log Event Extended Format
+// (LEEF) events.
+type leefStage struct {
+	cfg    *LEEFConfig
+	logger log.Logger
+}
+
+// newLEEFStage creates a new leef pipeline stage from a config.
+func newLEEFStage(logger log.Logger, cfg LEEFConfig) (Stage, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return toStage(&leefStage{
+		cfg:    &cfg,
+		logger: log.With(logger, "component", "stage", "type", StageTypeLEEF),
+	}), nil
+}
+
+// Process implements Stage
+func (l *leefStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	input, ok := getSourceInput(l.logger, extracted, l.cfg.Source, entry)
+	if !ok {
+		return
+	}
+
+	if err := parseLEEF(input, extracted); err != nil {
+		if Debug {
+			level.Debug(l.logger).Log("msg", "failed to parse leef event", "err", err)
+		}
+		return
+	}
+	if Debug {
+		level.Debug(l.logger).Log("msg", "extracted data debug in leef stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+}
+
+// Name implements Stage
+func (l *leefStage) Name() string {
+	return StageTypeLEEF
+}
+
+// parseLEEF parses a LEEF 1.0 or 2.0 event into extracted. The event may be
+// prefixed, for example by a syslog header, and looks like:
+//
+//	LEEF:1.0|Vendor|Product|1.0|Event|src=10.0.0.1<tab>usrName=user
+//	LEEF:2.0|Vendor|Product|1.0|Event|^|src=10.0.0.1^usrName=user
+func parseLEEF(input string, extracted map[string]interface{}) error {
+	i := strings.Index(input, "LEEF:")
+	if i < 0 {
+		return errors.New("missing LEEF: prefix")
+	}
+
+	header, attributes, err := splitHeader(input[i+len("LEEF:"):], len(leefHeaderFields))
+	if err != nil {
+		return err
+	}
+
+	// LEEF 2.0 adds a header field with the delimiter of the attributes,
+	// which defaults to a tab.
+	delimiter := "\t"
+	if strings.HasPrefix(header[0], "2") {
+		field, rest, ok := strings.Cut(attributes, "|")
+		if ok {
+			delimiter, err = parseLEEFDelimiter(field)
+			if err != nil {
+				return err
+			}
+			attributes = rest
+		}
+	}
+
+	for i, name := range leefHeaderFields {
+		extracted[name] = header[i]
+	}
+	for _, attr := range strings.Split(attributes, delimiter) {
+		key, value, ok := strings.Cut(attr, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		extracted[key] = value
+	}
+	return nil
+}
+
+// parseLEEFDelimiter parses the delimiter field of a LEEF 2.0 header, which is
+// either a single character or its hexadecimal code like x09 or 0x09.
+func parseLEEFDelimiter(s string) (string, error) {
+	switch {
+	case s == "":
+		return "\t", nil
+	case len(s) == 1:
+		return s, nil
+	}
+
+	lower := strings.ToLower(s)
+	hex, ok := strings.CutPrefix(lower, "0x")
+	if !ok {
+		hex, ok = strings.CutPrefix(lower, "x")
+	}
+	if !ok {
+		return "", fmt.Errorf("invalid delimiter %q", s)
+	}
+	code, err := strconv.ParseUint(hex, 16, 8)
+	if err != nil || code == 0 {
+		return "", fmt.Errorf("invalid delimiter %q", s)
+	}
+	return string(rune(code)), nil
+}
@@ -0,0 +1,129 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+var testLEEFLogLine = "Jan 18 11:07:53 host LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tcat=anomaly\tmsg=there are spaces in this message"
+
+func TestLEEF(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config          string
+		extracted       map[string]interface{}
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"LEEF 1.0": {
+			`stage.leef {}`,
+			nil,
+			testLEEFLogLine,
+			map[string]interface{}{
+				"leef_version":    "1.0",
+				"vendor":          "Microsoft",
+				"product":         "MSExchange",
+				"product_version": "4.0 SP1",
+				"event_id":        "15345",
+				"src":             "192.0.2.0",
+				"dst":             "172.50.123.1",
+				"sev":             "5",
+				"cat":             "anomaly",
+				"msg":             "there are spaces in this message",
+			},
+		},
+		"LEEF 2.0 with a character delimiter": {
+			`stage.leef {}`,
+			nil,
+			`LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5^url=http://example.com/?a=b`,
+			map[string]interface{}{
+				"leef_version":    "2.0",
+				"vendor":          "Lancope",
+				"product":         "StealthWatch",
+				"product_version": "1.0",
+				"event_id":        "41",
+				"src":             "10.0.1.8",
+				"dst":             "10.0.0.5",
+				"sev":             "5",
+				"url":             "http://example.com/?a=b",
+			},
+		},
+		"LEEF 2.0 with a hexadecimal delimiter": {
+			`stage.leef {}`,
+			nil,
+			`LEEF:2.0|Vendor|Product|1.0|42|0x7c|src=10.0.1.8|dst=10.0.0.5`,
+			map[string]interface{}{
+				"leef_version":    "2.0",
+				"vendor":          "Vendor",
+				"product":         "Product",
+				"product_version": "1.0",
+				"event_id":        "42",
+				"src":             "10.0.1.8",
+				"dst":             "10.0.0.5",
+			},
+		},
+		"LEEF 2.0 with an invalid delimiter is ignored": {
+			`stage.leef {}`,
+			nil,
+			`LEEF:2.0|Vendor|Product|1.0|42|xzz|src=10.0.1.8`,
+			map[string]interface{}{},
+		},
+		"from source": {
+			`
+			stage.leef {
+				source = "event"
+			}`,
+			map[string]interface{}{"event": "LEEF:1.0|V|P|1|E|usrName=alice"},
+			"ignored",
+			map[string]interface{}{
+				"event":           "LEEF:1.0|V|P|1|E|usrName=alice",
+				"leef_version":    "1.0",
+				"vendor":          "V",
+				"product":         "P",
+				"product_version": "1",
+				"event_id":        "E",
+				"usrName":         "alice",
+			},
+		},
+		"not a LEEF event": {
+			`stage.leef {}`,
+			nil,
+			`CEF:0|V|P|1|1|N|1|src=10.0.0.1`,
+			map[string]interface{}{},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(testData.extracted, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func FuzzLEEFStage(f *testing.F) {
+	fuzzStage(f, StageConfig{LEEFConfig: &LEEFConfig{}},
+		testLEEFLogLine, `LEEF:2.0|a|b|c|d|x09|k=v`, `LEEF:2.0|a|b|c|d|0x|`, `LEEF:2|||||`)
+}
+
+func BenchmarkLEEFStage(b *testing.B) {
+	benchmarkStages(b, testLEEFLogLine, map[string]StageConfig{
+		"leef": {LEEFConfig: &LEEFConfig{}},
+		"regex": {RegexConfig: &RegexConfig{
+			Expression: "LEEF:(?P<leef_version>[^|]*)\\|(?P<vendor>[^|]*)\\|(?P<product>[^|]*)\\|(?P<product_version>[^|]*)\\|(?P<event_id>[^|]*)\\|src=(?P<src>[^\\t]*)\\tdst=(?P<dst>[^\\t]*)\\tsev=(?P<sev>[^\\t]*)\\tcat=(?P<cat>[^\\t]*)\\tmsg=(?P<msg>[^\\t]*)",
+		}},
+	})
+}
@@ -191,6 +191,14 @@ func (m *matcherStage) processLogQL(e Entry) (Entry, bool) {
 	return e, false
 }
 
+// streamPatterns implements patternsReporter.
+func (m *matcherStage) streamPatterns() []StreamPatterns {
+	if r, ok := m.stage.(patternsReporter); ok {
+		return r.streamPatterns()
+	}
+	return nil
+}
+
 // Name implements Stage
 func (m *matcherStage) Name() string {
 	return StageTypeMatch
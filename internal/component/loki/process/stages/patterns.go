@@ -0,0 +1,452 @@
+package stages
+
+import (
+	"container/list"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+)
+
+// Configuration errors.
+var (
+	ErrPatternsInvalidMaxPatterns = errors.New("patterns stage max_patterns must be greater than 0")
+	ErrPatternsInvalidMaxStreams  = errors.New("patterns stage max_streams must be greater than 0")
+	ErrPatternsInvalidMaxTokens   = errors.New("patterns stage max_tokens must be greater than 0")
+	ErrPatternsInvalidSimilarity  = errors.New("patterns stage similarity_threshold must be between 0 and 1")
+	ErrPatternsInvalidTopPatterns = errors.New("patterns stage top_patterns must not be negative")
+)
+
+const (
+	// patternIDKey and patternKey are the structured metadata keys holding
+	// the ID and the text of the pattern of a line.
+	patternIDKey = "pattern_id"
+	patternKey   = "pattern"
+
+	// patternWildcard replaces the variable tokens of a pattern.
+	patternWildcard = "<_>"
+
+	// drainPrefixDepth is the number of leading tokens used to route lines
+	// through the prefix tree, and drainMaxChildren the number of distinct
+	// tokens a node of the tree can have before routing the others to a
+	// wildcard child.
+	drainPrefixDepth = 2
+	drainMaxChildren = 100
+)
+
+// PatternsConfig contains the configuration for a patternsStage.
+type PatternsConfig struct {
+	SimilarityThreshold float64 `alloy:"similarity_threshold,attr,optional"`
+	MaxPatterns         int     `alloy:"max_patterns,attr,optional"`
+	MaxStreams          int     `alloy:"max_streams,attr,optional"`
+	MaxTokens           int     `alloy:"max_tokens,attr,optional"`
+	TopPatterns         int     `alloy:"top_patterns,attr,optional"`
+}
+
+// DefaultPatternsConfig applies the default values on
+var DefaultPatternsConfig = PatternsConfig{
+	SimilarityThreshold: 0.3,
+	MaxPatterns:         300,
+	MaxStreams:          1000,
+	MaxTokens:           128,
+	TopPatterns:         10,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *PatternsConfig) SetToDefault() {
+	*args = DefaultPatternsConfig
+}
+
+// Validate implements syntax.Validator.
+func (args *PatternsConfig) Validate() error {
+	if args.SimilarityThreshold < 0 || args.SimilarityThreshold > 1 {
+		return ErrPatternsInvalidSimilarity
+	}
+	if args.MaxPatterns <= 0 {
+		return ErrPatternsInvalidMaxPatterns
+	}
+	if args.MaxStreams <= 0 {
+		return ErrPatternsInvalidMaxStreams
+	}
+	if args.MaxTokens <= 0 {
+		return ErrPatternsInvalidMaxTokens
+	}
+	if args.TopPatterns < 0 {
+		return ErrPatternsInvalidTopPatterns
+	}
+	return nil
+}
+
+// StreamPatterns reports the most frequent patterns of a stream.
+type StreamPatterns struct {
+	Labels   map[string]string `alloy:"labels,attr"`
+	Patterns []PatternStatus   `alloy:"pattern,block,optional"`
+}
+
+// PatternStatus reports on a pattern and the number of lines which matched it.
+type PatternStatus struct {
+	ID      string `alloy:"id,attr"`
+	Pattern string `alloy:"pattern,attr"`
+	Count   uint64 `alloy:"count,attr"`
+}
+
+// patternsReporter is implemented by stages which can report the patterns
+// they found.
+type patternsReporter interface {
+	streamPatterns() []StreamPatterns
+}
+
+// patternsStage clusters the lines of each stream into patterns with the
+// Drain algorithm.
+type patternsStage struct {
+	logger log.Logger
+	cfg    PatternsConfig
+
+	mut     sync.Mutex
+	streams map[model.Fingerprint]*list.Element
+	lru     *list.List // Of *patternsStream, most recently used first.
+}
+
+// patternsStream holds the patterns of a single stream.
+type patternsStream struct {
+	fingerprint model.Fingerprint
+	labels      model.LabelSet
+	drain       *drain
+}
+
+// newPatternsStage creates a PatternsStage from config
+func newPatternsStage(logger log.Logger, config PatternsConfig) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &patternsStage{
+		logger:  log.With(logger, "component", "stage", "type", StageTypePatterns),
+		cfg:     config,
+		streams: make(map[model.Fingerprint]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// Run implements Stage
+func (p *patternsStage) Run(in chan Entry) chan Entry {
+	return RunWith(in, func(e Entry) Entry {
+		tokens, glued := tokenizePatternLine(e.Line, p.cfg.MaxTokens)
+		if len(tokens) == 0 {
+			return e
+		}
+
+		p.mut.Lock()
+		cluster := p.stream(e.Labels).drain.train(tokens, glued)
+		id, pattern := cluster.id, cluster.pattern
+		p.mut.Unlock()
+
+		metadata := make([]logproto.LabelAdapter, 0, len(e.StructuredMetadata)+2)
+		metadata = append(metadata, e.StructuredMetadata...)
+		e.StructuredMetadata = append(metadata,
+			logproto.LabelAdapter{Name: patternIDKey, Value: id},
+			logproto.LabelAdapter{Name: patternKey, Value: pattern},
+		)
+		return e
+	})
+}
+
+// stream returns the patterns of the stream with the given labels, evicting
+// the least recently used stream if there are too many of them.
+func (p *patternsStage) stream(labels model.LabelSet) *patternsStream {
+	fp := labels.Fingerprint()
+	if el, ok := p.streams[fp]; ok {
+		p.lru.MoveToFront(el)
+		return el.Value.(*patternsStream)
+	}
+
+	if p.lru.Len() >= p.cfg.MaxStreams {
+		oldest := p.lru.Remove(p.lru.Back()).(*patternsStream)
+		delete(p.streams, oldest.fingerprint)
+	}
+	s := &patternsStream{
+		fingerprint: fp,
+		labels:      labels.Clone(),
+		drain:       newDrain(p.cfg.SimilarityThreshold, p.cfg.MaxPatterns),
+	}
+	p.streams[fp] = p.lru.PushFront(s)
+	return s
+}
+
+// streamPatterns implements patternsReporter.
+func (p *patternsStage) streamPatterns() []StreamPatterns {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	res := make([]StreamPatterns, 0, p.lru.Len())
+	for el := p.lru.Front(); el != nil; el = el.Next() {
+		s := el.Value.(*patternsStream)
+		labels := make(map[string]string, len(s.labels))
+		for k, v := range s.labels {
+			labels[string(k)] = string(v)
+		}
+		res = append(res, StreamPatterns{
+			Labels:   labels,
+			Patterns: s.drain.top(p.cfg.TopPatterns),
+		})
+	}
+	return res
+}
+
+// Name implements Stage
+func (p *patternsStage) Name() string {
+	return StageTypePatterns
+}
+
+// Cleanup implements Stage.
+func (*patternsStage) Cleanup() {
+	// no-op
+}
+
+// tokenizePatternLine splits a line into tokens at white space, and splits
+// key=value pairs after the equal sign so that keys are kept in patterns when
+// their values vary. glued reports for each token whether it is directly
+// followed by the next one. Lines with more than maxTokens tokens are not
+// tokenized.
+func tokenizePatternLine(line string, maxTokens int) (tokens []string, glued []bool) {
+	for _, field := range strings.Fields(line) {
+		if i := strings.IndexByte(field, '='); i > 0 && i < len(field)-1 {
+			tokens = append(tokens, field[:i+1], field[i+1:])
+			glued = append(glued, true, false)
+		} else {
+			tokens = append(tokens, field)
+			glued = append(glued, false)
+		}
+		if len(tokens) > maxTokens {
+			return nil, nil
+		}
+	}
+	return tokens, glued
+}
+
+// drain clusters lines into patterns with the Drain algorithm, described in
+// "Drain: An Online Log Parsing Approach with Fixed Depth Tree" by Pinjia He,
+// Jieming Zhu, Zibin Zheng and Michael R. Lyu.
+//
+// Lines are routed through a prefix tree by their number of tokens and their
+// first tokens, and then compared to the patterns of the leaf they reach. A
+// line is merged into the most similar pattern if it's similar enough,
+// replacing the tokens which differ with a wildcard, and creates a new pattern
+// otherwise.
+type drain struct {
+	threshold   float64
+	maxClusters int
+
+	root *drainNode
+	lru  *list.List // Of *drainCluster, most recently used first.
+	ids  map[string]*drainCluster
+}
+
+type drainNode struct {
+	parent   *drainNode
+	key      string
+	children map[string]*drainNode
+	clusters []*drainCluster
+}
+
+type drainCluster struct {
+	tokens  []string
+	glued   []bool
+	count   uint64
+	id      string
+	pattern string
+
+	leaf *drainNode
+	el   *list.Element
+}
+
+func newDrain(threshold float64, maxClusters int) *drain {
+	return &drain{
+		threshold:   threshold,
+		maxClusters: maxClusters,
+		root:        &drainNode{children: map[string]*drainNode{}},
+		lru:         list.New(),
+		ids:         map[string]*drainCluster{},
+	}
+}
+
+// train adds a tokenized line to the patterns and returns the cluster it
+// belongs to.
+func (d *drain) train(tokens []string, glued []bool) *drainCluster {
+	leaf := d.leaf(tokens)
+
+	if c := d.match(leaf, tokens); c != nil {
+		changed := false
+		for i, token := range tokens {
+			if c.tokens[i] != patternWildcard && c.tokens[i] != token {
+				c.tokens[i] = patternWildcard
+				changed = true
+			}
+		}
+		if changed {
+			c.render()
+		}
+		c.count++
+		d.lru.MoveToFront(c.el)
+		return c
+	}
+
+	if d.lru.Len() >= d.maxClusters {
+		d.evict(d.lru.Back().Value.(*drainCluster))
+	}
+	c := &drainCluster{
+		tokens: slices.Clone(tokens),
+		glued:  glued,
+		count:  1,
+		leaf:   leaf,
+	}
+	c.render()
+	c.id = d.newID(c.pattern)
+	d.ids[c.id] = c
+	c.el = d.lru.PushFront(c)
+	leaf.clusters = append(leaf.clusters, c)
+	return c
+}
+
+// leaf returns the leaf of the prefix tree for a tokenized line, creating it
+// if needed.
+func (d *drain) leaf(tokens []string) *drainNode {
+	node := d.root.child(strconv.Itoa(len(tokens)))
+	for i := 0; i < drainPrefixDepth && i < len(tokens); i++ {
+		token := tokens[i]
+		if hasDigit(token) {
+			token = patternWildcard
+		}
+		if next, ok := node.children[token]; ok {
+			node = next
+			continue
+		}
+		// Route the token to the wildcard child once the node has too many
+		// children, so that the tree doesn't grow with variable tokens.
+		if len(node.children) >= drainMaxChildren-1 {
+			token = patternWildcard
+		}
+		node = node.child(token)
+	}
+	return node
+}
+
+func (n *drainNode) child(token string) *drainNode {
+	if c, ok := n.children[token]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = map[string]*drainNode{}
+	}
+	c := &drainNode{parent: n, key: token}
+	n.children[token] = c
+	return c
+}
+
+// match returns the most similar cluster of a leaf for a tokenized line, or
+// nil if none of them is similar enough.
+func (d *drain) match(leaf *drainNode, tokens []string) *drainCluster {
+	var (
+		best           *drainCluster
+		bestSimilarity = -1.0
+		bestWildcards  = -1
+	)
+	for _, c := range leaf.clusters {
+		similarity, wildcards := c.similarity(tokens)
+		if similarity > bestSimilarity || (similarity == bestSimilarity && wildcards > bestWildcards) {
+			best, bestSimilarity, bestWildcards = c, similarity, wildcards
+		}
+	}
+	if best == nil || bestSimilarity < d.threshold {
+		return nil
+	}
+	return best
+}
+
+// similarity returns the share of the tokens of a line which are equal to the
+// tokens of the pattern, and the number of wildcards in the pattern.
+func (c *drainCluster) similarity(tokens []string) (float64, int) {
+	var same, wildcards int
+	for i, token := range c.tokens {
+		if token == patternWildcard {
+			wildcards++
+			continue
+		}
+		if token == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(c.tokens)), wildcards
+}
+
+// newID returns the ID of a new cluster, which is a hash of its first
+// pattern. The ID doesn't change when the pattern is generalized, and is
+// unique among the clusters of the stream.
+func (d *drain) newID(pattern string) string {
+	h := xxhash.Sum64String(pattern)
+	for {
+		id := strconv.FormatUint(h, 16)
+		if _, ok := d.ids[id]; !ok {
+			return id
+		}
+		h++
+	}
+}
+
+// render updates the text of the pattern.
+func (c *drainCluster) render() {
+	var sb strings.Builder
+	for i, token := range c.tokens {
+		sb.WriteString(token)
+		if i < len(c.tokens)-1 && !c.glued[i] {
+			sb.WriteByte(' ')
+		}
+	}
+	c.pattern = sb.String()
+}
+
+// evict removes a cluster, and the nodes of the prefix tree which are left
+// empty.
+func (d *drain) evict(c *drainCluster) {
+	d.lru.Remove(c.el)
+	delete(d.ids, c.id)
+	c.leaf.clusters = slices.DeleteFunc(c.leaf.clusters, func(other *drainCluster) bool {
+		return other == c
+	})
+	for n := c.leaf; n.parent != nil && len(n.children) == 0 && len(n.clusters) == 0; n = n.parent {
+		delete(n.parent.children, n.key)
+	}
+}
+
+// top returns the n patterns which matched the most lines.
+func (d *drain) top(n int) []PatternStatus {
+	res := make([]PatternStatus, 0, d.lru.Len())
+	for el := d.lru.Front(); el != nil; el = el.Next() {
+		c := el.Value.(*drainCluster)
+		res = append(res, PatternStatus{ID: c.id, Pattern: c.pattern, Count: c.count})
+	}
+	slices.SortStableFunc(res, func(a, b PatternStatus) int {
+		switch {
+		case a.Count > b.Count:
+			return -1
+		case a.Count < b.Count:
+			return 1
+		}
+		return strings.Compare(a.Pattern, b.Pattern)
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
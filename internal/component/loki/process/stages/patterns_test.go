@@ -0,0 +1,262 @@
+package stages
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/pkg/push"
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+func TestPatternsCorpora(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		file      string
+		threshold float64
+		expected  []PatternStatus
+	}{
+		"hdfs": {
+			"hdfs.log",
+			DefaultPatternsConfig.SimilarityThreshold,
+			[]PatternStatus{
+				{Pattern: "081109 <_> <_> INFO dfs.FSNamesystem: BLOCK* NameSystem.addStoredBlock: blockMap updated: <_> is added to <_> size <_>", Count: 21},
+				{Pattern: "081109 <_> <_> INFO dfs.DataNode$DataXceiver: Receiving block <_> src: <_> dest: <_>", Count: 17},
+				{Pattern: "081109 <_> <_> WARN dfs.DataNode$DataXceiver: <_> exception while serving <_> to <_>", Count: 16},
+				{Pattern: "081109 <_> <_> INFO dfs.DataNode$PacketResponder: PacketResponder <_> for block <_> terminating", Count: 13},
+				{Pattern: "081109 <_> <_> INFO dfs.DataNode$PacketResponder: Received block <_> of size <_> from <_>", Count: 13},
+			},
+		},
+		"logfmt": {
+			"logfmt.log",
+			DefaultPatternsConfig.SimilarityThreshold,
+			[]PatternStatus{
+				{Pattern: `ts=<_> level=info msg="request completed" method=<_> path=<_> status=<_> duration=<_> trace_id=<_>`, Count: 26},
+				{Pattern: `ts=<_> level=warn msg="slow query" table=<_> rows=<_> duration=<_>`, Count: 22},
+				{Pattern: `ts=<_> level=error msg="failed to connect to upstream" upstream=<_> attempt=<_> err="connection refused"`, Count: 18},
+				{Pattern: `ts=<_> level=info msg="cache refreshed" entries=<_> evicted=<_>`, Count: 14},
+			},
+		},
+		"nginx": {
+			"nginx.log",
+			0.5,
+			[]PatternStatus{
+				{Pattern: `<_> - - <_> +0000] "POST /api/orders HTTP/1.1" <_> <_> "https://shop.example.com/cart" "curl/8.5.0"`, Count: 28},
+				{Pattern: `<_> - - <_> +0000] "GET /healthz HTTP/1.1" 200 2 "-" "kube-probe/1.29"`, Count: 26},
+				{Pattern: `<_> - - <_> +0000] "GET <_> HTTP/1.1" 200 <_> "-" "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"`, Count: 26},
+			},
+		},
+		"nginx with the default threshold": {
+			"nginx.log",
+			DefaultPatternsConfig.SimilarityThreshold,
+			[]PatternStatus{
+				{Pattern: `<_> - - <_> +0000] <_> <_> HTTP/1.1" <_> <_> <_> <_>`, Count: 54},
+				{Pattern: `<_> - - <_> +0000] "GET <_> HTTP/1.1" 200 <_> "-" "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"`, Count: 26},
+			},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := DefaultPatternsConfig
+			cfg.SimilarityThreshold = testData.threshold
+			s, err := newPatternsStage(util_log.Logger, cfg)
+			require.NoError(t, err)
+
+			entries := readPatternsCorpus(t, testData.file)
+			out := processEntries(s, entries...)
+			require.Len(t, out, len(entries))
+
+			patterns := s.(*patternsStage).streamPatterns()
+			require.Len(t, patterns, 1)
+			// The IDs depend on the first line of each pattern.
+			for i := range patterns[0].Patterns {
+				require.NotEmpty(t, patterns[0].Patterns[i].ID)
+				patterns[0].Patterns[i].ID = ""
+			}
+			assert.Equal(t, testData.expected, patterns[0].Patterns)
+		})
+	}
+}
+
+func TestPatternsStructuredMetadata(t *testing.T) {
+	t.Parallel()
+
+	pl, err := NewPipeline(util_log.Logger, loadConfig(`stage.patterns {}`), nil, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+	require.NoError(t, err)
+
+	ts := time.Now()
+	first := newEntry(nil, nil, "user 1 logged in", ts)
+	first.StructuredMetadata = push.LabelsAdapter{{Name: "pod", Value: "p-1"}}
+	out := processEntries(pl,
+		first,
+		newEntry(nil, nil, "user 2 logged in", ts),
+		newEntry(nil, nil, "", ts),
+	)
+	require.Len(t, out, 3)
+
+	id := func(pattern string) string {
+		return strconv.FormatUint(xxhash.Sum64String(pattern), 16)
+	}
+	// The first line creates a pattern of its own, which the second line
+	// generalizes without changing its ID.
+	assert.Equal(t, push.LabelsAdapter{
+		{Name: "pod", Value: "p-1"},
+		{Name: patternIDKey, Value: id("user 1 logged in")},
+		{Name: patternKey, Value: "user 1 logged in"},
+	}, out[0].StructuredMetadata)
+	assert.Equal(t, push.LabelsAdapter{
+		{Name: patternIDKey, Value: id("user 1 logged in")},
+		{Name: patternKey, Value: "user <_> logged in"},
+	}, out[1].StructuredMetadata)
+	// Empty lines have no pattern.
+	assert.Empty(t, out[2].StructuredMetadata)
+}
+
+func TestPatternsMemoryBounds(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultPatternsConfig
+	cfg.MaxPatterns = 2
+	cfg.MaxStreams = 2
+	cfg.MaxTokens = 4
+	s, err := newPatternsStage(util_log.Logger, cfg)
+	require.NoError(t, err)
+
+	ts := time.Now()
+	processEntries(s,
+		newEntry(nil, model.LabelSet{"app": "a"}, "disk full", ts),
+		newEntry(nil, model.LabelSet{"app": "a"}, "cache miss for key", ts),
+		newEntry(nil, model.LabelSet{"app": "a"}, "started", ts),
+		newEntry(nil, model.LabelSet{"app": "a"}, "too many tokens in this line", ts),
+		newEntry(nil, model.LabelSet{"app": "b"}, "started", ts),
+		newEntry(nil, model.LabelSet{"app": "c"}, "started", ts),
+	)
+
+	patterns := s.(*patternsStage).streamPatterns()
+	require.Len(t, patterns, 2)
+	assert.Equal(t, map[string]string{"app": "c"}, patterns[0].Labels)
+	assert.Equal(t, map[string]string{"app": "b"}, patterns[1].Labels)
+
+	// The least recently used pattern of a stream is evicted, along with the
+	// nodes of the prefix tree which only led to it.
+	d := newDrain(cfg.SimilarityThreshold, cfg.MaxPatterns)
+	for _, line := range []string{"disk full", "cache miss for key", "started"} {
+		tokens, glued := tokenizePatternLine(line, cfg.MaxTokens)
+		d.train(tokens, glued)
+	}
+	assert.Equal(t, []PatternStatus{
+		{ID: strconv.FormatUint(xxhash.Sum64String("cache miss for key"), 16), Pattern: "cache miss for key", Count: 1},
+		{ID: strconv.FormatUint(xxhash.Sum64String("started"), 16), Pattern: "started", Count: 1},
+	}, d.top(10))
+	assert.NotContains(t, d.root.children, "2")
+}
+
+func TestDrainStableIDs(t *testing.T) {
+	t.Parallel()
+
+	d := newDrain(DefaultPatternsConfig.SimilarityThreshold, DefaultPatternsConfig.MaxPatterns)
+	train := func(line string) *drainCluster {
+		tokens, glued := tokenizePatternLine(line, DefaultPatternsConfig.MaxTokens)
+		return d.train(tokens, glued)
+	}
+	id := strconv.FormatUint(xxhash.Sum64String("user 1 logged in"), 16)
+	require.Equal(t, id, train("user 1 logged in").id)
+	c := train("user 2 logged in")
+	require.Equal(t, "user <_> logged in", c.pattern)
+	require.Equal(t, id, c.id)
+
+	// The IDs of the clusters of a stream are unique.
+	require.NotEqual(t, id, d.newID("user 1 logged in"))
+}
+
+func TestPatternsNestedPipeline(t *testing.T) {
+	t.Parallel()
+
+	pl, err := NewPipeline(util_log.Logger, loadConfig(`
+	stage.match {
+		selector = "{app=\"a\"}"
+
+		stage.patterns {
+			top_patterns = 1
+		}
+	}`), nil, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+	require.NoError(t, err)
+
+	ts := time.Now()
+	processEntries(pl,
+		newEntry(nil, model.LabelSet{"app": "a"}, "user 1 logged in", ts),
+		newEntry(nil, model.LabelSet{"app": "a"}, "user 2 logged in", ts),
+		newEntry(nil, model.LabelSet{"app": "a"}, "user 2 logged out", ts),
+		newEntry(nil, model.LabelSet{"app": "b"}, "user 2 logged out", ts),
+	)
+	assert.Equal(t, []StreamPatterns{{
+		Labels: map[string]string{"app": "a"},
+		Patterns: []PatternStatus{{
+			ID:      strconv.FormatUint(xxhash.Sum64String("user 1 logged in"), 16),
+			Pattern: "user <_> logged <_>",
+			Count:   3,
+		}},
+	}}, pl.Patterns())
+}
+
+func TestPatternsConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mutate func(*PatternsConfig)
+		err    error
+	}{
+		"similarity threshold": {func(c *PatternsConfig) { c.SimilarityThreshold = 1.1 }, ErrPatternsInvalidSimilarity},
+		"max patterns":         {func(c *PatternsConfig) { c.MaxPatterns = 0 }, ErrPatternsInvalidMaxPatterns},
+		"max streams":          {func(c *PatternsConfig) { c.MaxStreams = 0 }, ErrPatternsInvalidMaxStreams},
+		"max tokens":           {func(c *PatternsConfig) { c.MaxTokens = 0 }, ErrPatternsInvalidMaxTokens},
+		"top patterns":         {func(c *PatternsConfig) { c.TopPatterns = -1 }, ErrPatternsInvalidTopPatterns},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+			cfg := DefaultPatternsConfig
+			testData.mutate(&cfg)
+			require.ErrorIs(t, cfg.Validate(), testData.err)
+		})
+	}
+
+	_, err := NewPipeline(util_log.Logger, loadConfig(`stage.patterns {}`), nil, prometheus.NewRegistry(), featuregate.StabilityGenerallyAvailable)
+	require.ErrorContains(t, err, `stage "patterns" is at stability level "experimental"`)
+}
+
+func BenchmarkPatternsStage(b *testing.B) {
+	benchmarkStages(b, regexLogFixture, map[string]StageConfig{
+		"patterns": {PatternsConfig: &DefaultPatternsConfig},
+	})
+}
+
+func readPatternsCorpus(t *testing.T, name string) []Entry {
+	f, err := os.Open(filepath.Join("testdata", "patterns", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []Entry
+	ts := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entries = append(entries, newEntry(nil, nil, scanner.Text(), ts))
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
@@ -17,16 +17,22 @@ import (
 // We define these as pointers types so we can use reflection to check that
 // exactly one is set.
 type StageConfig struct {
+	AccessLogConfig       *AccessLogConfig       `alloy:"access_log,block,optional"`
+	CEFConfig             *CEFConfig             `alloy:"cef,block,optional"`
 	CRIConfig             *CRIConfig             `alloy:"cri,block,optional"`
+	CSVConfig             *CSVConfig             `alloy:"csv,block,optional"`
 	DecolorizeConfig      *DecolorizeConfig      `alloy:"decolorize,block,optional"`
+	DedupConfig           *DedupConfig           `alloy:"dedup,block,optional"`
 	DockerConfig          *DockerConfig          `alloy:"docker,block,optional"`
 	DropConfig            *DropConfig            `alloy:"drop,block,optional"`
 	EventLogMessageConfig *EventLogMessageConfig `alloy:"eventlogmessage,block,optional"`
+	ExprConfig            *ExprConfig            `alloy:"expr,block,optional"`
 	GeoIPConfig           *GeoIPConfig           `alloy:"geoip,block,optional"`
 	JSONConfig            *JSONConfig            `alloy:"json,block,optional"`
 	LabelAllowConfig      *LabelAllowConfig      `alloy:"label_keep,block,optional"`
 	LabelDropConfig       *LabelDropConfig       `alloy:"label_drop,block,optional"`
 	LabelsConfig          *LabelsConfig          `alloy:"labels,block,optional"`
+	LEEFConfig            *LEEFConfig            `alloy:"leef,block,optional"`
 	LimitConfig           *LimitConfig           `alloy:"limit,block,optional"`
 	LogfmtConfig          *LogfmtConfig          `alloy:"logfmt,block,optional"`
 	LuhnFilterConfig      *LuhnFilterConfig      `alloy:"luhn,block,optional"`
@@ -35,6 +41,8 @@ type StageConfig struct {
 	MultilineConfig       *MultilineConfig       `alloy:"multiline,block,optional"`
 	OutputConfig          *OutputConfig          `alloy:"output,block,optional"`
 	PackConfig            *PackConfig            `alloy:"pack,block,optional"`
+	PatternsConfig        *PatternsConfig        `alloy:"patterns,block,optional"`
+	PseudonymizeConfig    *PseudonymizeConfig    `alloy:"pseudonymize,block,optional"`
 	RegexConfig           *RegexConfig           `alloy:"regex,block,optional"`
 	ReplaceConfig         *ReplaceConfig         `alloy:"replace,block,optional"`
 	StaticLabelsConfig    *StaticLabelsConfig    `alloy:"static_labels,block,optional"`
@@ -44,6 +52,7 @@ type StageConfig struct {
 	TenantConfig          *TenantConfig          `alloy:"tenant,block,optional"`
 	TimestampConfig       *TimestampConfig       `alloy:"timestamp,block,optional"`
 	WindowsEventConfig    *WindowsEventConfig    `alloy:"windowsevent,block,optional"`
+	XMLConfig             *XMLConfig             `alloy:"xml,block,optional"`
 }
 
 var rateLimiter *rate.Limiter
@@ -183,6 +192,23 @@ func (p *Pipeline) Size() int {
 	return len(p.stages)
 }
 
+// Patterns returns the most frequent patterns found by the patterns stages of
+// the pipeline, including the ones of nested pipelines.
+func (p *Pipeline) Patterns() []StreamPatterns {
+	return p.streamPatterns()
+}
+
+// streamPatterns implements patternsReporter.
+func (p *Pipeline) streamPatterns() []StreamPatterns {
+	var res []StreamPatterns
+	for _, s := range p.stages {
+		if r, ok := s.(patternsReporter); ok {
+			res = append(res, r.streamPatterns()...)
+		}
+	}
+	return res
+}
+
 func SetReadLineRateLimiter(rateVal float64, burstVal int, drop bool) {
 	rateLimiter = rate.NewLimiter(rate.Limit(rateVal), burstVal)
 	rateLimiterDrop = drop
@@ -0,0 +1,313 @@
+package stages
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+// Configuration errors.
+var (
+	ErrPseudonymizeKeyRequired      = errors.New("pseudonymize stage key is required")
+	ErrPseudonymizeNothingToMatch   = errors.New("pseudonymize stage requires at least one of detectors, expressions or fields")
+	ErrPseudonymizeUnknownDetector  = errors.New("pseudonymize stage detector is unknown")
+	ErrPseudonymizeInvalidTokenSize = errors.New("pseudonymize stage token_length must be between 1 and 64")
+	ErrPseudonymizeEmptyField       = errors.New("pseudonymize stage fields can't be empty")
+)
+
+// Built-in detectors of the pseudonymize stage.
+const (
+	pseudonymizeDetectorEmail = "email"
+	pseudonymizeDetectorIPv4  = "ipv4"
+	pseudonymizeDetectorIPv6  = "ipv6"
+)
+
+var (
+	pseudonymizeEmailRegex = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	pseudonymizeIPv4Regex  = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\b`)
+	// pseudonymizeIPv6Regex only finds candidates, which are then validated by
+	// netip.ParseAddr.
+	pseudonymizeIPv6Regex = regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`)
+)
+
+// PseudonymizeConfig contains the configuration for a pseudonymizeStage.
+type PseudonymizeConfig struct {
+	Key              alloytypes.Secret `alloy:"key,attr"`
+	Detectors        []string          `alloy:"detectors,attr,optional"`
+	Expressions      []string          `alloy:"expressions,attr,optional"`
+	Fields           []string          `alloy:"fields,attr,optional"`
+	FormatPreserving bool              `alloy:"format_preserving,attr,optional"`
+	TokenLength      int               `alloy:"token_length,attr,optional"`
+	Prefix           string            `alloy:"prefix,attr,optional"`
+}
+
+// DefaultPseudonymizeConfig applies the default values on
+var DefaultPseudonymizeConfig = PseudonymizeConfig{
+	TokenLength: 16,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *PseudonymizeConfig) SetToDefault() {
+	*args = DefaultPseudonymizeConfig
+}
+
+// Validate implements syntax.Validator.
+func (args *PseudonymizeConfig) Validate() error {
+	if args.Key == "" {
+		return ErrPseudonymizeKeyRequired
+	}
+	if len(args.Detectors) == 0 && len(args.Expressions) == 0 && len(args.Fields) == 0 {
+		return ErrPseudonymizeNothingToMatch
+	}
+	for _, d := range args.Detectors {
+		switch d {
+		case pseudonymizeDetectorEmail, pseudonymizeDetectorIPv4, pseudonymizeDetectorIPv6:
+		default:
+			return fmt.Errorf("%w: %q", ErrPseudonymizeUnknownDetector, d)
+		}
+	}
+	for _, f := range args.Fields {
+		if f == "" {
+			return ErrPseudonymizeEmptyField
+		}
+	}
+	if args.TokenLength < 1 || args.TokenLength > 2*sha256.Size {
+		return ErrPseudonymizeInvalidTokenSize
+	}
+	return nil
+}
+
+// pseudonymizeStage replaces personal data with tokens derived from a keyed
+// hash, so that the same value is always replaced by the same token.
+type pseudonymizeStage struct {
+	logger      log.Logger
+	cfg         PseudonymizeConfig
+	expressions []*regexp.Regexp
+	detectors   []func(line string) [][]int
+}
+
+// pseudonymizeSpan is a part of a line to replace.
+type pseudonymizeSpan struct {
+	start, end int
+}
+
+// newPseudonymizeStage creates a pseudonymizeStage from config.
+func newPseudonymizeStage(logger log.Logger, config PseudonymizeConfig) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &pseudonymizeStage{
+		logger: log.With(logger, "component", "stage", "type", StageTypePseudonymize),
+		cfg:    config,
+	}
+	for _, expr := range config.Expressions {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", ErrCouldNotCompileRegex, err)
+		}
+		s.expressions = append(s.expressions, re)
+	}
+	for _, d := range config.Detectors {
+		switch d {
+		case pseudonymizeDetectorEmail:
+			s.detectors = append(s.detectors, func(line string) [][]int {
+				return pseudonymizeEmailRegex.FindAllStringIndex(line, -1)
+			})
+		case pseudonymizeDetectorIPv4:
+			s.detectors = append(s.detectors, func(line string) [][]int {
+				return pseudonymizeIPv4Regex.FindAllStringIndex(line, -1)
+			})
+		case pseudonymizeDetectorIPv6:
+			s.detectors = append(s.detectors, findIPv6)
+		}
+	}
+	return toStage(s), nil
+}
+
+// findIPv6 returns the positions of the IPv6 addresses of line. Candidates
+// glued to other words, such as the "d::" of "std::vector", are ignored.
+func findIPv6(line string) [][]int {
+	var res [][]int
+	for _, loc := range pseudonymizeIPv6Regex.FindAllStringIndex(line, -1) {
+		if loc[0] > 0 && isPseudonymizeWordByte(line[loc[0]-1]) {
+			continue
+		}
+		if loc[1] < len(line) && isPseudonymizeWordByte(line[loc[1]]) {
+			continue
+		}
+		if addr, err := netip.ParseAddr(line[loc[0]:loc[1]]); err != nil || !addr.Is6() {
+			continue
+		}
+		res = append(res, loc)
+	}
+	return res
+}
+
+func isPseudonymizeWordByte(b byte) bool {
+	return b == '_' || b == '.' || b == ':' || b == '-' ||
+		('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// isPseudonymizeWordPart reports whether line[start:end] is glued to the
+// letters or digits around it, and is thus only a part of a word.
+func isPseudonymizeWordPart(line string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(line[:start])
+	first, _ := utf8.DecodeRuneInString(line[start:end])
+	last, _ := utf8.DecodeLastRuneInString(line[start:end])
+	after, _ := utf8.DecodeRuneInString(line[end:])
+	return (isPseudonymizeWordRune(before) && isPseudonymizeWordRune(first)) ||
+		(isPseudonymizeWordRune(last) && isPseudonymizeWordRune(after))
+}
+
+func isPseudonymizeWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Process implements Processor.
+func (s *pseudonymizeStage) Process(_ model.LabelSet, extracted map[string]interface{}, _ *time.Time, entry *string) {
+	mac := hmac.New(sha256.New, []byte(s.cfg.Key))
+
+	// The values of the extracted fields are replaced wherever they appear in
+	// the line too.
+	var values []string
+	for _, field := range s.cfg.Fields {
+		v, ok := extracted[field]
+		if !ok {
+			continue
+		}
+		value, err := getString(v)
+		if err != nil {
+			level.Debug(s.logger).Log("msg", "failed to convert extracted value to string", "field", field, "err", err)
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		extracted[field] = s.pseudonymize(mac, value)
+		values = append(values, value)
+	}
+
+	if entry == nil || *entry == "" {
+		return
+	}
+	*entry = s.pseudonymizeLine(mac, *entry, values)
+}
+
+// pseudonymizeLine replaces the values, the matches of the expressions and
+// the findings of the detectors in line. All the parts to replace are found
+// in the original line, so that a token is never pseudonymized again.
+func (s *pseudonymizeStage) pseudonymizeLine(mac hash.Hash, line string, values []string) string {
+	var spans []pseudonymizeSpan
+	for _, value := range values {
+		for offset := 0; ; {
+			i := strings.Index(line[offset:], value)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(value)
+			// Values are only replaced where they're a whole word, so that a
+			// short value such as "al" isn't replaced inside "total".
+			if !isPseudonymizeWordPart(line, start, end) {
+				spans = append(spans, pseudonymizeSpan{start, end})
+				offset = end
+			} else {
+				_, size := utf8.DecodeRuneInString(line[start:])
+				offset = start + size
+			}
+		}
+	}
+	for _, re := range s.expressions {
+		for _, loc := range re.FindAllStringSubmatchIndex(line, -1) {
+			// Only the capture groups are replaced when there are any.
+			if len(loc) == 2 {
+				spans = append(spans, pseudonymizeSpan{loc[0], loc[1]})
+				continue
+			}
+			for i := 2; i < len(loc); i += 2 {
+				if loc[i] >= 0 && loc[i] < loc[i+1] {
+					spans = append(spans, pseudonymizeSpan{loc[i], loc[i+1]})
+				}
+			}
+		}
+	}
+	for _, detect := range s.detectors {
+		for _, loc := range detect(line) {
+			spans = append(spans, pseudonymizeSpan{loc[0], loc[1]})
+		}
+	}
+	if len(spans) == 0 {
+		return line
+	}
+
+	// Overlapping spans are resolved by keeping the first and longest one.
+	slices.SortFunc(spans, func(a, b pseudonymizeSpan) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return b.end - a.end
+	})
+	var sb strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span.start < last || span.start == span.end {
+			continue
+		}
+		sb.WriteString(line[last:span.start])
+		sb.WriteString(s.pseudonymize(mac, line[span.start:span.end]))
+		last = span.end
+	}
+	sb.WriteString(line[last:])
+	return sb.String()
+}
+
+// pseudonymize returns the token of value. With format_preserving, IP
+// addresses are replaced by other IP addresses of the same family, and the
+// local part of email addresses is replaced by the token.
+func (s *pseudonymizeStage) pseudonymize(mac hash.Hash, value string) string {
+	if !s.cfg.FormatPreserving {
+		return s.token(mac, value)
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		sum := s.sum(mac, value)
+		if addr.Is4() {
+			return netip.AddrFrom4([4]byte(sum[:4])).String()
+		}
+		return netip.AddrFrom16([16]byte(sum[:16])).String()
+	}
+	if at := strings.LastIndexByte(value, '@'); at > 0 && pseudonymizeEmailRegex.FindString(value) == value {
+		return s.token(mac, value[:at]) + value[at:]
+	}
+	return s.token(mac, value)
+}
+
+func (s *pseudonymizeStage) token(mac hash.Hash, value string) string {
+	return s.cfg.Prefix + hex.EncodeToString(s.sum(mac, value))[:s.cfg.TokenLength]
+}
+
+func (s *pseudonymizeStage) sum(mac hash.Hash, value string) []byte {
+	mac.Reset()
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// Name implements Stage.
+func (s *pseudonymizeStage) Name() string {
+	return StageTypePseudonymize
+}
@@ -0,0 +1,206 @@
+package stages
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+const testPseudonymizeKey = "s3cr3t"
+
+func TestPseudonymize(t *testing.T) {
+	t.Parallel()
+
+	token := func(value string, length int) string {
+		mac := hmac.New(sha256.New, []byte(testPseudonymizeKey))
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))[:length]
+	}
+	sum := func(value string) []byte {
+		mac := hmac.New(sha256.New, []byte(testPseudonymizeKey))
+		mac.Write([]byte(value))
+		return mac.Sum(nil)
+	}
+
+	tests := map[string]struct {
+		config            string
+		line              string
+		expectedLine      string
+		expectedExtracted map[string]interface{}
+	}{
+		"detectors": {
+			`
+			stage.pseudonymize {
+				key       = "s3cr3t"
+				detectors = ["email", "ipv4", "ipv6"]
+			}`,
+			"login of jane.doe@example.com from 192.168.1.10 and 2001:db8::1 at 10:42:01 with std::vector",
+			"login of " + token("jane.doe@example.com", 16) + " from " + token("192.168.1.10", 16) + " and " + token("2001:db8::1", 16) + " at 10:42:01 with std::vector",
+			nil,
+		},
+		"same input maps to the same token": {
+			`
+			stage.pseudonymize {
+				key          = "s3cr3t"
+				detectors    = ["ipv4"]
+				token_length = 8
+				prefix       = "ip_"
+			}`,
+			"10.0.0.1 -> 10.0.0.2 -> 10.0.0.1",
+			"ip_" + token("10.0.0.1", 8) + " -> ip_" + token("10.0.0.2", 8) + " -> ip_" + token("10.0.0.1", 8),
+			nil,
+		},
+		"expressions replace their capture groups": {
+			`
+			stage.pseudonymize {
+				key         = "s3cr3t"
+				expressions = ["user_id=(\\d+)", "session-[a-z]+"]
+			}`,
+			"user_id=1234 opened session-abc",
+			"user_id=" + token("1234", 16) + " opened " + token("session-abc", 16),
+			nil,
+		},
+		"fields": {
+			`
+			stage.logfmt {
+				mapping = { user = "", ip = "", status = "" }
+			}
+			stage.pseudonymize {
+				key               = "s3cr3t"
+				fields            = ["user", "ip", "missing"]
+				detectors         = ["ipv4"]
+				format_preserving = true
+			}`,
+			"user=jdoe ip=10.0.0.1 status=200 peer=10.0.0.1",
+			"user=" + token("jdoe", 16) + " ip=" + netip.AddrFrom4([4]byte(sum("10.0.0.1")[:4])).String() + " status=200 peer=" + netip.AddrFrom4([4]byte(sum("10.0.0.1")[:4])).String(),
+			map[string]interface{}{
+				"user":   token("jdoe", 16),
+				"ip":     netip.AddrFrom4([4]byte(sum("10.0.0.1")[:4])).String(),
+				"status": "200",
+			},
+		},
+		"fields are only replaced as whole words": {
+			`
+			stage.logfmt {
+				mapping = { user = "" }
+			}
+			stage.pseudonymize {
+				key    = "s3cr3t"
+				fields = ["user"]
+			}`,
+			"user=al msg=\"al signed in, total=2 al_x=1 al\"",
+			"user=" + token("al", 16) + " msg=\"" + token("al", 16) + " signed in, total=2 al_x=1 " + token("al", 16) + "\"",
+			map[string]interface{}{
+				"user": token("al", 16),
+			},
+		},
+		"format preserving": {
+			`
+			stage.pseudonymize {
+				key               = "s3cr3t"
+				detectors         = ["email", "ipv4", "ipv6"]
+				format_preserving = true
+			}`,
+			"jane.doe@example.com 192.168.1.10 2001:db8::1",
+			token("jane.doe", 16) + "@example.com " + netip.AddrFrom4([4]byte(sum("192.168.1.10")[:4])).String() + " " + netip.AddrFrom16([16]byte(sum("2001:db8::1")[:16])).String(),
+			nil,
+		},
+		"overlapping matches": {
+			`
+			stage.pseudonymize {
+				key         = "s3cr3t"
+				detectors   = ["email"]
+				expressions = ["doe"]
+			}`,
+			"jane.doe@example.com and john.doe",
+			token("jane.doe@example.com", 16) + " and john." + token("doe", 16),
+			nil,
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(nil, nil, testData.line, time.Now()))
+			require.Len(t, out, 1)
+			assert.Equal(t, testData.expectedLine, out[0].Line)
+			if testData.expectedExtracted != nil {
+				assert.Equal(t, testData.expectedExtracted, out[0].Extracted)
+			}
+		})
+	}
+}
+
+func TestPseudonymizeKey(t *testing.T) {
+	t.Parallel()
+
+	run := func(key string) string {
+		cfg := DefaultPseudonymizeConfig
+		cfg.Key = alloytypes.Secret(key)
+		cfg.Detectors = []string{pseudonymizeDetectorIPv4}
+		s, err := newPseudonymizeStage(util_log.Logger, cfg)
+		require.NoError(t, err)
+		return processEntries(s, newEntry(nil, nil, "10.0.0.1", time.Now()))[0].Line
+	}
+	assert.Equal(t, run("a"), run("a"))
+	assert.NotEqual(t, run("a"), run("b"))
+}
+
+func TestPseudonymizeConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		mutate func(*PseudonymizeConfig)
+		err    error
+	}{
+		"missing key":      {func(c *PseudonymizeConfig) { c.Key = "" }, ErrPseudonymizeKeyRequired},
+		"nothing to match": {func(c *PseudonymizeConfig) { c.Detectors = nil }, ErrPseudonymizeNothingToMatch},
+		"unknown detector": {func(c *PseudonymizeConfig) { c.Detectors = []string{"phone"} }, ErrPseudonymizeUnknownDetector},
+		"empty field":      {func(c *PseudonymizeConfig) { c.Fields = []string{""} }, ErrPseudonymizeEmptyField},
+		"token length":     {func(c *PseudonymizeConfig) { c.TokenLength = 65 }, ErrPseudonymizeInvalidTokenSize},
+		"expression":       {func(c *PseudonymizeConfig) { c.Expressions = []string{"("} }, ErrCouldNotCompileRegex},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+			cfg := DefaultPseudonymizeConfig
+			cfg.Key = testPseudonymizeKey
+			cfg.Detectors = []string{pseudonymizeDetectorEmail}
+			testData.mutate(&cfg)
+			_, err := newPseudonymizeStage(util_log.Logger, cfg)
+			require.ErrorContains(t, err, testData.err.Error())
+		})
+	}
+
+	_, err := NewPipeline(util_log.Logger, loadConfig(`
+	stage.pseudonymize {
+		key       = "s3cr3t"
+		detectors = ["email"]
+	}`), nil, prometheus.NewRegistry(), featuregate.StabilityGenerallyAvailable)
+	require.ErrorContains(t, err, `stage "pseudonymize" is at stability level "experimental"`)
+}
+
+func BenchmarkPseudonymizeStage(b *testing.B) {
+	benchmarkStages(b, regexLogFixture, map[string]StageConfig{
+		"pseudonymize": {PseudonymizeConfig: &PseudonymizeConfig{
+			Key:         testPseudonymizeKey,
+			Detectors:   []string{pseudonymizeDetectorEmail, pseudonymizeDetectorIPv4, pseudonymizeDetectorIPv6},
+			TokenLength: DefaultPseudonymizeConfig.TokenLength,
+		}},
+	})
+}
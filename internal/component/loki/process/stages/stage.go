@@ -16,17 +16,23 @@ import (
 
 // TODO(@tpaschalis) Let's use this as the list of stages we need to port over.
 const (
+	StageTypeAccessLog  = "access_log"
+	StageTypeCEF        = "cef"
 	StageTypeCRI        = "cri"
+	StageTypeCSV        = "csv"
 	StageTypeDecolorize = "decolorize"
+	StageTypeDedup      = "dedup"
 	StageTypeDocker     = "docker"
 	StageTypeDrop       = "drop"
 	//TODO(thampiotr): Add support for eventlogmessage stage
 	StageTypeEventLogMessage    = "eventlogmessage"
+	StageTypeExpr               = "expr"
 	StageTypeGeoIP              = "geoip"
 	StageTypeJSON               = "json"
 	StageTypeLabel              = "labels"
 	StageTypeLabelAllow         = "labelallow"
 	StageTypeLabelDrop          = "labeldrop"
+	StageTypeLEEF               = "leef"
 	StageTypeLimit              = "limit"
 	StageTypeLogfmt             = "logfmt"
 	StageTypeLuhn               = "luhn"
@@ -35,7 +41,9 @@ const (
 	StageTypeMultiline          = "multiline"
 	StageTypeOutput             = "output"
 	StageTypePack               = "pack"
+	StageTypePatterns           = "patterns"
 	StageTypePipeline           = "pipeline"
+	StageTypePseudonymize       = "pseudonymize"
 	StageTypeRegex              = "regex"
 	StageTypeReplace            = "replace"
 	StageTypeSampling           = "sampling"
@@ -45,11 +53,21 @@ const (
 	StageTypeTenant             = "tenant"
 	StageTypeTimestamp          = "timestamp"
 	StageTypeWindowsEvent       = "windowsevent"
+	StageTypeXML                = "xml"
 )
 
 // Add stages that are not GA. Stages that are not specified here are considered GA.
 var stagesUnstable = map[string]featuregate.Stability{
 	StageTypeWindowsEvent: featuregate.StabilityExperimental,
+	StageTypeAccessLog:    featuregate.StabilityExperimental,
+	StageTypeCEF:          featuregate.StabilityExperimental,
+	StageTypeCSV:          featuregate.StabilityExperimental,
+	StageTypeDedup:        featuregate.StabilityExperimental,
+	StageTypeExpr:         featuregate.StabilityExperimental,
+	StageTypeLEEF:         featuregate.StabilityExperimental,
+	StageTypePatterns:     featuregate.StabilityExperimental,
+	StageTypePseudonymize: featuregate.StabilityExperimental,
+	StageTypeXML:          featuregate.StabilityExperimental,
 }
 
 // Processor takes an existing set of labels, timestamp and log entry and returns either a possibly mutated
@@ -148,6 +166,31 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.CSVConfig != nil:
+		s, err = newCSVStage(logger, *cfg.CSVConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.XMLConfig != nil:
+		s, err = newXMLStage(logger, *cfg.XMLConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.CEFConfig != nil:
+		s, err = newCEFStage(logger, *cfg.CEFConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.LEEFConfig != nil:
+		s, err = newLEEFStage(logger, *cfg.LEEFConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.AccessLogConfig != nil:
+		s, err = newAccessLogStage(logger, *cfg.AccessLogConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.LogfmtConfig != nil:
 		s, err = newLogfmtStage(logger, *cfg.LogfmtConfig)
 		if err != nil {
@@ -173,6 +216,16 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.PatternsConfig != nil:
+		s, err = newPatternsStage(logger, *cfg.PatternsConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.PseudonymizeConfig != nil:
+		s, err = newPseudonymizeStage(logger, *cfg.PseudonymizeConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.RegexConfig != nil:
 		s, err = newRegexStage(logger, *cfg.RegexConfig)
 		if err != nil {
@@ -252,6 +305,16 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		}
 	case cfg.SamplingConfig != nil:
 		s = newSamplingStage(logger, *cfg.SamplingConfig, registerer)
+	case cfg.DedupConfig != nil:
+		s, err = newDedupStage(logger, *cfg.DedupConfig, registerer)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.ExprConfig != nil:
+		s, err = newExprStage(logger, *cfg.ExprConfig, registerer)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.EventLogMessageConfig != nil:
 		s = newEventLogMessageStage(logger, cfg.EventLogMessageConfig)
 	case cfg.WindowsEventConfig != nil:
@@ -3,10 +3,13 @@ package stages
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
 	"github.com/grafana/alloy/internal/util"
 )
 
@@ -197,3 +200,34 @@ func stringsContain(values []string, search string) bool {
 
 	return false
 }
+
+// getSourceInput returns the input of a parsing stage: the value of source in
+// the extracted map when source is set, or the log line otherwise. It returns
+// false if there's no input to parse.
+func getSourceInput(logger log.Logger, extracted map[string]interface{}, source *string, entry *string) (string, bool) {
+	if source == nil {
+		if entry == nil {
+			if Debug {
+				level.Debug(logger).Log("msg", "cannot parse a nil entry")
+			}
+			return "", false
+		}
+		return *entry, true
+	}
+
+	v, ok := extracted[*source]
+	if !ok {
+		if Debug {
+			level.Debug(logger).Log("msg", "source does not exist in the set of extracted values", "source", *source)
+		}
+		return "", false
+	}
+	value, err := getString(v)
+	if err != nil {
+		if Debug {
+			level.Debug(logger).Log("msg", "failed to convert source value to string", "source", *source, "err", err, "type", reflect.TypeOf(v))
+		}
+		return "", false
+	}
+	return value, true
+}
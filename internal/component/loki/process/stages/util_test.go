@@ -5,12 +5,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/loki/v3/pkg/logproto"
 
 	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/util"
 )
 
 func newEntry(ex map[string]interface{}, lbs model.LabelSet, line string, ts time.Time) Entry {
@@ -241,3 +245,42 @@ func TestParseTimestampWithoutYear(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkStages benchmarks processing line with each of the stages, for
+// example to compare a parsing stage to the equivalent regex stage.
+func benchmarkStages(b *testing.B, line string, stages map[string]StageConfig) {
+	for name, cfg := range stages {
+		b.Run(name, func(b *testing.B) {
+			stage, err := New(util.TestAlloyLogger(b), nil, cfg, prometheus.NewRegistry(), featuregate.StabilityExperimental)
+			require.NoError(b, err)
+
+			in := make(chan Entry)
+			out := stage.Run(in)
+			defer close(in)
+
+			ts := time.Now()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				in <- newEntry(nil, nil, line, ts)
+				<-out
+			}
+		})
+	}
+}
+
+// fuzzStage checks that processing any line with the stage doesn't panic.
+func fuzzStage(f *testing.F, cfg StageConfig, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	stage, err := New(util.TestAlloyLogger(f), nil, cfg, nil, featuregate.StabilityExperimental)
+	require.NoError(f, err)
+	processor := stage.(*stageProcessor).Processor
+
+	f.Fuzz(func(t *testing.T, line string) {
+		ts := time.Now()
+		processor.Process(model.LabelSet{}, map[string]interface{}{}, &ts, &line)
+	})
+}
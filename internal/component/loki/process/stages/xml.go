@@ -0,0 +1,281 @@
+package stages
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors
+var (
+	ErrXMLExpressionsRequired = errors.New("xml expressions are required")
+	ErrCouldNotCompileXPath   = errors.New("could not compile XPath expression")
+	ErrEmptyXMLStageSource    = errors.New("empty source")
+)
+
+// XMLConfig represents an xml Stage configuration
+type XMLConfig struct {
+	Expressions map[string]string `alloy:"expressions,attr"`
+	Source      *string           `alloy:"source,attr,optional"`
+}
+
+// validateXMLConfig validates an xml stage config and returns the compiled
+// path expressions.
+func validateXMLConfig(c *XMLConfig) (map[string]xmlPath, error) {
+	if len(c.Expressions) == 0 {
+		return nil, ErrXMLExpressionsRequired
+	}
+	if c.Source != nil && *c.Source == "" {
+		return nil, ErrEmptyXMLStageSource
+	}
+
+	expressions := make(map[string]xmlPath, len(c.Expressions))
+	for n, e := range c.Expressions {
+		// If there is no expression, look for an element with the name
+		// anywhere in the document.
+		if e == "" {
+			e = "//" + n
+		}
+		path, err := compileXMLPath(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrCouldNotCompileXPath, e, err)
+		}
+		expressions[n] = path
+	}
+	return expressions, nil
+}
+
+// xmlStage sets extracted data using XPath expressions.
+type xmlStage struct {
+	cfg         *XMLConfig
+	expressions map[string]xmlPath
+	logger      log.Logger
+}
+
+// newXMLStage creates a new xml pipeline stage from a config.
+func newXMLStage(logger log.Logger, cfg XMLConfig) (Stage, error) {
+	expressions, err := validateXMLConfig(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	return toStage(&xmlStage{
+		cfg:         &cfg,
+		expressions: expressions,
+		logger:      log.With(logger, "component", "stage", "type", StageTypeXML),
+	}), nil
+}
+
+// Process implements Stage
+func (x *xmlStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	input, ok := getSourceInput(x.logger, extracted, x.cfg.Source, entry)
+	if !ok {
+		return
+	}
+
+	doc, err := parseXML(input)
+	if err != nil {
+		if Debug {
+			level.Debug(x.logger).Log("msg", "failed to parse xml", "err", err)
+		}
+		return
+	}
+
+	for n, path := range x.expressions {
+		if value, ok := path.evaluate(doc); ok {
+			extracted[n] = value
+		}
+	}
+	if Debug {
+		level.Debug(x.logger).Log("msg", "extracted data debug in xml stage", "extracted data", fmt.Sprintf("%v", extracted))
+	}
+}
+
+// Name implements Stage
+func (x *xmlStage) Name() string {
+	return StageTypeXML
+}
+
+// xmlNode is an element of a parsed XML document.
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// value returns the text of n and its descendants, like the string value of
+// an element in XPath.
+func (n *xmlNode) value() string {
+	if len(n.children) == 0 {
+		return strings.TrimSpace(n.text.String())
+	}
+	var sb strings.Builder
+	n.writeText(&sb)
+	return strings.TrimSpace(sb.String())
+}
+
+func (n *xmlNode) writeText(sb *strings.Builder) {
+	sb.WriteString(n.text.String())
+	for _, c := range n.children {
+		c.writeText(sb)
+	}
+}
+
+// parseXML parses a document into a tree of elements. The returned node is a
+// virtual document node whose only child is the root element.
+func parseXML(input string) (*xmlNode, error) {
+	var (
+		doc   = &xmlNode{}
+		stack = []*xmlNode{doc}
+		dec   = xml.NewDecoder(strings.NewReader(input))
+	)
+	// Entities and charsets other than UTF-8 are kept as is.
+	dec.Strict = false
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: tok.Name.Local, attrs: tok.Attr}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 1 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		}
+	}
+	if len(doc.children) == 0 {
+		return nil, errors.New("document has no root element")
+	}
+	return doc, nil
+}
+
+// xmlPath is a compiled path expression, which supports a subset of XPath:
+// child (/) and descendant (//) steps with element names or the * wildcard,
+// and a final attribute (@name) step. Names match the local name of elements
+// and attributes, ignoring their namespace.
+type xmlPath struct {
+	steps []xmlStep
+	attr  string
+}
+
+type xmlStep struct {
+	descendant bool
+	name       string
+}
+
+func compileXMLPath(expr string) (xmlPath, error) {
+	var path xmlPath
+
+	rest := expr
+	if !strings.HasPrefix(rest, "/") {
+		// Relative paths are relative to the document.
+		rest = "/" + rest
+	}
+	for rest != "" {
+		var step xmlStep
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			step.descendant = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "/"):
+			rest = rest[1:]
+		default:
+			return path, fmt.Errorf("unexpected %q", rest)
+		}
+
+		name := rest
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			name, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+		if name == "" {
+			return path, errors.New("empty step")
+		}
+
+		if attr, ok := strings.CutPrefix(name, "@"); ok {
+			if rest != "" || step.descendant || attr == "" || !isXMLName(attr) {
+				return path, fmt.Errorf("invalid attribute step %q", name)
+			}
+			path.attr = attr
+			break
+		}
+		if name != "*" && !isXMLName(name) {
+			return path, fmt.Errorf("invalid step %q", name)
+		}
+		step.name = name
+		path.steps = append(path.steps, step)
+	}
+	if len(path.steps) == 0 {
+		return path, errors.New("path must select an element")
+	}
+	return path, nil
+}
+
+// isXMLName returns whether name can be matched against the local name of an
+// element or attribute.
+func isXMLName(name string) bool {
+	return !strings.ContainsAny(name, "[]()=\"' \t*@|")
+}
+
+// evaluate returns the value of the first node in document order selected by
+// p in doc.
+func (p xmlPath) evaluate(doc *xmlNode) (string, bool) {
+	nodes := []*xmlNode{doc}
+	for _, step := range p.steps {
+		var next []*xmlNode
+		for _, n := range nodes {
+			next = step.appendMatches(next, n)
+		}
+		if len(next) == 0 {
+			return "", false
+		}
+		nodes = next
+	}
+
+	if p.attr == "" {
+		return nodes[0].value(), true
+	}
+	for _, n := range nodes {
+		for _, a := range n.attrs {
+			if a.Name.Local == p.attr {
+				return a.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// appendMatches appends the nodes selected by s from n to dst.
+func (s xmlStep) appendMatches(dst []*xmlNode, n *xmlNode) []*xmlNode {
+	for _, c := range n.children {
+		if s.name == "*" || c.name == s.name {
+			dst = append(dst, c)
+		}
+		if s.descendant {
+			dst = s.appendMatches(dst, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,172 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+
+	"github.com/grafana/alloy/internal/featuregate"
+)
+
+var testXMLLogLine = `<?xml version="1.0" encoding="utf-16"?>` +
+	`<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">` +
+	`<System><Provider Name="Microsoft-Windows-Security-Auditing"/><EventID>4625</EventID><Level>0</Level></System>` +
+	`<EventData><Data Name="TargetUserName">alice</Data><Data Name="IpAddress">10.0.0.7</Data></EventData>` +
+	`<Message>An account failed to log on.</Message>` +
+	`</Event>`
+
+func TestXML(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config          string
+		extracted       map[string]interface{}
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"extract elements and attributes": {
+			`
+			stage.xml {
+				expressions = {
+					event_id = "/Event/System/EventID",
+					provider = "/Event/System/Provider/@Name",
+					user     = "//Data",
+					Message  = "",
+					missing  = "/Event/Missing",
+				}
+			}`,
+			nil,
+			testXMLLogLine,
+			map[string]interface{}{
+				"event_id": "4625",
+				"provider": "Microsoft-Windows-Security-Auditing",
+				"user":     "alice",
+				"Message":  "An account failed to log on.",
+			},
+		},
+		"wildcards and string value of elements": {
+			`
+			stage.xml {
+				expressions = {
+					level  = "/*/System/Level",
+					system = "Event/System",
+				}
+			}`,
+			nil,
+			testXMLLogLine,
+			map[string]interface{}{
+				"level":  "0",
+				"system": "46250",
+			},
+		},
+		"from source": {
+			`
+			stage.xml {
+				expressions = { payload = "" }
+			}
+			stage.xml {
+				expressions = { order_id = "/order/@id", total = "/order/total" }
+				source      = "payload"
+			}`,
+			nil,
+			`<msg><payload>&lt;order id="42"&gt;&lt;total&gt;9.99&lt;/total&gt;&lt;/order&gt;</payload></msg>`,
+			map[string]interface{}{
+				"payload":  `<order id="42"><total>9.99</total></order>`,
+				"order_id": "42",
+				"total":    "9.99",
+			},
+		},
+		"malformed document is ignored": {
+			`
+			stage.xml {
+				expressions = { a = "/a" }
+			}`,
+			nil,
+			`not xml at all`,
+			map[string]interface{}{},
+		},
+	}
+
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.DefaultRegisterer, featuregate.StabilityExperimental)
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(testData.extracted, nil, testData.entry, time.Now()))[0]
+			assert.Equal(t, testData.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+func TestXMLConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	emptySource := ""
+	tests := map[string]struct {
+		config XMLConfig
+		err    error
+	}{
+		"missing expressions": {
+			XMLConfig{},
+			ErrXMLExpressionsRequired,
+		},
+		"empty source": {
+			XMLConfig{Expressions: map[string]string{"a": ""}, Source: &emptySource},
+			ErrEmptyXMLStageSource,
+		},
+		"empty step": {
+			XMLConfig{Expressions: map[string]string{"a": "/a//"}},
+			ErrCouldNotCompileXPath,
+		},
+		"attribute in the middle of a path": {
+			XMLConfig{Expressions: map[string]string{"a": "/a/@b/c"}},
+			ErrCouldNotCompileXPath,
+		},
+		"predicate": {
+			XMLConfig{Expressions: map[string]string{"a": "/a[1]"}},
+			ErrCouldNotCompileXPath,
+		},
+		"attribute only": {
+			XMLConfig{Expressions: map[string]string{"a": "@b"}},
+			ErrCouldNotCompileXPath,
+		},
+	}
+	for testName, testData := range tests {
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+			_, err := validateXMLConfig(&testData.config)
+			require.ErrorIs(t, err, testData.err)
+		})
+	}
+}
+
+func FuzzXMLStage(f *testing.F) {
+	fuzzStage(f, StageConfig{XMLConfig: &XMLConfig{
+		Expressions: map[string]string{
+			"event_id": "/Event/System/EventID",
+			"provider": "//Provider/@Name",
+			"any":      "//*",
+		},
+	}}, testXMLLogLine, `<a><b>`, `<a b="c"/>`, `</a>`)
+}
+
+func BenchmarkXMLStage(b *testing.B) {
+	benchmarkStages(b, testXMLLogLine, map[string]StageConfig{
+		"xml": {XMLConfig: &XMLConfig{
+			Expressions: map[string]string{
+				"event_id": "/Event/System/EventID",
+				"provider": "/Event/System/Provider/@Name",
+				"user":     "//Data",
+			},
+		}},
+		"regex": {RegexConfig: &RegexConfig{
+			Expression: `<Provider Name="(?P<provider>[^"]*)"/><EventID>(?P<event_id>[^<]*)</EventID>.*?<Data[^>]*>(?P<user>[^<]*)</Data>`,
+		}},
+	})
+}
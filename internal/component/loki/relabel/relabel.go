@@ -136,6 +136,10 @@ func (c *Component) Run(ctx context.Context) error {
 				func() string {
 					return fmt.Sprintf("entry: %s, labels: %s => %s", entry.Line, entry.Labels.String(), lbls.String())
 				},
+				livedebugging.WithLabels(func() []labels.Labels {
+					relabelled := loki.Entry{Labels: lbls, Entry: entry.Entry}
+					return []labels.Labels{entry.LabelsWithMetadata(), relabelled.LabelsWithMetadata()}
+				}),
 			))
 
 			if len(lbls) == 0 {
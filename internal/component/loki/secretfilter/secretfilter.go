@@ -19,6 +19,7 @@ import (
 	"github.com/grafana/alloy/internal/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 )
 
 //go:embed gitleaks.toml
@@ -246,6 +247,7 @@ func (c *Component) Run(ctx context.Context) error {
 				func() string {
 					return fmt.Sprintf("%s => %s", entry.Line, newEntry.Line)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{entry.LabelsWithMetadata()} }),
 			))
 
 			for _, f := range c.fanout {
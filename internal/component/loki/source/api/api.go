@@ -8,6 +8,7 @@ import (
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/spool"
 	fnet "github.com/grafana/alloy/internal/component/common/net"
 	"github.com/grafana/alloy/internal/component/common/relabel"
 	"github.com/grafana/alloy/internal/component/loki/source/api/internal/lokipush"
@@ -34,6 +35,7 @@ type Arguments struct {
 	Labels               map[string]string   `alloy:"labels,attr,optional"`
 	RelabelRules         relabel.Rules       `alloy:"relabel_rules,attr,optional"`
 	UseIncomingTimestamp bool                `alloy:"use_incoming_timestamp,attr,optional"`
+	Spool                *spool.Arguments    `alloy:"spool,block,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -59,17 +61,16 @@ type Component struct {
 	serverMut sync.Mutex
 	server    *lokipush.PushAPIServer
 
-	// Use separate receivers mutex to address potential deadlock when Update drains the current server.
+	// The forwarder has its own mutex, which addresses potential deadlocks when Update drains the current server.
 	// e.g. https://github.com/grafana/agent/issues/3391
-	receiversMut sync.RWMutex
-	receivers    []loki.LogsReceiver
+	forwarder *spool.Forwarder
 }
 
 func New(opts component.Options, args Arguments) (*Component, error) {
 	c := &Component{
 		opts:               opts,
 		entriesChan:        make(chan loki.Entry),
-		receivers:          args.ForwardTo,
+		forwarder:          spool.NewForwarder(opts.Logger, opts.Registerer, opts.DataPath),
 		uncheckedCollector: util.NewUncheckedCollector(nil),
 	}
 	opts.Registerer.MustRegister(c.uncheckedCollector)
@@ -81,21 +82,21 @@ func New(opts component.Options, args Arguments) (*Component, error) {
 }
 
 func (c *Component) Run(ctx context.Context) (err error) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
 	defer c.stop()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.forwarder.Run(ctx)
+	}()
+
 	for {
 		select {
 		case entry := <-c.entriesChan:
-			c.receiversMut.RLock()
-			receivers := c.receivers
-			c.receiversMut.RUnlock()
-
-			for _, receiver := range receivers {
-				select {
-				case receiver.Chan() <- entry:
-				case <-ctx.Done():
-					return
-				}
+			if c.forwarder.Forward(ctx, entry) != nil {
+				return
 			}
 		case <-ctx.Done():
 			return
@@ -122,9 +123,9 @@ func (c *Component) Update(args component.Arguments) error {
 		}
 	}
 
-	c.receiversMut.Lock()
-	c.receivers = newArgs.ForwardTo
-	c.receiversMut.Unlock()
+	if err := c.forwarder.Update(newArgs.ForwardTo, newArgs.Spool); err != nil {
+		return fmt.Errorf("failed to open spool: %w", err)
+	}
 
 	c.serverMut.Lock()
 	defer c.serverMut.Unlock()
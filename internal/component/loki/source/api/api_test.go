@@ -4,15 +4,18 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/phayes/freeport"
 
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/loki/v3/pkg/logproto"
 	"github.com/grafana/regexp"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,6 +24,7 @@ import (
 	"github.com/grafana/alloy/internal/component/common/loki"
 	"github.com/grafana/alloy/internal/component/common/loki/client"
 	"github.com/grafana/alloy/internal/component/common/loki/client/fake"
+	"github.com/grafana/alloy/internal/component/common/loki/spool"
 	"github.com/grafana/alloy/internal/component/common/net"
 	"github.com/grafana/alloy/internal/component/common/relabel"
 	"github.com/grafana/alloy/internal/util"
@@ -72,6 +76,62 @@ func TestLokiSourceAPI_Simple(t *testing.T) {
 	})
 }
 
+func TestLokiSourceAPI_Spool(t *testing.T) {
+	opts := defaultOptions(t)
+	opts.DataPath = t.TempDir()
+	spoolArgs := &spool.Arguments{MaxSize: units.MiB, Mode: spool.ModeBlock}
+
+	// The receiver of the first component never reads, so that the entries
+	// stay in the spool.
+	args := testArgsWith(t, func(a *Arguments) {
+		a.ForwardTo = []loki.LogsReceiver{loki.NewLogsReceiver()}
+		a.Spool = spoolArgs
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	comp, err := New(opts, args)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, comp.Run(ctx))
+	}()
+	waitForServerToBeReady(t, comp)
+
+	lokiClient := newTestLokiClient(t, args, opts)
+	for _, line := range []string{"first", "second"} {
+		lokiClient.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"source": "test"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		}
+	}
+	lokiClient.Stop()
+	require.Eventually(t, func() bool {
+		return testutil.GatherAndCompare(opts.Registerer.(*prometheus.Registry), strings.NewReader(`
+# HELP loki_source_spool_entries_written_total Number of entries written to the spool
+# TYPE loki_source_spool_entries_written_total counter
+loki_source_spool_entries_written_total 2
+`), "loki_source_spool_entries_written_total") == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	// The entries are replayed by the next component using the same data
+	// path.
+	receiver := fake.NewClient(func() {})
+	defer receiver.Stop()
+	opts.Registerer = prometheus.NewRegistry()
+	args = testArgsWith(t, func(a *Arguments) {
+		a.ForwardTo = []loki.LogsReceiver{receiver.LogsReceiver()}
+		a.Spool = spoolArgs
+	})
+	_, shutdown := startTestComponent(t, opts, args, t.Context())
+	defer shutdown()
+
+	require.Eventually(t, func() bool { return len(receiver.Received()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "first", receiver.Received()[0].Line)
+	assert.Equal(t, "second", receiver.Received()[1].Line)
+}
+
 func TestLokiSourceAPI_Update(t *testing.T) {
 	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
 	defer cancel()
@@ -5,6 +5,7 @@ package file
 // It uses the Go stdlib's compress/* packages for decoding.
 
 import (
+	"archive/tar"
 	"bufio"
 	"compress/bzip2"
 	"compress/gzip"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/klauspost/compress/zstd"
 	"github.com/prometheus/common/model"
 	"go.uber.org/atomic"
 	"golang.org/x/text/encoding"
@@ -34,9 +36,12 @@ import (
 
 func supportedCompressedFormats() map[string]struct{} {
 	return map[string]struct{}{
-		"gz":  {},
-		"z":   {},
-		"bz2": {},
+		"gz":     {},
+		"z":      {},
+		"bz2":    {},
+		"zst":    {},
+		"tar":    {},
+		"tar.gz": {},
 		// TODO: add support for zip.
 	}
 }
@@ -61,6 +66,10 @@ type decompressor struct {
 	size     int64
 	cfg      DecompressionConfig
 
+	// rotations is set when the file may be the compressed sibling of a
+	// rotated file which was tailed.
+	rotations *rotations
+
 	componentStopping func() bool
 }
 
@@ -73,6 +82,7 @@ func newDecompressor(
 	labels model.LabelSet,
 	encodingFormat string,
 	cfg DecompressionConfig,
+	rotations *rotations,
 	componentStopping func() bool,
 ) (*decompressor, error) {
 
@@ -107,6 +117,7 @@ func newDecompressor(
 		position:          pos,
 		decoder:           decoder,
 		cfg:               cfg,
+		rotations:         rotations,
 		componentStopping: componentStopping,
 	}
 
@@ -132,6 +143,23 @@ func mountReader(f *os.File, logger log.Logger, format CompressionFormat) (reade
 	case "bz2":
 		decompressLib = "bzip2"
 		reader = bzip2.NewReader(f)
+	case "zst":
+		decompressLib = "github.com/klauspost/compress/zstd"
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	case "tar":
+		decompressLib = "archive/tar"
+		reader = &tarReader{tr: tar.NewReader(f)}
+	case "tar.gz":
+		decompressLib = "compress/gzip and archive/tar"
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(f)
+		if err == nil {
+			reader = &tarReader{tr: tar.NewReader(gz)}
+		}
 	}
 
 	if err != nil && err != io.EOF {
@@ -242,14 +270,38 @@ func (d *decompressor) readLines(handler loki.EntryHandler, done chan struct{})
 		level.Error(d.logger).Log("msg", "error mounting new reader", "err", err)
 		return
 	}
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
 
 	level.Info(d.logger).Log("msg", "successfully mounted reader", "path", d.path, "ext", filepath.Ext(d.path))
 
+	// If the file is the compressed sibling of a rotated file which was
+	// tailed, the lines which were already read by the tailer are skipped.
+	var skipBytes int64
+	if d.rotations != nil && d.position == 0 {
+		br := bufio.NewReaderSize(r, fingerprintSize)
+		data, _ := br.Peek(fingerprintSize)
+		if offset, ok := d.rotations.match(data); ok {
+			level.Info(d.logger).Log("msg", "resuming rotated file", "path", d.path, "offset", offset)
+			skipBytes = offset
+		}
+		r = br
+	}
+
 	bufferSize := 4096
 	buffer := make([]byte, bufferSize)
 	maxLoglineSize := 2000000 // 2 MB
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(buffer, maxLoglineSize)
+	var readBytes int64
+	if skipBytes > 0 {
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			readBytes += int64(advance)
+			return advance, token, err
+		})
+	}
 	for line := int64(1); ; line++ {
 		if !scanner.Scan() {
 			break
@@ -271,6 +323,14 @@ func (d *decompressor) readLines(handler loki.EntryHandler, done chan struct{})
 		}
 		d.posAndSizeMtx.RUnlock()
 
+		if skipBytes > 0 && readBytes <= skipBytes {
+			// skip lines already read by the tailer of the rotated file.
+			d.posAndSizeMtx.Lock()
+			d.position++
+			d.posAndSizeMtx.Unlock()
+			continue
+		}
+
 		text := scanner.Text()
 		var finalText string
 		if d.decoder != nil {
@@ -302,6 +362,53 @@ func (d *decompressor) readLines(handler loki.EntryHandler, done chan struct{})
 	}
 }
 
+// tarReader reads the regular files of a tar archive one after the other,
+// ending each of them with a new line.
+type tarReader struct {
+	tr             *tar.Reader
+	inFile         bool
+	last           byte
+	pendingNewline bool
+}
+
+func (t *tarReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if t.pendingNewline {
+			t.pendingNewline = false
+			p[0] = '\n'
+			return 1, nil
+		}
+		if !t.inFile {
+			hdr, err := t.tr.Next()
+			if err != nil {
+				return 0, err
+			}
+			if !hdr.FileInfo().Mode().IsRegular() {
+				continue
+			}
+			t.inFile = true
+			t.last = '\n'
+		}
+
+		n, err := t.tr.Read(p)
+		if n > 0 {
+			t.last = p[n-1]
+		}
+		if err == io.EOF {
+			t.inFile = false
+			t.pendingNewline = t.last != '\n'
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
 func (d *decompressor) markPositionAndSize() error {
 	// Lock this update because it can be called in two different goroutines
 	d.posAndSizeMtx.RLock()
@@ -4,8 +4,13 @@ package file
 // of the reader interface.
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -14,8 +19,10 @@ import (
 	"github.com/grafana/alloy/internal/component/common/loki/positions"
 	"github.com/grafana/alloy/internal/util"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/go-kit/log"
 	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/klauspost/compress/zstd"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
@@ -241,6 +248,7 @@ func TestDecompressor(t *testing.T) {
 		labels,
 		"",
 		DecompressionConfig{Format: "gz"},
+		nil,
 		func() bool { return true },
 	)
 	require.NoError(t, err)
@@ -297,6 +305,7 @@ func TestDecompressorPositionFileEntryDeleted(t *testing.T) {
 		labels,
 		"",
 		DecompressionConfig{Format: "gz"},
+		nil,
 		func() bool { return false },
 	)
 	require.NoError(t, err)
@@ -344,6 +353,7 @@ func TestDecompressor_RunCalledTwice(t *testing.T) {
 		labels,
 		"",
 		DecompressionConfig{Format: "gz"},
+		nil,
 		func() bool { return true },
 	)
 	require.NoError(t, err)
@@ -352,3 +362,158 @@ func TestDecompressor_RunCalledTwice(t *testing.T) {
 	decompressor.Run(t.Context())
 	positionsFile.Stop()
 }
+
+func TestDecompressor_Formats(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+	l := util.TestLogger(t)
+	tempDir := t.TempDir()
+	positionsFile, err := positions.New(l, positions.Config{
+		SyncPeriod:    50 * time.Millisecond,
+		PositionsFile: filepath.Join(tempDir, "positions.yaml"),
+	})
+	require.NoError(t, err)
+	defer positionsFile.Stop()
+
+	for _, format := range []CompressionFormat{"gz", "zst", "tar", "tar.gz"} {
+		t.Run(string(format), func(t *testing.T) {
+			filename := filepath.Join(tempDir, "app.log."+string(format))
+			writeCompressedFile(t, filename, format, "line 1\nline 2\n", "line 3")
+
+			ch := loki.NewLogsReceiver()
+			decompressor, err := newDecompressor(
+				newMetrics(nil),
+				l,
+				ch,
+				positionsFile,
+				filename,
+				model.LabelSet{"filename": model.LabelValue(filename)},
+				"",
+				DecompressionConfig{Format: format},
+				nil,
+				func() bool { return true },
+			)
+			require.NoError(t, err)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				decompressor.Run(t.Context())
+			}()
+
+			for _, want := range []string{"line 1", "line 2", "line 3"} {
+				select {
+				case logEntry := <-ch.Chan():
+					require.Equal(t, want, logEntry.Line)
+				case <-time.After(5 * time.Second):
+					require.FailNow(t, "failed waiting for log line")
+				}
+			}
+			<-done
+		})
+	}
+}
+
+func TestDecompressor_Rotation(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+	l := util.TestLogger(t)
+	tempDir := t.TempDir()
+	positionsFile, err := positions.New(l, positions.Config{
+		SyncPeriod:    50 * time.Millisecond,
+		PositionsFile: filepath.Join(tempDir, "positions.yaml"),
+	})
+	require.NoError(t, err)
+	defer positionsFile.Stop()
+
+	// The file was tailed up to the end of the second line before being
+	// rotated and compressed.
+	tailed := []byte("line 1\nline 2\n")
+	rotations := newRotations(l, positionsFile)
+	rotations.add(rotation{
+		Fingerprint: fingerprint{Hash: xxhash.Sum64(tailed), Size: len(tailed)},
+		Offset:      int64(len(tailed)),
+	})
+
+	filename := filepath.Join(tempDir, "app.log.1.gz")
+	writeCompressedFile(t, filename, "gz", "line 1\nline 2\nline 3\nline 4\n")
+
+	ch := loki.NewLogsReceiver()
+	labels := model.LabelSet{"filename": model.LabelValue(filename)}
+	decompressor, err := newDecompressor(
+		newMetrics(nil),
+		l,
+		ch,
+		positionsFile,
+		filename,
+		labels,
+		"",
+		DecompressionConfig{Format: "gz"},
+		rotations,
+		func() bool { return true },
+	)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		decompressor.Run(t.Context())
+	}()
+
+	for _, want := range []string{"line 3", "line 4"} {
+		select {
+		case logEntry := <-ch.Chan():
+			require.Equal(t, want, logEntry.Line)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log line")
+		}
+	}
+	<-done
+
+	require.Empty(t, rotations.load())
+	pos, err := positionsFile.Get(filename, labels.String())
+	require.NoError(t, err)
+	require.Equal(t, int64(4), pos)
+}
+
+// writeCompressedFile writes a compressed file. The contents are concatenated,
+// except for tar archives where each of them is a member.
+func writeCompressedFile(t *testing.T, filename string, format CompressionFormat, contents ...string) {
+	f, err := os.Create(filename)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.WriteCloser
+	switch format {
+	case "gz", "tar.gz":
+		w = gzip.NewWriter(f)
+	case "zst":
+		w, err = zstd.NewWriter(f)
+		require.NoError(t, err)
+	case "tar":
+		w = nopWriteCloser{f}
+	default:
+		t.Fatalf("unsupported format %q", format)
+	}
+
+	if format == "tar" || format == "tar.gz" {
+		tw := tar.NewWriter(w)
+		for i, content := range contents {
+			require.NoError(t, tw.WriteHeader(&tar.Header{
+				Name:     fmt.Sprintf("app.log.%d", i),
+				Mode:     0600,
+				Size:     int64(len(content)),
+				Typeflag: tar.TypeReg,
+			}))
+			_, err = tw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+	} else {
+		_, err = w.Write([]byte(strings.Join(contents, "")))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -42,13 +42,14 @@ const (
 // Arguments holds values which are used to configure the loki.source.file
 // component.
 type Arguments struct {
-	Targets             []discovery.Target  `alloy:"targets,attr"`
-	ForwardTo           []loki.LogsReceiver `alloy:"forward_to,attr"`
-	Encoding            string              `alloy:"encoding,attr,optional"`
-	DecompressionConfig DecompressionConfig `alloy:"decompression,block,optional"`
-	FileWatch           FileWatch           `alloy:"file_watch,block,optional"`
-	TailFromEnd         bool                `alloy:"tail_from_end,attr,optional"`
-	LegacyPositionsFile string              `alloy:"legacy_positions_file,attr,optional"`
+	Targets             []discovery.Target        `alloy:"targets,attr"`
+	ForwardTo           []loki.LogsReceiver       `alloy:"forward_to,attr"`
+	Encoding            string                    `alloy:"encoding,attr,optional"`
+	DecompressionConfig DecompressionConfig       `alloy:"decompression,block,optional"`
+	CompressedRotations CompressedRotationsConfig `alloy:"compressed_rotations,block,optional"`
+	FileWatch           FileWatch                 `alloy:"file_watch,block,optional"`
+	TailFromEnd         bool                      `alloy:"tail_from_end,attr,optional"`
+	LegacyPositionsFile string                    `alloy:"legacy_positions_file,attr,optional"`
 }
 
 type FileWatch struct {
@@ -68,6 +69,14 @@ func (a *Arguments) SetToDefault() {
 	*a = DefaultArguments
 }
 
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.DecompressionConfig.Enabled && a.CompressedRotations.Enabled {
+		return fmt.Errorf("decompression and compressed_rotations can't be enabled at the same time")
+	}
+	return nil
+}
+
 type DecompressionConfig struct {
 	Enabled      bool              `alloy:"enabled,attr"`
 	InitialDelay time.Duration     `alloy:"initial_delay,attr,optional"`
@@ -86,6 +95,7 @@ type Component struct {
 	handler   loki.LogsReceiver
 	receivers []loki.LogsReceiver
 	posFile   positions.Positions
+	rotations *rotations
 	tasks     map[positions.Entry]runnerTask
 
 	stopping atomic.Bool
@@ -121,6 +131,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		handler:       loki.NewLogsReceiver(),
 		receivers:     args.ForwardTo,
 		posFile:       positionsFile,
+		rotations:     newRotations(o.Logger, positionsFile),
 		tasks:         make(map[positions.Entry]runnerTask),
 		updateReaders: make(chan struct{}, 1),
 	}
@@ -280,8 +291,9 @@ type targetInfo struct {
 	ReadOffset int64  `alloy:"read_offset,attr"`
 }
 
-// For most files, createReader returns a tailer implementation. If the file suffix alludes to it being
-// a compressed file, then a decompressor will be created instead.
+// For most files, createReader returns a tailer implementation. If decompression is enabled, or if
+// compressed rotations are enabled and the file suffix alludes to it being a compressed file, then a
+// decompressor will be created instead.
 func (c *Component) createReader(path string, labels model.LabelSet) (reader, error) {
 	fi, err := os.Stat(path)
 	if err != nil {
@@ -296,8 +308,21 @@ func (c *Component) createReader(path string, labels model.LabelSet) (reader, er
 		return nil, fmt.Errorf("failed to tail file, it was a directory %s", path)
 	}
 
+	decompressionConfig := c.args.DecompressionConfig
+	var rotations *rotations
+	if c.args.CompressedRotations.Enabled {
+		rotations = c.rotations
+		if format, ok := formatFromPath(path); ok {
+			decompressionConfig = DecompressionConfig{
+				Enabled:      true,
+				InitialDelay: c.args.CompressedRotations.InitialDelay,
+				Format:       format,
+			}
+		}
+	}
+
 	var reader reader
-	if c.args.DecompressionConfig.Enabled {
+	if decompressionConfig.Enabled {
 		decompressor, err := newDecompressor(
 			c.metrics,
 			c.opts.Logger,
@@ -306,7 +331,8 @@ func (c *Component) createReader(path string, labels model.LabelSet) (reader, er
 			path,
 			labels,
 			c.args.Encoding,
-			c.args.DecompressionConfig,
+			decompressionConfig,
+			rotations,
 			c.IsStopping,
 		)
 		if err != nil {
@@ -329,6 +355,7 @@ func (c *Component) createReader(path string, labels model.LabelSet) (reader, er
 			c.args.Encoding,
 			pollOptions,
 			c.args.TailFromEnd,
+			rotations,
 			c.IsStopping,
 		)
 		if err != nil {
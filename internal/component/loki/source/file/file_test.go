@@ -465,3 +465,12 @@ func checkMsg(t *testing.T, ch loki.LogsReceiver, msg string, timeout time.Durat
 		require.FailNow(t, "failed waiting for log line")
 	}
 }
+
+func TestArgumentsValidate(t *testing.T) {
+	args := DefaultArguments
+	args.DecompressionConfig = DecompressionConfig{Enabled: true, Format: "gz"}
+	require.NoError(t, args.Validate())
+
+	args.CompressedRotations = CompressedRotationsConfig{Enabled: true}
+	require.EqualError(t, args.Validate(), "decompression and compressed_rotations can't be enabled at the same time")
+}
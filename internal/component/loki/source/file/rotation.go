@@ -0,0 +1,173 @@
+package file
+
+// Rotations keep track of the files which were tailed until they were
+// rotated, so that reading the compressed sibling of a rotated file resumes
+// from the offset reached by the tailer, instead of sending the whole file
+// again.
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	// fingerprintSize is the maximum number of bytes at the start of a file
+	// used to identify its content.
+	fingerprintSize = 1024
+	// maxRotations is the maximum number of rotated files remembered.
+	maxRotations = 100
+)
+
+// rotationsKey is the key of the rotated files in the positions file.
+var rotationsKey = positions.CursorKey("rotations")
+
+// CompressedRotationsConfig configures the handling of compressed rotated
+// files.
+type CompressedRotationsConfig struct {
+	Enabled      bool          `alloy:"enabled,attr"`
+	InitialDelay time.Duration `alloy:"initial_delay,attr,optional"`
+}
+
+// formatFromPath returns the compression format of a file from its suffix.
+func formatFromPath(path string) (CompressionFormat, bool) {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz", true
+	case strings.HasSuffix(name, ".tar"):
+		return "tar", true
+	case strings.HasSuffix(name, ".gz"):
+		return "gz", true
+	case strings.HasSuffix(name, ".z"):
+		return "z", true
+	case strings.HasSuffix(name, ".bz2"):
+		return "bz2", true
+	case strings.HasSuffix(name, ".zst"):
+		return "zst", true
+	}
+	return "", false
+}
+
+// fingerprint identifies a file by the hash of its first bytes.
+type fingerprint struct {
+	Hash uint64 `json:"hash"`
+	Size int    `json:"size"`
+}
+
+// matches returns whether data starts with the bytes of the fingerprint.
+func (f fingerprint) matches(data []byte) bool {
+	return f.Size > 0 && len(data) >= f.Size && xxhash.Sum64(data[:f.Size]) == f.Hash
+}
+
+// readFingerprint returns the fingerprint of the file at path, and the bytes
+// it was computed from.
+func readFingerprint(path string) (fingerprint, []byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fingerprint{}, nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, fingerprintSize)
+	n, err := io.ReadFull(f, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fingerprint{}, nil, err
+	}
+	data = data[:n]
+	return fingerprint{Hash: xxhash.Sum64(data), Size: n}, data, nil
+}
+
+// rotation is a rotated file, which was read up to Offset.
+type rotation struct {
+	Fingerprint fingerprint `json:"fingerprint"`
+	Offset      int64       `json:"offset"`
+}
+
+// rotations is the list of rotated files, stored in the positions file.
+type rotations struct {
+	logger    log.Logger
+	positions positions.Positions
+
+	mut sync.Mutex
+}
+
+func newRotations(logger log.Logger, positions positions.Positions) *rotations {
+	return &rotations{
+		logger:    logger,
+		positions: positions,
+	}
+}
+
+// add remembers a rotated file. The oldest rotated files are forgotten once
+// there are more than maxRotations.
+func (r *rotations) add(rot rotation) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	list := r.load()
+	for i, existing := range list {
+		if existing.Fingerprint == rot.Fingerprint {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	list = append(list, rot)
+	if len(list) > maxRotations {
+		list = list[len(list)-maxRotations:]
+	}
+	r.store(list)
+}
+
+// match returns the offset reached in the rotated file whose content starts
+// with data, and forgets it.
+func (r *rotations) match(data []byte) (int64, bool) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	list := r.load()
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Fingerprint.matches(data) {
+			offset := list[i].Offset
+			r.store(append(list[:i], list[i+1:]...))
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+func (r *rotations) load() []rotation {
+	value := r.positions.GetString(rotationsKey, "")
+	if value == "" {
+		return nil
+	}
+	var list []rotation
+	if err := json.Unmarshal([]byte(value), &list); err != nil {
+		level.Warn(r.logger).Log("msg", "failed to decode rotated files from positions file", "err", err)
+		return nil
+	}
+	return list
+}
+
+func (r *rotations) store(list []rotation) {
+	if len(list) == 0 {
+		r.positions.Remove(rotationsKey, "")
+		return
+	}
+	value, err := json.Marshal(list)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to encode rotated files", "err", err)
+		return
+	}
+	r.positions.PutString(rotationsKey, "", string(value))
+}
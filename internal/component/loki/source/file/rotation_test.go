@@ -0,0 +1,104 @@
+package file
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+	"github.com/grafana/alloy/internal/util"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]CompressionFormat{
+		"/var/log/app.log.1.gz":   "gz",
+		"/var/log/app.log.1.GZ":   "gz",
+		"/var/log/app.log.tar.gz": "tar.gz",
+		"/var/log/app.log.tgz":    "tar.gz",
+		"/var/log/app.log.tar":    "tar",
+		"/var/log/app.log.z":      "z",
+		"/var/log/app.log.bz2":    "bz2",
+		"/var/log/app.log.zst":    "zst",
+	}
+	for path, want := range tests {
+		got, ok := formatFromPath(path)
+		require.True(t, ok, path)
+		require.Equal(t, want, got, path)
+	}
+
+	_, ok := formatFromPath("/var/log/app.log.1")
+	require.False(t, ok)
+}
+
+func TestRotations(t *testing.T) {
+	l := util.TestLogger(t)
+	positionsPath := filepath.Join(t.TempDir(), "positions.yaml")
+	positionsFile, err := positions.New(l, positions.Config{
+		SyncPeriod:    10 * time.Second,
+		PositionsFile: positionsPath,
+	})
+	require.NoError(t, err)
+
+	r := newRotations(l, positionsFile)
+	first := []byte("first line\n")
+	second := []byte("second line\n")
+	r.add(rotation{Fingerprint: fingerprint{Hash: xxhash.Sum64(first), Size: len(first)}, Offset: 5})
+	r.add(rotation{Fingerprint: fingerprint{Hash: xxhash.Sum64(second), Size: len(second)}, Offset: 7})
+
+	// Data which is shorter than the fingerprint or with another content
+	// doesn't match.
+	_, ok := r.match([]byte("first"))
+	require.False(t, ok)
+	_, ok = r.match([]byte("third line\n"))
+	require.False(t, ok)
+
+	// The rotations are kept across restarts.
+	positionsFile.Stop()
+	positionsFile, err = positions.New(l, positions.Config{
+		SyncPeriod:    10 * time.Second,
+		PositionsFile: positionsPath,
+	})
+	require.NoError(t, err)
+	defer positionsFile.Stop()
+	r = newRotations(l, positionsFile)
+
+	offset, ok := r.match([]byte("first line\nand more lines\n"))
+	require.True(t, ok)
+	require.Equal(t, int64(5), offset)
+
+	// A rotation is only matched once.
+	_, ok = r.match([]byte("first line\nand more lines\n"))
+	require.False(t, ok)
+
+	offset, ok = r.match(second)
+	require.True(t, ok)
+	require.Equal(t, int64(7), offset)
+	require.Empty(t, r.load())
+}
+
+func TestRotations_Max(t *testing.T) {
+	l := util.TestLogger(t)
+	positionsFile, err := positions.New(l, positions.Config{
+		SyncPeriod:    10 * time.Second,
+		PositionsFile: filepath.Join(t.TempDir(), "positions.yaml"),
+	})
+	require.NoError(t, err)
+	defer positionsFile.Stop()
+
+	r := newRotations(l, positionsFile)
+	for i := 0; i < maxRotations+10; i++ {
+		data := []byte(fmt.Sprintf("line %d\n", i))
+		r.add(rotation{Fingerprint: fingerprint{Hash: xxhash.Sum64(data), Size: len(data)}, Offset: int64(i)})
+	}
+	require.Len(t, r.load(), maxRotations)
+
+	_, ok := r.match([]byte("line 0\n"))
+	require.False(t, ok)
+	offset, ok := r.match([]byte(fmt.Sprintf("line %d\n", maxRotations+9)))
+	require.True(t, ok)
+	require.Equal(t, int64(maxRotations+9), offset)
+}
@@ -49,6 +49,7 @@ func TestRunnerTailer(t *testing.T) {
 			MaxPollFrequency: 25 * time.Millisecond,
 		},
 		false,
+		nil,
 		func() bool { return true },
 	)
 	require.NoError(t, err)
@@ -91,6 +92,7 @@ func TestRunnerDecompressor(t *testing.T) {
 		labels,
 		"",
 		DecompressionConfig{Format: "gz"},
+		nil,
 		func() bool { return true },
 	)
 	require.NoError(t, err)
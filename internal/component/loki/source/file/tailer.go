@@ -50,10 +50,17 @@ type tailer struct {
 
 	tail    *tail.Tail
 	decoder *encoding.Decoder
+
+	// rotations is set when the compressed siblings of rotated files are
+	// read. The fingerprint of the file and the last position read from it
+	// are then tracked to detect rotations.
+	rotations   *rotations
+	fingerprint fingerprint
+	lastPos     int64
 }
 
 func newTailer(metrics *metrics, logger log.Logger, receiver loki.LogsReceiver, positions positions.Positions, path string,
-	labels model.LabelSet, encoding string, pollOptions watch.PollingFileWatcherOptions, tailFromEnd bool, componentStopping func() bool) (*tailer, error) {
+	labels model.LabelSet, encoding string, pollOptions watch.PollingFileWatcherOptions, tailFromEnd bool, rotations *rotations, componentStopping func() bool) (*tailer, error) {
 
 	tailer := &tailer{
 		metrics:           metrics,
@@ -66,6 +73,7 @@ func newTailer(metrics *metrics, logger log.Logger, receiver loki.LogsReceiver,
 		running:           atomic.NewBool(false),
 		tailFromEnd:       tailFromEnd,
 		pollOptions:       pollOptions,
+		rotations:         rotations,
 		componentStopping: componentStopping,
 	}
 
@@ -219,6 +227,10 @@ func (t *tailer) initRun() (loki.EntryHandler, error) {
 	}
 
 	t.tail = tail
+	if t.rotations != nil {
+		t.fingerprint, _, _ = readFingerprint(t.path)
+		t.lastPos = pos
+	}
 
 	labelsMiddleware := t.labels.Merge(model.LabelSet{filenameLabel: model.LabelValue(t.path)})
 	handler := loki.AddLabelsMiddleware(labelsMiddleware).Wrap(loki.NewEntryHandler(t.receiver.Chan(), func() {}))
@@ -346,10 +358,37 @@ func (t *tailer) markPositionAndSize() error {
 	t.metrics.totalBytes.WithLabelValues(t.path).Set(float64(size))
 	t.metrics.readBytes.WithLabelValues(t.path).Set(float64(pos))
 	t.positions.Put(t.path, t.labelsStr, pos)
+	t.trackRotation(pos)
 
 	return nil
 }
 
+// trackRotation compares the first bytes of the file with the ones seen
+// previously. When they differ, the file was rotated, and the position
+// reached in the previous file is remembered so that its compressed sibling
+// can be read from there.
+func (t *tailer) trackRotation(pos int64) {
+	if t.rotations == nil {
+		return
+	}
+	fp, data, err := readFingerprint(t.path)
+	if err != nil {
+		// The file was moved away, but pos is still the position reached in
+		// the open file.
+		if os.IsNotExist(err) {
+			t.lastPos = pos
+		}
+		level.Debug(t.logger).Log("msg", "failed to read file fingerprint", "path", t.path, "error", err)
+		return
+	}
+	if t.fingerprint.Size > 0 && !t.fingerprint.matches(data) {
+		level.Debug(t.logger).Log("msg", "file was rotated", "path", t.path, "position", t.lastPos)
+		t.rotations.add(rotation{Fingerprint: t.fingerprint, Offset: t.lastPos})
+	}
+	t.fingerprint = fp
+	t.lastPos = pos
+}
+
 func (t *tailer) stop(done chan struct{}) {
 	// Save the current position before shutting down tailer to ensure that if the file is tailed again
 	// it start where it left off.
@@ -381,6 +420,11 @@ func (t *tailer) stop(done chan struct{}) {
 	// we should clear the entry from the positions file.
 	if !t.componentStopping() {
 		t.positions.Remove(t.path, t.labelsStr)
+
+		// The file may be gone because it was rotated.
+		if t.rotations != nil && t.fingerprint.Size > 0 {
+			t.rotations.add(rotation{Fingerprint: t.fingerprint, Offset: t.lastPos})
+		}
 	}
 }
 
@@ -122,6 +122,7 @@ func TestTailer(t *testing.T) {
 			MaxPollFrequency: 25 * time.Millisecond,
 		},
 		false,
+		nil,
 		func() bool { return true },
 	)
 	require.NoError(t, err)
@@ -217,6 +218,7 @@ func TestTailerPositionFileEntryDeleted(t *testing.T) {
 			MaxPollFrequency: 25 * time.Millisecond,
 		},
 		false,
+		nil,
 		func() bool { return false },
 	)
 	require.NoError(t, err)
@@ -281,6 +283,7 @@ func TestTailerDeleteFileInstant(t *testing.T) {
 			MaxPollFrequency: 25 * time.Millisecond,
 		},
 		false,
+		nil,
 		func() bool { return true },
 	)
 	require.NoError(t, err)
@@ -302,3 +305,112 @@ func TestTailerDeleteFileInstant(t *testing.T) {
 		t.Fatal("tailer deadlocked")
 	}
 }
+
+func TestTailerCompressedRotation(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("go.opencensus.io/stats/view.(*worker).start"))
+	l := util.TestLogger(t)
+	ch1 := loki.NewLogsReceiver()
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "app.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("line 1\nline 2\n"), 0600))
+	positionsFile, err := positions.New(l, positions.Config{
+		SyncPeriod:        50 * time.Millisecond,
+		PositionsFile:     filepath.Join(tempDir, "positions.yaml"),
+		IgnoreInvalidYaml: false,
+		ReadOnly:          false,
+	})
+	require.NoError(t, err)
+	defer positionsFile.Stop()
+
+	rotations := newRotations(l, positionsFile)
+	labels := model.LabelSet{"filename": model.LabelValue(logPath)}
+	tailer, err := newTailer(
+		newMetrics(nil),
+		l,
+		ch1,
+		positionsFile,
+		logPath,
+		labels,
+		"",
+		watch.PollingFileWatcherOptions{
+			MinPollFrequency: 25 * time.Millisecond,
+			MaxPollFrequency: 25 * time.Millisecond,
+		},
+		false,
+		rotations,
+		func() bool { return true },
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		tailer.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, want := range []string{"line 1", "line 2"} {
+		select {
+		case logEntry := <-ch1.Chan():
+			require.Equal(t, want, logEntry.Line)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log line")
+		}
+	}
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		pos, err := positionsFile.Get(logPath, labels.String())
+		assert.NoError(c, err)
+		assert.Equal(c, int64(14), pos)
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Rotate the file.
+	require.NoError(t, os.Rename(logPath, logPath+".1"))
+	require.NoError(t, os.WriteFile(logPath, []byte("new line\n"), 0600))
+	select {
+	case logEntry := <-ch1.Chan():
+		require.Equal(t, "new line", logEntry.Line)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "failed waiting for log line")
+	}
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		list := rotations.load()
+		if assert.Len(c, list, 1) {
+			assert.Equal(c, int64(14), list[0].Offset)
+		}
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// The compressed sibling is only read from where the tailer stopped.
+	gzPath := logPath + ".1.gz"
+	writeCompressedFile(t, gzPath, "gz", "line 1\nline 2\nline 3\n")
+	decompressor, err := newDecompressor(
+		newMetrics(nil),
+		l,
+		ch1,
+		positionsFile,
+		gzPath,
+		model.LabelSet{"filename": model.LabelValue(gzPath)},
+		"",
+		DecompressionConfig{Format: "gz"},
+		rotations,
+		func() bool { return true },
+	)
+	require.NoError(t, err)
+	decompressorDone := make(chan struct{})
+	go func() {
+		defer close(decompressorDone)
+		decompressor.Run(t.Context())
+	}()
+
+	select {
+	case logEntry := <-ch1.Chan():
+		require.Equal(t, "line 3", logEntry.Line)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "failed waiting for log line")
+	}
+	<-decompressorDone
+}
@@ -2,6 +2,7 @@ package gelf
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/grafana/loki/v3/clients/pkg/promtail/scrapeconfig"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/spool"
 	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
 	"github.com/grafana/alloy/internal/component/loki/source/gelf/internal/target"
 	"github.com/grafana/alloy/internal/featuregate"
@@ -36,11 +38,19 @@ type Component struct {
 	o         component.Options
 	metrics   *target.Metrics
 	handler   *handler
-	receivers []loki.LogsReceiver
+	forwarder *spool.Forwarder
 }
 
 // Run starts the component.
 func (c *Component) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.forwarder.Run(ctx)
+	}()
+
 	defer func() {
 		c.target.Stop()
 	}()
@@ -49,7 +59,6 @@ func (c *Component) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case entry := <-c.handler.c:
-			c.mut.RLock()
 			lokiEntry := loki.Entry{
 				Labels: entry.Labels,
 				Entry:  entry.Entry,
@@ -57,10 +66,9 @@ func (c *Component) Run(ctx context.Context) error {
 			if lokiEntry.Labels["job"] == "" {
 				lokiEntry.Labels["job"] = model.LabelValue(c.o.ID)
 			}
-			for _, r := range c.receivers {
-				r.Chan() <- lokiEntry
+			if err := c.forwarder.Forward(ctx, lokiEntry); err != nil {
+				return nil
 			}
-			c.mut.RUnlock()
 		}
 	}
 }
@@ -72,10 +80,12 @@ func (c *Component) Update(args component.Arguments) error {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
+	if err := c.forwarder.Update(newArgs.Receivers, newArgs.Spool); err != nil {
+		return fmt.Errorf("failed to open spool: %w", err)
+	}
 	if c.target != nil {
 		c.target.Stop()
 	}
-	c.receivers = newArgs.Receivers
 
 	var rcs []*relabel.Config
 	if len(newArgs.RelabelRules) > 0 {
@@ -97,6 +107,7 @@ type Arguments struct {
 	UseIncomingTimestamp bool                `alloy:"use_incoming_timestamp,attr,optional"`
 	RelabelRules         alloy_relabel.Rules `alloy:"relabel_rules,attr,optional"`
 	Receivers            []loki.LogsReceiver `alloy:"forward_to,attr"`
+	Spool                *spool.Arguments    `alloy:"spool,block,optional"`
 }
 
 func defaultArgs() Arguments {
@@ -123,9 +134,10 @@ func convertConfig(a Arguments) *scrapeconfig.GelfTargetConfig {
 func New(o component.Options, args Arguments) (*Component, error) {
 	metrics := target.NewMetrics(o.Registerer)
 	c := &Component{
-		o:       o,
-		metrics: metrics,
-		handler: &handler{c: make(chan loki.Entry)},
+		o:         o,
+		metrics:   metrics,
+		handler:   &handler{c: make(chan loki.Entry)},
+		forwarder: spool.NewForwarder(o.Logger, o.Registerer, o.DataPath),
 	}
 	// Call to Update() to start readers and set receivers once at the start.
 	if err := c.Update(args); err != nil {
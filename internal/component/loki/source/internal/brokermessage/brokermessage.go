@@ -0,0 +1,163 @@
+// Package brokermessage turns the messages received from message brokers, such
+// as MQTT or NATS, into log entries. It's shared by the loki.source components
+// subscribing to message brokers.
+package brokermessage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+)
+
+// Supported message formats.
+const (
+	FormatPlain = "plain"
+	FormatJSON  = "json"
+)
+
+// Config configures how messages are parsed.
+type Config struct {
+	// Format of the messages, either FormatPlain or FormatJSON.
+	Format string
+	// MessageField is the field of JSON messages used as the log line. The
+	// whole message is used when it's empty or missing.
+	MessageField string
+	// TimestampField is the field of JSON messages used as the timestamp of
+	// the log entry when UseIncomingTimestamp is set.
+	TimestampField string
+	// UseIncomingTimestamp sets the timestamp of the log entries from the
+	// messages instead of the time they were received.
+	UseIncomingTimestamp bool
+	// Labels are added to every log entry.
+	Labels model.LabelSet
+	// RelabelConfigs are applied to the labels of the messages.
+	RelabelConfigs []*relabel.Config
+}
+
+// Validate returns an error if the format is not supported.
+func (c Config) Validate() error {
+	switch c.Format {
+	case FormatPlain, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q, must be either %q or %q", c.Format, FormatPlain, FormatJSON)
+	}
+}
+
+// Message is a message received from a broker.
+type Message struct {
+	// Data is the payload of the message.
+	Data []byte
+	// Labels are the internal labels describing the message, such as its
+	// topic, which can be used by the relabel rules.
+	Labels model.LabelSet
+	// Timestamp is the time the broker received the message, if known.
+	Timestamp time.Time
+}
+
+// Parse returns the log entry of a message. It returns false if the message
+// was dropped by the relabel rules.
+func (c Config) Parse(msg Message) (loki.Entry, bool, error) {
+	lbs, keep := c.labels(msg.Labels)
+	if !keep {
+		return loki.Entry{}, false, nil
+	}
+
+	line, ts := string(msg.Data), msg.Timestamp
+	if c.Format == FormatJSON {
+		var err error
+		line, ts, err = c.parseJSON(msg.Data, ts)
+		if err != nil {
+			return loki.Entry{}, false, err
+		}
+	}
+	if !c.UseIncomingTimestamp || ts.IsZero() {
+		ts = time.Now()
+	}
+
+	return loki.Entry{
+		Labels: lbs,
+		Entry: logproto.Entry{
+			Timestamp: ts,
+			Line:      line,
+		},
+	}, true, nil
+}
+
+func (c Config) parseJSON(data []byte, ts time.Time) (string, time.Time, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", ts, fmt.Errorf("message is not a JSON object: %w", err)
+	}
+
+	line := string(bytes.TrimSpace(data))
+	if raw, ok := fields[c.MessageField]; ok && c.MessageField != "" {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			line = s
+		} else {
+			line = string(raw)
+		}
+	}
+
+	if raw, ok := fields[c.TimestampField]; ok && c.TimestampField != "" && c.UseIncomingTimestamp {
+		parsed, err := parseTimestamp(raw)
+		if err != nil {
+			return "", ts, fmt.Errorf("failed to parse field %q: %w", c.TimestampField, err)
+		}
+		ts = parsed
+	}
+	return line, ts, nil
+}
+
+// parseTimestamp parses an RFC3339 timestamp, or a number of seconds since the
+// Unix epoch.
+func parseTimestamp(raw json.RawMessage) (time.Time, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	seconds, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp must be an RFC3339 string or a number of seconds")
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(math.Round(frac*float64(time.Second)))), nil
+}
+
+// labels applies the relabel rules to the labels of a message, and returns
+// them with the static labels, without the internal ones.
+func (c Config) labels(msgLabels model.LabelSet) (model.LabelSet, bool) {
+	out := c.Labels.Clone()
+	if len(c.RelabelConfigs) == 0 {
+		return out, true
+	}
+
+	builder := labels.NewScratchBuilder(len(msgLabels))
+	for name, value := range msgLabels {
+		builder.Add(string(name), string(value))
+	}
+	builder.Sort()
+	processed, keep := relabel.Process(builder.Labels(), c.RelabelConfigs...)
+	if !keep {
+		return nil, false
+	}
+	processed.Range(func(l labels.Label) {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			return
+		}
+		out[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	})
+	return out, true
+}
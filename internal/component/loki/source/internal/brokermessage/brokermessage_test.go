@@ -0,0 +1,123 @@
+package brokermessage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Plain(t *testing.T) {
+	cfg := Config{
+		Format: FormatPlain,
+		Labels: model.LabelSet{"job": "devices"},
+		RelabelConfigs: []*relabel.Config{
+			{
+				SourceLabels: model.LabelNames{"__topic"},
+				Regex:        relabel.MustNewRegexp("devices/([^/]+)/logs"),
+				TargetLabel:  "device",
+				Replacement:  "$1",
+				Action:       relabel.Replace,
+			},
+			{
+				SourceLabels: model.LabelNames{"__topic"},
+				Regex:        relabel.MustNewRegexp("devices/ignored/.*"),
+				Action:       relabel.Drop,
+			},
+		},
+	}
+	require.NoError(t, cfg.Validate())
+
+	incoming := time.Unix(100, 0)
+	entry, ok, err := cfg.Parse(Message{
+		Data:      []byte("hello"),
+		Labels:    model.LabelSet{"__topic": "devices/d1/logs"},
+		Timestamp: incoming,
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "hello", entry.Line)
+	require.Equal(t, model.LabelSet{"job": "devices", "device": "d1"}, entry.Labels)
+	require.WithinDuration(t, time.Now(), entry.Timestamp, time.Second)
+
+	cfg.UseIncomingTimestamp = true
+	entry, ok, err = cfg.Parse(Message{Data: []byte("hello"), Timestamp: incoming})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, incoming, entry.Timestamp)
+
+	_, ok, err = cfg.Parse(Message{
+		Data:   []byte("hello"),
+		Labels: model.LabelSet{"__topic": "devices/ignored/logs"},
+	})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestParse_JSON(t *testing.T) {
+	cfg := Config{
+		Format:               FormatJSON,
+		MessageField:         "msg",
+		TimestampField:       "ts",
+		UseIncomingTimestamp: true,
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		wantLine string
+		wantTime time.Time
+		wantErr  string
+	}{
+		{
+			name:     "string message and RFC3339 timestamp",
+			data:     `{"msg": "hello", "ts": "2024-01-02T03:04:05.5Z"}`,
+			wantLine: "hello",
+			wantTime: time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC),
+		},
+		{
+			name:     "object message and unix timestamp",
+			data:     `{"msg": {"a": 1}, "ts": 1700000000.25}`,
+			wantLine: `{"a": 1}`,
+			wantTime: time.Unix(1700000000, 250000000),
+		},
+		{
+			name:     "missing fields",
+			data:     ` {"level": "info"} `,
+			wantLine: `{"level": "info"}`,
+		},
+		{
+			name:    "not an object",
+			data:    `hello`,
+			wantErr: "message is not a JSON object",
+		},
+		{
+			name:    "invalid timestamp",
+			data:    `{"ts": true}`,
+			wantErr: `failed to parse field "ts"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, ok, err := cfg.Parse(Message{Data: []byte(tc.data)})
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, tc.wantLine, entry.Line)
+			if tc.wantTime.IsZero() {
+				require.WithinDuration(t, time.Now(), entry.Timestamp, time.Second)
+			} else {
+				require.True(t, tc.wantTime.Equal(entry.Timestamp), entry.Timestamp)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	require.ErrorContains(t, Config{Format: "xml"}.Validate(), `unsupported format "xml"`)
+}
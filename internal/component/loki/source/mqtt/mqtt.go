@@ -0,0 +1,194 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/component/loki/source/internal/brokermessage"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.mqtt",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the loki.source.mqtt
+// component.
+type Arguments struct {
+	Brokers      []string          `alloy:"brokers,attr"`
+	Topics       []string          `alloy:"topics,attr"`
+	QoS          int               `alloy:"qos,attr,optional"`
+	ClientID     string            `alloy:"client_id,attr,optional"`
+	CleanSession bool              `alloy:"clean_session,attr,optional"`
+	Username     string            `alloy:"username,attr,optional"`
+	Password     alloytypes.Secret `alloy:"password,attr,optional"`
+	TLSConfig    config.TLSConfig  `alloy:"tls_config,block,optional"`
+
+	Format               string            `alloy:"format,attr,optional"`
+	MessageField         string            `alloy:"message_field,attr,optional"`
+	TimestampField       string            `alloy:"timestamp_field,attr,optional"`
+	UseIncomingTimestamp bool              `alloy:"use_incoming_timestamp,attr,optional"`
+	Labels               map[string]string `alloy:"labels,attr,optional"`
+
+	ForwardTo    []loki.LogsReceiver `alloy:"forward_to,attr"`
+	RelabelRules alloy_relabel.Rules `alloy:"relabel_rules,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for a mqtt component.
+var DefaultArguments = Arguments{
+	QoS:            1,
+	CleanSession:   true,
+	Format:         brokermessage.FormatPlain,
+	MessageField:   "message",
+	TimestampField: "timestamp",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Brokers) == 0 {
+		return fmt.Errorf("brokers must not be empty")
+	}
+	if len(a.Topics) == 0 {
+		return fmt.Errorf("topics must not be empty")
+	}
+	if a.QoS < 0 || a.QoS > 2 {
+		return fmt.Errorf("qos must be 0, 1 or 2")
+	}
+	if err := a.TLSConfig.Validate(); err != nil {
+		return err
+	}
+	return a.convert().Validate()
+}
+
+// convert returns the configuration used to parse the messages.
+func (a *Arguments) convert() brokermessage.Config {
+	lbls := make(model.LabelSet, len(a.Labels))
+	for k, v := range a.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return brokermessage.Config{
+		Format:               a.Format,
+		MessageField:         a.MessageField,
+		TimestampField:       a.TimestampField,
+		UseIncomingTimestamp: a.UseIncomingTimestamp,
+		Labels:               lbls,
+		RelabelConfigs:       alloy_relabel.ComponentToPromRelabelConfigs(a.RelabelRules),
+	}
+}
+
+// Component implements the loki.source.mqtt component.
+type Component struct {
+	opts component.Options
+
+	mut    sync.RWMutex
+	fanout []loki.LogsReceiver
+	target *target
+
+	handler loki.LogsReceiver
+}
+
+// New creates a new loki.source.mqtt component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		fanout:  args.ForwardTo,
+		handler: loki.NewLogsReceiver(),
+	}
+
+	// Call to Update() to connect to the brokers and set receivers once at the
+	// start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		level.Info(c.opts.Logger).Log("msg", "loki.source.mqtt component shutting down, stopping target")
+		if c.target != nil {
+			c.target.stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.handler.Chan():
+			c.mut.RLock()
+			for _, receiver := range c.fanout {
+				receiver.Chan() <- entry
+			}
+			c.mut.RUnlock()
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	tlsConfig, err := promconfig.NewTLSConfig(newArgs.TLSConfig.Convert())
+	if err != nil {
+		return fmt.Errorf("invalid tls_config: %w", err)
+	}
+	clientID := newArgs.ClientID
+	if clientID == "" {
+		clientID = c.opts.ID
+	}
+	clientOpts := paho.NewClientOptions().
+		SetClientID(clientID).
+		SetCleanSession(newArgs.CleanSession).
+		SetUsername(newArgs.Username).
+		SetPassword(string(newArgs.Password)).
+		SetTLSConfig(tlsConfig).
+		SetOrderMatters(true).
+		SetAutoAckDisabled(true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second)
+	for _, broker := range newArgs.Brokers {
+		clientOpts.AddBroker(broker)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.fanout = newArgs.ForwardTo
+
+	if c.target != nil {
+		c.target.stop()
+	}
+	c.target = newTarget(c.opts.Logger, clientOpts, newArgs.Topics, byte(newArgs.QoS), newArgs.convert(), c.handler)
+	return nil
+}
@@ -0,0 +1,245 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestAlloyConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	brokers                = ["tcp://localhost:1883"]
+	topics                 = ["devices/+/logs"]
+	qos                    = 2
+	client_id              = "alloy"
+	clean_session          = false
+	username               = "user"
+	password               = "password"
+	format                 = "json"
+	use_incoming_timestamp = true
+	labels                 = {component = "loki.source.mqtt"}
+	forward_to             = []
+`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.NoError(t, err)
+	require.Equal(t, "message", args.MessageField)
+	require.Equal(t, "timestamp", args.TimestampField)
+
+	err = syntax.Unmarshal([]byte(`
+	brokers    = ["tcp://localhost:1883"]
+	topics     = ["logs"]
+	qos        = 3
+	forward_to = []
+`), &args)
+	require.ErrorContains(t, err, "qos must be 0, 1 or 2")
+
+	err = syntax.Unmarshal([]byte(`
+	brokers    = ["tcp://localhost:1883"]
+	topics     = ["logs"]
+	format     = "xml"
+	forward_to = []
+`), &args)
+	require.ErrorContains(t, err, `unsupported format "xml"`)
+}
+
+func TestLokiSourceMQTT(t *testing.T) {
+	broker := newTestBroker(t)
+
+	var re alloy_relabel.Regexp
+	require.NoError(t, re.UnmarshalText([]byte("devices/([^/]+)/logs")))
+	args := DefaultArguments
+	args.Brokers = []string{"tcp://" + broker.addr}
+	args.Topics = []string{"devices/+/logs"}
+	args.Format = "json"
+	args.Labels = map[string]string{"job": "devices"}
+	args.RelabelRules = alloy_relabel.Rules{{
+		SourceLabels: []string{labelTopic},
+		Regex:        re,
+		TargetLabel:  "device",
+		Replacement:  "$1",
+		Action:       alloy_relabel.Replace,
+	}}
+	receiver := loki.NewLogsReceiver()
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+
+	c, err := New(component.Options{
+		ID:         "loki.source.mqtt.test",
+		Logger:     util.TestLogger(t),
+		Registerer: prometheus.NewRegistry(),
+	}, args)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Equal(t, []string{"devices/+/logs"}, broker.waitSubscribed(t))
+
+	broker.publish(t, "devices/d1/logs", `{"message": "booted", "level": "info"}`)
+	select {
+	case entry := <-receiver.Chan():
+		require.Equal(t, "booted", entry.Line)
+		require.Equal(t, model.LabelSet{"job": "devices", "device": "d1"}, entry.Labels)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry received")
+	}
+	require.Equal(t, `{"message": "booted", "level": "info"}`, broker.waitAck(t))
+
+	// Messages which can't be parsed are dropped, and acknowledged.
+	broker.publish(t, "devices/d1/logs", `not json`)
+	require.Equal(t, `not json`, broker.waitAck(t))
+	select {
+	case entry := <-receiver.Chan():
+		t.Fatalf("unexpected entry %q", entry.Line)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTarget_NoAckWhenStopped(t *testing.T) {
+	broker := newTestBroker(t)
+
+	clientOpts := paho.NewClientOptions().
+		SetClientID("test").
+		SetAutoAckDisabled(true).
+		SetOrderMatters(true).
+		AddBroker("tcp://" + broker.addr)
+	// The handler is never read, so the entry can't be handed over.
+	handler := loki.NewLogsReceiver()
+	parser := DefaultArguments.convert()
+	tgt := newTarget(util.TestLogger(t), clientOpts, []string{"logs"}, 1, parser, handler)
+
+	broker.waitSubscribed(t)
+	broker.publish(t, "logs", "first")
+	time.Sleep(100 * time.Millisecond)
+	tgt.stop()
+
+	select {
+	case payload := <-broker.acks:
+		t.Fatalf("unexpected acknowledgement of message %q", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// testBroker is an embedded MQTT broker which reports the subscriptions of its
+// clients and the QoS 1 messages they acknowledged.
+type testBroker struct {
+	mochi.HookBase
+
+	server *mochi.Server
+	addr   string
+
+	subscribed chan []string
+	acks       chan string
+
+	mut      sync.Mutex
+	inflight map[uint16]string // Payloads of the messages waiting for an acknowledgement.
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	b := &testBroker{
+		server: mochi.New(&mochi.Options{
+			InlineClient: true,
+			Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}),
+		subscribed: make(chan []string, 10),
+		acks:       make(chan string, 10),
+		inflight:   make(map[uint16]string),
+	}
+	require.NoError(t, b.server.AddHook(new(auth.AllowHook), nil))
+	require.NoError(t, b.server.AddHook(b, nil))
+
+	listener := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	require.NoError(t, b.server.AddListener(listener))
+	require.NoError(t, b.server.Serve())
+	t.Cleanup(func() { _ = b.server.Close() })
+	b.addr = listener.Address()
+	return b
+}
+
+// ID implements mochi.Hook.
+func (b *testBroker) ID() string { return "test" }
+
+// Provides implements mochi.Hook.
+func (b *testBroker) Provides(hook byte) bool {
+	return bytes.Contains([]byte{mochi.OnSubscribed, mochi.OnQosPublish, mochi.OnQosComplete}, []byte{hook})
+}
+
+// OnSubscribed implements mochi.Hook.
+func (b *testBroker) OnSubscribed(cl *mochi.Client, pk packets.Packet, _ []byte) {
+	if cl.Net.Inline {
+		return
+	}
+	var topics []string
+	for _, f := range pk.Filters {
+		topics = append(topics, f.Filter)
+	}
+	b.subscribed <- topics
+}
+
+// OnQosPublish implements mochi.Hook.
+func (b *testBroker) OnQosPublish(_ *mochi.Client, pk packets.Packet, _ int64, _ int) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.inflight[pk.PacketID] = string(pk.Payload)
+}
+
+// OnQosComplete implements mochi.Hook.
+func (b *testBroker) OnQosComplete(_ *mochi.Client, pk packets.Packet) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.acks <- b.inflight[pk.PacketID]
+	delete(b.inflight, pk.PacketID)
+}
+
+// publish sends a QoS 1 message to the subscribers of topic.
+func (b *testBroker) publish(t *testing.T, topic, payload string) {
+	require.NoError(t, b.server.Publish(topic, []byte(payload), false, 1))
+}
+
+func (b *testBroker) waitSubscribed(t *testing.T) []string {
+	select {
+	case topics := <-b.subscribed:
+		return topics
+	case <-time.After(10 * time.Second):
+		t.Fatal("client didn't subscribe")
+		return nil
+	}
+}
+
+// waitAck returns the payload of the next message acknowledged by a client.
+func (b *testBroker) waitAck(t *testing.T) string {
+	select {
+	case payload := <-b.acks:
+		return payload
+	case <-time.After(5 * time.Second):
+		t.Fatal("message wasn't acknowledged")
+		return ""
+	}
+}
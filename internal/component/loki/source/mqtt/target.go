@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"strconv"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/loki/source/internal/brokermessage"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Internal labels of the messages, available to the relabel rules.
+const (
+	labelTopic    = "__meta_mqtt_topic"
+	labelQoS      = "__meta_mqtt_qos"
+	labelRetained = "__meta_mqtt_retained"
+)
+
+// disconnectQuiesce is the time in milliseconds given to the client to finish
+// the work in progress when disconnecting.
+const disconnectQuiesce = 250
+
+// target subscribes to the topics of the brokers and sends the received
+// messages to the handler.
+type target struct {
+	logger  log.Logger
+	client  paho.Client
+	topics  map[string]byte
+	parser  brokermessage.Config
+	handler loki.LogsReceiver
+	done    chan struct{}
+}
+
+func newTarget(logger log.Logger, opts *paho.ClientOptions, topics []string, qos byte, parser brokermessage.Config, handler loki.LogsReceiver) *target {
+	t := &target{
+		logger:  logger,
+		topics:  make(map[string]byte, len(topics)),
+		parser:  parser,
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	for _, topic := range topics {
+		t.topics[topic] = qos
+	}
+
+	// Subscribing when connected also restores the subscriptions after the
+	// client reconnects.
+	opts.SetOnConnectHandler(t.subscribe)
+	opts.SetConnectionLostHandler(func(_ paho.Client, err error) {
+		level.Warn(t.logger).Log("msg", "lost connection to MQTT broker, reconnecting", "err", err)
+	})
+	t.client = paho.NewClient(opts)
+
+	// The client connects in the background, and retries until it succeeds.
+	t.client.Connect()
+	return t
+}
+
+func (t *target) subscribe(client paho.Client) {
+	level.Info(t.logger).Log("msg", "connected to MQTT broker, subscribing to topics")
+	token := client.SubscribeMultiple(t.topics, t.handleMessage)
+	go func() {
+		if token.Wait(); token.Error() != nil {
+			level.Error(t.logger).Log("msg", "failed to subscribe to topics", "err", token.Error())
+		}
+	}()
+}
+
+// handleMessage is called for every message in the order they are received.
+// The messages are only acknowledged once their entry was handed over, so the
+// messages with a QoS of 1 or 2 are delivered at least once.
+func (t *target) handleMessage(_ paho.Client, msg paho.Message) {
+	entry, ok, err := t.parser.Parse(brokermessage.Message{
+		Data: msg.Payload(),
+		Labels: model.LabelSet{
+			labelTopic:    model.LabelValue(msg.Topic()),
+			labelQoS:      model.LabelValue(strconv.Itoa(int(msg.Qos()))),
+			labelRetained: model.LabelValue(strconv.FormatBool(msg.Retained())),
+		},
+	})
+	if err != nil {
+		level.Error(t.logger).Log("msg", "message parsing error", "topic", msg.Topic(), "err", err)
+		msg.Ack()
+		return
+	}
+	if !ok {
+		msg.Ack()
+		return
+	}
+
+	select {
+	case t.handler.Chan() <- entry:
+		msg.Ack()
+	case <-t.done:
+		// The message isn't acknowledged, so that the broker sends it again
+		// to the next session.
+	}
+}
+
+func (t *target) stop() {
+	close(t.done)
+	t.client.Disconnect(disconnectQuiesce)
+}
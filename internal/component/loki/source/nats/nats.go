@@ -0,0 +1,300 @@
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/component/loki/source/internal/brokermessage"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.nats",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the loki.source.nats
+// component.
+type Arguments struct {
+	Servers         []string          `alloy:"servers,attr"`
+	Subjects        []string          `alloy:"subjects,attr,optional"`
+	QueueGroup      string            `alloy:"queue_group,attr,optional"`
+	JetStream       *JetStream        `alloy:"jetstream,block,optional"`
+	Username        string            `alloy:"username,attr,optional"`
+	Password        alloytypes.Secret `alloy:"password,attr,optional"`
+	Token           alloytypes.Secret `alloy:"token,attr,optional"`
+	CredentialsFile string            `alloy:"credentials_file,attr,optional"`
+	TLSConfig       config.TLSConfig  `alloy:"tls_config,block,optional"`
+
+	Format               string            `alloy:"format,attr,optional"`
+	MessageField         string            `alloy:"message_field,attr,optional"`
+	TimestampField       string            `alloy:"timestamp_field,attr,optional"`
+	UseIncomingTimestamp bool              `alloy:"use_incoming_timestamp,attr,optional"`
+	Labels               map[string]string `alloy:"labels,attr,optional"`
+
+	ForwardTo    []loki.LogsReceiver `alloy:"forward_to,attr"`
+	RelabelRules alloy_relabel.Rules `alloy:"relabel_rules,attr,optional"`
+}
+
+// JetStream configures the consumer used to read the messages of a JetStream
+// stream.
+type JetStream struct {
+	Stream        string        `alloy:"stream,attr"`
+	Consumer      string        `alloy:"consumer,attr,optional"`
+	AckWait       time.Duration `alloy:"ack_wait,attr,optional"`
+	DeliverPolicy string        `alloy:"deliver_policy,attr,optional"`
+	MaxAckPending int           `alloy:"max_ack_pending,attr,optional"`
+}
+
+// Supported deliver policies of JetStream consumers.
+const (
+	DeliverAll  = "all"
+	DeliverNew  = "new"
+	DeliverLast = "last"
+)
+
+// DefaultJetStream provides the default arguments of the jetstream block.
+var DefaultJetStream = JetStream{
+	AckWait:       30 * time.Second,
+	DeliverPolicy: DeliverAll,
+	MaxAckPending: 1000,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (j *JetStream) SetToDefault() {
+	*j = DefaultJetStream
+}
+
+// Validate implements syntax.Validator.
+func (j *JetStream) Validate() error {
+	if j.Stream == "" {
+		return fmt.Errorf("stream must not be empty")
+	}
+	if j.AckWait <= 0 {
+		return fmt.Errorf("ack_wait must be greater than 0")
+	}
+	if j.MaxAckPending <= 0 {
+		return fmt.Errorf("max_ack_pending must be greater than 0")
+	}
+	switch j.DeliverPolicy {
+	case DeliverAll, DeliverNew, DeliverLast:
+	default:
+		return fmt.Errorf("unsupported deliver_policy %q, must be one of %q, %q or %q", j.DeliverPolicy, DeliverAll, DeliverNew, DeliverLast)
+	}
+	return nil
+}
+
+// consumerConfig returns the configuration of the JetStream consumer reading
+// the subjects.
+func (j *JetStream) consumerConfig(subjects []string) jetstream.ConsumerConfig {
+	cfg := jetstream.ConsumerConfig{
+		Durable:        j.Consumer,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        j.AckWait,
+		MaxAckPending:  j.MaxAckPending,
+		FilterSubjects: subjects,
+	}
+	switch j.DeliverPolicy {
+	case DeliverNew:
+		cfg.DeliverPolicy = jetstream.DeliverNewPolicy
+	case DeliverLast:
+		cfg.DeliverPolicy = jetstream.DeliverLastPolicy
+	default:
+		cfg.DeliverPolicy = jetstream.DeliverAllPolicy
+	}
+	return cfg
+}
+
+// DefaultArguments provides the default arguments for a nats component.
+var DefaultArguments = Arguments{
+	Format:         brokermessage.FormatPlain,
+	MessageField:   "message",
+	TimestampField: "timestamp",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Servers) == 0 {
+		return fmt.Errorf("servers must not be empty")
+	}
+	if a.JetStream == nil && len(a.Subjects) == 0 {
+		return fmt.Errorf("subjects must not be empty")
+	}
+	if a.JetStream != nil && a.QueueGroup != "" {
+		return fmt.Errorf("queue_group can't be used with the jetstream block, use a durable consumer instead")
+	}
+	if a.Token != "" && (a.Username != "" || a.CredentialsFile != "") {
+		return fmt.Errorf("at most one of token, username or credentials_file can be set")
+	}
+	if a.Username != "" && a.CredentialsFile != "" {
+		return fmt.Errorf("at most one of token, username or credentials_file can be set")
+	}
+	if err := a.TLSConfig.Validate(); err != nil {
+		return err
+	}
+	return a.convert().Validate()
+}
+
+// convert returns the configuration used to parse the messages.
+func (a *Arguments) convert() brokermessage.Config {
+	lbls := make(model.LabelSet, len(a.Labels))
+	for k, v := range a.Labels {
+		lbls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return brokermessage.Config{
+		Format:               a.Format,
+		MessageField:         a.MessageField,
+		TimestampField:       a.TimestampField,
+		UseIncomingTimestamp: a.UseIncomingTimestamp,
+		Labels:               lbls,
+		RelabelConfigs:       alloy_relabel.ComponentToPromRelabelConfigs(a.RelabelRules),
+	}
+}
+
+// connectOptions returns the options used to connect to the servers.
+func (a *Arguments) connectOptions(name string) ([]natsgo.Option, error) {
+	tlsConfig, err := promconfig.NewTLSConfig(a.TLSConfig.Convert())
+	if err != nil {
+		return nil, fmt.Errorf("invalid tls_config: %w", err)
+	}
+
+	opts := []natsgo.Option{
+		natsgo.Name(name),
+		// The TLS configuration is only used if the server requires TLS, or
+		// if its URL uses the tls scheme.
+		withTLSConfig(tlsConfig),
+		natsgo.RetryOnFailedConnect(true),
+		natsgo.MaxReconnects(-1),
+		natsgo.ReconnectWait(5 * time.Second),
+	}
+	switch {
+	case a.Username != "":
+		opts = append(opts, natsgo.UserInfo(a.Username, string(a.Password)))
+	case a.Token != "":
+		opts = append(opts, natsgo.Token(string(a.Token)))
+	case a.CredentialsFile != "":
+		opts = append(opts, natsgo.UserCredentials(a.CredentialsFile))
+	}
+	return opts, nil
+}
+
+func withTLSConfig(tlsConfig *tls.Config) natsgo.Option {
+	return func(o *natsgo.Options) error {
+		o.TLSConfig = tlsConfig
+		return nil
+	}
+}
+
+// Component implements the loki.source.nats component.
+type Component struct {
+	opts component.Options
+
+	mut    sync.RWMutex
+	fanout []loki.LogsReceiver
+	target *target
+
+	handler loki.LogsReceiver
+}
+
+// New creates a new loki.source.nats component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		fanout:  args.ForwardTo,
+		handler: loki.NewLogsReceiver(),
+	}
+
+	// Call to Update() to connect to the servers and set receivers once at the
+	// start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		level.Info(c.opts.Logger).Log("msg", "loki.source.nats component shutting down, stopping target")
+		if c.target != nil {
+			c.target.stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.handler.Chan():
+			c.mut.RLock()
+			for _, receiver := range c.fanout {
+				receiver.Chan() <- entry
+			}
+			c.mut.RUnlock()
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	connectOpts, err := newArgs.connectOptions(c.opts.ID)
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.fanout = newArgs.ForwardTo
+
+	if c.target != nil {
+		c.target.stop()
+		c.target = nil
+	}
+
+	// The connection is established in the background if the servers aren't
+	// available yet.
+	conn, err := natsgo.Connect(strings.Join(newArgs.Servers, ","), connectOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS servers: %w", err)
+	}
+	t, err := newTarget(c.opts.Logger, conn, newArgs.Subjects, newArgs.QueueGroup, newArgs.JetStream, newArgs.convert(), c.handler)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	c.target = t
+	return nil
+}
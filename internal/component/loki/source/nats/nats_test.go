@@ -0,0 +1,269 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestAlloyConfig(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`
+	servers     = ["nats://localhost:4222"]
+	subjects    = ["logs.>"]
+	queue_group = "alloy"
+	token       = "token"
+	format      = "json"
+	forward_to  = []
+`), &args)
+	require.NoError(t, err)
+	require.Nil(t, args.JetStream)
+
+	err = syntax.Unmarshal([]byte(`
+	servers    = ["nats://localhost:4222"]
+	forward_to = []
+	jetstream {
+		stream   = "LOGS"
+		consumer = "alloy"
+	}
+`), &args)
+	require.NoError(t, err)
+	require.Equal(t, &JetStream{
+		Stream:        "LOGS",
+		Consumer:      "alloy",
+		AckWait:       30 * time.Second,
+		DeliverPolicy: DeliverAll,
+		MaxAckPending: 1000,
+	}, args.JetStream)
+
+	for _, tc := range []struct {
+		config string
+		err    string
+	}{
+		{
+			config: `servers = ["nats://localhost:4222"]`,
+			err:    "subjects must not be empty",
+		},
+		{
+			config: `
+			servers     = ["nats://localhost:4222"]
+			queue_group = "alloy"
+			jetstream {
+				stream = "LOGS"
+			}`,
+			err: "queue_group can't be used with the jetstream block",
+		},
+		{
+			config: `
+			servers  = ["nats://localhost:4222"]
+			jetstream {
+				stream         = "LOGS"
+				deliver_policy = "first"
+			}`,
+			err: `unsupported deliver_policy "first"`,
+		},
+		{
+			config: `
+			servers  = ["nats://localhost:4222"]
+			subjects = ["logs"]
+			username = "user"
+			token    = "token"`,
+			err: "at most one of token, username or credentials_file can be set",
+		},
+	} {
+		err := syntax.Unmarshal([]byte(tc.config+"\nforward_to = []"), &args)
+		require.ErrorContains(t, err, tc.err)
+	}
+}
+
+func TestLokiSourceNATS(t *testing.T) {
+	srv := runServer(t)
+
+	var re alloy_relabel.Regexp
+	require.NoError(t, re.UnmarshalText([]byte(`logs\.([^.]+)`)))
+	args := DefaultArguments
+	args.Servers = []string{srv.ClientURL()}
+	args.Subjects = []string{"logs.*"}
+	args.QueueGroup = "alloy"
+	args.Format = "json"
+	args.Labels = map[string]string{"job": "services"}
+	args.RelabelRules = alloy_relabel.Rules{{
+		SourceLabels: []string{labelSubject},
+		Regex:        re,
+		TargetLabel:  "service",
+		Replacement:  "$1",
+		Action:       alloy_relabel.Replace,
+	}}
+	receiver := loki.NewLogsReceiver()
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+
+	c, err := New(component.Options{
+		ID:         "loki.source.nats.test",
+		Logger:     util.TestLogger(t),
+		Registerer: prometheus.NewRegistry(),
+	}, args)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var subs []server.SubDetail
+	require.Eventually(t, func() bool {
+		subsz, err := srv.Subsz(&server.SubszOptions{Subscriptions: true, Test: "logs.api"})
+		require.NoError(t, err)
+		subs = subsz.Subs
+		return len(subs) > 0
+	}, 10*time.Second, 10*time.Millisecond, "client didn't subscribe")
+	require.Len(t, subs, 1)
+	require.Equal(t, "logs.*", subs[0].Subject)
+	require.Equal(t, "alloy", subs[0].Queue)
+
+	nc := connect(t, srv)
+	require.NoError(t, nc.Publish("logs.api", []byte(`{"message": "started", "timestamp": 1700000000}`)))
+	require.NoError(t, nc.Publish("logs.api", []byte(`not json`)))
+	require.NoError(t, nc.Publish("logs.db", []byte(`{"message": "ready"}`)))
+	for _, expected := range []loki.Entry{
+		{Labels: model.LabelSet{"job": "services", "service": "api"}},
+		{Labels: model.LabelSet{"job": "services", "service": "db"}},
+	} {
+		select {
+		case entry := <-receiver.Chan():
+			require.Equal(t, expected.Labels, entry.Labels)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no entry received")
+		}
+	}
+}
+
+func TestTarget_JetStream(t *testing.T) {
+	srv := runServer(t)
+	js, err := jetstream.New(connect(t, srv))
+	require.NoError(t, err)
+	stream, err := js.CreateStream(t.Context(), jetstream.StreamConfig{Name: "LOGS", Subjects: []string{"logs.>"}})
+	require.NoError(t, err)
+
+	_, err = js.Publish(t.Context(), "logs.api", []byte(`{"message": "hello"}`))
+	require.NoError(t, err)
+	_, err = js.Publish(t.Context(), "logs.api", []byte(`not json`))
+	require.NoError(t, err)
+	stored, err := stream.GetMsg(t.Context(), 1)
+	require.NoError(t, err)
+
+	args := DefaultArguments
+	args.Format = "json"
+	args.UseIncomingTimestamp = true
+	args.RelabelRules = alloy_relabel.Rules{
+		relabelRule(t, labelStream, "stream"),
+		relabelRule(t, labelConsumer, "consumer"),
+	}
+	jsArgs := DefaultJetStream
+	jsArgs.Stream = "LOGS"
+	jsArgs.Consumer = "alloy"
+	startTarget := func(handler loki.LogsReceiver) *target {
+		tgt, err := newTarget(util.TestLogger(t), connect(t, srv), []string{"logs.>"}, "", &jsArgs, args.convert(), handler)
+		require.NoError(t, err)
+		return tgt
+	}
+	consumerInfo := func() *jetstream.ConsumerInfo {
+		consumer, err := stream.Consumer(t.Context(), "alloy")
+		require.NoError(t, err)
+		info, err := consumer.Info(t.Context())
+		require.NoError(t, err)
+		return info
+	}
+
+	// Delivered messages are acknowledged once handed over, and use the time
+	// they were stored. Messages which can't be parsed are terminated.
+	handler := loki.NewLogsReceiver()
+	tgt := startTarget(handler)
+	select {
+	case entry := <-handler.Chan():
+		require.Equal(t, "hello", entry.Line)
+		require.True(t, stored.Time.Equal(entry.Timestamp))
+		require.Equal(t, model.LabelSet{"stream": "LOGS", "consumer": "alloy"}, entry.Labels)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry received")
+	}
+	require.Eventually(t, func() bool {
+		info := consumerInfo()
+		return info.AckFloor.Stream == 2 && info.NumAckPending == 0
+	}, 5*time.Second, 10*time.Millisecond, "messages weren't acknowledged")
+
+	// Messages which weren't handed over when stopping are delivered again to
+	// the next consumer.
+	_, err = js.Publish(t.Context(), "logs.api", []byte(`{"message": "pending"}`))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return consumerInfo().NumAckPending == 1
+	}, 5*time.Second, 10*time.Millisecond, "message wasn't delivered")
+	tgt.stop()
+	select {
+	case entry := <-handler.Chan():
+		t.Fatalf("unexpected entry %q", entry.Line)
+	default:
+	}
+
+	handler = loki.NewLogsReceiver()
+	tgt = startTarget(handler)
+	defer tgt.stop()
+	select {
+	case entry := <-handler.Chan():
+		require.Equal(t, "pending", entry.Line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message wasn't delivered again")
+	}
+	require.Eventually(t, func() bool {
+		info := consumerInfo()
+		return info.AckFloor.Stream == 3 && info.NumAckPending == 0
+	}, 5*time.Second, 10*time.Millisecond, "message wasn't acknowledged")
+}
+
+// relabelRule returns a rule copying the value of a label to another one.
+func relabelRule(t *testing.T, source, target string) *alloy_relabel.Config {
+	var cfg alloy_relabel.Config
+	require.NoError(t, syntax.Unmarshal([]byte(fmt.Sprintf(`
+	source_labels = [%q]
+	target_label  = %q
+`, source, target)), &cfg))
+	return &cfg
+}
+
+// runServer runs a NATS server with JetStream enabled on a random port.
+func runServer(t *testing.T) *server.Server {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natsserver.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func connect(t *testing.T, srv *server.Server) *natsgo.Conn {
+	nc, err := natsgo.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	return nc
+}
@@ -0,0 +1,183 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/loki/source/internal/brokermessage"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Internal labels of the messages, available to the relabel rules.
+const (
+	labelSubject  = "__meta_nats_subject"
+	labelStream   = "__meta_nats_stream"
+	labelConsumer = "__meta_nats_consumer"
+)
+
+// consumerRetryInterval is the time to wait before trying again to create the
+// JetStream consumer.
+const consumerRetryInterval = 5 * time.Second
+
+// target subscribes to the subjects of the servers, or consumes the messages
+// of a JetStream stream, and sends the received messages to the handler.
+type target struct {
+	logger  log.Logger
+	conn    *natsgo.Conn
+	parser  brokermessage.Config
+	handler loki.LogsReceiver
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newTarget(logger log.Logger, conn *natsgo.Conn, subjects []string, queueGroup string, js *JetStream, parser brokermessage.Config, handler loki.LogsReceiver) (*target, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &target{
+		logger:  logger,
+		conn:    conn,
+		parser:  parser,
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	if js != nil {
+		t.wg.Add(1)
+		go t.consume(js.Stream, js.consumerConfig(subjects))
+		return t, nil
+	}
+
+	// Subscriptions are sent again by the client when it reconnects.
+	for _, subject := range subjects {
+		var err error
+		if queueGroup != "" {
+			_, err = conn.QueueSubscribe(subject, queueGroup, t.handleMessage)
+		} else {
+			_, err = conn.Subscribe(subject, t.handleMessage)
+		}
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to subscribe to subject %q: %w", subject, err)
+		}
+	}
+	return t, nil
+}
+
+// handleMessage handles the messages of core NATS subscriptions, which are
+// delivered at most once.
+func (t *target) handleMessage(msg *natsgo.Msg) {
+	entry, ok, err := t.parser.Parse(brokermessage.Message{
+		Data:   msg.Data,
+		Labels: model.LabelSet{labelSubject: model.LabelValue(msg.Subject)},
+	})
+	if err != nil {
+		level.Error(t.logger).Log("msg", "message parsing error", "subject", msg.Subject, "err", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	select {
+	case t.handler.Chan() <- entry:
+	case <-t.ctx.Done():
+	}
+}
+
+// consume creates the JetStream consumer, retrying until it succeeds, and
+// consumes its messages until the target is stopped.
+func (t *target) consume(stream string, cfg jetstream.ConsumerConfig) {
+	defer t.wg.Done()
+
+	js, err := jetstream.New(t.conn)
+	if err != nil {
+		level.Error(t.logger).Log("msg", "failed to create JetStream context", "err", err)
+		return
+	}
+
+	var consumer jetstream.Consumer
+	for {
+		consumer, err = js.CreateOrUpdateConsumer(t.ctx, stream, cfg)
+		if err == nil {
+			break
+		}
+		level.Error(t.logger).Log("msg", "failed to create JetStream consumer, retrying", "stream", stream, "err", err)
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(consumerRetryInterval):
+		}
+	}
+
+	consumeCtx, err := consumer.Consume(t.handleJetStreamMessage, jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+		level.Warn(t.logger).Log("msg", "error while consuming JetStream messages", "stream", stream, "err", err)
+	}))
+	if err != nil {
+		level.Error(t.logger).Log("msg", "failed to consume JetStream messages", "stream", stream, "err", err)
+		return
+	}
+	<-t.ctx.Done()
+	consumeCtx.Stop()
+	// Wait for the message being handled, if any, so that it's not
+	// acknowledged after the connection is closed.
+	<-consumeCtx.Closed()
+}
+
+// handleJetStreamMessage handles the messages of the JetStream consumer. The
+// messages are only acknowledged once their entry was handed over, so they're
+// delivered at least once.
+func (t *target) handleJetStreamMessage(msg jetstream.Msg) {
+	lbls := model.LabelSet{labelSubject: model.LabelValue(msg.Subject())}
+	var ts time.Time
+	if md, err := msg.Metadata(); err == nil {
+		lbls[labelStream] = model.LabelValue(md.Stream)
+		lbls[labelConsumer] = model.LabelValue(md.Consumer)
+		ts = md.Timestamp
+	}
+
+	entry, ok, err := t.parser.Parse(brokermessage.Message{
+		Data:      msg.Data(),
+		Labels:    lbls,
+		Timestamp: ts,
+	})
+	if err != nil {
+		level.Error(t.logger).Log("msg", "message parsing error", "subject", msg.Subject(), "err", err)
+		// The message will never be parsed, so it isn't delivered again.
+		t.ack(msg, msg.Term)
+		return
+	}
+	if !ok {
+		t.ack(msg, msg.Ack)
+		return
+	}
+
+	select {
+	case t.handler.Chan() <- entry:
+		t.ack(msg, msg.Ack)
+	case <-t.ctx.Done():
+		// The message is delivered again to the next consumer.
+		t.ack(msg, msg.Nak)
+	}
+}
+
+func (t *target) ack(msg jetstream.Msg, ack func() error) {
+	if err := ack(); err != nil {
+		level.Warn(t.logger).Log("msg", "failed to acknowledge JetStream message", "subject", msg.Subject(), "err", err)
+	}
+}
+
+func (t *target) stop() {
+	t.cancel()
+	t.wg.Wait()
+	t.conn.Close()
+}
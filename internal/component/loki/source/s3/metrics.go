@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/util"
+)
+
+// metrics holds the metrics of the loki.source.s3 component.
+type metrics struct {
+	objectsRead      prometheus.Counter
+	objectsCorrupted prometheus.Counter
+	entries          prometheus.Counter
+	invalidLines     prometheus.Counter
+	errors           prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	var m metrics
+	m.objectsRead = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_source_s3_objects_read_total",
+		Help: "Number of objects read entirely.",
+	})
+	m.objectsCorrupted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_source_s3_objects_corrupted_total",
+		Help: "Number of objects skipped because they couldn't be decoded.",
+	})
+	m.entries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_source_s3_entries_total",
+		Help: "Number of log entries read from objects.",
+	})
+	m.invalidLines = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_source_s3_invalid_lines_total",
+		Help: "Number of lines dropped because they aren't valid JSON.",
+	})
+	m.errors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_source_s3_errors_total",
+		Help: "Number of errors while listing or fetching objects.",
+	})
+
+	if reg != nil {
+		m.objectsRead = util.MustRegisterOrGet(reg, m.objectsRead).(prometheus.Counter)
+		m.objectsCorrupted = util.MustRegisterOrGet(reg, m.objectsCorrupted).(prometheus.Counter)
+		m.entries = util.MustRegisterOrGet(reg, m.entries).(prometheus.Counter)
+		m.invalidLines = util.MustRegisterOrGet(reg, m.invalidLines).(prometheus.Counter)
+		m.errors = util.MustRegisterOrGet(reg, m.errors).(prometheus.Counter)
+	}
+	return &m
+}
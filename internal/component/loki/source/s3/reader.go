@@ -0,0 +1,303 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// maxLineSize is the maximum size of a line in an object.
+const maxLineSize = 2 * 1024 * 1024
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// recentObjects is the number of objects read last which are remembered in
+// the cursor, so that the objects added with a key which comes before them are
+// still read.
+const recentObjects = 1000
+
+// cursor is the progress of the component in a bucket and prefix, stored in
+// the positions file. Objects are listed in the order of their keys: After is
+// a key up to which all objects were read, and Read holds the sorted keys
+// after it of the objects read last. Key is the object being read, and Lines
+// the number of lines already sent from it.
+type cursor struct {
+	After string   `json:"after,omitempty"`
+	Read  []string `json:"read,omitempty"`
+	Key   string   `json:"key,omitempty"`
+	Lines int64    `json:"lines,omitempty"`
+}
+
+// isRead returns whether the object key was already read.
+func (c *cursor) isRead(key string) bool {
+	if key <= c.After {
+		return true
+	}
+	_, found := slices.BinarySearch(c.Read, key)
+	return found
+}
+
+// markRead marks the object being read as read entirely. Only the last
+// recentObjects objects are remembered: older ones are forgotten by moving
+// After forward.
+func (c *cursor) markRead() {
+	if i, found := slices.BinarySearch(c.Read, c.Key); !found && c.Key > c.After {
+		c.Read = slices.Insert(c.Read, i, c.Key)
+	}
+	if n := len(c.Read) - recentObjects; n > 0 {
+		c.After = c.Read[n-1]
+		c.Read = slices.Delete(c.Read, 0, n)
+	}
+	c.Key, c.Lines = "", 0
+}
+
+// The cursor is stored as two entries of the positions file, so that the
+// progress in the object being read can be stored after each line without
+// encoding the keys of the objects read.
+func cursorKey(bucket, prefix string) string {
+	return positions.CursorKey(fmt.Sprintf("s3://%s/%s", bucket, prefix))
+}
+
+func progressKey(bucket, prefix string) string {
+	return positions.CursorKey(fmt.Sprintf("s3-progress://%s/%s", bucket, prefix))
+}
+
+func (c *Component) loadCursor(bucket, prefix string) cursor {
+	var cur, progress cursor
+	if !c.decodeCursor(cursorKey(bucket, prefix), &cur) || !c.decodeCursor(progressKey(bucket, prefix), &progress) {
+		return cursor{}
+	}
+	if progress.Key != "" {
+		cur.Key, cur.Lines = progress.Key, progress.Lines
+	}
+	return cur
+}
+
+func (c *Component) decodeCursor(key string, cur *cursor) bool {
+	value := c.posFile.GetString(key, "")
+	if value == "" {
+		return true
+	}
+	if err := json.Unmarshal([]byte(value), cur); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to decode cursor from positions file, reading all objects", "err", err)
+		return false
+	}
+	return true
+}
+
+// storeCursor stores the objects read.
+func (c *Component) storeCursor(bucket, prefix string, cur cursor) {
+	c.encodeCursor(cursorKey(bucket, prefix), cursor{After: cur.After, Read: cur.Read})
+	c.storeProgress(bucket, prefix, cur)
+}
+
+// storeProgress stores the progress in the object being read.
+func (c *Component) storeProgress(bucket, prefix string, cur cursor) {
+	c.encodeCursor(progressKey(bucket, prefix), cursor{Key: cur.Key, Lines: cur.Lines})
+}
+
+func (c *Component) encodeCursor(key string, cur cursor) {
+	value, err := json.Marshal(cur)
+	if err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to encode cursor", "err", err)
+		return
+	}
+	c.posFile.PutString(key, "", string(value))
+}
+
+// poll reads the objects which were added since the last poll.
+func (c *Component) poll(ctx context.Context) {
+	c.mut.RLock()
+	args, client, labels := c.args, c.client, c.labels
+	c.mut.RUnlock()
+
+	cur := c.loadCursor(args.Bucket, args.Prefix)
+
+	// An object which wasn't read entirely is resumed first.
+	if cur.Key != "" {
+		if err := c.readObject(ctx, client, args, labels, &cur); err != nil {
+			c.setHealth(err)
+			return
+		}
+	}
+
+	// The objects after the recently read ones are listed again, to read the
+	// ones which were added since with a key that comes before them.
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket:     aws.String(args.Bucket),
+		Prefix:     aws.String(args.Prefix),
+		StartAfter: aws.String(cur.After),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			c.setHealth(fmt.Errorf("failed to list objects: %w", err))
+			return
+		}
+		for _, object := range page.Contents {
+			if ctx.Err() != nil {
+				return
+			}
+			if cur.isRead(aws.ToString(object.Key)) {
+				continue
+			}
+			cur.Key = aws.ToString(object.Key)
+			cur.Lines = 0
+			if err := c.readObject(ctx, client, args, labels, &cur); err != nil {
+				c.setHealth(err)
+				return
+			}
+		}
+	}
+	c.setHealth(nil)
+}
+
+// readObject sends the lines of the object cur.Key, starting after the ones
+// already sent, and updates the cursor. Objects which can't be decoded, or
+// which were deleted, are skipped.
+func (c *Component) readObject(ctx context.Context, client *s3.Client, args Arguments, labels model.LabelSet, cur *cursor) error {
+	object, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(args.Bucket),
+		Key:    aws.String(cur.Key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		level.Warn(c.opts.Logger).Log("msg", "skipping object which was deleted", "key", cur.Key)
+		cur.markRead()
+		c.storeCursor(args.Bucket, args.Prefix, *cur)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get object %q: %w", cur.Key, err)
+	}
+	body := &bodyReader{r: object.Body}
+	defer object.Body.Close()
+
+	err = c.readLines(ctx, body, args, labels, cur)
+	switch {
+	case err == nil:
+		c.metrics.objectsRead.Inc()
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case body.err != nil:
+		return fmt.Errorf("failed to read object %q: %w", cur.Key, body.err)
+	default:
+		level.Warn(c.opts.Logger).Log("msg", "skipping corrupted object", "key", cur.Key, "err", err)
+		c.metrics.objectsCorrupted.Inc()
+	}
+
+	cur.markRead()
+	c.storeCursor(args.Bucket, args.Prefix, *cur)
+	return nil
+}
+
+func (c *Component) readLines(ctx context.Context, body io.Reader, args Arguments, labels model.LabelSet, cur *cursor) error {
+	r, err := decompress(body, args.Compression)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	entryLabels := labels.Merge(model.LabelSet{
+		"filename": model.LabelValue(fmt.Sprintf("s3://%s/%s", args.Bucket, cur.Key)),
+	})
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	for line := int64(1); scanner.Scan(); line++ {
+		if line <= cur.Lines {
+			// skip the lines sent before a restart or an error.
+			continue
+		}
+
+		text := scanner.Text()
+		if args.Format == FormatJSONLines {
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, scanner.Bytes()); err != nil {
+				level.Debug(c.opts.Logger).Log("msg", "dropping line which isn't valid JSON", "key", cur.Key, "line", line, "err", err)
+				c.metrics.invalidLines.Inc()
+				cur.Lines = line
+				continue
+			}
+			text = compacted.String()
+		}
+
+		err := c.send(ctx, loki.Entry{
+			Labels: entryLabels,
+			Entry: logproto.Entry{
+				Timestamp: time.Now(),
+				Line:      text,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		c.metrics.entries.Inc()
+		cur.Lines = line
+		c.storeProgress(args.Bucket, args.Prefix, *cur)
+	}
+	return scanner.Err()
+}
+
+// decompress returns a reader of the decompressed content of r.
+func decompress(r io.Reader, compression string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if compression == CompressionAuto {
+		compression = CompressionNone
+		magic, _ := br.Peek(len(zstdMagic))
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			compression = CompressionGzip
+		case bytes.HasPrefix(magic, zstdMagic):
+			compression = CompressionZstd
+		}
+	}
+
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(br)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// bodyReader records the errors while reading an object, to tell them apart
+// from the errors while decoding it.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,234 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/positions"
+	remotes3 "github.com/grafana/alloy/internal/component/remote/s3"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.s3",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Supported values of the compression argument.
+const (
+	CompressionAuto = "auto"
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Supported values of the format argument.
+const (
+	FormatLines     = "lines"
+	FormatJSONLines = "json_lines"
+)
+
+// Arguments holds values which are used to configure the loki.source.s3
+// component.
+type Arguments struct {
+	Bucket        string              `alloy:"bucket,attr"`
+	Prefix        string              `alloy:"prefix,attr,optional"`
+	PollFrequency time.Duration       `alloy:"poll_frequency,attr,optional"`
+	Compression   string              `alloy:"compression,attr,optional"`
+	Format        string              `alloy:"format,attr,optional"`
+	Labels        map[string]string   `alloy:"labels,attr,optional"`
+	ForwardTo     []loki.LogsReceiver `alloy:"forward_to,attr"`
+	Client        remotes3.Client     `alloy:"client,block,optional"`
+}
+
+// DefaultArguments holds the default settings of loki.source.s3.
+var DefaultArguments = Arguments{
+	PollFrequency: time.Minute,
+	Compression:   CompressionAuto,
+	Format:        FormatLines,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.Bucket == "" {
+		return fmt.Errorf("bucket must not be empty")
+	}
+	if a.PollFrequency <= 0 {
+		return fmt.Errorf("poll_frequency must be greater than 0")
+	}
+	switch a.Compression {
+	case CompressionAuto, CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("unsupported compression %q, must be one of %q, %q, %q or %q", a.Compression, CompressionAuto, CompressionNone, CompressionGzip, CompressionZstd)
+	}
+	switch a.Format {
+	case FormatLines, FormatJSONLines:
+	default:
+		return fmt.Errorf("unsupported format %q, must be one of %q or %q", a.Format, FormatLines, FormatJSONLines)
+	}
+	if (a.Client.AccessKey == "") != (a.Client.Secret == "") {
+		return fmt.Errorf("if key or secret are specified then the other must also be specified")
+	}
+	return nil
+}
+
+// Component implements the loki.source.s3 component.
+type Component struct {
+	opts    component.Options
+	metrics *metrics
+	posFile positions.Positions
+
+	mut       sync.RWMutex
+	args      Arguments
+	client    *s3.Client
+	receivers []loki.LogsReceiver
+	labels    model.LabelSet
+	health    component.Health
+
+	updated chan struct{}
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new loki.source.s3 component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	err := os.MkdirAll(o.DataPath, 0750)
+	if err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	positionsFile, err := positions.New(o.Logger, positions.Config{
+		SyncPeriod:        10 * time.Second,
+		PositionsFile:     filepath.Join(o.DataPath, "positions.yml"),
+		IgnoreInvalidYaml: false,
+		ReadOnly:          false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+		posFile: positionsFile,
+		updated: make(chan struct{}, 1),
+	}
+
+	if err := c.Update(args); err != nil {
+		positionsFile.Stop()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.posFile.Stop()
+
+	for {
+		c.mut.RLock()
+		pollFrequency := c.args.PollFrequency
+		c.mut.RUnlock()
+
+		c.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.updated:
+		case <-time.After(pollFrequency):
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	client, err := remotes3.NewS3Client(newArgs.Client)
+	if err != nil {
+		return fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	labels := make(model.LabelSet, len(newArgs.Labels))
+	for k, v := range newArgs.Labels {
+		labels[model.LabelName(k)] = model.LabelValue(v)
+	}
+
+	c.mut.Lock()
+	c.args = newArgs
+	c.client = client
+	c.receivers = newArgs.ForwardTo
+	c.labels = labels
+	c.mut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.health
+}
+
+func (c *Component) setHealth(err error) {
+	health := component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "objects listed and read",
+		UpdateTime: time.Now(),
+	}
+	if err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to read objects, retrying at next poll", "err", err)
+		c.metrics.errors.Inc()
+		health.Health = component.HealthTypeUnhealthy
+		health.Message = err.Error()
+	}
+
+	c.mut.Lock()
+	c.health = health
+	c.mut.Unlock()
+}
+
+// send forwards an entry to every receiver.
+func (c *Component) send(ctx context.Context, entry loki.Entry) error {
+	c.mut.RLock()
+	receivers := c.receivers
+	c.mut.RUnlock()
+
+	for _, receiver := range receivers {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case receiver.Chan() <- entry:
+		}
+	}
+	return nil
+}
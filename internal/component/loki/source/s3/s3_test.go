@@ -0,0 +1,369 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/loki"
+	remotes3 "github.com/grafana/alloy/internal/component/remote/s3"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestLokiSourceS3(t *testing.T) {
+	bucket := newFakeBucket(t)
+	bucket.put("logs/a.log", []byte("a1\na2\n"))
+	bucket.put("logs/b.log.gz", gzipData(t, "b1\nb2"))
+	bucket.put("logs/c.log.zst", zstdData(t, "c1\n"))
+	bucket.put("logs/d.log.gz", append([]byte{0x1f, 0x8b}, "not gzip"...))
+	bucket.put("other/x.log", []byte("x1\n"))
+
+	dataPath := t.TempDir()
+	receiver := loki.NewLogsReceiver()
+	args := testArguments(bucket.url, receiver)
+	args.Labels = map[string]string{"job": "s3"}
+
+	c, stop := runComponent(t, dataPath, args)
+	require.Equal(t, []string{"a1", "a2", "b1", "b2", "c1"}, receiveLines(t, receiver, 5))
+
+	// New objects are read at the next poll.
+	bucket.put("logs/e.log", []byte("e1\n"))
+	entry := receiveEntry(t, receiver)
+	require.Equal(t, "e1", entry.Line)
+	require.Equal(t, model.LabelSet{"job": "s3", "filename": "s3://test-bucket/logs/e.log"}, entry.Labels)
+	require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.objectsCorrupted))
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+	stop()
+
+	// Only the objects added while stopped are read after a restart.
+	bucket.put("logs/f.log", []byte("f1\n"))
+	_, stop = runComponent(t, dataPath, args)
+	defer stop()
+	require.Equal(t, []string{"f1"}, receiveLines(t, receiver, 1))
+	requireNoEntry(t, receiver)
+}
+
+func TestLokiSourceS3_JSONLinesAndResume(t *testing.T) {
+	bucket := newFakeBucket(t)
+	bucket.put("a.json", []byte("{\"msg\": \"one\"}\nnot json\n{\"msg\": \"two\"}\n{\"msg\": \"three\"}\n"))
+
+	dataPath := t.TempDir()
+	receiver := loki.NewLogsReceiver()
+	args := testArguments(bucket.url, receiver)
+	args.Prefix = ""
+	args.Format = FormatJSONLines
+
+	c, stop := runComponent(t, dataPath, args)
+	require.Equal(t, []string{`{"msg":"one"}`}, receiveLines(t, receiver, 1))
+	// Stop while the second entry is waiting for the receiver.
+	time.Sleep(100 * time.Millisecond)
+	stop()
+	require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.invalidLines))
+
+	// The object is resumed after the lines which were sent.
+	_, stop = runComponent(t, dataPath, args)
+	defer stop()
+	require.Equal(t, []string{`{"msg":"two"}`, `{"msg":"three"}`}, receiveLines(t, receiver, 2))
+}
+
+func TestLokiSourceS3_LateObjects(t *testing.T) {
+	bucket := newFakeBucket(t)
+	bucket.put("logs/a.log", []byte("a1\n"))
+	bucket.put("logs/c.log", []byte("c1\n"))
+
+	dataPath := t.TempDir()
+	receiver := loki.NewLogsReceiver()
+	args := testArguments(bucket.url, receiver)
+	_, stop := runComponent(t, dataPath, args)
+	require.Equal(t, []string{"a1", "c1"}, receiveLines(t, receiver, 2))
+
+	// Objects added with a key which comes before the last object read are
+	// still read.
+	bucket.put("logs/b.log", []byte("b1\n"))
+	require.Equal(t, []string{"b1"}, receiveLines(t, receiver, 1))
+	stop()
+
+	// And aren't read again after a restart.
+	_, stop = runComponent(t, dataPath, args)
+	defer stop()
+	requireNoEntry(t, receiver)
+}
+
+func TestLokiSourceS3_DeletedObject(t *testing.T) {
+	bucket := newFakeBucket(t)
+	bucket.put("a.log", []byte("a1\na2\n"))
+
+	dataPath := t.TempDir()
+	receiver := loki.NewLogsReceiver()
+	args := testArguments(bucket.url, receiver)
+	args.Prefix = ""
+
+	_, stop := runComponent(t, dataPath, args)
+	require.Equal(t, []string{"a1"}, receiveLines(t, receiver, 1))
+	// Stop while the second entry is waiting for the receiver.
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	// The object being read was deleted while stopped, so it's skipped.
+	bucket.delete("a.log")
+	bucket.put("b.log", []byte("b1\n"))
+	c, stop := runComponent(t, dataPath, args)
+	defer stop()
+	require.Equal(t, []string{"b1"}, receiveLines(t, receiver, 1))
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+}
+
+func TestCursor(t *testing.T) {
+	var cur cursor
+	for i := range recentObjects + 2 {
+		cur.Key = fmt.Sprintf("%05d", i)
+		cur.Lines = 10
+		cur.markRead()
+	}
+	require.Len(t, cur.Read, recentObjects)
+	require.Equal(t, "00001", cur.After)
+	require.Equal(t, "00002", cur.Read[0])
+	require.Empty(t, cur.Key)
+	require.Zero(t, cur.Lines)
+
+	require.True(t, cur.isRead("00000"))
+	require.True(t, cur.isRead("00500"))
+	require.False(t, cur.isRead("00500a"))
+	require.False(t, cur.isRead("99999"))
+}
+
+func TestLokiSourceS3_Unavailable(t *testing.T) {
+	bucket := newFakeBucket(t)
+	bucket.put("logs/a.log", []byte("a1\n"))
+	bucket.setUnavailable(true)
+
+	receiver := loki.NewLogsReceiver()
+	c, stop := runComponent(t, t.TempDir(), testArguments(bucket.url, receiver))
+	defer stop()
+
+	require.Eventually(t, func() bool {
+		return c.CurrentHealth().Health == component.HealthTypeUnhealthy
+	}, 30*time.Second, 10*time.Millisecond)
+	requireNoEntry(t, receiver)
+
+	// The objects are read once the bucket is available again.
+	bucket.setUnavailable(false)
+	require.Equal(t, []string{"a1"}, receiveLines(t, receiver, 1))
+}
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`
+		bucket     = "logs"
+		prefix     = "app/"
+		forward_to = []
+		client {
+			endpoint       = "http://localhost:9000"
+			use_path_style = true
+		}
+	`), &args)
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, args.PollFrequency)
+	require.Equal(t, CompressionAuto, args.Compression)
+	require.Equal(t, FormatLines, args.Format)
+	require.True(t, args.Client.UsePathStyle)
+
+	err = syntax.Unmarshal([]byte(`
+		bucket      = "logs"
+		compression = "lz4"
+		forward_to  = []
+	`), &args)
+	require.ErrorContains(t, err, `unsupported compression "lz4"`)
+
+	err = syntax.Unmarshal([]byte(`
+		bucket     = "logs"
+		format     = "csv"
+		forward_to = []
+	`), &args)
+	require.ErrorContains(t, err, `unsupported format "csv"`)
+}
+
+func testArguments(url string, receiver loki.LogsReceiver) Arguments {
+	args := DefaultArguments
+	args.Bucket = "test-bucket"
+	args.Prefix = "logs/"
+	args.PollFrequency = 50 * time.Millisecond
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+	args.Client = remotes3.Client{
+		AccessKey:    "key",
+		Secret:       "secret",
+		Endpoint:     url,
+		UsePathStyle: true,
+		Region:       "us-east-1",
+	}
+	return args
+}
+
+func runComponent(t *testing.T, dataPath string, args Arguments) (*Component, func()) {
+	c, err := New(component.Options{
+		ID:         "loki.source.s3.test",
+		Logger:     util.TestLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		DataPath:   dataPath,
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	return c, func() {
+		cancel()
+		<-done
+	}
+}
+
+func receiveEntry(t *testing.T, receiver loki.LogsReceiver) loki.Entry {
+	select {
+	case e := <-receiver.Chan():
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no entry received")
+		return loki.Entry{}
+	}
+}
+
+func receiveLines(t *testing.T, receiver loki.LogsReceiver, n int) []string {
+	var lines []string
+	for len(lines) < n {
+		lines = append(lines, receiveEntry(t, receiver).Line)
+	}
+	return lines
+}
+
+func requireNoEntry(t *testing.T, receiver loki.LogsReceiver) {
+	select {
+	case e := <-receiver.Chan():
+		t.Fatalf("unexpected entry %q", e.Line)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func gzipData(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func zstdData(t *testing.T, content string) []byte {
+	w, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer w.Close()
+	return w.EncodeAll([]byte(content), nil)
+}
+
+// fakeBucket is an S3 compatible server with a single bucket, supporting
+// path-style ListObjectsV2 and GetObject requests.
+type fakeBucket struct {
+	url string
+
+	mut         sync.Mutex
+	objects     map[string][]byte
+	unavailable bool
+}
+
+func newFakeBucket(t *testing.T) *fakeBucket {
+	b := &fakeBucket{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(http.HandlerFunc(b.serveHTTP))
+	t.Cleanup(srv.Close)
+	b.url = srv.URL
+	return b
+}
+
+func (b *fakeBucket) put(key string, data []byte) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.objects[key] = data
+}
+
+func (b *fakeBucket) delete(key string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	delete(b.objects, key)
+}
+
+func (b *fakeBucket) setUnavailable(unavailable bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.unavailable = unavailable
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name     `xml:"ListBucketResult"`
+	Name        string       `xml:"Name"`
+	Prefix      string       `xml:"Prefix"`
+	KeyCount    int          `xml:"KeyCount"`
+	IsTruncated bool         `xml:"IsTruncated"`
+	Contents    []listObject `xml:"Contents"`
+}
+
+type listObject struct {
+	Key  string `xml:"Key"`
+	Size int    `xml:"Size"`
+}
+
+func (b *fakeBucket) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.unavailable {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/test-bucket")
+	if path == "" || path == "/" {
+		prefix, startAfter := r.URL.Query().Get("prefix"), r.URL.Query().Get("start-after")
+		result := listBucketResult{Name: "test-bucket", Prefix: prefix}
+		var keys []string
+		for key := range b.objects {
+			if strings.HasPrefix(key, prefix) && key > startAfter {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result.Contents = append(result.Contents, listObject{Key: key, Size: len(b.objects[key])})
+		}
+		result.KeyCount = len(keys)
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(result)
+		return
+	}
+
+	data, ok := b.objects[strings.TrimPrefix(path, "/")]
+	if !ok {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		return
+	}
+	_, _ = w.Write(data)
+}
@@ -2,11 +2,13 @@ package syslog
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sync"
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/spool"
 	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
 	st "github.com/grafana/alloy/internal/component/loki/source/syslog/internal/syslogtarget"
 	"github.com/grafana/alloy/internal/featuregate"
@@ -32,6 +34,7 @@ type Arguments struct {
 	SyslogListeners []ListenerConfig    `alloy:"listener,block"`
 	ForwardTo       []loki.LogsReceiver `alloy:"forward_to,attr"`
 	RelabelRules    alloy_relabel.Rules `alloy:"relabel_rules,attr,optional"`
+	Spool           *spool.Arguments    `alloy:"spool,block,optional"`
 }
 
 // Component implements the loki.source.syslog component.
@@ -41,19 +44,19 @@ type Component struct {
 
 	mut     sync.RWMutex
 	args    Arguments
-	fanout  []loki.LogsReceiver
 	targets []*st.SyslogTarget
 
-	handler loki.LogsReceiver
+	handler   loki.LogsReceiver
+	forwarder *spool.Forwarder
 }
 
 // New creates a new loki.source.syslog component.
 func New(o component.Options, args Arguments) (*Component, error) {
 	c := &Component{
-		opts:    o,
-		metrics: st.NewMetrics(o.Registerer),
-		handler: loki.NewLogsReceiver(),
-		fanout:  args.ForwardTo,
+		opts:      o,
+		metrics:   st.NewMetrics(o.Registerer),
+		handler:   loki.NewLogsReceiver(),
+		forwarder: spool.NewForwarder(o.Logger, o.Registerer, o.DataPath),
 
 		targets: []*st.SyslogTarget{},
 	}
@@ -68,6 +71,14 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 // Run implements component.Component.
 func (c *Component) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.forwarder.Run(ctx)
+	}()
+
 	defer func() {
 		level.Info(c.opts.Logger).Log("msg", "loki.source.syslog component shutting down, stopping listeners")
 		for _, l := range c.targets {
@@ -83,11 +94,9 @@ func (c *Component) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case entry := <-c.handler.Chan():
-			c.mut.RLock()
-			for _, receiver := range c.fanout {
-				receiver.Chan() <- entry
+			if err := c.forwarder.Forward(ctx, entry); err != nil {
+				return nil
 			}
-			c.mut.RUnlock()
 		}
 	}
 }
@@ -98,7 +107,9 @@ func (c *Component) Update(args component.Arguments) error {
 	defer c.mut.Unlock()
 
 	newArgs := args.(Arguments)
-	c.fanout = newArgs.ForwardTo
+	if err := c.forwarder.Update(newArgs.ForwardTo, newArgs.Spool); err != nil {
+		return fmt.Errorf("failed to open spool: %w", err)
+	}
 
 	var rcs []*relabel.Config
 	if len(newArgs.RelabelRules) > 0 {
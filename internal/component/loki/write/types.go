@@ -31,6 +31,7 @@ type EndpointOptions struct {
 	RetryOnHTTP429    bool                    `alloy:"retry_on_http_429,attr,optional"`
 	HTTPClientConfig  *types.HTTPClientConfig `alloy:",squash"`
 	QueueConfig       QueueConfig             `alloy:"queue_config,block,optional"`
+	Protocol          string                  `alloy:"protocol,attr,optional"`
 }
 
 // GetDefaultEndpointOptions defines the default settings for sending logs to a
@@ -48,6 +49,7 @@ func GetDefaultEndpointOptions() EndpointOptions {
 		MaxBackoffRetries: 10,
 		HTTPClientConfig:  types.CloneDefaultHTTPClientConfig(),
 		RetryOnHTTP429:    true,
+		Protocol:          client.ProtocolLoki,
 	}
 
 	return defaultEndpointOptions
@@ -64,6 +66,12 @@ func (r *EndpointOptions) Validate() error {
 		return fmt.Errorf("failed to parse remote url %q: %w", r.URL, err)
 	}
 
+	switch r.Protocol {
+	case client.ProtocolLoki, client.ProtocolOTLP:
+	default:
+		return fmt.Errorf("unsupported protocol %q, must be one of %q or %q", r.Protocol, client.ProtocolLoki, client.ProtocolOTLP)
+	}
+
 	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
 	if r.HTTPClientConfig != nil {
 		return r.HTTPClientConfig.Validate()
@@ -75,8 +83,10 @@ func (r *EndpointOptions) Validate() error {
 // QueueConfig controls how the queue logs remote write client is configured. Note that this client is only used when the
 // loki.write component has WAL support enabled.
 type QueueConfig struct {
-	Capacity     units.Base2Bytes `alloy:"capacity,attr,optional"`
-	DrainTimeout time.Duration    `alloy:"drain_timeout,attr,optional"`
+	Capacity        units.Base2Bytes `alloy:"capacity,attr,optional"`
+	DrainTimeout    time.Duration    `alloy:"drain_timeout,attr,optional"`
+	TenantRateLimit units.Base2Bytes `alloy:"tenant_rate_limit,attr,optional"`
+	TenantRateBurst units.Base2Bytes `alloy:"tenant_rate_burst,attr,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -108,9 +118,12 @@ func (args Arguments) convertClientConfigs() []client.Config {
 			TenantID:               cfg.TenantID,
 			DropRateLimitedBatches: !cfg.RetryOnHTTP429,
 			Queue: client.QueueConfig{
-				Capacity:     int(cfg.QueueConfig.Capacity),
-				DrainTimeout: cfg.QueueConfig.DrainTimeout,
+				Capacity:        int(cfg.QueueConfig.Capacity),
+				DrainTimeout:    cfg.QueueConfig.DrainTimeout,
+				TenantRateLimit: int(cfg.QueueConfig.TenantRateLimit),
+				TenantRateBurst: int(cfg.QueueConfig.TenantRateBurst),
 			},
+			Protocol: cfg.Protocol,
 		}
 		res = append(res, cc)
 	}
@@ -1,7 +1,9 @@
 package write
 
 import (
+	"compress/gzip"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +16,8 @@ import (
 	loki_util "github.com/grafana/loki/v3/pkg/util"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.uber.org/atomic"
 
 	"github.com/grafana/alloy/internal/component/common/loki"
@@ -56,6 +60,39 @@ func TestBadAlloyConfig(t *testing.T) {
 	require.ErrorContains(t, err, "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured")
 }
 
+func TestBadProtocolAlloyConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	endpoint {
+		url      = "http://0.0.0.0:11111/otlp/v1/logs"
+		protocol = "otlp_grpc"
+	}
+`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.ErrorContains(t, err, `unsupported protocol "otlp_grpc", must be one of "loki" or "otlp"`)
+}
+
+func TestQueueConfigTenantRateLimit(t *testing.T) {
+	var exampleAlloyConfig = `
+	endpoint {
+		url = "http://0.0.0.0:11111/loki/api/v1/push"
+
+		queue_config {
+			tenant_rate_limit = "1MiB"
+			tenant_rate_burst = "4MiB"
+		}
+	}
+`
+
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(exampleAlloyConfig), &args))
+	cfgs := args.convertClientConfigs()
+	require.Len(t, cfgs, 1)
+	require.Equal(t, 1024*1024, cfgs[0].Queue.TenantRateLimit)
+	require.Equal(t, 4*1024*1024, cfgs[0].Queue.TenantRateBurst)
+}
+
 func TestUnmarshallWalAttrributes(t *testing.T) {
 	type testcase struct {
 		raw           string
@@ -206,6 +243,94 @@ LOOP:
 	require.Equal(t, entries[1].Line, logEntry.Entry.Line)
 }
 
+func TestWriteOTLP(t *testing.T) {
+	t.Run("wal disabled", func(t *testing.T) {
+		testOTLPEndpoint(t, func(args *Arguments) {})
+	})
+
+	t.Run("wal enabled", func(t *testing.T) {
+		testOTLPEndpoint(t, func(args *Arguments) {
+			args.WAL.Enabled = true
+		})
+	})
+}
+
+func testOTLPEndpoint(t *testing.T, alterConfig func(arguments *Arguments)) {
+	// Set up the server that will receive the log entry, and expose it on ch.
+	ch := make(chan plog.Logs)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		buf, err := io.ReadAll(gz)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req := plogotlp.NewExportRequest()
+		if err := req.UnmarshalProto(buf); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ch <- req.Logs()
+	}))
+	defer srv.Close()
+
+	// Set up the component Arguments.
+	cfg := fmt.Sprintf(`
+		endpoint {
+			url        = "%s"
+			batch_wait = "10ms"
+			protocol   = "otlp"
+		}
+		external_labels = { cluster = "c-1" }
+	`, srv.URL)
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(cfg), &args))
+
+	alterConfig(&args)
+
+	// Set up and start the component.
+	tc, err := componenttest.NewControllerFromID(util.TestLogger(t), "loki.write")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), args)
+		require.NoError(t, err)
+	}()
+	require.NoError(t, tc.WaitExports(time.Second))
+
+	logEntry := loki.Entry{
+		Labels: model.LabelSet{"foo": "bar"},
+		Entry: logproto.Entry{
+			Timestamp:          time.Now(),
+			Line:               "very important log",
+			StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "abc"}},
+		},
+	}
+	exports := tc.Exports().(Exports)
+	exports.Receiver.Chan() <- logEntry
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("no logs received")
+	case logs := <-ch:
+		require.Equal(t, 1, logs.ResourceLogs().Len())
+		rl := logs.ResourceLogs().At(0)
+		require.Equal(t, map[string]any{"foo": "bar", "cluster": "c-1"}, rl.Resource().Attributes().AsRaw())
+		records := rl.ScopeLogs().At(0).LogRecords()
+		require.Equal(t, 1, records.Len())
+		require.Equal(t, logEntry.Line, records.At(0).Body().Str())
+		require.Equal(t, logEntry.Timestamp.UnixNano(), records.At(0).Timestamp().AsTime().UnixNano())
+		require.Equal(t, map[string]any{"trace_id": "abc"}, records.At(0).Attributes().AsRaw())
+	}
+}
+
 func TestEntrySentToTwoWriteComponents(t *testing.T) {
 	t.Run("wal disabled", func(t *testing.T) {
 		testMultipleEndpoint(t, func(arguments *Arguments) {})
@@ -0,0 +1,107 @@
+package livedebuggingpublisher
+
+import (
+	"github.com/prometheus/prometheus/model/labels"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// The label sets of OpenTelemetry data are used to filter live debugging data.
+// There is one label set per record, holding the attributes of the resource
+// merged with the attributes of the record. Record attributes take precedence.
+
+func logsLabels(ld plog.Logs) []labels.Labels {
+	var res []labels.Labels
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			records := rl.ScopeLogs().At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				res = append(res, attributesLabels(rl.Resource().Attributes(), records.At(k).Attributes()))
+			}
+		}
+	}
+	return res
+}
+
+func tracesLabels(td ptrace.Traces) []labels.Labels {
+	var res []labels.Labels
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			spans := rs.ScopeSpans().At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				res = append(res, attributesLabels(rs.Resource().Attributes(), spans.At(k).Attributes()))
+			}
+		}
+	}
+	return res
+}
+
+// metricsLabels returns one label set per data point. The name of the metric
+// is set as the __name__ label.
+func metricsLabels(md pmetric.Metrics) []labels.Labels {
+	var res []labels.Labels
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			metrics := rm.ScopeMetrics().At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				metric := metrics.At(k)
+				for _, attrs := range dataPointsAttributes(metric) {
+					lbls := labels.NewBuilder(attributesLabels(rm.Resource().Attributes(), attrs))
+					lbls.Set(labels.MetricName, metric.Name())
+					res = append(res, lbls.Labels())
+				}
+			}
+		}
+	}
+	return res
+}
+
+func dataPointsAttributes(metric pmetric.Metric) []pcommon.Map {
+	var res []pcommon.Map
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+			res = append(res, metric.Gauge().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < metric.Sum().DataPoints().Len(); i++ {
+			res = append(res, metric.Sum().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < metric.Histogram().DataPoints().Len(); i++ {
+			res = append(res, metric.Histogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < metric.ExponentialHistogram().DataPoints().Len(); i++ {
+			res = append(res, metric.ExponentialHistogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := 0; i < metric.Summary().DataPoints().Len(); i++ {
+			res = append(res, metric.Summary().DataPoints().At(i).Attributes())
+		}
+	}
+
+	// Metrics without data points can still be matched by name.
+	if len(res) == 0 {
+		res = append(res, pcommon.NewMap())
+	}
+	return res
+}
+
+func attributesLabels(resourceAttrs, recordAttrs pcommon.Map) labels.Labels {
+	b := labels.NewBuilder(labels.EmptyLabels())
+	resourceAttrs.Range(func(k string, v pcommon.Value) bool {
+		b.Set(k, v.AsString())
+		return true
+	})
+	recordAttrs.Range(func(k string, v pcommon.Value) bool {
+		b.Set(k, v.AsString())
+		return true
+	})
+	return b.Labels()
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/grafana/alloy/internal/component/otelcol"
 	"github.com/grafana/alloy/internal/component/otelcol/internal/textmarshaler"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/prometheus/prometheus/model/labels"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -22,6 +23,7 @@ func PublishLogsIfActive(debugDataPublisher livedebugging.DebugDataPublisher, co
 			return string(data)
 		},
 		livedebugging.WithTargetComponentIDs(extractIds(nextLogs)),
+		livedebugging.WithLabels(func() []labels.Labels { return logsLabels(ld) }),
 	))
 }
 
@@ -38,6 +40,7 @@ func PublishTracesIfActive(debugDataPublisher livedebugging.DebugDataPublisher,
 			return string(data)
 		},
 		livedebugging.WithTargetComponentIDs(extractIds(nextTraces)),
+		livedebugging.WithLabels(func() []labels.Labels { return tracesLabels(td) }),
 	))
 }
 
@@ -54,6 +57,7 @@ func PublishMetricsIfActive(debugDataPublisher livedebugging.DebugDataPublisher,
 			return string(data)
 		},
 		livedebugging.WithTargetComponentIDs(extractIds(nextMetrics)),
+		livedebugging.WithLabels(func() []labels.Labels { return metricsLabels(md) }),
 	))
 }
 
@@ -0,0 +1,434 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.aggregate",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the prometheus.aggregate
+// component.
+type Arguments struct {
+	// Where the aggregated metrics and the raw metrics are forwarded to.
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// How often the aggregated metrics are flushed.
+	Interval time.Duration `alloy:"interval,attr,optional"`
+
+	// Whether to stop forwarding the raw metrics matched by a rule.
+	DropInputs bool `alloy:"drop_inputs,attr,optional"`
+
+	Rules []RuleConfig `alloy:"rule,block"`
+}
+
+// RuleConfig configures an aggregation of the metrics matching a selector.
+type RuleConfig struct {
+	Match   string   `alloy:"match,attr,optional"`
+	By      []string `alloy:"by,attr,optional"`
+	Without []string `alloy:"without,attr,optional"`
+	Outputs []string `alloy:"outputs,attr"`
+}
+
+// Validate implements syntax.Validator.
+func (r *RuleConfig) Validate() error {
+	if len(r.By) > 0 && len(r.Without) > 0 {
+		return errors.New("only one of by or without can be set")
+	}
+	if slices.Contains(r.Without, labels.MetricName) {
+		return fmt.Errorf("%s can't be used in without", labels.MetricName)
+	}
+	if r.Match != "" {
+		if _, err := parser.ParseMetricSelector(r.Match); err != nil {
+			return fmt.Errorf("invalid match selector: %w", err)
+		}
+	}
+	if len(r.Outputs) == 0 {
+		return errors.New("at least one output must be set")
+	}
+	for i, output := range r.Outputs {
+		if _, ok := supportedOutputs[output]; !ok {
+			return fmt.Errorf("unsupported output %q", output)
+		}
+		if slices.Contains(r.Outputs[:i], output) {
+			return fmt.Errorf("duplicate output %q", output)
+		}
+	}
+	return nil
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		Interval: time.Minute,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.aggregate
+// component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+// Component implements the prometheus.aggregate component.
+type Component struct {
+	opts component.Options
+
+	mut         sync.RWMutex
+	args        Arguments
+	aggregators []*aggregator
+	intervalCh  chan struct{}
+
+	receiver *prometheus.Interceptor
+	fanout   *prometheus.Fanout
+	exited   atomic.Bool
+
+	lateSamples    prometheus_client.Counter
+	droppedSamples prometheus_client.Counter
+	flushedSeries  prometheus_client.Counter
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+	_ storage.Appendable  = (*Component)(nil)
+)
+
+// New creates a new prometheus.aggregate component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:       o,
+		intervalCh: make(chan struct{}, 1),
+	}
+
+	c.lateSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_aggregate_late_samples_total",
+		Help: "Total number of samples which weren't aggregated, because they were older than the previous sample of their series or the current window.",
+	})
+	c.droppedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_aggregate_dropped_samples_total",
+		Help: "Total number of samples which weren't aggregated, because they couldn't be aggregated with the other samples of their group.",
+	})
+	c.flushedSeries = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_aggregate_flushed_series_total",
+		Help: "Total number of aggregated series forwarded.",
+	})
+	for _, metric := range []prometheus_client.Collector{c.lateSamples, c.droppedSamples, c.flushedSeries} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		// The samples are aggregated by the appenders returned by Appender when
+		// they're committed.
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if c.dropped(l) {
+				return ref, nil
+			}
+			return next.Append(ref, l, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if c.dropped(l) {
+				return ref, nil
+			}
+			return next.AppendHistogram(ref, l, t, h, fh)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if c.dropped(l) {
+				return ref, nil
+			}
+			return next.AppendExemplar(ref, l, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if c.dropped(l) {
+				return ref, nil
+			}
+			return next.UpdateMetadata(ref, l, m)
+		}),
+		prometheus.WithCTZeroSampleHook(func(ref storage.SeriesRef, l labels.Labels, t, ct int64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if c.dropped(l) {
+				return ref, nil
+			}
+			return next.AppendCTZeroSample(ref, l, t, ct)
+		}),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.exited.Store(true)
+
+	for {
+		c.mut.RLock()
+		interval := c.args.Interval
+		c.mut.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.intervalCh:
+			// The interval changed, which also reset the windows.
+		case <-time.After(interval):
+			c.flush(ctx)
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+
+	// Changing the rules or the interval resets the aggregation state.
+	if newArgs.Interval != c.args.Interval || !reflect.DeepEqual(newArgs.Rules, c.args.Rules) {
+		now := time.Now()
+		aggregators := make([]*aggregator, 0, len(newArgs.Rules))
+		for i, rule := range newArgs.Rules {
+			a, err := newAggregator(rule, newArgs.Interval, now)
+			if err != nil {
+				return fmt.Errorf("invalid rule %d: %w", i, err)
+			}
+			aggregators = append(aggregators, a)
+		}
+		c.aggregators = aggregators
+
+		select {
+		case c.intervalCh <- struct{}{}:
+		default:
+		}
+	}
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.args = newArgs
+	return nil
+}
+
+// Appender implements storage.Appendable. The samples matching the rules are
+// only aggregated when the appender is committed.
+func (c *Component) Appender(ctx context.Context) storage.Appender {
+	return &appender{Appender: c.receiver.Appender(ctx), c: c}
+}
+
+// aggregate adds the samples to the aggregators of the rules matching their
+// series.
+func (c *Component) aggregate(samples []pendingSample) {
+	// Each aggregator has its own lock, so that the samples of different
+	// rules, and the flushes, don't wait for each other.
+	c.mut.RLock()
+	aggregators := c.aggregators
+	c.mut.RUnlock()
+
+	for _, s := range samples {
+		for _, a := range aggregators {
+			if !a.Matches(s.l) {
+				continue
+			}
+
+			switch err := a.Add(s.l, s.t, s.v, s.fh); {
+			case errors.Is(err, errLateSample):
+				c.lateSamples.Inc()
+			case err != nil:
+				c.droppedSamples.Inc()
+			}
+		}
+	}
+}
+
+// matched returns whether the series matches any of the rules.
+func (c *Component) matched(l labels.Labels) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	for _, a := range c.aggregators {
+		if a.Matches(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropped returns whether the data of a series shouldn't be forwarded.
+func (c *Component) dropped(l labels.Labels) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if !c.args.DropInputs {
+		return false
+	}
+	for _, a := range c.aggregators {
+		if a.Matches(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush forwards the outputs of all the aggregators.
+func (c *Component) flush(ctx context.Context) {
+	now := time.Now()
+
+	c.mut.RLock()
+	aggregators := c.aggregators
+	c.mut.RUnlock()
+
+	var outputs []outputSample
+	for _, a := range aggregators {
+		outputs = append(outputs, a.Flush(now)...)
+	}
+
+	if len(outputs) == 0 {
+		return
+	}
+
+	t := now.UnixMilli()
+	app := c.fanout.Appender(ctx)
+	for _, s := range outputs {
+		var err error
+		if s.fh != nil {
+			_, err = app.AppendHistogram(0, s.labels, t, nil, s.fh)
+		} else {
+			_, err = app.Append(0, s.labels, t, s.v)
+		}
+		if err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to forward aggregated series", "series", s.labels, "err", err)
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to forward aggregated series", "err", err)
+		return
+	}
+	c.flushedSeries.Add(float64(len(outputs)))
+}
+
+// pendingSample is a sample appended to an appender which isn't committed yet.
+type pendingSample struct {
+	l  labels.Labels
+	t  int64
+	v  float64
+	fh *histogram.FloatHistogram
+}
+
+// appender forwards the samples to the receiver of the component, and
+// buffers the samples matching the rules until it's committed, so that the
+// samples of an appender which is rolled back aren't aggregated.
+type appender struct {
+	storage.Appender
+
+	c       *Component
+	pending []pendingSample
+}
+
+var _ storage.Appender = (*appender)(nil)
+
+// Append implements storage.Appender.
+func (a *appender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := a.Appender.Append(ref, l, t, v)
+	if err == nil && a.c.matched(l) {
+		a.pending = append(a.pending, pendingSample{l: l, t: t, v: v})
+	}
+	return ref, err
+}
+
+// AppendHistogram implements storage.Appender.
+func (a *appender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	ref, err := a.Appender.AppendHistogram(ref, l, t, h, fh)
+	if err == nil && a.c.matched(l) {
+		// The histogram is copied, since the caller may reuse it once it's
+		// appended.
+		var sample *histogram.FloatHistogram
+		if fh != nil {
+			sample = fh.Copy()
+		} else {
+			sample = h.ToFloat(nil)
+		}
+		a.pending = append(a.pending, pendingSample{l: l, t: t, fh: sample})
+	}
+	return ref, err
+}
+
+// Commit implements storage.Appender.
+func (a *appender) Commit() error {
+	err := a.Appender.Commit()
+	a.c.aggregate(a.pending)
+	a.pending = nil
+	return err
+}
+
+// Rollback implements storage.Appender.
+func (a *appender) Rollback() error {
+	a.pending = nil
+	return a.Appender.Rollback()
+}
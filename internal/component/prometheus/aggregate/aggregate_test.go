@@ -0,0 +1,217 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    string
+		errMsg string
+	}{
+		{
+			name: "valid",
+			cfg: `
+				forward_to = []
+				rule {
+					match   = "{__name__=~\"http_.*\"}"
+					by      = ["job"]
+					outputs = ["sum", "total"]
+				}
+			`,
+		},
+		{
+			name: "by and without",
+			cfg: `
+				forward_to = []
+				rule {
+					by      = ["job"]
+					without = ["instance"]
+					outputs = ["sum"]
+				}
+			`,
+			errMsg: "only one of by or without can be set",
+		},
+		{
+			name: "invalid match",
+			cfg: `
+				forward_to = []
+				rule {
+					match   = "{job="
+					outputs = ["sum"]
+				}
+			`,
+			errMsg: "invalid match selector",
+		},
+		{
+			name: "unsupported output",
+			cfg: `
+				forward_to = []
+				rule {
+					outputs = ["median"]
+				}
+			`,
+			errMsg: `unsupported output "median"`,
+		},
+		{
+			name: "duplicate output",
+			cfg: `
+				forward_to = []
+				rule {
+					outputs = ["sum", "sum"]
+				}
+			`,
+			errMsg: `duplicate output "sum"`,
+		},
+		{
+			name: "invalid interval",
+			cfg: `
+				forward_to = []
+				interval   = "0s"
+				rule {
+					outputs = ["sum"]
+				}
+			`,
+			errMsg: "interval must be greater than 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.cfg), &args)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name       string
+		dropInputs bool
+	}{
+		{name: "keep inputs", dropInputs: false},
+		{name: "drop inputs", dropInputs: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := make(chan testappender.MetricSample, 1000)
+
+			var args Arguments
+			args.SetToDefault()
+			args.Interval = 100 * time.Millisecond
+			args.DropInputs = tc.dropInputs
+			args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{Sample: func(s testappender.MetricSample) { received <- s }}}
+			args.Rules = []RuleConfig{{
+				Match:   `{__name__="requests_total"}`,
+				By:      []string{"job"},
+				Outputs: []string{"sum"},
+			}}
+
+			ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.aggregate")
+			require.NoError(t, err)
+			go func() {
+				require.NoError(t, ctrl.Run(componenttest.TestContext(t), args))
+			}()
+			require.NoError(t, ctrl.WaitRunning(5*time.Second))
+
+			ts := timestamp.FromTime(time.Now())
+			app := ctrl.Exports().(Exports).Receiver.Appender(t.Context())
+			for i, instance := range []string{"a", "b", "c"} {
+				_, err := app.Append(0, labels.FromStrings("__name__", "requests_total", "job", "api", "instance", instance), ts, float64(i+1))
+				require.NoError(t, err)
+			}
+			_, err = app.Append(0, labels.FromStrings("__name__", "up", "job", "api"), ts, 1)
+			require.NoError(t, err)
+			require.NoError(t, app.Commit())
+
+			var sawInputs, sawUnmatched bool
+			timeout := time.After(10 * time.Second)
+			for {
+				select {
+				case s := <-received:
+					switch s.Labels.Get("__name__") {
+					case "requests_total":
+						sawInputs = true
+						continue
+					case "up":
+						sawUnmatched = true
+						continue
+					}
+					require.Equal(t, labels.FromStrings("__name__", "requests_total:100ms_by_job_sum", "job", "api"), s.Labels)
+					require.Equal(t, 6.0, s.Value)
+					require.Equal(t, !tc.dropInputs, sawInputs)
+					require.True(t, sawUnmatched)
+					return
+				case <-timeout:
+					require.FailNow(t, "timed out waiting for the aggregated series")
+				}
+			}
+		})
+	}
+}
+
+func TestAggregate_Rollback(t *testing.T) {
+	received := make(chan testappender.MetricSample, 1000)
+
+	var args Arguments
+	args.SetToDefault()
+	args.Interval = 100 * time.Millisecond
+	args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{Sample: func(s testappender.MetricSample) { received <- s }}}
+	args.Rules = []RuleConfig{{
+		Match:   `{__name__="requests_total"}`,
+		By:      []string{"job"},
+		Outputs: []string{"sum"},
+	}}
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.aggregate")
+	require.NoError(t, err)
+	go func() {
+		require.NoError(t, ctrl.Run(componenttest.TestContext(t), args))
+	}()
+	require.NoError(t, ctrl.WaitRunning(5*time.Second))
+
+	ts := timestamp.FromTime(time.Now())
+	receiver := ctrl.Exports().(Exports).Receiver
+
+	// The samples of an appender which is rolled back aren't aggregated.
+	app := receiver.Appender(t.Context())
+	_, err = app.Append(0, labels.FromStrings("__name__", "requests_total", "job", "api", "instance", "a"), ts, 5)
+	require.NoError(t, err)
+	require.NoError(t, app.Rollback())
+
+	app = receiver.Appender(t.Context())
+	_, err = app.Append(0, labels.FromStrings("__name__", "requests_total", "job", "api", "instance", "b"), ts, 2)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case s := <-received:
+			if s.Labels.Get("__name__") != "requests_total:100ms_by_job_sum" {
+				continue
+			}
+			require.Equal(t, 2.0, s.Value)
+			return
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for the aggregated series")
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package aggregate
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Outputs which can be computed by an aggregation rule.
+const (
+	outputCount    = "count"
+	outputIncrease = "increase"
+	outputMax      = "max"
+	outputMin      = "min"
+	outputSum      = "sum"
+	outputTotal    = "total"
+)
+
+var supportedOutputs = map[string]struct{}{
+	outputCount:    {},
+	outputIncrease: {},
+	outputMax:      {},
+	outputMin:      {},
+	outputSum:      {},
+	outputTotal:    {},
+}
+
+var (
+	errLateSample   = errors.New("sample is older than the previous sample of the series or the window")
+	errTypeMismatch = errors.New("float and histogram samples can't be aggregated together")
+)
+
+// aggregator aggregates the samples matching a rule into groups of series
+// sharing the same metric name and grouping labels. It's safe for concurrent
+// use.
+type aggregator struct {
+	matchers []*labels.Matcher
+	by       []string
+	without  []string
+	outputs  []string
+	suffix   string
+
+	mut sync.Mutex
+
+	// Start of the current window, in milliseconds. Samples more than one
+	// interval older than it are late.
+	windowStart int64
+	interval    int64
+
+	groups map[uint64]*group
+	series map[uint64]*seriesState
+}
+
+// group holds the aggregation state of the series with the same output
+// labels.
+type group struct {
+	labels      labels.Labels
+	isHistogram bool
+	seen        bool
+
+	// Last value of each series in the current window.
+	last  map[uint64]float64
+	lastH map[uint64]*histogram.FloatHistogram
+
+	min, max        float64
+	increase, total float64
+
+	increaseH, totalH *histogram.FloatHistogram
+}
+
+// groupSnapshot holds the state of a group at the end of a window, from which
+// its outputs are computed.
+type groupSnapshot struct {
+	labels      labels.Labels
+	isHistogram bool
+
+	count                          int
+	min, max, sum, increase, total float64
+
+	lastH             map[uint64]*histogram.FloatHistogram
+	increaseH, totalH *histogram.FloatHistogram
+}
+
+// seriesState holds the last sample of an input series, to compute the
+// increase of counters across windows.
+type seriesState struct {
+	t     int64
+	v     float64
+	h     *histogram.FloatHistogram
+	group *group
+	seen  bool
+}
+
+func newAggregator(rule RuleConfig, interval time.Duration, now time.Time) (*aggregator, error) {
+	var matchers []*labels.Matcher
+	if rule.Match != "" {
+		var err error
+		if matchers, err = parser.ParseMetricSelector(rule.Match); err != nil {
+			return nil, err
+		}
+	}
+
+	return &aggregator{
+		matchers:    matchers,
+		by:          rule.By,
+		without:     rule.Without,
+		outputs:     rule.Outputs,
+		suffix:      outputSuffix(rule, interval),
+		windowStart: now.UnixMilli(),
+		interval:    interval.Milliseconds(),
+		groups:      make(map[uint64]*group),
+		series:      make(map[uint64]*seriesState),
+	}, nil
+}
+
+// outputSuffix returns the suffix appended to the metric name of the output
+// series, for example :1m_by_job for a rule grouping by job every minute.
+func outputSuffix(rule RuleConfig, interval time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString(":")
+	sb.WriteString(model.Duration(interval).String())
+	if len(rule.By) > 0 {
+		sb.WriteString("_by_")
+		sb.WriteString(strings.Join(rule.By, "_"))
+	}
+	if len(rule.Without) > 0 {
+		sb.WriteString("_without_")
+		sb.WriteString(strings.Join(rule.Without, "_"))
+	}
+	return sb.String()
+}
+
+// Matches returns whether a series is aggregated by the aggregator.
+func (a *aggregator) Matches(l labels.Labels) bool {
+	for _, m := range a.matchers {
+		if !m.Matches(l.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Add aggregates a sample of a series. Exactly one of v or fh is used,
+// depending on whether fh is nil.
+func (a *aggregator) Add(l labels.Labels, t int64, v float64, fh *histogram.FloatHistogram) error {
+	key := l.Hash()
+	// The group labels are computed before locking, as they don't depend on
+	// the state of the aggregator.
+	gl := a.groupLabels(l)
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	s, ok := a.series[key]
+
+	// Stale markers end the series, the next sample will start a new one.
+	if (fh == nil && value.IsStaleNaN(v)) || (fh != nil && value.IsStaleNaN(fh.Sum)) {
+		delete(a.series, key)
+		return nil
+	}
+	if (ok && t <= s.t) || t < a.windowStart-a.interval {
+		return errLateSample
+	}
+
+	g := a.group(gl, fh != nil)
+	if g.isHistogram != (fh != nil) {
+		return errTypeMismatch
+	}
+	// A series which moved to another group is handled as a new series.
+	if ok && s.group != g {
+		ok = false
+	}
+
+	if fh == nil {
+		var delta float64
+		if ok && s.h == nil {
+			delta = v - s.v
+			if v < s.v {
+				// The counter was reset.
+				delta = v
+			}
+		}
+		g.last[key] = v
+		g.min, g.max = math.Min(g.min, v), math.Max(g.max, v)
+		g.increase += delta
+		g.total += delta
+	} else {
+		var delta *histogram.FloatHistogram
+		if ok && s.h != nil {
+			delta = fh.Copy()
+			if !fh.DetectReset(s.h) {
+				if _, err := delta.Sub(s.h); err != nil {
+					return err
+				}
+			}
+		}
+		if delta != nil {
+			var err error
+			if g.increaseH, err = addHistogram(g.increaseH, delta); err != nil {
+				return err
+			}
+			if g.totalH, err = addHistogram(g.totalH, delta); err != nil {
+				return err
+			}
+		}
+		g.lastH[key] = fh
+	}
+
+	g.seen = true
+	a.series[key] = &seriesState{t: t, v: v, h: fh, group: g, seen: true}
+	return nil
+}
+
+// groupLabels returns the labels of the group a series belongs to.
+func (a *aggregator) groupLabels(l labels.Labels) labels.Labels {
+	lb := labels.NewBuilder(l)
+	switch {
+	case len(a.by) > 0:
+		lb.Keep(append([]string{labels.MetricName}, a.by...)...)
+	case len(a.without) > 0:
+		lb.Del(a.without...)
+	}
+	return lb.Labels()
+}
+
+// group returns the group with the labels gl, creating it if needed.
+func (a *aggregator) group(gl labels.Labels, isHistogram bool) *group {
+	key := gl.Hash()
+	g, ok := a.groups[key]
+	if !ok {
+		g = &group{
+			labels:      gl,
+			isHistogram: isHistogram,
+			last:        make(map[uint64]float64),
+			lastH:       make(map[uint64]*histogram.FloatHistogram),
+			min:         math.Inf(1),
+			max:         math.Inf(-1),
+		}
+		a.groups[key] = g
+	}
+	return g
+}
+
+// outputSample is a sample computed by an aggregator.
+type outputSample struct {
+	labels labels.Labels
+	v      float64
+	fh     *histogram.FloatHistogram
+}
+
+// Flush returns the outputs of the groups which received samples in the
+// window ending at now, and starts a new window. Groups and series which
+// didn't receive any sample in the window are forgotten.
+func (a *aggregator) Flush(now time.Time) []outputSample {
+	// The outputs are computed from snapshots of the groups, so that samples
+	// can be added to the new window meanwhile.
+	snapshots := a.snapshot(now)
+
+	var res []outputSample
+	for _, g := range snapshots {
+		for _, output := range a.outputs {
+			s := outputSample{labels: a.outputLabels(g.labels, output)}
+			switch {
+			case output == outputCount:
+				s.v = float64(g.count)
+			case output == outputMin && !g.isHistogram:
+				s.v = g.min
+			case output == outputMax && !g.isHistogram:
+				s.v = g.max
+			case output == outputSum && !g.isHistogram:
+				s.v = g.sum
+			case output == outputSum:
+				var err error
+				for _, fh := range g.lastH {
+					if s.fh, err = addHistogram(s.fh, fh); err != nil {
+						break
+					}
+				}
+				if err != nil {
+					continue
+				}
+			case output == outputIncrease && !g.isHistogram:
+				s.v = g.increase
+			case output == outputIncrease:
+				s.fh = emptyIfNil(g.increaseH, g.lastH)
+				s.fh.CounterResetHint = histogram.GaugeType
+			case output == outputTotal && !g.isHistogram:
+				s.v = g.total
+			case output == outputTotal:
+				s.fh = emptyIfNil(g.totalH, g.lastH)
+			default:
+				// min and max aren't computed for histograms.
+				continue
+			}
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// snapshot returns the state of the groups which received samples in the
+// window ending at now, and starts a new window.
+func (a *aggregator) snapshot(now time.Time) []groupSnapshot {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	var snapshots []groupSnapshot
+	for key, g := range a.groups {
+		if !g.seen {
+			delete(a.groups, key)
+			continue
+		}
+
+		snap := groupSnapshot{
+			labels:      g.labels,
+			isHistogram: g.isHistogram,
+			count:       len(g.last) + len(g.lastH),
+			min:         g.min,
+			max:         g.max,
+			increase:    g.increase,
+			total:       g.total,
+			lastH:       g.lastH,
+			increaseH:   g.increaseH,
+		}
+		for _, v := range g.last {
+			snap.sum += v
+		}
+		// The total keeps accumulating in the next windows.
+		if g.totalH != nil {
+			snap.totalH = g.totalH.Copy()
+		}
+		snapshots = append(snapshots, snap)
+
+		g.seen = false
+		clear(g.last)
+		g.lastH = make(map[uint64]*histogram.FloatHistogram)
+		g.min, g.max = math.Inf(1), math.Inf(-1)
+		g.increase, g.increaseH = 0, nil
+	}
+
+	for key, s := range a.series {
+		if !s.seen {
+			delete(a.series, key)
+			continue
+		}
+		s.seen = false
+	}
+
+	a.windowStart = now.UnixMilli()
+	return snapshots
+}
+
+func (a *aggregator) outputLabels(gl labels.Labels, output string) labels.Labels {
+	lb := labels.NewBuilder(gl)
+	lb.Set(labels.MetricName, gl.Get(labels.MetricName)+a.suffix+"_"+output)
+	return lb.Labels()
+}
+
+// addHistogram adds fh to sum, and returns the result. sum is allocated if
+// it's nil.
+func addHistogram(sum, fh *histogram.FloatHistogram) (*histogram.FloatHistogram, error) {
+	if sum == nil {
+		return fh.Copy(), nil
+	}
+	return sum.Add(fh)
+}
+
+// emptyIfNil returns a copy of fh, or an empty histogram with the schema of
+// the histograms in last if fh is nil.
+func emptyIfNil(fh *histogram.FloatHistogram, last map[uint64]*histogram.FloatHistogram) *histogram.FloatHistogram {
+	if fh != nil {
+		return fh.Copy()
+	}
+	empty := &histogram.FloatHistogram{}
+	for _, h := range last {
+		empty.Schema, empty.ZeroThreshold, empty.CustomValues = h.Schema, h.ZeroThreshold, h.CustomValues
+		break
+	}
+	return empty
+}
@@ -0,0 +1,172 @@
+package aggregate
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator_Floats(t *testing.T) {
+	start := time.Unix(1000, 0)
+	a, err := newAggregator(RuleConfig{
+		Match:   `{__name__="requests_total"}`,
+		By:      []string{"job"},
+		Outputs: []string{"count", "sum", "min", "max", "increase", "total"},
+	}, time.Minute, start)
+	require.NoError(t, err)
+
+	require.False(t, a.Matches(labels.FromStrings("__name__", "errors_total")))
+
+	seriesA := labels.FromStrings("__name__", "requests_total", "job", "api", "instance", "a")
+	seriesB := labels.FromStrings("__name__", "requests_total", "job", "api", "instance", "b")
+	ts := start.UnixMilli()
+
+	// The first sample of a series doesn't count towards the increase.
+	require.NoError(t, a.Add(seriesA, ts+1, 10, nil))
+	require.NoError(t, a.Add(seriesA, ts+2, 15, nil))
+	require.NoError(t, a.Add(seriesB, ts+1, 5, nil))
+	require.ErrorIs(t, a.Add(seriesB, ts+1, 6, nil), errLateSample)
+
+	require.Equal(t, map[string]float64{
+		"requests_total:1m_by_job_count":    2,
+		"requests_total:1m_by_job_sum":      20,
+		"requests_total:1m_by_job_min":      5,
+		"requests_total:1m_by_job_max":      15,
+		"requests_total:1m_by_job_increase": 5,
+		"requests_total:1m_by_job_total":    5,
+	}, floatOutputs(t, a.Flush(start.Add(time.Minute))))
+
+	// The counter of series A is reset.
+	require.NoError(t, a.Add(seriesA, ts+60_001, 3, nil))
+	require.NoError(t, a.Add(seriesB, ts+60_001, 7, nil))
+
+	require.Equal(t, map[string]float64{
+		"requests_total:1m_by_job_count":    2,
+		"requests_total:1m_by_job_sum":      10,
+		"requests_total:1m_by_job_min":      3,
+		"requests_total:1m_by_job_max":      7,
+		"requests_total:1m_by_job_increase": 5,
+		"requests_total:1m_by_job_total":    10,
+	}, floatOutputs(t, a.Flush(start.Add(2*time.Minute))))
+
+	// Samples older than one interval before the window are late.
+	require.ErrorIs(t, a.Add(seriesA, ts+30_000, 1, nil), errLateSample)
+
+	// Groups without samples in a window are forgotten.
+	require.Empty(t, a.Flush(start.Add(3*time.Minute)))
+	require.Empty(t, a.groups)
+	require.Empty(t, a.series)
+}
+
+func TestAggregator_StaleMarker(t *testing.T) {
+	start := time.Unix(1000, 0)
+	a, err := newAggregator(RuleConfig{Without: []string{"instance"}, Outputs: []string{"total"}}, time.Minute, start)
+	require.NoError(t, err)
+
+	series := labels.FromStrings("__name__", "requests_total", "job", "api", "instance", "a")
+	ts := start.UnixMilli()
+
+	require.NoError(t, a.Add(series, ts+1, 10, nil))
+	require.NoError(t, a.Add(series, ts+2, math.Float64frombits(value.StaleNaN), nil))
+	require.NoError(t, a.Add(series, ts+3, 12, nil))
+
+	// The series restarted after the stale marker, so its first sample
+	// doesn't count towards the increase.
+	require.Equal(t, map[string]float64{
+		"requests_total:1m_without_instance_total": 0,
+	}, floatOutputs(t, a.Flush(start.Add(time.Minute))))
+}
+
+func TestAggregator_Histograms(t *testing.T) {
+	start := time.Unix(1000, 0)
+	a, err := newAggregator(RuleConfig{By: []string{"job"}, Outputs: []string{"count", "sum", "increase", "max"}}, time.Minute, start)
+	require.NoError(t, err)
+
+	seriesA := labels.FromStrings("__name__", "latency", "job", "api", "instance", "a")
+	seriesB := labels.FromStrings("__name__", "latency", "job", "api", "instance", "b")
+	ts := start.UnixMilli()
+
+	require.NoError(t, a.Add(seriesA, ts+1, 0, testHistogram(2)))
+	require.NoError(t, a.Add(seriesA, ts+2, 0, testHistogram(5)))
+	require.NoError(t, a.Add(seriesB, ts+1, 0, testHistogram(1)))
+	require.ErrorIs(t, a.Add(labels.FromStrings("__name__", "latency", "job", "api", "instance", "c"), ts+1, 1, nil), errTypeMismatch)
+
+	outputs := a.Flush(start.Add(time.Minute))
+	sortOutputs(outputs)
+
+	// max isn't computed for histograms.
+	require.Len(t, outputs, 3)
+	require.Equal(t, "latency:1m_by_job_count", outputs[0].labels.Get("__name__"))
+	require.Equal(t, 2.0, outputs[0].v)
+	require.Equal(t, "latency:1m_by_job_increase", outputs[1].labels.Get("__name__"))
+	require.Equal(t, 3.0, outputs[1].fh.Count)
+	require.Equal(t, histogram.GaugeType, outputs[1].fh.CounterResetHint)
+	require.Equal(t, "latency:1m_by_job_sum", outputs[2].labels.Get("__name__"))
+	require.Equal(t, 6.0, outputs[2].fh.Count)
+}
+
+func testHistogram(count float64) *histogram.FloatHistogram {
+	return &histogram.FloatHistogram{
+		Count:           count,
+		Sum:             count,
+		Schema:          0,
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 1}},
+		PositiveBuckets: []float64{count},
+	}
+}
+
+func floatOutputs(t *testing.T, outputs []outputSample) map[string]float64 {
+	res := make(map[string]float64, len(outputs))
+	for _, s := range outputs {
+		require.Nil(t, s.fh)
+		require.Equal(t, "api", s.labels.Get("job"))
+		require.Empty(t, s.labels.Get("instance"))
+		res[s.labels.Get("__name__")] = s.v
+	}
+	return res
+}
+
+func sortOutputs(outputs []outputSample) {
+	sort.Slice(outputs, func(i, j int) bool {
+		return labels.Compare(outputs[i].labels, outputs[j].labels) < 0
+	})
+}
+
+func TestAggregator_Concurrent(t *testing.T) {
+	start := time.Unix(1000, 0)
+	a, err := newAggregator(RuleConfig{
+		By:      []string{"job"},
+		Outputs: []string{"count", "sum", "total"},
+	}, time.Minute, start)
+	require.NoError(t, err)
+
+	// Samples are added while the aggregator is flushed.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l := labels.FromStrings("__name__", "requests_total", "job", "api", "instance", strconv.Itoa(i))
+			for j := range 1000 {
+				_ = a.Add(l, start.UnixMilli()+int64(j), float64(j), nil)
+			}
+		}()
+	}
+	for range 10 {
+		a.Flush(start)
+	}
+	wg.Wait()
+
+	outputs := floatOutputs(t, a.Flush(start))
+	require.Equal(t, 4.0, outputs["requests_total:1m_by_job_count"])
+	require.Equal(t, 4*999.0, outputs["requests_total:1m_by_job_sum"])
+	require.Equal(t, 4*999.0, outputs["requests_total:1m_by_job_total"])
+}
@@ -0,0 +1,345 @@
+package cardinality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.cardinality",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Actions applied to the series exceeding a limit.
+const (
+	ActionDrop    = "drop"
+	ActionReplace = "replace"
+)
+
+// Arguments holds values which are used to configure the
+// prometheus.cardinality component.
+type Arguments struct {
+	// Where the metrics within the limits are forwarded to.
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// Default limits, 0 means unlimited.
+	MaxSeriesPerMetric int `alloy:"max_series_per_metric,attr,optional"`
+	MaxValuesPerLabel  int `alloy:"max_values_per_label,attr,optional"`
+
+	Action        string `alloy:"action,attr,optional"`
+	OverflowValue string `alloy:"overflow_value,attr,optional"`
+
+	MaxTrackedSeries int           `alloy:"max_tracked_series,attr,optional"`
+	SeriesTimeout    time.Duration `alloy:"series_timeout,attr,optional"`
+	TopN             int           `alloy:"top_n,attr,optional"`
+
+	MetricLimits []MetricLimit `alloy:"metric_limit,block,optional"`
+	LabelLimits  []LabelLimit  `alloy:"label_limit,block,optional"`
+}
+
+// MetricLimit overrides the maximum number of series of a metric.
+type MetricLimit struct {
+	Name      string `alloy:"name,attr"`
+	MaxSeries int    `alloy:"max_series,attr"`
+}
+
+// LabelLimit overrides the maximum number of values of a label.
+type LabelLimit struct {
+	Name      string `alloy:"name,attr"`
+	MaxValues int    `alloy:"max_values,attr"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		Action:           ActionDrop,
+		OverflowValue:    "__overflow__",
+		MaxTrackedSeries: 1_000_000,
+		SeriesTimeout:    10 * time.Minute,
+		TopN:             10,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	switch {
+	case args.Action != ActionDrop && args.Action != ActionReplace:
+		return fmt.Errorf("action must be one of %q or %q, got %q", ActionDrop, ActionReplace, args.Action)
+	case args.Action == ActionReplace && args.OverflowValue == "":
+		return errors.New("overflow_value must be set when action is replace")
+	case args.MaxSeriesPerMetric < 0:
+		return fmt.Errorf("max_series_per_metric can't be negative")
+	case args.MaxValuesPerLabel < 0:
+		return fmt.Errorf("max_values_per_label can't be negative")
+	case args.MaxTrackedSeries <= 0:
+		return fmt.Errorf("max_tracked_series must be greater than 0 and is %d", args.MaxTrackedSeries)
+	case args.SeriesTimeout <= 0:
+		return fmt.Errorf("series_timeout must be greater than 0")
+	case args.TopN <= 0:
+		return fmt.Errorf("top_n must be greater than 0 and is %d", args.TopN)
+	}
+
+	metrics := make(map[string]struct{}, len(args.MetricLimits))
+	for _, l := range args.MetricLimits {
+		if _, ok := metrics[l.Name]; ok {
+			return fmt.Errorf("duplicate metric_limit for metric %q", l.Name)
+		}
+		if l.MaxSeries < 0 {
+			return fmt.Errorf("metric_limit for metric %q: max_series can't be negative", l.Name)
+		}
+		metrics[l.Name] = struct{}{}
+	}
+
+	labelNames := make(map[string]struct{}, len(args.LabelLimits))
+	for _, l := range args.LabelLimits {
+		if l.Name == labels.MetricName {
+			return fmt.Errorf("label_limit can't be set for %s, use metric_limit instead", labels.MetricName)
+		}
+		if _, ok := labelNames[l.Name]; ok {
+			return fmt.Errorf("duplicate label_limit for label %q", l.Name)
+		}
+		if l.MaxValues < 0 {
+			return fmt.Errorf("label_limit for label %q: max_values can't be negative", l.Name)
+		}
+		labelNames[l.Name] = struct{}{}
+	}
+	return nil
+}
+
+func (args *Arguments) limits() limits {
+	l := limits{
+		maxSeriesPerMetric: args.MaxSeriesPerMetric,
+		maxValuesPerLabel:  args.MaxValuesPerLabel,
+		metrics:            make(map[string]int, len(args.MetricLimits)),
+		labels:             make(map[string]int, len(args.LabelLimits)),
+		replace:            args.Action == ActionReplace,
+		overflowValue:      args.OverflowValue,
+	}
+	for _, ml := range args.MetricLimits {
+		l.metrics[ml.Name] = ml.MaxSeries
+	}
+	for _, ll := range args.LabelLimits {
+		l.labels[ll.Name] = ll.MaxValues
+	}
+	return l
+}
+
+// Exports holds values which are exported by the prometheus.cardinality
+// component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+// Component implements the prometheus.cardinality component.
+type Component struct {
+	opts component.Options
+
+	mut  sync.RWMutex
+	args Arguments
+
+	tracker  *tracker
+	receiver *prometheus.Interceptor
+	fanout   *prometheus.Fanout
+	exited   atomic.Bool
+
+	droppedSamples   prometheus_client.Counter
+	replacedSamples  prometheus_client.Counter
+	untrackedSamples prometheus_client.Counter
+}
+
+var (
+	_ component.Component      = (*Component)(nil)
+	_ component.DebugComponent = (*Component)(nil)
+)
+
+// New creates a new prometheus.cardinality component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:    o,
+		tracker: newTracker(),
+	}
+
+	c.droppedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_cardinality_dropped_samples_total",
+		Help: "Total number of samples dropped because their series exceeded a limit.",
+	})
+	c.replacedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_cardinality_replaced_samples_total",
+		Help: "Total number of samples whose label values were replaced because their series exceeded a label limit.",
+	})
+	c.untrackedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_cardinality_untracked_samples_total",
+		Help: "Total number of samples dropped because their series couldn't be tracked, as max_tracked_series was reached.",
+	})
+	activeSeries := prometheus_client.NewGaugeFunc(prometheus_client.GaugeOpts{
+		Name: "alloy_prometheus_cardinality_active_series",
+		Help: "Number of active series tracked.",
+	}, func() float64 { return float64(c.tracker.ActiveSeries()) })
+	for _, metric := range []prometheus_client.Collector{c.droppedSamples, c.replacedSamples, c.untrackedSamples, activeSeries} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, keep := c.admit(l)
+			if !keep {
+				return 0, nil
+			}
+			return next.Append(seriesRef(ref, l, newLbl), newLbl, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, keep := c.admit(l)
+			if !keep {
+				return 0, nil
+			}
+			return next.AppendHistogram(seriesRef(ref, l, newLbl), newLbl, t, h, fh)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, keep := c.admit(l)
+			if !keep {
+				return 0, nil
+			}
+			return next.AppendExemplar(seriesRef(ref, l, newLbl), newLbl, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, keep := c.admit(l)
+			if !keep {
+				return 0, nil
+			}
+			return next.UpdateMetadata(seriesRef(ref, l, newLbl), newLbl, m)
+		}),
+		prometheus.WithCTZeroSampleHook(func(ref storage.SeriesRef, l labels.Labels, t, ct int64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, keep := c.admit(l)
+			if !keep {
+				return 0, nil
+			}
+			return next.AppendCTZeroSample(seriesRef(ref, l, newLbl), newLbl, t, ct)
+		}),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// seriesRef returns the reference to pass to the next appender, which can't
+// be the original one if the labels were replaced.
+func seriesRef(ref storage.SeriesRef, l, newLbl labels.Labels) storage.SeriesRef {
+	if labels.Equal(l, newLbl) {
+		return ref
+	}
+	return 0
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.exited.Store(true)
+
+	for {
+		c.mut.RLock()
+		timeout := c.args.SeriesTimeout
+		c.mut.RUnlock()
+
+		// Sweeping more often than the timeout keeps the series count close
+		// to the actual number of active series.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(max(timeout/10, time.Second)):
+			c.tracker.Sweep(time.Now().Add(-timeout))
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.tracker.SetLimits(newArgs.limits(), newArgs.MaxTrackedSeries)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.args = newArgs
+	return nil
+}
+
+// admit returns the labels to forward the data of a series with, and whether
+// it should be forwarded.
+func (c *Component) admit(l labels.Labels) (labels.Labels, bool) {
+	newLbl, res := c.tracker.Admit(l, time.Now())
+	switch res {
+	case dropped:
+		c.droppedSamples.Inc()
+		return labels.EmptyLabels(), false
+	case replaced:
+		c.replacedSamples.Inc()
+	case untracked:
+		c.untrackedSamples.Inc()
+		return labels.EmptyLabels(), false
+	}
+	return newLbl, true
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	topN := c.args.TopN
+	c.mut.RUnlock()
+
+	return c.tracker.Report(topN)
+}
@@ -0,0 +1,131 @@
+package cardinality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    string
+		errMsg string
+	}{
+		{
+			name: "valid",
+			cfg: `
+				forward_to            = []
+				max_series_per_metric = 1000
+				action                = "replace"
+
+				metric_limit {
+					name       = "http_requests_total"
+					max_series = 5000
+				}
+
+				label_limit {
+					name       = "user_id"
+					max_values = 10
+				}
+			`,
+		},
+		{
+			name: "invalid action",
+			cfg: `
+				forward_to = []
+				action     = "keep"
+			`,
+			errMsg: `action must be one of "drop" or "replace", got "keep"`,
+		},
+		{
+			name: "duplicate metric limit",
+			cfg: `
+				forward_to = []
+				metric_limit {
+					name       = "up"
+					max_series = 1
+				}
+				metric_limit {
+					name       = "up"
+					max_series = 2
+				}
+			`,
+			errMsg: `duplicate metric_limit for metric "up"`,
+		},
+		{
+			name: "metric name label limit",
+			cfg: `
+				forward_to = []
+				label_limit {
+					name       = "__name__"
+					max_values = 1
+				}
+			`,
+			errMsg: "label_limit can't be set for __name__",
+		},
+		{
+			name: "invalid max tracked series",
+			cfg: `
+				forward_to         = []
+				max_tracked_series = 0
+			`,
+			errMsg: "max_tracked_series must be greater than 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.cfg), &args)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCardinality(t *testing.T) {
+	var received []labels.Labels
+
+	var args Arguments
+	args.SetToDefault()
+	args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{
+		Sample: func(s testappender.MetricSample) { received = append(received, s.Labels) },
+	}}
+	args.LabelLimits = []LabelLimit{{Name: "user_id", MaxValues: 2}}
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.cardinality")
+	require.NoError(t, err)
+	go func() {
+		require.NoError(t, ctrl.Run(componenttest.TestContext(t), args))
+	}()
+	require.NoError(t, ctrl.WaitRunning(5*time.Second))
+
+	app := ctrl.Exports().(Exports).Receiver.Appender(t.Context())
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := app.Append(0, labels.FromStrings("__name__", "requests_total", "user_id", id), 1000, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, []labels.Labels{
+		labels.FromStrings("__name__", "requests_total", "user_id", "1"),
+		labels.FromStrings("__name__", "requests_total", "user_id", "2"),
+	}, received)
+
+	c, err := ctrl.GetComponent()
+	require.NoError(t, err)
+	report := c.(*Component).DebugInfo().(Report)
+	require.Equal(t, 2, report.ActiveSeries)
+	require.Equal(t, []MetricCardinality{{Name: "requests_total", Series: 2}}, report.TopMetrics)
+}
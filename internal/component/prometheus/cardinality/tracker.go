@@ -0,0 +1,265 @@
+package cardinality
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// admitResult is the outcome of admitting a series in the tracker.
+type admitResult int
+
+const (
+	// The series is tracked and within the limits.
+	admitted admitResult = iota
+	// The series exceeded a label limit, and the value of the label was
+	// replaced.
+	replaced
+	// The series exceeded a limit and must be dropped.
+	dropped
+	// The series is within the limits but the tracker is full, so it can't be
+	// tracked and must be dropped.
+	untracked
+)
+
+// limits holds the cardinality limits enforced by a tracker.
+type limits struct {
+	maxSeriesPerMetric int
+	maxValuesPerLabel  int
+	metrics            map[string]int
+	labels             map[string]int
+
+	// When replace is set, the values of the labels exceeding their limit are
+	// replaced with overflowValue instead of dropping the series.
+	replace       bool
+	overflowValue string
+}
+
+func (l *limits) maxSeries(metric string) int {
+	if max, ok := l.metrics[metric]; ok {
+		return max
+	}
+	return l.maxSeriesPerMetric
+}
+
+func (l *limits) maxValues(label string) int {
+	if max, ok := l.labels[label]; ok {
+		return max
+	}
+	return l.maxValuesPerLabel
+}
+
+// tracker counts the active series per metric name and the distinct values
+// of each label across active series, and enforces limits on them.
+type tracker struct {
+	mut              sync.Mutex
+	limits           limits
+	maxTrackedSeries int
+
+	series      map[uint64]*trackedSeries
+	metrics     map[string]int
+	labelValues map[string]map[string]int
+}
+
+type trackedSeries struct {
+	labels   labels.Labels
+	lastSeen time.Time
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		series:      make(map[uint64]*trackedSeries),
+		metrics:     make(map[string]int),
+		labelValues: make(map[string]map[string]int),
+	}
+}
+
+// SetLimits changes the limits of the tracker. Series which are already
+// tracked stay tracked, even if they exceed the new limits.
+func (t *tracker) SetLimits(l limits, maxTrackedSeries int) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.limits, t.maxTrackedSeries = l, maxTrackedSeries
+}
+
+// Admit checks whether a series is within the limits, and starts tracking it
+// if it is. The returned labels are the labels of the series to forward,
+// which differ from l when the result is replaced.
+func (t *tracker) Admit(l labels.Labels, now time.Time) (labels.Labels, admitResult) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	res := t.admit(l, now)
+	if res != dropped || !t.limits.replace {
+		return l, res
+	}
+
+	exceeded := t.exceededLabels(l)
+	if len(exceeded) == 0 {
+		// The series exceeded the limit of its metric, which can't be fixed.
+		return l, dropped
+	}
+	lb := labels.NewBuilder(l)
+	for _, name := range exceeded {
+		lb.Set(name, t.limits.overflowValue)
+	}
+	replacement := lb.Labels()
+	if res := t.admit(replacement, now); res != admitted {
+		return l, res
+	}
+	return replacement, replaced
+}
+
+func (t *tracker) admit(l labels.Labels, now time.Time) admitResult {
+	key := l.Hash()
+	if s, ok := t.series[key]; ok {
+		s.lastSeen = now
+		return admitted
+	}
+
+	metric := l.Get(labels.MetricName)
+	if max := t.limits.maxSeries(metric); max > 0 && t.metrics[metric] >= max {
+		return dropped
+	}
+	if len(t.exceededLabels(l)) > 0 {
+		return dropped
+	}
+	// New series can't be checked against the limits once they're no longer
+	// tracked, so they're dropped rather than forwarded unchecked.
+	if len(t.series) >= t.maxTrackedSeries {
+		return untracked
+	}
+
+	t.series[key] = &trackedSeries{labels: l, lastSeen: now}
+	t.metrics[metric]++
+	l.Range(func(lbl labels.Label) {
+		if lbl.Name == labels.MetricName {
+			return
+		}
+		values, ok := t.labelValues[lbl.Name]
+		if !ok {
+			values = make(map[string]int)
+			t.labelValues[lbl.Name] = values
+		}
+		values[lbl.Value]++
+	})
+	return admitted
+}
+
+// exceededLabels returns the names of the labels of l which have a new value
+// that would exceed the limit of the label.
+func (t *tracker) exceededLabels(l labels.Labels) []string {
+	var exceeded []string
+	l.Range(func(lbl labels.Label) {
+		if lbl.Name == labels.MetricName || lbl.Value == t.limits.overflowValue {
+			return
+		}
+		max := t.limits.maxValues(lbl.Name)
+		if max <= 0 {
+			return
+		}
+		values := t.labelValues[lbl.Name]
+		if _, ok := values[lbl.Value]; ok {
+			return
+		}
+		// The overflow value doesn't count towards the limit.
+		n := len(values)
+		if _, ok := values[t.limits.overflowValue]; ok {
+			n--
+		}
+		if n >= max {
+			exceeded = append(exceeded, lbl.Name)
+		}
+	})
+	return exceeded
+}
+
+// Sweep stops tracking the series which weren't seen since before.
+func (t *tracker) Sweep(before time.Time) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	for key, s := range t.series {
+		if !s.lastSeen.Before(before) {
+			continue
+		}
+		delete(t.series, key)
+
+		metric := s.labels.Get(labels.MetricName)
+		if t.metrics[metric]--; t.metrics[metric] <= 0 {
+			delete(t.metrics, metric)
+		}
+		s.labels.Range(func(lbl labels.Label) {
+			values := t.labelValues[lbl.Name]
+			if values == nil {
+				return
+			}
+			if values[lbl.Value]--; values[lbl.Value] <= 0 {
+				delete(values, lbl.Value)
+			}
+			if len(values) == 0 {
+				delete(t.labelValues, lbl.Name)
+			}
+		})
+	}
+}
+
+// ActiveSeries returns the number of tracked series.
+func (t *tracker) ActiveSeries() int {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return len(t.series)
+}
+
+// Report returns the n metrics with the most series and the n labels with
+// the most values.
+func (t *tracker) Report(n int) Report {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	metrics := make([]MetricCardinality, 0, len(t.metrics))
+	for name, series := range t.metrics {
+		metrics = append(metrics, MetricCardinality{Name: name, Series: series})
+	}
+	slices.SortFunc(metrics, func(a, b MetricCardinality) int {
+		return cmp.Or(cmp.Compare(b.Series, a.Series), cmp.Compare(a.Name, b.Name))
+	})
+
+	labelNames := make([]LabelCardinality, 0, len(t.labelValues))
+	for name, values := range t.labelValues {
+		labelNames = append(labelNames, LabelCardinality{Name: name, Values: len(values)})
+	}
+	slices.SortFunc(labelNames, func(a, b LabelCardinality) int {
+		return cmp.Or(cmp.Compare(b.Values, a.Values), cmp.Compare(a.Name, b.Name))
+	})
+
+	return Report{
+		ActiveSeries: len(t.series),
+		TopMetrics:   metrics[:min(n, len(metrics))],
+		TopLabels:    labelNames[:min(n, len(labelNames))],
+	}
+}
+
+// Report is the cardinality report of the component, exposed as its debug
+// info.
+type Report struct {
+	ActiveSeries int                 `alloy:"active_series,attr"`
+	TopMetrics   []MetricCardinality `alloy:"metric,block,optional"`
+	TopLabels    []LabelCardinality  `alloy:"label,block,optional"`
+}
+
+// MetricCardinality is the number of active series of a metric.
+type MetricCardinality struct {
+	Name   string `alloy:"name,attr"`
+	Series int    `alloy:"series,attr"`
+}
+
+// LabelCardinality is the number of distinct values of a label across active
+// series.
+type LabelCardinality struct {
+	Name   string `alloy:"name,attr"`
+	Values int    `alloy:"values,attr"`
+}
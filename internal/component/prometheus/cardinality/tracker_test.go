@@ -0,0 +1,177 @@
+package cardinality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_MetricLimits(t *testing.T) {
+	tr := newTracker()
+	tr.SetLimits(limits{
+		maxSeriesPerMetric: 2,
+		metrics:            map[string]int{"unlimited": 0},
+	}, 100)
+	now := time.Now()
+
+	for _, instance := range []string{"a", "b"} {
+		_, res := tr.Admit(labels.FromStrings("__name__", "up", "instance", instance), now)
+		require.Equal(t, admitted, res)
+	}
+	_, res := tr.Admit(labels.FromStrings("__name__", "up", "instance", "c"), now)
+	require.Equal(t, dropped, res)
+
+	// Series which are already tracked are still admitted.
+	_, res = tr.Admit(labels.FromStrings("__name__", "up", "instance", "a"), now)
+	require.Equal(t, admitted, res)
+
+	for _, instance := range []string{"a", "b", "c"} {
+		_, res := tr.Admit(labels.FromStrings("__name__", "unlimited", "instance", instance), now)
+		require.Equal(t, admitted, res)
+	}
+
+	// Inactive series make room for new ones.
+	tr.Sweep(now.Add(time.Second))
+	require.Equal(t, 0, tr.ActiveSeries())
+	_, res = tr.Admit(labels.FromStrings("__name__", "up", "instance", "c"), now)
+	require.Equal(t, admitted, res)
+}
+
+func TestTracker_LabelLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		replace    bool
+		expectRes  admitResult
+		expectLbls labels.Labels
+	}{
+		{
+			name:       "drop",
+			expectRes:  dropped,
+			expectLbls: labels.FromStrings("__name__", "requests_total", "user_id", "3"),
+		},
+		{
+			name:       "replace",
+			replace:    true,
+			expectRes:  replaced,
+			expectLbls: labels.FromStrings("__name__", "requests_total", "user_id", "__overflow__"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := newTracker()
+			tr.SetLimits(limits{
+				labels:        map[string]int{"user_id": 2},
+				replace:       tc.replace,
+				overflowValue: "__overflow__",
+			}, 100)
+			now := time.Now()
+
+			for _, id := range []string{"1", "2"} {
+				_, res := tr.Admit(labels.FromStrings("__name__", "requests_total", "user_id", id), now)
+				require.Equal(t, admitted, res)
+			}
+
+			l, res := tr.Admit(labels.FromStrings("__name__", "requests_total", "user_id", "3"), now)
+			require.Equal(t, tc.expectRes, res)
+			require.Equal(t, tc.expectLbls, l)
+
+			// Other metrics can't add values to the label either.
+			_, res = tr.Admit(labels.FromStrings("__name__", "errors_total", "user_id", "4"), now)
+			require.Equal(t, tc.expectRes, res)
+		})
+	}
+}
+
+func TestTracker_MaxTrackedSeries(t *testing.T) {
+	tests := []struct {
+		name       string
+		replace    bool
+		expectRes  admitResult
+		expectLbls labels.Labels
+	}{
+		{
+			name:       "drop",
+			expectRes:  dropped,
+			expectLbls: labels.FromStrings("__name__", "requests_total", "user_id", "2"),
+		},
+		{
+			name:       "replace",
+			replace:    true,
+			expectRes:  replaced,
+			expectLbls: labels.FromStrings("__name__", "requests_total", "user_id", "__overflow__"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := newTracker()
+			tr.SetLimits(limits{
+				metrics:       map[string]int{"up": 1},
+				labels:        map[string]int{"user_id": 1},
+				replace:       tc.replace,
+				overflowValue: "__overflow__",
+			}, 3)
+			now := time.Now()
+
+			for _, l := range []labels.Labels{
+				labels.FromStrings("__name__", "up", "instance", "a"),
+				labels.FromStrings("__name__", "requests_total", "user_id", "1"),
+				labels.FromStrings("__name__", "requests_total", "user_id", "__overflow__"),
+			} {
+				_, res := tr.Admit(l, now)
+				require.Equal(t, admitted, res)
+			}
+			require.Equal(t, 3, tr.ActiveSeries())
+
+			// The limits are still enforced once the tracker is full.
+			_, res := tr.Admit(labels.FromStrings("__name__", "up", "instance", "b"), now)
+			require.Equal(t, dropped, res)
+			l, res := tr.Admit(labels.FromStrings("__name__", "requests_total", "user_id", "2"), now)
+			require.Equal(t, tc.expectRes, res)
+			require.Equal(t, tc.expectLbls, l)
+
+			// New series within the limits can't be tracked, and are dropped.
+			l = labels.FromStrings("__name__", "build_info", "instance", "a")
+			_, res = tr.Admit(l, now)
+			require.Equal(t, untracked, res)
+			require.Equal(t, 3, tr.ActiveSeries())
+
+			// Tracked series are still admitted.
+			_, res = tr.Admit(labels.FromStrings("__name__", "up", "instance", "a"), now)
+			require.Equal(t, admitted, res)
+		})
+	}
+}
+
+func TestTracker_Report(t *testing.T) {
+	tr := newTracker()
+	tr.SetLimits(limits{}, 100)
+	now := time.Now()
+
+	for _, l := range []labels.Labels{
+		labels.FromStrings("__name__", "requests_total", "instance", "a", "user_id", "1"),
+		labels.FromStrings("__name__", "requests_total", "instance", "a", "user_id", "2"),
+		labels.FromStrings("__name__", "requests_total", "instance", "a", "user_id", "3"),
+		labels.FromStrings("__name__", "up", "instance", "a"),
+		labels.FromStrings("__name__", "up", "instance", "b"),
+		labels.FromStrings("__name__", "build_info", "instance", "a"),
+	} {
+		_, res := tr.Admit(l, now)
+		require.Equal(t, admitted, res)
+	}
+
+	require.Equal(t, Report{
+		ActiveSeries: 6,
+		TopMetrics: []MetricCardinality{
+			{Name: "requests_total", Series: 3},
+			{Name: "up", Series: 2},
+		},
+		TopLabels: []LabelCardinality{
+			{Name: "user_id", Values: 3},
+			{Name: "instance", Values: 2},
+		},
+	}, tr.Report(2))
+}
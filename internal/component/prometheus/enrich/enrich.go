@@ -0,0 +1,356 @@
+// Package enrich provides the prometheus.enrich component.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	lru "github.com/hashicorp/golang-lru/v2"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.enrich",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the prometheus.enrich
+// component.
+type Arguments struct {
+	// The targets to use for enrichment.
+	Targets []discovery.Target `alloy:"targets,attr"`
+
+	// Which label from targets to use for matching, for example "instance".
+	TargetMatchLabel string `alloy:"target_match_label,attr"`
+
+	// Which label from metrics to match against. If not specified,
+	// TargetMatchLabel is used.
+	MetricsMatchLabel string `alloy:"metrics_match_label,attr,optional"`
+
+	// List of labels to copy from targets to metrics. If empty, all labels
+	// which don't start with a double underscore are copied.
+	LabelsToCopy []string `alloy:"labels_to_copy,attr,optional"`
+
+	// Where to forward the metrics after enrichment.
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// Cache size to use for LRU cache.
+	CacheSize int `alloy:"max_cache_size,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		CacheSize: 100_000,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.TargetMatchLabel == "" {
+		return fmt.Errorf("target_match_label must not be empty")
+	}
+	if args.CacheSize <= 0 {
+		return fmt.Errorf("max_cache_size must be greater than 0 and is %d", args.CacheSize)
+	}
+	for _, l := range args.LabelsToCopy {
+		if l == labels.MetricName {
+			return fmt.Errorf("labels_to_copy can't contain %s", labels.MetricName)
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.enrich component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+// Component implements the prometheus.enrich component.
+type Component struct {
+	opts component.Options
+	ls   labelstore.LabelStore
+
+	mut          sync.RWMutex
+	args         Arguments
+	targetsCache map[string]labels.Labels
+
+	receiver *prometheus.Interceptor
+	fanout   *prometheus.Fanout
+	exited   atomic.Bool
+
+	cacheMut sync.RWMutex
+	cache    *lru.Cache[uint64, labels.Labels]
+
+	cacheHits       prometheus_client.Counter
+	cacheMisses     prometheus_client.Counter
+	targetMatches   prometheus_client.Counter
+	targetMisses    prometheus_client.Counter
+	enrichedSamples prometheus_client.Counter
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.enrich component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts: o,
+		ls:   data.(labelstore.LabelStore),
+	}
+
+	c.cacheHits = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_enrich_cache_hits_total",
+		Help: "Total number of series whose enriched labels were found in the cache.",
+	})
+	c.cacheMisses = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_enrich_cache_misses_total",
+		Help: "Total number of series whose enriched labels weren't found in the cache.",
+	})
+	c.targetMatches = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_enrich_target_matches_total",
+		Help: "Total number of series which matched a target.",
+	})
+	c.targetMisses = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_enrich_target_misses_total",
+		Help: "Total number of series which didn't match any target.",
+	})
+	c.enrichedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_enrich_samples_enriched_total",
+		Help: "Total number of samples forwarded with labels copied from a target.",
+	})
+	for _, metric := range []prometheus_client.Collector{c.cacheHits, c.cacheMisses, c.targetMatches, c.targetMisses, c.enrichedSamples} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, c.ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		c.ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, enriched := c.enrich(l, value.IsStaleNaN(v))
+			if !enriched {
+				return next.Append(ref, l, t, v)
+			}
+			c.enrichedSamples.Inc()
+			return next.Append(0, newLbl, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			stale := (h != nil && value.IsStaleNaN(h.Sum)) || (fh != nil && value.IsStaleNaN(fh.Sum))
+			newLbl, enriched := c.enrich(l, stale)
+			if !enriched {
+				return next.AppendHistogram(ref, l, t, h, fh)
+			}
+			c.enrichedSamples.Inc()
+			return next.AppendHistogram(0, newLbl, t, h, fh)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, enriched := c.enrich(l, false)
+			if !enriched {
+				return next.AppendExemplar(ref, l, e)
+			}
+			return next.AppendExemplar(0, newLbl, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, enriched := c.enrich(l, false)
+			if !enriched {
+				return next.UpdateMetadata(ref, l, m)
+			}
+			return next.UpdateMetadata(0, newLbl, m)
+		}),
+		prometheus.WithCTZeroSampleHook(func(ref storage.SeriesRef, l labels.Labels, t, ct int64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			newLbl, enriched := c.enrich(l, false)
+			if !enriched {
+				return next.AppendCTZeroSample(ref, l, t, ct)
+			}
+			return next.AppendCTZeroSample(0, newLbl, t, ct)
+		}),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.exited.Store(true)
+
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.targetsCache = buildTargetsCache(newArgs.Targets, newArgs.TargetMatchLabel, newArgs.LabelsToCopy)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.args = newArgs
+
+	// The enriched labels depend on the targets, so they can't be reused.
+	c.clearCache(newArgs.CacheSize)
+	return nil
+}
+
+// buildTargetsCache indexes the labels to copy from each target by the value
+// of its match label.
+func buildTargetsCache(targets []discovery.Target, matchLabel string, labelsToCopy []string) map[string]labels.Labels {
+	cache := make(map[string]labels.Labels, len(targets))
+	for _, target := range targets {
+		matchValue, ok := target.Get(matchLabel)
+		if !ok || matchValue == "" {
+			continue
+		}
+
+		lb := labels.NewScratchBuilder(0)
+		if len(labelsToCopy) == 0 {
+			// Labels starting with a double underscore are internal, and
+			// copying them could overwrite the metric name.
+			target.ForEachLabel(func(k, v string) bool {
+				if !strings.HasPrefix(k, "__") {
+					lb.Add(k, v)
+				}
+				return true
+			})
+		} else {
+			for _, name := range labelsToCopy {
+				if v, ok := target.Get(name); ok && v != "" {
+					lb.Add(name, v)
+				}
+			}
+		}
+		lb.Sort()
+		cache[matchValue] = lb.Labels()
+	}
+	return cache
+}
+
+// enrich returns the labels of the series with the labels of its target, and
+// whether they differ from the original labels.
+func (c *Component) enrich(l labels.Labels, stale bool) (labels.Labels, bool) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	globalRef := c.ls.GetOrAddGlobalRefID(l)
+	enriched, found := c.getFromCache(globalRef)
+	if found {
+		c.cacheHits.Inc()
+	} else {
+		c.cacheMisses.Inc()
+		enriched = c.lookup(l)
+		c.addToCache(globalRef, enriched)
+	}
+
+	// Stale markers end the series, so its labels won't be needed anymore.
+	if stale {
+		c.deleteFromCache(globalRef)
+	}
+
+	if enriched.IsEmpty() || labels.Equal(enriched, l) {
+		return l, false
+	}
+	return enriched, true
+}
+
+// lookup returns the labels of the series with the labels of its target, or
+// empty labels if it doesn't match any target.
+func (c *Component) lookup(l labels.Labels) labels.Labels {
+	matchLabel := c.args.MetricsMatchLabel
+	if matchLabel == "" {
+		matchLabel = c.args.TargetMatchLabel
+	}
+
+	matchValue := l.Get(matchLabel)
+	targetLabels, ok := c.targetsCache[matchValue]
+	if matchValue == "" || !ok {
+		c.targetMisses.Inc()
+		return labels.EmptyLabels()
+	}
+	c.targetMatches.Inc()
+
+	lb := labels.NewBuilder(l)
+	targetLabels.Range(func(lbl labels.Label) {
+		lb.Set(lbl.Name, lbl.Value)
+	})
+	return lb.Labels()
+}
+
+func (c *Component) getFromCache(id uint64) (labels.Labels, bool) {
+	c.cacheMut.RLock()
+	defer c.cacheMut.RUnlock()
+
+	return c.cache.Get(id)
+}
+
+func (c *Component) addToCache(id uint64, l labels.Labels) {
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+
+	c.cache.Add(id, l)
+}
+
+func (c *Component) deleteFromCache(id uint64) {
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+
+	c.cache.Remove(id)
+}
+
+func (c *Component) clearCache(cacheSize int) {
+	c.cacheMut.Lock()
+	defer c.cacheMut.Unlock()
+
+	cache, _ := lru.New[uint64, labels.Labels](cacheSize)
+	c.cache = cache
+}
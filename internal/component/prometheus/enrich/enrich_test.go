@@ -0,0 +1,134 @@
+package enrich
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    string
+		errMsg string
+	}{
+		{
+			name: "valid",
+			cfg: `
+				targets             = []
+				target_match_label  = "ip"
+				metrics_match_label = "instance"
+				labels_to_copy      = ["rack"]
+				forward_to          = []
+			`,
+		},
+		{
+			name: "copy metric name",
+			cfg: `
+				targets            = []
+				target_match_label = "ip"
+				labels_to_copy     = ["__name__"]
+				forward_to         = []
+			`,
+			errMsg: "labels_to_copy can't contain __name__",
+		},
+		{
+			name: "invalid cache size",
+			cfg: `
+				targets            = []
+				target_match_label = "ip"
+				max_cache_size     = 0
+				forward_to         = []
+			`,
+			errMsg: "max_cache_size must be greater than 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.cfg), &args)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	targets := []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{
+			"__address__": "10.0.0.1:9100",
+			"ip":          "10.0.0.1",
+			"rack":        "r1",
+			"datacenter":  "dc1",
+		}),
+	}
+
+	tests := []struct {
+		name         string
+		labelsToCopy []string
+		input        labels.Labels
+		expected     labels.Labels
+	}{
+		{
+			name:     "copy all labels",
+			input:    labels.FromStrings("__name__", "up", "instance", "10.0.0.1"),
+			expected: labels.FromStrings("__name__", "up", "datacenter", "dc1", "instance", "10.0.0.1", "ip", "10.0.0.1", "rack", "r1"),
+		},
+		{
+			name:         "copy selected labels",
+			labelsToCopy: []string{"rack"},
+			input:        labels.FromStrings("__name__", "up", "instance", "10.0.0.1"),
+			expected:     labels.FromStrings("__name__", "up", "instance", "10.0.0.1", "rack", "r1"),
+		},
+		{
+			name:     "no match",
+			input:    labels.FromStrings("__name__", "up", "instance", "10.0.0.2"),
+			expected: labels.FromStrings("__name__", "up", "instance", "10.0.0.2"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var received []labels.Labels
+
+			var args Arguments
+			args.SetToDefault()
+			args.Targets = targets
+			args.TargetMatchLabel = "ip"
+			args.MetricsMatchLabel = "instance"
+			args.LabelsToCopy = tc.labelsToCopy
+			args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{
+				Sample: func(s testappender.MetricSample) { received = append(received, s.Labels) },
+			}}
+
+			ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.enrich")
+			require.NoError(t, err)
+			go func() {
+				require.NoError(t, ctrl.Run(componenttest.TestContext(t), args))
+			}()
+			require.NoError(t, ctrl.WaitRunning(5*time.Second))
+
+			// The second sample is enriched from the cache.
+			app := ctrl.Exports().(Exports).Receiver.Appender(t.Context())
+			for ts := range int64(2) {
+				_, err := app.Append(0, tc.input, ts, 1)
+				require.NoError(t, err)
+			}
+			require.NoError(t, app.Commit())
+
+			require.Equal(t, []labels.Labels{tc.expected, tc.expected}, received)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package haproxy
+
+import (
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/grafana/alloy/internal/static/integrations/haproxy_exporter"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.haproxy",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "haproxy"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	return integrations.NewIntegrationWithInstanceKey(opts.Logger, a.Convert(), defaultInstanceKey)
+}
+
+// DefaultArguments holds the default settings for the haproxy exporter.
+var DefaultArguments = Arguments{
+	ScrapeURI: haproxy_exporter.DefaultConfig.ScrapeURI,
+	Timeout:   haproxy_exporter.DefaultConfig.Timeout,
+}
+
+// Arguments controls the haproxy exporter.
+type Arguments struct {
+	ScrapeURI string        `alloy:"scrape_uri,attr,optional"`
+	Insecure  bool          `alloy:"insecure,attr,optional"`
+	Timeout   time.Duration `alloy:"timeout,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+func (a *Arguments) Convert() *haproxy_exporter.Config {
+	return &haproxy_exporter.Config{
+		ScrapeURI: a.ScrapeURI,
+		Insecure:  a.Insecure,
+		Timeout:   a.Timeout,
+	}
+}
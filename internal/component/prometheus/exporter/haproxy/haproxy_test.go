@@ -0,0 +1,51 @@
+package haproxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/static/integrations/haproxy_exporter"
+	"github.com/grafana/alloy/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlloyUnmarshal(t *testing.T) {
+	alloyConfig := `
+	scrape_uri = "unix:/run/haproxy/admin.sock"
+	insecure   = true
+	timeout    = "1s"
+	`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(alloyConfig), &args)
+	require.NoError(t, err)
+
+	expected := Arguments{
+		ScrapeURI: "unix:/run/haproxy/admin.sock",
+		Insecure:  true,
+		Timeout:   time.Second,
+	}
+	require.Equal(t, expected, args)
+}
+
+func TestAlloyUnmarshal_Defaults(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(``), &args)
+	require.NoError(t, err)
+	require.Equal(t, DefaultArguments, args)
+}
+
+func TestConvert(t *testing.T) {
+	args := Arguments{
+		ScrapeURI: "unix:/run/haproxy/admin.sock",
+		Insecure:  true,
+		Timeout:   time.Second,
+	}
+
+	expected := &haproxy_exporter.Config{
+		ScrapeURI: "unix:/run/haproxy/admin.sock",
+		Insecure:  true,
+		Timeout:   time.Second,
+	}
+	require.Equal(t, expected, args.Convert())
+}
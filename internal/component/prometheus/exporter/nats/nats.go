@@ -0,0 +1,55 @@
+package nats
+
+import (
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/grafana/alloy/internal/static/integrations/nats_exporter"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.nats",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "nats"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	return integrations.NewIntegrationWithInstanceKey(opts.Logger, a.Convert(), defaultInstanceKey)
+}
+
+// DefaultArguments holds the default settings for the nats exporter.
+var DefaultArguments = Arguments{
+	URL:     nats_exporter.DefaultConfig.URL,
+	Timeout: nats_exporter.DefaultConfig.Timeout,
+}
+
+// Arguments controls the nats exporter.
+type Arguments struct {
+	URL       string        `alloy:"url,attr,optional"`
+	Insecure  bool          `alloy:"insecure,attr,optional"`
+	Timeout   time.Duration `alloy:"timeout,attr,optional"`
+	JetStream bool          `alloy:"jetstream,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+func (a *Arguments) Convert() *nats_exporter.Config {
+	return &nats_exporter.Config{
+		URL:       a.URL,
+		Insecure:  a.Insecure,
+		Timeout:   a.Timeout,
+		JetStream: a.JetStream,
+	}
+}
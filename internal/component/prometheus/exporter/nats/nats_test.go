@@ -0,0 +1,53 @@
+package nats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/static/integrations/nats_exporter"
+	"github.com/grafana/alloy/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlloyUnmarshal(t *testing.T) {
+	alloyConfig := `
+	url       = "https://nats.example.com:8222"
+	insecure  = true
+	timeout   = "2s"
+	jetstream = true
+	`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(alloyConfig), &args)
+	require.NoError(t, err)
+
+	expected := Arguments{
+		URL:       "https://nats.example.com:8222",
+		Insecure:  true,
+		Timeout:   2 * time.Second,
+		JetStream: true,
+	}
+	require.Equal(t, expected, args)
+}
+
+func TestAlloyUnmarshal_Defaults(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(``), &args)
+	require.NoError(t, err)
+	require.Equal(t, DefaultArguments, args)
+}
+
+func TestConvert(t *testing.T) {
+	args := Arguments{
+		URL:       "https://nats.example.com:8222",
+		Timeout:   2 * time.Second,
+		JetStream: true,
+	}
+
+	expected := &nats_exporter.Config{
+		URL:       "https://nats.example.com:8222",
+		Timeout:   2 * time.Second,
+		JetStream: true,
+	}
+	require.Equal(t, expected, args.Convert())
+}
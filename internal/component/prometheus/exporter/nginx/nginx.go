@@ -0,0 +1,53 @@
+package nginx
+
+import (
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/grafana/alloy/internal/static/integrations/nginx_exporter"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.nginx",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "nginx"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	return integrations.NewIntegrationWithInstanceKey(opts.Logger, a.Convert(), defaultInstanceKey)
+}
+
+// DefaultArguments holds the default settings for the nginx exporter.
+var DefaultArguments = Arguments{
+	ScrapeURI: nginx_exporter.DefaultConfig.ScrapeURI,
+	Timeout:   nginx_exporter.DefaultConfig.Timeout,
+}
+
+// Arguments controls the nginx exporter.
+type Arguments struct {
+	ScrapeURI string        `alloy:"scrape_uri,attr,optional"`
+	Insecure  bool          `alloy:"insecure,attr,optional"`
+	Timeout   time.Duration `alloy:"timeout,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+func (a *Arguments) Convert() *nginx_exporter.Config {
+	return &nginx_exporter.Config{
+		ScrapeURI: a.ScrapeURI,
+		Insecure:  a.Insecure,
+		Timeout:   a.Timeout,
+	}
+}
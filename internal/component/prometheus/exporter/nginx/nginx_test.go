@@ -0,0 +1,51 @@
+package nginx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/static/integrations/nginx_exporter"
+	"github.com/grafana/alloy/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlloyUnmarshal(t *testing.T) {
+	alloyConfig := `
+	scrape_uri = "https://nginx.example.com/basic_status"
+	insecure   = true
+	timeout    = "1s"
+	`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(alloyConfig), &args)
+	require.NoError(t, err)
+
+	expected := Arguments{
+		ScrapeURI: "https://nginx.example.com/basic_status",
+		Insecure:  true,
+		Timeout:   time.Second,
+	}
+	require.Equal(t, expected, args)
+}
+
+func TestAlloyUnmarshal_Defaults(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(``), &args)
+	require.NoError(t, err)
+	require.Equal(t, DefaultArguments, args)
+}
+
+func TestConvert(t *testing.T) {
+	args := Arguments{
+		ScrapeURI: "https://nginx.example.com/basic_status",
+		Insecure:  true,
+		Timeout:   time.Second,
+	}
+
+	expected := &nginx_exporter.Config{
+		ScrapeURI: "https://nginx.example.com/basic_status",
+		Insecure:  true,
+		Timeout:   time.Second,
+	}
+	require.Equal(t, expected, args.Convert())
+}
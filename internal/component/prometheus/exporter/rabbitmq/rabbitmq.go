@@ -0,0 +1,80 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/grafana/alloy/internal/static/integrations/rabbitmq_exporter"
+	"github.com/grafana/alloy/syntax/alloytypes"
+	config_util "github.com/prometheus/common/config"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.rabbitmq",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "rabbitmq"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	return integrations.NewIntegrationWithInstanceKey(opts.Logger, a.Convert(), defaultInstanceKey)
+}
+
+// DefaultArguments holds the default settings for the rabbitmq exporter.
+var DefaultArguments = Arguments{
+	URL:           rabbitmq_exporter.DefaultConfig.URL,
+	Username:      rabbitmq_exporter.DefaultConfig.Username,
+	Password:      alloytypes.Secret(rabbitmq_exporter.DefaultConfig.Password),
+	Timeout:       rabbitmq_exporter.DefaultConfig.Timeout,
+	IncludeQueues: rabbitmq_exporter.DefaultConfig.IncludeQueues,
+	ExcludeQueues: rabbitmq_exporter.DefaultConfig.ExcludeQueues,
+}
+
+// Arguments controls the rabbitmq exporter.
+type Arguments struct {
+	URL           string            `alloy:"url,attr,optional"`
+	Username      string            `alloy:"username,attr,optional"`
+	Password      alloytypes.Secret `alloy:"password,attr,optional"`
+	Insecure      bool              `alloy:"insecure,attr,optional"`
+	Timeout       time.Duration     `alloy:"timeout,attr,optional"`
+	IncludeQueues string            `alloy:"include_queues,attr,optional"`
+	ExcludeQueues string            `alloy:"exclude_queues,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if _, err := regexp.Compile(a.IncludeQueues); err != nil {
+		return fmt.Errorf("include_queues is invalid: %w", err)
+	}
+	if _, err := regexp.Compile(a.ExcludeQueues); err != nil {
+		return fmt.Errorf("exclude_queues is invalid: %w", err)
+	}
+	return nil
+}
+
+func (a *Arguments) Convert() *rabbitmq_exporter.Config {
+	return &rabbitmq_exporter.Config{
+		URL:           a.URL,
+		Username:      a.Username,
+		Password:      config_util.Secret(a.Password),
+		Insecure:      a.Insecure,
+		Timeout:       a.Timeout,
+		IncludeQueues: a.IncludeQueues,
+		ExcludeQueues: a.ExcludeQueues,
+	}
+}
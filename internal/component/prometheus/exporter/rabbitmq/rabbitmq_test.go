@@ -0,0 +1,72 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/static/integrations/rabbitmq_exporter"
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlloyUnmarshal(t *testing.T) {
+	alloyConfig := `
+	url            = "https://rabbitmq.example.com:15671"
+	username       = "monitoring"
+	password       = "secret"
+	insecure       = true
+	timeout        = "3s"
+	include_queues = "^orders"
+	exclude_queues = "^amq\\."
+	`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(alloyConfig), &args)
+	require.NoError(t, err)
+
+	expected := Arguments{
+		URL:           "https://rabbitmq.example.com:15671",
+		Username:      "monitoring",
+		Password:      alloytypes.Secret("secret"),
+		Insecure:      true,
+		Timeout:       3 * time.Second,
+		IncludeQueues: "^orders",
+		ExcludeQueues: "^amq\\.",
+	}
+	require.Equal(t, expected, args)
+}
+
+func TestAlloyUnmarshal_Defaults(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(``), &args)
+	require.NoError(t, err)
+	require.Equal(t, DefaultArguments, args)
+}
+
+func TestAlloyUnmarshal_InvalidRegex(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`include_queues = "("`), &args)
+	require.ErrorContains(t, err, "include_queues is invalid")
+}
+
+func TestConvert(t *testing.T) {
+	args := Arguments{
+		URL:           "https://rabbitmq.example.com:15671",
+		Username:      "monitoring",
+		Password:      alloytypes.Secret("secret"),
+		Timeout:       3 * time.Second,
+		IncludeQueues: ".*",
+		ExcludeQueues: "^$",
+	}
+
+	expected := &rabbitmq_exporter.Config{
+		URL:           "https://rabbitmq.example.com:15671",
+		Username:      "monitoring",
+		Password:      "secret",
+		Timeout:       3 * time.Second,
+		IncludeQueues: ".*",
+		ExcludeQueues: "^$",
+	}
+	require.Equal(t, expected, args.Convert())
+}
@@ -26,14 +26,18 @@ import (
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/elasticsearch"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/gcp"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/github"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter/haproxy"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/kafka"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/memcached"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/mongodb"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/mssql"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/mysql"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter/nats"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter/nginx"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/oracledb"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/postgres"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/process"
+	"github.com/grafana/alloy/internal/component/prometheus/exporter/rabbitmq"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/redis"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/self"
 	"github.com/grafana/alloy/internal/component/prometheus/exporter/snmp"
@@ -201,6 +205,22 @@ func TestInstanceKey(t *testing.T) {
 			// TODO: it may not be enough - we may need the repositories and orgs? or use hash?
 			expectedInstanceLabel: "api.github.com:8080",
 		},
+		{
+			testName:      "haproxy",
+			componentName: "prometheus.exporter.haproxy",
+			args: haproxy.Arguments{
+				ScrapeURI: "http://host01:8404/stats;csv",
+			},
+			expectedInstanceLabel: "host01:8404",
+		},
+		{
+			testName:      "haproxy socket",
+			componentName: "prometheus.exporter.haproxy",
+			args: haproxy.Arguments{
+				ScrapeURI: "unix:/run/haproxy/admin.sock",
+			},
+			expectedInstanceLabel: "/run/haproxy/admin.sock",
+		},
 		// TODO: kafka exporters won't build successfully if it cannot connect right away to kafka. This is not
 		//       desired, we should keep retrying connection.
 		// {
@@ -272,6 +292,22 @@ func TestInstanceKey(t *testing.T) {
 			},
 			expectedInstanceLabel: "tcp(host01:3306)/dbname",
 		},
+		{
+			testName:      "nats",
+			componentName: "prometheus.exporter.nats",
+			args: nats.Arguments{
+				URL: "http://host01:8222",
+			},
+			expectedInstanceLabel: "host01:8222",
+		},
+		{
+			testName:      "nginx",
+			componentName: "prometheus.exporter.nginx",
+			args: nginx.Arguments{
+				ScrapeURI: "http://host01:8080/stub_status",
+			},
+			expectedInstanceLabel: "host01:8080",
+		},
 		{
 			testName:      "oracledb",
 			componentName: "prometheus.exporter.oracledb",
@@ -310,6 +346,16 @@ func TestInstanceKey(t *testing.T) {
 			temporaryHostname:     "test-agent",
 			expectedInstanceLabel: "test-agent",
 		},
+		{
+			testName:      "rabbitmq",
+			componentName: "prometheus.exporter.rabbitmq",
+			args: rabbitmq.Arguments{
+				URL:           "http://host01:15672",
+				IncludeQueues: ".*",
+				ExcludeQueues: "^$",
+			},
+			expectedInstanceLabel: "host01:15672",
+		},
 		{
 			testName:      "redis",
 			componentName: "prometheus.exporter.redis",
@@ -60,6 +60,19 @@ func NewFanout(children []storage.Appendable, componentID string, register prome
 	}
 }
 
+// WithChildren creates a fanout appendable to children which reports to the
+// same metrics as f, since the metrics of a component can only be registered
+// once.
+func (f *Fanout) WithChildren(children []storage.Appendable) *Fanout {
+	return &Fanout{
+		children:       children,
+		componentID:    f.componentID,
+		writeLatency:   f.writeLatency,
+		samplesCounter: f.samplesCounter,
+		ls:             f.ls,
+	}
+}
+
 // UpdateChildren allows changing of the children of the fanout.
 func (f *Fanout) UpdateChildren(children []storage.Appendable) {
 	f.mut.Lock()
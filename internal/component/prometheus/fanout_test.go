@@ -4,7 +4,10 @@ import (
 	"testing"
 
 	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/util/testappender"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/prometheus/prometheus/storage"
 
@@ -26,3 +29,18 @@ func TestCommit(t *testing.T) {
 	err := app.Commit()
 	require.NoError(t, err)
 }
+
+func TestWithChildren(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ls := labelstore.New(nil, reg)
+	child := testappender.FuncAppendable{Sample: func(testappender.MetricSample) {}}
+	fanout := NewFanout(nil, "1", reg, ls)
+	sibling := fanout.WithChildren([]storage.Appendable{child})
+
+	app := sibling.Appender(t.Context())
+	_, err := app.Append(0, labels.FromStrings("__name__", "metric"), 1000, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 1.0, testutil.ToFloat64(fanout.samplesCounter))
+	require.Equal(t, 1, testutil.CollectAndCount(reg, "prometheus_forwarded_samples_total"))
+}
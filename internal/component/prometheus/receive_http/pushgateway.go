@@ -0,0 +1,433 @@
+package receive_http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+// PushgatewayArguments configures the Pushgateway-compatible endpoints.
+type PushgatewayArguments struct {
+	ForwardInterval time.Duration `alloy:"forward_interval,attr,optional"`
+	Persist         bool          `alloy:"persist,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *PushgatewayArguments) SetToDefault() {
+	*args = PushgatewayArguments{
+		ForwardInterval: 15 * time.Second,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *PushgatewayArguments) Validate() error {
+	if args.ForwardInterval <= 0 {
+		return fmt.Errorf("forward_interval must be greater than 0")
+	}
+	return nil
+}
+
+// pushTimeMetric is the name of the metric holding the time of the last push
+// of each group.
+const pushTimeMetric = "push_time_seconds"
+
+// pushgateway stores the metric groups pushed to the Pushgateway-compatible
+// endpoints, and forwards them at a regular interval, like a Pushgateway
+// would expose them to be scraped.
+type pushgateway struct {
+	logger     log.Logger
+	appendable storage.Appendable
+
+	mut    sync.Mutex
+	groups map[string]*pushGroup
+	dirty  bool
+}
+
+// pushGroup is a group of metrics pushed with the same grouping key.
+type pushGroup struct {
+	// The grouping key, including the job.
+	Labels   map[string]string
+	Families map[string]*dto.MetricFamily
+	PushTime time.Time
+}
+
+func newPushgateway(logger log.Logger, appendable storage.Appendable) *pushgateway {
+	return &pushgateway{
+		logger:     logger,
+		appendable: appendable,
+		groups:     make(map[string]*pushGroup),
+	}
+}
+
+// ServeHTTP implements http.Handler for the /metrics/job/... endpoints.
+func (p *pushgateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	groupingKey, err := parseGroupingKey(strings.TrimPrefix(r.URL.Path, "/metrics/"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := groupKey(groupingKey)
+
+	if r.Method == http.MethodDelete {
+		p.mut.Lock()
+		stale := p.groupSamples(p.groups[key])
+		delete(p.groups, key)
+		p.dirty = true
+		p.mut.Unlock()
+
+		p.appendStale(r.Context(), stale)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	families, err := decodeFamilies(r, groupingKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mut.Lock()
+	old := p.groups[key]
+	oldSamples := p.groupSamples(old)
+	group := &pushGroup{Labels: groupingKey, Families: families, PushTime: time.Now()}
+	// POST only replaces the metrics with the same name as the pushed ones.
+	if r.Method == http.MethodPost && old != nil {
+		for name, mf := range old.Families {
+			if _, ok := families[name]; !ok {
+				families[name] = mf
+			}
+		}
+	}
+	p.groups[key] = group
+	p.dirty = true
+	newSamples := p.groupSamples(group)
+	p.mut.Unlock()
+
+	// The series which disappeared from the group are marked as stale.
+	p.appendStale(r.Context(), staleSamples(oldSamples, newSamples))
+
+	if err := p.appendSamples(r.Context(), newSamples, time.Now()); err != nil {
+		level.Warn(p.logger).Log("msg", "failed to forward pushed metrics", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Forward forwards the metrics of all the groups.
+func (p *pushgateway) Forward(ctx context.Context) error {
+	p.mut.Lock()
+	var samples []pushedSample
+	for _, g := range p.groups {
+		samples = append(samples, p.groupSamples(g)...)
+	}
+	p.mut.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+	return p.appendSamples(ctx, samples, time.Now())
+}
+
+func (p *pushgateway) appendSamples(ctx context.Context, samples []pushedSample, now time.Time) error {
+	app := p.appendable.Appender(ctx)
+	t := now.UnixMilli()
+	for _, s := range samples {
+		if _, err := app.Append(0, s.labels, t, s.v); err != nil {
+			_ = app.Rollback()
+			return err
+		}
+	}
+	return app.Commit()
+}
+
+func (p *pushgateway) appendStale(ctx context.Context, samples []pushedSample) {
+	if len(samples) == 0 {
+		return
+	}
+	for i := range samples {
+		samples[i].v = math.Float64frombits(value.StaleNaN)
+	}
+	if err := p.appendSamples(ctx, samples, time.Now()); err != nil {
+		level.Warn(p.logger).Log("msg", "failed to forward stale markers of pushed metrics", "err", err)
+	}
+}
+
+// pushedSample is a sample of a pushed metric.
+type pushedSample struct {
+	labels labels.Labels
+	v      float64
+}
+
+// groupSamples returns the samples of the metrics of a group, and its push
+// time. It returns nil if g is nil.
+func (p *pushgateway) groupSamples(g *pushGroup) []pushedSample {
+	if g == nil {
+		return nil
+	}
+
+	var samples []pushedSample
+	for _, mf := range g.Families {
+		familySamples(mf, func(l labels.Labels, v float64) {
+			samples = append(samples, pushedSample{labels: l, v: v})
+		})
+	}
+
+	lb := labels.NewBuilder(labels.FromMap(g.Labels))
+	lb.Set(labels.MetricName, pushTimeMetric)
+	samples = append(samples, pushedSample{
+		labels: lb.Labels(),
+		v:      float64(g.PushTime.UnixNano()) / 1e9,
+	})
+	return samples
+}
+
+// staleSamples returns the samples of previous whose series aren't in
+// current.
+func staleSamples(previous, current []pushedSample) []pushedSample {
+	if len(previous) == 0 {
+		return nil
+	}
+	series := make(map[uint64]struct{}, len(current))
+	for _, s := range current {
+		series[s.labels.Hash()] = struct{}{}
+	}
+
+	var res []pushedSample
+	for _, s := range previous {
+		if _, ok := series[s.labels.Hash()]; !ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// familySamples calls fn for every sample of the metric family, expanding
+// summaries and histograms into their series.
+func familySamples(mf *dto.MetricFamily, fn func(l labels.Labels, v float64)) {
+	name := mf.GetName()
+	for _, m := range mf.GetMetric() {
+		base := labels.NewBuilder(labels.EmptyLabels())
+		for _, lp := range m.GetLabel() {
+			base.Set(lp.GetName(), lp.GetValue())
+		}
+		series := func(name string, v float64, extra ...string) {
+			lb := labels.NewBuilder(base.Labels())
+			lb.Set(labels.MetricName, name)
+			for i := 0; i+1 < len(extra); i += 2 {
+				lb.Set(extra[i], extra[i+1])
+			}
+			fn(lb.Labels(), v)
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			series(name, m.GetCounter().GetValue())
+		case dto.MetricType_GAUGE:
+			series(name, m.GetGauge().GetValue())
+		case dto.MetricType_UNTYPED:
+			series(name, m.GetUntyped().GetValue())
+		case dto.MetricType_SUMMARY:
+			s := m.GetSummary()
+			for _, q := range s.GetQuantile() {
+				series(name, q.GetValue(), model.QuantileLabel, formatFloat(q.GetQuantile()))
+			}
+			series(name+"_sum", s.GetSampleSum())
+			series(name+"_count", float64(s.GetSampleCount()))
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			h := m.GetHistogram()
+			var hasInf bool
+			for _, b := range h.GetBucket() {
+				if math.IsInf(b.GetUpperBound(), 1) {
+					hasInf = true
+				}
+				series(name+"_bucket", float64(b.GetCumulativeCount()), model.BucketLabel, formatFloat(b.GetUpperBound()))
+			}
+			if !hasInf {
+				series(name+"_bucket", float64(h.GetSampleCount()), model.BucketLabel, "+Inf")
+			}
+			series(name+"_sum", h.GetSampleSum())
+			series(name+"_count", float64(h.GetSampleCount()))
+		}
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// parseGroupingKey parses the grouping key from a path in the form
+// job/<JOB>/<LABEL_NAME>/<LABEL_VALUE>/..., where names ending with @base64
+// have base64url-encoded values.
+func parseGroupingKey(path string) (map[string]string, error) {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) < 2 || len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid grouping key %q", path)
+	}
+
+	groupingKey := make(map[string]string, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		name, val := parts[i], parts[i+1]
+		if encoded, ok := strings.CutSuffix(name, "@base64"); ok {
+			name = encoded
+			decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(val, "="))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 encoding of the value of label %q: %w", name, err)
+			}
+			val = string(decoded)
+		}
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("invalid label name %q in grouping key", name)
+		}
+		if _, ok := groupingKey[name]; ok {
+			return nil, fmt.Errorf("duplicate label %q in grouping key", name)
+		}
+		groupingKey[name] = val
+	}
+	if groupingKey[model.JobLabel] == "" {
+		return nil, errors.New("job name must not be empty")
+	}
+	return groupingKey, nil
+}
+
+// groupKey returns a string uniquely identifying a grouping key.
+func groupKey(groupingKey map[string]string) string {
+	return labels.FromMap(groupingKey).String()
+}
+
+// decodeFamilies decodes the metric families of a push request, in the text
+// or protobuf format, and adds the grouping key to their metrics.
+func decodeFamilies(r *http.Request, groupingKey map[string]string) (map[string]*dto.MetricFamily, error) {
+	families := make(map[string]*dto.MetricFamily)
+	dec := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode pushed metrics: %w", err)
+		}
+		if mf.GetName() == pushTimeMetric {
+			return nil, fmt.Errorf("pushed metrics can't contain %s", pushTimeMetric)
+		}
+		setGroupingLabels(mf, groupingKey)
+		families[mf.GetName()] = mf
+	}
+	return families, nil
+}
+
+// setGroupingLabels sets the labels of the grouping key on every metric of
+// the family, overriding the labels with the same name.
+func setGroupingLabels(mf *dto.MetricFamily, groupingKey map[string]string) {
+	for _, m := range mf.GetMetric() {
+		lps := slices.DeleteFunc(m.Label, func(lp *dto.LabelPair) bool {
+			_, ok := groupingKey[lp.GetName()]
+			return ok
+		})
+		for name, val := range groupingKey {
+			lps = append(lps, &dto.LabelPair{Name: proto.String(name), Value: proto.String(val)})
+		}
+		m.Label = lps
+	}
+}
+
+// persistedGroup is the representation of a pushGroup on disk. The metrics
+// are stored in the text format.
+type persistedGroup struct {
+	Labels   map[string]string `json:"labels"`
+	PushTime time.Time         `json:"push_time"`
+	Metrics  string            `json:"metrics"`
+}
+
+// Save writes the groups to path, if they changed since they were last saved.
+func (p *pushgateway) Save(path string) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if !p.dirty {
+		return nil
+	}
+
+	persisted := make([]persistedGroup, 0, len(p.groups))
+	for _, g := range p.groups {
+		var buf bytes.Buffer
+		for _, mf := range g.Families {
+			if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+				return err
+			}
+		}
+		persisted = append(persisted, persistedGroup{Labels: g.Labels, PushTime: g.PushTime, Metrics: buf.String()})
+	}
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a crash can't leave a partially
+	// written file behind.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// Load reads the groups saved to path. It's a no-op if path doesn't exist.
+func (p *pushgateway) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var persisted []persistedGroup
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	groups := make(map[string]*pushGroup, len(persisted))
+	for _, pg := range persisted {
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(strings.NewReader(pg.Metrics))
+		if err != nil {
+			return fmt.Errorf("failed to decode the metrics of group %v: %w", pg.Labels, err)
+		}
+		groups[groupKey(pg.Labels)] = &pushGroup{Labels: pg.Labels, Families: families, PushTime: pg.PushTime}
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.groups = groups
+	return nil
+}
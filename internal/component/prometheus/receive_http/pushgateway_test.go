@@ -0,0 +1,164 @@
+package receive_http
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/alloy/internal/util"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroupingKey(t *testing.T) {
+	tests := []struct {
+		path   string
+		expect map[string]string
+		errMsg string
+	}{
+		{
+			path:   "job/batch",
+			expect: map[string]string{"job": "batch"},
+		},
+		{
+			path:   "job/batch/instance/host-1/",
+			expect: map[string]string{"job": "batch", "instance": "host-1"},
+		},
+		{
+			path:   "job@base64/YmF0Y2gvMQ/path@base64/L3Zhci90bXA=",
+			expect: map[string]string{"job": "batch/1", "path": "/var/tmp"},
+		},
+		{
+			path:   "job/batch/instance",
+			errMsg: `invalid grouping key "job/batch/instance"`,
+		},
+		{
+			path:   "instance/host-1",
+			errMsg: "job name must not be empty",
+		},
+		{
+			path:   "job/batch/__name__/up",
+			errMsg: `invalid label name "__name__" in grouping key`,
+		},
+		{
+			path:   "job/batch/job/other",
+			errMsg: `duplicate label "job" in grouping key`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			groupingKey, err := parseGroupingKey(tc.path)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, groupingKey)
+		})
+	}
+}
+
+func TestPushgateway(t *testing.T) {
+	samples := make(chan testSample, 100)
+	p := newPushgateway(util.TestLogger(t), testAppendable(samples)[0])
+
+	push(t, p, http.MethodPut, "/metrics/job/batch/instance/a", "# TYPE processed counter\nprocessed{instance=\"b\"} 10\nduration_seconds 2.5\n")
+	require.Equal(t, map[string]float64{
+		`{__name__="processed", instance="a", job="batch"}`:        10,
+		`{__name__="duration_seconds", instance="a", job="batch"}`: 2.5,
+	}, drainValues(samples, false))
+
+	// POST only replaces the metrics with the same names.
+	push(t, p, http.MethodPost, "/metrics/job/batch/instance/a", "processed 20\n")
+	require.Equal(t, map[string]float64{
+		`{__name__="processed", instance="a", job="batch"}`:        20,
+		`{__name__="duration_seconds", instance="a", job="batch"}`: 2.5,
+	}, drainValues(samples, false))
+
+	// PUT replaces the whole group, and the missing series are marked as stale.
+	push(t, p, http.MethodPut, "/metrics/job/batch/instance/a", "processed 30\n")
+	values := drainValues(samples, true)
+	require.True(t, value.IsStaleNaN(values[`{__name__="duration_seconds", instance="a", job="batch"}`]))
+	require.Equal(t, float64(30), values[`{__name__="processed", instance="a", job="batch"}`])
+
+	// The groups are forwarded again at every interval.
+	require.NoError(t, p.Forward(t.Context()))
+	require.Equal(t, map[string]float64{
+		`{__name__="processed", instance="a", job="batch"}`: 30,
+	}, drainValues(samples, false))
+
+	push(t, p, http.MethodDelete, "/metrics/job/batch/instance/a", "")
+	values = drainValues(samples, true)
+	require.True(t, value.IsStaleNaN(values[`{__name__="processed", instance="a", job="batch"}`]))
+	require.NoError(t, p.Forward(t.Context()))
+	require.Empty(t, samples)
+}
+
+func TestPushgateway_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pushgateway.json")
+
+	p := newPushgateway(util.TestLogger(t), testAppendable(make(chan testSample, 100))[0])
+	push(t, p, http.MethodPut, "/metrics/job/batch", "# TYPE latency histogram\nlatency_bucket{le=\"1\"} 2\nlatency_bucket{le=\"+Inf\"} 3\nlatency_sum 4\nlatency_count 3\n")
+	require.NoError(t, p.Save(path))
+
+	samples := make(chan testSample, 100)
+	loaded := newPushgateway(util.TestLogger(t), testAppendable(samples)[0])
+	require.NoError(t, loaded.Load(path))
+	require.NoError(t, loaded.Forward(t.Context()))
+	require.Equal(t, map[string]float64{
+		`{__name__="latency_bucket", job="batch", le="1"}`:    2,
+		`{__name__="latency_bucket", job="batch", le="+Inf"}`: 3,
+		`{__name__="latency_sum", job="batch"}`:               4,
+		`{__name__="latency_count", job="batch"}`:             3,
+	}, drainValues(samples, false))
+
+	// Loading a file which doesn't exist is a no-op.
+	require.NoError(t, loaded.Load(filepath.Join(t.TempDir(), "missing.json")))
+}
+
+func TestPushgateway_InvalidPush(t *testing.T) {
+	p := newPushgateway(util.TestLogger(t), testAppendable(make(chan testSample, 100))[0])
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/metrics/job/batch", strings.NewReader("push_time_seconds 1\n")))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "pushed metrics can't contain push_time_seconds")
+}
+
+func push(t *testing.T, p *pushgateway, method, path, body string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if method == http.MethodDelete {
+		require.Equal(t, http.StatusAccepted, rec.Code)
+	} else {
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
+
+// drainValues returns the last value received for every series, except for
+// the push_time_seconds series. Stale markers are only kept if keepStale is
+// true.
+func drainValues(samples chan testSample, keepStale bool) map[string]float64 {
+	values := make(map[string]float64)
+	for {
+		select {
+		case s := <-samples:
+			if s.l.Get(labels.MetricName) == pushTimeMetric {
+				continue
+			}
+			if math.IsNaN(s.val) && !keepStale {
+				continue
+			}
+			values[s.l.String()] = s.val
+		default:
+			return values
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/grafana/alloy/internal/component"
@@ -36,6 +38,15 @@ func init() {
 type Arguments struct {
 	Server    *fnet.ServerConfig   `alloy:",squash"`
 	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// Experimental endpoints, which are only mounted if their block is set.
+	Pushgateway *PushgatewayArguments `alloy:"pushgateway,block,optional"`
+	OTLP        *OTLPArguments        `alloy:"otlp,block,optional"`
+}
+
+// OTLPArguments configures the OTLP metrics endpoint.
+type OTLPArguments struct {
+	PromoteResourceAttributes []string `alloy:"promote_resource_attributes,attr,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -48,12 +59,22 @@ func (args *Arguments) SetToDefault() {
 type Component struct {
 	opts               component.Options
 	handler            http.Handler
+	otlpHandler        http.Handler
+	pushgateway        *pushgateway
 	fanout             *alloyprom.Fanout
 	uncheckedCollector *util.UncheckedCollector
 
 	updateMut sync.RWMutex
 	args      Arguments
 	server    *fnet.TargetServer
+
+	// The OTLP configuration is read by the handler on every request, so it
+	// can't be guarded by updateMut, which is held while the server shuts
+	// down.
+	otlpMut  sync.RWMutex
+	otlpArgs OTLPArguments
+
+	pushgatewayLoaded bool
 }
 
 func New(opts component.Options, args Arguments) (*Component, error) {
@@ -67,15 +88,18 @@ func New(opts component.Options, args Arguments) (*Component, error) {
 	uncheckedCollector := util.NewUncheckedCollector(nil)
 	opts.Registerer.MustRegister(uncheckedCollector)
 
-	//TODO: Make this configurable in the future?
-	supportedRemoteWriteProtoMsgs := config.RemoteWriteProtoMsgs{config.RemoteWriteProtoMsgV1}
+	// The protobuf message of each request is negotiated from its Content-Type
+	// header, so both Remote Write 1.0 and 2.0 senders are accepted.
+	supportedRemoteWriteProtoMsgs := config.RemoteWriteProtoMsgs{config.RemoteWriteProtoMsgV1, config.RemoteWriteProtoMsgV2}
 
 	c := &Component{
 		opts:               opts,
 		handler:            remote.NewWriteHandler(opts.Logger, opts.Registerer, fanout, supportedRemoteWriteProtoMsgs),
+		pushgateway:        newPushgateway(opts.Logger, fanout),
 		fanout:             fanout,
 		uncheckedCollector: uncheckedCollector,
 	}
+	c.otlpHandler = remote.NewOTLPWriteHandler(opts.Logger, fanout, c.otlpConfig)
 
 	if err := c.Update(args); err != nil {
 		return nil, err
@@ -89,11 +113,58 @@ func (c *Component) Run(ctx context.Context) error {
 		c.updateMut.Lock()
 		defer c.updateMut.Unlock()
 		c.shutdownServer()
+		c.savePushgateway()
 	}()
 
-	<-ctx.Done()
-	level.Info(c.opts.Logger).Log("msg", "terminating due to context done")
-	return nil
+	for {
+		c.updateMut.RLock()
+		pushgatewayArgs := c.args.Pushgateway
+		c.updateMut.RUnlock()
+
+		// The pushed metrics are forwarded again at every interval, the same
+		// way a Pushgateway is scraped.
+		var forward <-chan time.Time
+		if pushgatewayArgs != nil {
+			forward = time.After(pushgatewayArgs.ForwardInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			level.Info(c.opts.Logger).Log("msg", "terminating due to context done")
+			return nil
+		case <-forward:
+			if err := c.pushgateway.Forward(ctx); err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to forward pushed metrics", "err", err)
+			}
+			c.updateMut.RLock()
+			c.savePushgateway()
+			c.updateMut.RUnlock()
+		}
+	}
+}
+
+// savePushgateway persists the pushed metrics if persistence is enabled. The
+// updateMut lock must be held when it's called.
+func (c *Component) savePushgateway() {
+	if c.args.Pushgateway == nil || !c.args.Pushgateway.Persist {
+		return
+	}
+	if err := c.pushgateway.Save(c.pushgatewayPath()); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to persist pushed metrics", "err", err)
+	}
+}
+
+func (c *Component) pushgatewayPath() string {
+	return filepath.Join(c.opts.DataPath, "pushgateway.json")
+}
+
+func (c *Component) otlpConfig() config.Config {
+	c.otlpMut.RLock()
+	defer c.otlpMut.RUnlock()
+
+	return config.Config{
+		OTLPConfig: config.OTLPConfig{PromoteResourceAttributes: c.otlpArgs.PromoteResourceAttributes},
+	}
 }
 
 // Update satisfies the Component interface.
@@ -101,10 +172,45 @@ func (c *Component) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 	c.fanout.UpdateChildren(newArgs.ForwardTo)
 
+	for _, feature := range []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "pushgateway", enabled: newArgs.Pushgateway != nil},
+		{name: "otlp", enabled: newArgs.OTLP != nil},
+	} {
+		if !feature.enabled {
+			continue
+		}
+		err := featuregate.CheckAllowed(featuregate.StabilityExperimental, c.opts.MinStability, fmt.Sprintf("%s block in prometheus.receive_http", feature.name))
+		if err != nil {
+			return err
+		}
+	}
+
+	if newArgs.OTLP != nil {
+		c.otlpMut.Lock()
+		c.otlpArgs = *newArgs.OTLP
+		c.otlpMut.Unlock()
+	}
+
 	c.updateMut.Lock()
 	defer c.updateMut.Unlock()
 
-	serverNeedsUpdate := !reflect.DeepEqual(c.args.Server, newArgs.Server)
+	// Persisted metrics are only loaded once, so that they don't overwrite
+	// the metrics pushed since the component started.
+	if newArgs.Pushgateway != nil && newArgs.Pushgateway.Persist && !c.pushgatewayLoaded {
+		if err := c.pushgateway.Load(c.pushgatewayPath()); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to load persisted pushed metrics", "err", err)
+		}
+		c.pushgatewayLoaded = true
+	}
+
+	// The endpoints only need to be mounted again if the server or the set of
+	// enabled endpoints changed.
+	serverNeedsUpdate := !reflect.DeepEqual(c.args.Server, newArgs.Server) ||
+		(c.args.Pushgateway == nil) != (newArgs.Pushgateway == nil) ||
+		(c.args.OTLP == nil) != (newArgs.OTLP == nil)
 	if !serverNeedsUpdate {
 		c.args = newArgs
 		return nil
@@ -119,6 +225,12 @@ func (c *Component) Update(args component.Arguments) error {
 
 	err = c.server.MountAndRun(func(router *mux.Router) {
 		router.Path("/api/v1/metrics/write").Methods("POST").Handler(c.handler)
+		if newArgs.OTLP != nil {
+			router.Path("/api/v1/otlp/v1/metrics").Methods("POST").Handler(c.otlpHandler)
+		}
+		if newArgs.Pushgateway != nil {
+			router.PathPrefix("/metrics/job").Methods("PUT", "POST", "DELETE").Handler(c.pushgateway)
+		}
 	})
 	if err != nil {
 		return err
@@ -1,10 +1,12 @@
 package receive_http
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/grafana/alloy/internal/component"
 	fnet "github.com/grafana/alloy/internal/component/common/net"
 	alloyprom "github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/service/labelstore"
 	"github.com/grafana/alloy/internal/util"
 	"github.com/phayes/freeport"
@@ -24,6 +27,9 @@ import (
 	"github.com/prometheus/prometheus/storage/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/protoadapt"
 )
@@ -158,6 +164,81 @@ func TestUpdate(t *testing.T) {
 	verifyExpectations(t, input02, expected02, actualSamples, args, ctx)
 }
 
+func TestExperimentalEndpoints(t *testing.T) {
+	actualSamples := make(chan testSample, 100)
+
+	args := Arguments{
+		Server: &fnet.ServerConfig{
+			HTTP: &fnet.HTTPConfig{
+				ListenAddress: "localhost",
+				ListenPort:    getFreePort(t),
+			},
+			GRPC: testGRPCConfig(t),
+		},
+		ForwardTo:   testAppendable(actualSamples),
+		Pushgateway: &PushgatewayArguments{ForwardInterval: time.Hour},
+		OTLP:        &OTLPArguments{PromoteResourceAttributes: []string{"env"}},
+	}
+
+	// The endpoints are experimental.
+	opts := testOptions(t)
+	opts.MinStability = featuregate.StabilityGenerallyAvailable
+	_, err := New(opts, args)
+	require.ErrorContains(t, err, "pushgateway block in prometheus.receive_http is at stability level")
+
+	opts = testOptions(t)
+	opts.MinStability = featuregate.StabilityExperimental
+	comp, err := New(opts, args)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	go func() {
+		require.NoError(t, comp.Run(ctx))
+	}()
+	waitForServerToBeReady(t, args)
+	baseURL := fmt.Sprintf("http://%s:%d", args.Server.HTTP.ListenAddress, args.Server.HTTP.ListenPort)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, baseURL+"/metrics/job/batch", strings.NewReader("processed 10\n"))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, float64(10), receivedValue(t, ctx, actualSamples, `{__name__="processed", job="batch"}`))
+
+	metrics := pmetric.NewMetrics()
+	rm := metrics.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "api")
+	rm.Resource().Attributes().PutStr("env", "prod")
+	m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("queue_size")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+	dp.SetDoubleValue(3)
+	body, err := pmetricotlp.NewExportRequestFromMetrics(metrics).MarshalProto()
+	require.NoError(t, err)
+
+	resp, err = http.Post(baseURL+"/api/v1/otlp/v1/metrics", "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, float64(3), receivedValue(t, ctx, actualSamples, `{__name__="queue_size", env="prod", job="api"}`))
+}
+
+// receivedValue returns the value of the first sample received for the series.
+func receivedValue(t *testing.T, ctx context.Context, actualSamples chan testSample, series string) float64 {
+	for {
+		select {
+		case s := <-actualSamples:
+			if s.l.String() == series {
+				return s.val
+			}
+		case <-ctx.Done():
+			t.Fatalf("test timed out waiting for %s", series)
+		}
+	}
+}
+
 func testGRPCConfig(t *testing.T) *fnet.GRPCConfig {
 	return &fnet.GRPCConfig{ListenAddress: "127.0.0.1", ListenPort: getFreePort(t)}
 }
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"time"
 
 	"github.com/grafana/alloy/internal/static/agentctl/waltools"
 	"github.com/olekukonko/tablewriter"
@@ -18,6 +20,9 @@ func InstallTools(cmd *cobra.Command) {
 		samplesCmd(),
 		targetStatsCmd(),
 		walStatsCmd(),
+		dumpCmd(),
+		cardinalityCmd(),
+		replayCmd(),
 	)
 }
 
@@ -203,6 +208,244 @@ deletion but then comes back at some point).`,
 	}
 }
 
+func dumpCmd() *cobra.Command {
+	var (
+		selector string
+		from     string
+		to       string
+		format   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dump [WAL directory]",
+		Short: "Dump samples for series matching a label selector",
+		Long: `dump reads a WAL directory and prints the samples of the series matching a
+label selector within a time range. The WAL is snapshotted first, so it can be
+dumped while Alloy is still running.
+
+Samples are printed in the OpenMetrics text format or as JSON, with one object
+per line for each series. Native histogram samples and stale markers aren't
+printed.
+
+Examples:
+
+Dump all the samples of the 'up' series:
+
+dump -s up /tmp/wal
+
+
+Dump the samples of 'job=a' from the last hour as JSON:
+
+dump -s '{job="a"}' --from 2024-01-01T10:00:00Z --to 2024-01-01T11:00:00Z --format json /tmp/wal
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			fromTime, toTime := mustParseRange(from, to)
+			if format != "openmetrics" && format != "json" {
+				fmt.Printf("unsupported format %q, must be one of openmetrics or json\n", format)
+				os.Exit(1)
+			}
+
+			directory, cleanup := mustSnapshotWAL(args[0])
+			defer cleanup()
+
+			series, err := waltools.DumpSamples(directory, selector, fromTime, toTime)
+			if err != nil {
+				fmt.Printf("failed to dump samples: %v\n", err)
+				cleanup()
+				os.Exit(1)
+			}
+
+			if format == "json" {
+				err = waltools.WriteJSON(os.Stdout, series)
+			} else {
+				err = waltools.WriteOpenMetrics(os.Stdout, series)
+			}
+			if err != nil {
+				fmt.Printf("failed to write samples: %v\n", err)
+				cleanup()
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "s", "{}", "label selector to search for")
+	cmd.Flags().StringVar(&from, "from", "", "oldest timestamp to dump, as RFC3339 or Unix seconds")
+	cmd.Flags().StringVar(&to, "to", "", "newest timestamp to dump, as RFC3339 or Unix seconds")
+	cmd.Flags().StringVarP(&format, "format", "f", "openmetrics", "output format, openmetrics or json")
+	return cmd
+}
+
+func cardinalityCmd() *cobra.Command {
+	var (
+		selector string
+		top      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cardinality [WAL directory]",
+		Short: "List the metrics and labels with the highest cardinality",
+		Long: `cardinality reads a WAL directory and lists the metrics with the most series and
+the labels with the most unique values, across the series matching a label
+selector. The WAL is snapshotted first, so it can be read while Alloy is still
+running.
+
+The result can be used to find the metrics and labels responsible for a
+cardinality increase, and to define relabeling rules which drop them.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(_ *cobra.Command, args []string) {
+			directory, cleanup := mustSnapshotWAL(args[0])
+			defer cleanup()
+
+			report, err := waltools.FindTopCardinality(directory, selector, top)
+			if err != nil {
+				fmt.Printf("failed to get cardinality: %v\n", err)
+				cleanup()
+				os.Exit(1)
+			}
+
+			fmt.Printf("Total Series: %d\n", report.Series)
+
+			fmt.Printf("\nTop metrics by series:\n")
+			metrics := tablewriter.NewWriter(os.Stdout)
+			metrics.SetHeader([]string{"Metric", "Series"})
+			for _, m := range report.Metrics {
+				metrics.Append([]string{m.Metric, fmt.Sprintf("%d", m.Instances)})
+			}
+			metrics.Render()
+
+			fmt.Printf("\nTop labels by unique values:\n")
+			labels := tablewriter.NewWriter(os.Stdout)
+			labels.SetHeader([]string{"Label", "Values", "Series"})
+			for _, l := range report.Labels {
+				labels.Append([]string{l.Label, fmt.Sprintf("%d", l.Values), fmt.Sprintf("%d", l.Series)})
+			}
+			labels.Render()
+		},
+	}
+
+	cmd.Flags().StringVarP(&selector, "selector", "s", "{}", "label selector to search for")
+	cmd.Flags().IntVarP(&top, "top", "n", 10, "number of metrics and labels to list")
+	return cmd
+}
+
+func replayCmd() *cobra.Command {
+	var (
+		opts    waltools.ReplayOptions
+		from    string
+		to      string
+		headers map[string]string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay [WAL directory]",
+		Short: "Send samples from the WAL to a remote_write endpoint",
+		Long: `replay reads a WAL directory and sends the samples of the series matching a
+label selector within a time range to a remote_write endpoint. The WAL is
+snapshotted first, so it can be replayed while Alloy is still running.
+
+Samples are sent in the order they were written to the WAL. Requests which fail
+with a recoverable error are retried. Native histogram samples aren't sent.
+
+Examples:
+
+Send the samples of 'job=a' from an hour-long outage to a Mimir tenant, at most
+10000 samples per second:
+
+replay -s '{job="a"}' --from 2024-01-01T10:00:00Z --to 2024-01-01T11:00:00Z \
+  --url http://mimir:9009/api/v1/push -H X-Scope-OrgID=tenant --rate 10000 /tmp/wal
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			opts.From, opts.To = mustParseRange(from, to)
+			opts.Headers = headers
+
+			directory, cleanup := mustSnapshotWAL(args[0])
+			defer cleanup()
+
+			stats, err := waltools.Replay(cmd.Context(), directory, opts)
+			fmt.Printf("Sent %d samples in %d requests\n", stats.Samples, stats.Requests)
+			if err != nil {
+				fmt.Printf("failed to replay samples: %v\n", err)
+				cleanup()
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Selector, "selector", "s", "{}", "label selector to search for")
+	cmd.Flags().StringVar(&from, "from", "", "oldest timestamp to send, as RFC3339 or Unix seconds")
+	cmd.Flags().StringVar(&to, "to", "", "newest timestamp to send, as RFC3339 or Unix seconds")
+	cmd.Flags().StringVar(&opts.URL, "url", "", "URL of the remote_write endpoint")
+	cmd.Flags().StringToStringVarP(&headers, "header", "H", nil, "extra header to send with requests, as name=value")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 30*time.Second, "timeout of each request")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 2000, "maximum number of samples per request")
+	cmd.Flags().Float64Var(&opts.SamplesPerSecond, "rate", 0, "maximum number of samples sent per second, 0 for no limit")
+	cmd.Flags().IntVar(&opts.MaxRetries, "max-retries", 10, "maximum number of retries of a failed request")
+	must(cmd.MarkFlagRequired("url"))
+	return cmd
+}
+
+// mustSnapshotWAL snapshots the WAL in directory, so that it can be read while
+// it's still being written to. It exits if the WAL can't be snapshotted.
+func mustSnapshotWAL(directory string) (string, func()) {
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		fmt.Printf("%s does not exist\n", directory)
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("error getting wal: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Check if ./wal is a subdirectory, use that instead.
+	if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
+		directory = filepath.Join(directory, "wal")
+	}
+
+	snapshot, cleanup, err := waltools.Snapshot(directory)
+	if err != nil {
+		fmt.Printf("failed to snapshot WAL: %v\n", err)
+		os.Exit(1)
+	}
+	return snapshot, cleanup
+}
+
+// mustParseRange parses the timestamps of a time range. It exits if either of
+// them is invalid.
+func mustParseRange(from, to string) (time.Time, time.Time) {
+	fromTime, err := parseTime(from)
+	if err != nil {
+		fmt.Printf("invalid --from: %v\n", err)
+		os.Exit(1)
+	}
+	toTime, err := parseTime(to)
+	if err != nil {
+		fmt.Printf("invalid --to: %v\n", err)
+		os.Exit(1)
+	}
+	if !fromTime.IsZero() && !toTime.IsZero() && toTime.Before(fromTime) {
+		fmt.Printf("--to must not be before --from\n")
+		os.Exit(1)
+	}
+	return fromTime, toTime
+}
+
+// parseTime parses a timestamp in the RFC3339 format or in Unix seconds. An
+// empty string is parsed as the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.UnixMilli(int64(secs * 1000)), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q as RFC3339 or Unix seconds", s)
+	}
+	return t, nil
+}
+
 func must(err error) {
 	if err != nil {
 		panic(err)
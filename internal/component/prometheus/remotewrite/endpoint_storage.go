@@ -0,0 +1,278 @@
+package remotewrite
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/static/metrics/wal"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+)
+
+// endpointStorage is a WAL and the remote storage which sends the data written
+// to it. The component has a storage which sends to all the endpoints, or only
+// to the first one when failover is enabled, in which case each of the other
+// endpoints has its own storage.
+type endpointStorage struct {
+	log  log.Logger
+	name string
+	dir  string
+	reg  prometheus_client.Registerer
+
+	walRegisterer *trackingRegisterer
+	walStore      *wal.Storage
+
+	mut              sync.RWMutex
+	metadataInWAL    bool
+	remoteRegisterer *trackingRegisterer
+	remoteStore      *remote.Storage
+	storage          storage.Storage
+
+	// lastTs is the last timestamp the WAL was truncated for.
+	lastTs int64
+}
+
+var _ wlog.WriteNotified = (*endpointStorage)(nil)
+
+// newEndpointStorage creates a storage in dir for the endpoint called name.
+// The name is empty for the storage of the component.
+func newEndpointStorage(logger log.Logger, reg prometheus_client.Registerer, name, dir string) (*endpointStorage, error) {
+	walRegisterer := &trackingRegisterer{reg: reg}
+	walStore, err := wal.NewStorage(log.With(logger, "subcomponent", "wal"), walRegisterer, dir)
+	if err != nil {
+		walRegisterer.UnregisterAll()
+		return nil, err
+	}
+
+	e := &endpointStorage{
+		log:           logger,
+		name:          name,
+		dir:           dir,
+		reg:           reg,
+		walRegisterer: walRegisterer,
+		walStore:      walStore,
+		lastTs:        math.MinInt64,
+	}
+	e.remoteRegisterer, e.remoteStore = e.newRemoteStorage(false)
+	e.storage = storage.NewFanout(logger, walStore, e.remoteStore)
+	walStore.SetNotifier(e)
+	return e, nil
+}
+
+func (e *endpointStorage) newRemoteStorage(metadataInWAL bool) (*trackingRegisterer, *remote.Storage) {
+	registerer := &trackingRegisterer{reg: e.reg}
+	return registerer, remote.NewStorage(log.With(e.log, "subcomponent", "rw"), registerer, startTime, e.dir, remoteFlushDeadline, nil, metadataInWAL)
+}
+
+// Appender returns an appender which writes to the WAL.
+func (e *endpointStorage) Appender(ctx context.Context) storage.Appender {
+	e.mut.RLock()
+	defer e.mut.RUnlock()
+	return e.storage.Appender(ctx)
+}
+
+// Notify implements wlog.WriteNotified.
+func (e *endpointStorage) Notify() {
+	e.mut.RLock()
+	defer e.mut.RUnlock()
+	e.remoteStore.Notify()
+}
+
+// LowestSentTimestamp returns the lowest timestamp sent by the queues of the
+// remote storage, in milliseconds.
+func (e *endpointStorage) LowestSentTimestamp() int64 {
+	e.mut.RLock()
+	defer e.mut.RUnlock()
+	return e.remoteStore.LowestSentTimestamp()
+}
+
+// ApplyConfig applies cfg to the remote storage.
+//
+// Metadata is only written to the WAL when a Remote Write 2.0 endpoint sends
+// it, since the other endpoints don't read it. The remote storage only
+// accepts Remote Write 2.0 endpoints if it's created with metadata in the WAL,
+// so it's created again when that changes.
+func (e *endpointStorage) ApplyConfig(cfg *config.Config) error {
+	var remoteWrite2, sendMetadata bool
+	for _, rw := range cfg.RemoteWriteConfigs {
+		if rw.ProtobufMessage == config.RemoteWriteProtoMsgV2 {
+			remoteWrite2 = true
+			sendMetadata = sendMetadata || rw.MetadataConfig.Send
+		}
+	}
+	e.walStore.SetMetadataRecords(sendMetadata)
+
+	e.mut.RLock()
+	prevStore, metadataInWAL := e.remoteStore, e.metadataInWAL
+	e.mut.RUnlock()
+	if remoteWrite2 == metadataInWAL {
+		return prevStore.ApplyConfig(cfg)
+	}
+
+	// The metrics of the previous remote storage are unregistered first, so
+	// that the new one can register the metrics of its queues.
+	e.remoteRegisterer.UnregisterAll()
+	registerer, remoteStore := e.newRemoteStorage(remoteWrite2)
+	if err := remoteStore.ApplyConfig(cfg); err != nil {
+		_ = remoteStore.Close()
+		registerer.UnregisterAll()
+		return err
+	}
+
+	e.mut.Lock()
+	e.metadataInWAL = remoteWrite2
+	e.remoteRegisterer = registerer
+	e.remoteStore = remoteStore
+	e.storage = storage.NewFanout(e.log, e.walStore, remoteStore)
+	e.mut.Unlock()
+
+	if err := prevStore.Close(); err != nil {
+		level.Warn(e.log).Log("msg", "error when closing the previous remote storage", "err", err)
+	}
+	return nil
+}
+
+// appendRecords appends records to the storage.
+func (e *endpointStorage) appendRecords(records []appendRecord) {
+	var (
+		app      = e.Appender(context.Background())
+		refs     = make(map[uint64]storage.SeriesRef)
+		failed   int
+		firstErr error
+	)
+	for _, r := range records {
+		if err := r.appendTo(app, refs); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Warn(e.log).Log("msg", "could not append to the WAL of the endpoint", "err", err)
+		return
+	}
+	if failed > 0 {
+		level.Warn(e.log).Log("msg", "could not append some of the data to the WAL of the endpoint", "failed", failed, "err", firstErr)
+	}
+}
+
+// Truncate truncates the WAL from the lowest timestamp sent by the remote
+// storage.
+func (e *endpointStorage) Truncate(minWALTime, maxWALTime time.Duration) {
+	// The timestamp ts is used to determine which series are not receiving
+	// samples and may be deleted from the WAL. Their most recent append
+	// timestamp is compared to ts, and if that timestamp is older than ts,
+	// they are considered inactive and may be deleted.
+	//
+	// Subtracting a duration from ts will delay when it will be considered
+	// inactive and scheduled for deletion.
+	ts := e.LowestSentTimestamp() - minWALTime.Milliseconds()
+	if ts < 0 {
+		ts = 0
+	}
+
+	// Network issues can prevent the result of LowestSentTimestamp from
+	// changing. We don't want data in the WAL to grow forever, so we set a cap
+	// on the maximum age data can be. If our ts is older than this cutoff point,
+	// we'll shift it forward to start deleting very stale data.
+	if maxTS := timestamp.FromTime(time.Now().Add(-maxWALTime)); ts < maxTS {
+		ts = maxTS
+	}
+
+	if ts == e.lastTs {
+		level.Debug(e.log).Log("msg", "not truncating the WAL, remote_write timestamp is unchanged", "ts", ts)
+		return
+	}
+	e.lastTs = ts
+
+	level.Debug(e.log).Log("msg", "truncating the WAL", "ts", ts)
+	err := e.walStore.Truncate(ts)
+	if err != nil {
+		// The only issue here is larger disk usage and a greater replay time,
+		// so we'll only log this as a warning.
+		level.Warn(e.log).Log("msg", "could not truncate WAL", "err", err)
+	}
+}
+
+// Close closes the WAL and the remote storage, and unregisters their metrics.
+func (e *endpointStorage) Close() error {
+	e.mut.RLock()
+	defer e.mut.RUnlock()
+	err := e.storage.Close()
+	e.remoteRegisterer.UnregisterAll()
+	e.walRegisterer.UnregisterAll()
+	return err
+}
+
+// trackingRegisterer registers the metrics of a WAL or a remote storage, and
+// unregisters them when it's closed, since the remote storage doesn't
+// unregister all of its metrics.
+//
+// Metrics which can't be registered aren't exposed, without failing. This is
+// the case of the metrics of the storage of an endpoint which don't have a
+// label identifying the endpoint, like the ones of the WAL, since the storage
+// of the component registers them first.
+type trackingRegisterer struct {
+	reg prometheus_client.Registerer
+
+	mut        sync.Mutex
+	registered []prometheus_client.Collector
+	closed     bool
+}
+
+var _ prometheus_client.Registerer = (*trackingRegisterer)(nil)
+
+// Register implements prometheus.Registerer.
+func (t *trackingRegisterer) Register(c prometheus_client.Collector) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if t.reg == nil || t.closed || t.reg.Register(c) != nil {
+		return nil
+	}
+	t.registered = append(t.registered, c)
+	return nil
+}
+
+// MustRegister implements prometheus.Registerer.
+func (t *trackingRegisterer) MustRegister(cs ...prometheus_client.Collector) {
+	for _, c := range cs {
+		_ = t.Register(c)
+	}
+}
+
+// Unregister implements prometheus.Registerer.
+func (t *trackingRegisterer) Unregister(c prometheus_client.Collector) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	// Once closed, a collector with the same descriptors may have been
+	// registered by someone else, and mustn't be unregistered.
+	if t.reg == nil || t.closed {
+		return false
+	}
+	t.registered = slices.DeleteFunc(t.registered, func(registered prometheus_client.Collector) bool {
+		return registered == c
+	})
+	return t.reg.Unregister(c)
+}
+
+// UnregisterAll unregisters all the metrics which are still registered, and
+// closes the registerer.
+func (t *trackingRegisterer) UnregisterAll() {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	for _, c := range t.registered {
+		t.reg.Unregister(c)
+	}
+	t.registered = nil
+	t.closed = true
+}
@@ -0,0 +1,62 @@
+package remotewrite
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointStorage_ApplyConfig(t *testing.T) {
+	e, err := newEndpointStorage(util.TestLogger(t), prometheus_client.NewRegistry(), "", t.TempDir())
+	require.NoError(t, err)
+	defer e.Close()
+
+	applyConfig := func(protobufMessage config.RemoteWriteProtoMsg, sendMetadata bool) {
+		var args Arguments
+		require.NoError(t, syntax.Unmarshal([]byte(fmt.Sprintf(`
+			endpoint {
+				url              = "http://localhost/api/v1/write"
+				protobuf_message = %q
+
+				metadata_config {
+					send = %t
+				}
+			}
+		`, protobufMessage, sendMetadata)), &args))
+
+		cfg, err := convertConfigs(args)
+		require.NoError(t, err)
+		require.NoError(t, e.ApplyConfig(cfg))
+	}
+	// metadataWritten reports whether the metadata of a series is written to
+	// the WAL, which fails for an unknown series.
+	metadataWritten := func() bool {
+		app := e.Appender(t.Context())
+		defer app.Rollback()
+		_, err := app.UpdateMetadata(0, labels.FromStrings("__name__", "unknown"), metadata.Metadata{})
+		return err != nil
+	}
+
+	applyConfig(config.RemoteWriteProtoMsgV1, true)
+	require.False(t, e.metadataInWAL)
+	require.False(t, metadataWritten())
+
+	applyConfig(config.RemoteWriteProtoMsgV2, true)
+	require.True(t, e.metadataInWAL)
+	require.True(t, metadataWritten())
+
+	applyConfig(config.RemoteWriteProtoMsgV2, false)
+	require.True(t, e.metadataInWAL)
+	require.False(t, metadataWritten())
+
+	applyConfig(config.RemoteWriteProtoMsgV1, true)
+	require.False(t, e.metadataInWAL)
+	require.False(t, metadataWritten())
+}
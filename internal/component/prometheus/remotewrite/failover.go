@@ -0,0 +1,115 @@
+package remotewrite
+
+import (
+	"slices"
+	"time"
+)
+
+// failoverCheckInterval is how often the health of the endpoints is checked
+// when failover is enabled. It's a variable so that tests can shorten it.
+var failoverCheckInterval = 5 * time.Second
+
+const remoteNameLabel = "remote_name"
+
+// failover decides which endpoint of a prometheus.remote_write component is
+// active. Endpoints are tried in the order they're declared in.
+//
+// Each endpoint has its own WAL. The samples are appended to the WAL of the
+// active endpoint and of all the endpoints before it, so a recovered endpoint
+// catches up on what it missed before it becomes active again. Endpoints
+// after the active one are passive and don't receive anything.
+type failover struct {
+	opts   FailoverOptions
+	names  []string
+	active int
+
+	// unhealthySince is when the active endpoint became unhealthy, or the
+	// zero time if it's healthy.
+	unhealthySince time.Time
+	// healthySince is when each endpoint before the active one became
+	// healthy, or the zero time if it's unhealthy.
+	healthySince []time.Time
+}
+
+// newFailover creates a new failover for the endpoints called names. The
+// state of prev is kept if it was created for the same endpoints.
+func newFailover(opts FailoverOptions, names []string, prev *failover) *failover {
+	if prev != nil && slices.Equal(prev.names, names) {
+		prev.opts = opts
+		return prev
+	}
+	return &failover{
+		opts:         opts,
+		names:        names,
+		healthySince: make([]time.Time, len(names)),
+	}
+}
+
+// Check updates the health of the endpoints from their lag, which is how far
+// behind the newest sample in the WAL each endpoint is. It returns true if the
+// active endpoint changed.
+func (f *failover) Check(now time.Time, lags []time.Duration) bool {
+	// Fail back to the first endpoint which has been healthy for long enough.
+	for i := 0; i < f.active; i++ {
+		if lags[i] > f.opts.MaxLag {
+			f.healthySince[i] = time.Time{}
+			continue
+		}
+		if f.healthySince[i].IsZero() {
+			f.healthySince[i] = now
+		}
+		if now.Sub(f.healthySince[i]) >= f.opts.FailbackAfter {
+			f.switchTo(i)
+			return true
+		}
+	}
+
+	if lags[f.active] <= f.opts.MaxLag {
+		f.unhealthySince = time.Time{}
+		return false
+	}
+	if f.unhealthySince.IsZero() {
+		f.unhealthySince = now
+	}
+	if f.active < len(f.names)-1 && now.Sub(f.unhealthySince) >= f.opts.FailoverAfter {
+		f.switchTo(f.active + 1)
+		return true
+	}
+	return false
+}
+
+func (f *failover) switchTo(i int) {
+	f.active = i
+	f.unhealthySince = time.Time{}
+	clear(f.healthySince)
+}
+
+// Lags returns the lag of each endpoint from the highest timestamp appended
+// to the component and the highest timestamp sent by each endpoint, in
+// milliseconds.
+func (f *failover) Lags(highest int64, sent []int64) []time.Duration {
+	lags := make([]time.Duration, len(f.names))
+	for i := range f.names {
+		lags[i] = time.Duration(highest-sent[i]) * time.Millisecond
+	}
+	return lags
+}
+
+// ReplayFrom returns the time from which the samples appended to the
+// component are kept, so that they can be appended to the next endpoint if the
+// component fails over. It covers the samples which the active endpoint may
+// not have sent when it became unhealthy.
+func (f *failover) ReplayFrom(now time.Time) time.Time {
+	if f.active == len(f.names)-1 {
+		// There's no endpoint to fail over to.
+		return now
+	}
+	from := now
+	if !f.unhealthySince.IsZero() {
+		from = f.unhealthySince
+	}
+	// The lag of an endpoint can be underestimated by up to a second, since
+	// the queues report the timestamps they sent with a precision of a
+	// second, and it's only checked every failoverCheckInterval.
+	return from.Add(-f.opts.MaxLag - time.Second - 2*failoverCheckInterval)
+}
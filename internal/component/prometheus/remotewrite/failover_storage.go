@@ -0,0 +1,376 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+// router routes the data appended to the component between the endpoints
+// when failover is enabled.
+//
+// The first endpoint uses the storage of the component, which receives all
+// the data as it's appended, just like when failover is disabled. Every other
+// endpoint has its own WAL and remote storage, and the data is appended to
+// them when it's committed, if they're sending data. The data committed
+// recently is kept in memory, so that it can be appended to the next endpoint
+// when the component fails over to it.
+type router struct {
+	log   log.Logger
+	first *endpointStorage
+
+	mut       sync.RWMutex
+	failover  *failover
+	endpoints []*endpointStorage // The endpoints after the first one.
+
+	replayMut sync.Mutex
+	replay    []replayBatch
+
+	// highest is the highest timestamp committed, in milliseconds.
+	highest atomic.Int64
+
+	activeEndpoint   *prometheus_client.GaugeVec
+	failoverSwitches prometheus_client.Counter
+}
+
+// replayBatch is the data committed by an appender.
+type replayBatch struct {
+	committed time.Time
+	records   []appendRecord
+}
+
+var _ storage.Appendable = (*router)(nil)
+
+// Appender implements storage.Appendable.
+func (r *router) Appender(ctx context.Context) storage.Appender {
+	app := r.first.Appender(ctx)
+
+	r.mut.RLock()
+	enabled := r.failover != nil
+	r.mut.RUnlock()
+	if !enabled {
+		return app
+	}
+	return &failoverAppender{Appender: app, r: r}
+}
+
+// Set enables failover with opts between the endpoints called names, or
+// disables it if opts is nil. endpoints are the storages of the endpoints
+// after the first one. It returns the storages of the endpoints which were
+// removed, which must be closed by the caller.
+func (r *router) Set(opts *FailoverOptions, names []string, endpoints []*endpointStorage) (removed []*endpointStorage) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	for _, e := range r.endpoints {
+		if !slices.Contains(endpoints, e) {
+			removed = append(removed, e)
+		}
+	}
+
+	var f *failover
+	if opts != nil {
+		f = newFailover(*opts, names, r.failover)
+	}
+	if f != r.failover {
+		r.replayMut.Lock()
+		r.replay = nil
+		r.replayMut.Unlock()
+	}
+	r.failover = f
+	r.endpoints = endpoints
+	r.updateActiveEndpoint()
+	return removed
+}
+
+// Endpoint returns the storage of the endpoint called name, or nil if there
+// isn't any.
+func (r *router) Endpoint(name string) *endpointStorage {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	for _, e := range r.endpoints {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// EachEndpoint calls fn for the storage of each endpoint after the first one.
+func (r *router) EachEndpoint(fn func(e *endpointStorage)) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	for _, e := range r.endpoints {
+		fn(e)
+	}
+}
+
+// Check checks the health of the endpoints and changes the active endpoint if
+// needed.
+func (r *router) Check(now time.Time) {
+	r.mut.RLock()
+	f, endpoints := r.failover, r.endpoints
+	r.mut.RUnlock()
+	if f == nil {
+		return
+	}
+
+	// Each endpoint has a single queue, so the lowest timestamp sent by its
+	// remote storage is the highest timestamp sent by the endpoint.
+	sent := make([]int64, 0, len(endpoints)+1)
+	sent = append(sent, r.first.LowestSentTimestamp())
+	for _, e := range endpoints {
+		sent = append(sent, e.LowestSentTimestamp())
+	}
+	// The queues report the timestamps they sent with a precision of a
+	// second, so the highest timestamp committed is rounded down likewise.
+	lags := f.Lags(r.highest.Load()/1000*1000, sent)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.failover != f {
+		return
+	}
+
+	prev := f.active
+	replayFrom := f.ReplayFrom(now)
+	if f.Check(now, lags) {
+		if f.active > prev {
+			// The samples which the previous endpoint may not have sent are
+			// appended before any new sample, so that the next endpoint
+			// receives them in order.
+			r.replayTo(r.endpoints[f.active-1], replayFrom)
+		}
+		level.Info(r.log).Log("msg", "changing the active endpoint", "previous", f.names[prev], "active", f.names[f.active])
+		r.failoverSwitches.Inc()
+		r.updateActiveEndpoint()
+	}
+	r.prune(f.ReplayFrom(now))
+}
+
+// Close closes the storages of the endpoints after the first one.
+func (r *router) Close() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	var errs []error
+	for _, e := range r.endpoints {
+		errs = append(errs, e.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// commit appends the records committed by an appender to the endpoints after
+// the first one which are sending data.
+func (r *router) commit(records []appendRecord) {
+	if len(records) == 0 {
+		return
+	}
+
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	f := r.failover
+	if f == nil {
+		return
+	}
+
+	for _, e := range r.endpoints[:f.active] {
+		e.appendRecords(records)
+	}
+
+	now := time.Now()
+	if f.ReplayFrom(now).Before(now) {
+		r.replayMut.Lock()
+		r.replay = append(r.replay, replayBatch{committed: now, records: records})
+		r.replayMut.Unlock()
+	}
+
+	for _, rec := range records {
+		if rec.kind == recordExemplar || rec.kind == recordMetadata {
+			continue
+		}
+		for {
+			highest := r.highest.Load()
+			if rec.t <= highest || r.highest.CompareAndSwap(highest, rec.t) {
+				break
+			}
+		}
+	}
+}
+
+// replayTo appends the data committed since from to e. It must be called with
+// r.mut held.
+func (r *router) replayTo(e *endpointStorage, from time.Time) {
+	r.replayMut.Lock()
+	defer r.replayMut.Unlock()
+	for _, b := range r.replay {
+		if !b.committed.Before(from) {
+			e.appendRecords(b.records)
+		}
+	}
+}
+
+// prune forgets the data committed before from.
+func (r *router) prune(from time.Time) {
+	r.replayMut.Lock()
+	defer r.replayMut.Unlock()
+	i := 0
+	for i < len(r.replay) && r.replay[i].committed.Before(from) {
+		i++
+	}
+	r.replay = slices.Delete(r.replay, 0, i)
+}
+
+// updateActiveEndpoint updates the metric reporting the active endpoint. It
+// must be called with r.mut held.
+func (r *router) updateActiveEndpoint() {
+	r.activeEndpoint.Reset()
+	if r.failover == nil {
+		return
+	}
+	for i, name := range r.failover.names {
+		if i == r.failover.active {
+			r.activeEndpoint.WithLabelValues(name).Set(1)
+		} else {
+			r.activeEndpoint.WithLabelValues(name).Set(0)
+		}
+	}
+}
+
+// failoverAppender appends to the storage of the first endpoint, and records
+// what it appends so that it can be appended to the other endpoints when it's
+// committed.
+type failoverAppender struct {
+	storage.Appender
+
+	r       *router
+	records []appendRecord
+}
+
+var _ storage.Appender = (*failoverAppender)(nil)
+
+// Append implements storage.Appender.
+func (a *failoverAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := a.Appender.Append(ref, l, t, v)
+	if err == nil {
+		a.records = append(a.records, appendRecord{kind: recordSample, l: l, t: t, v: v})
+	}
+	return ref, err
+}
+
+// AppendExemplar implements storage.Appender.
+func (a *failoverAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	ref, err := a.Appender.AppendExemplar(ref, l, e)
+	if err == nil {
+		a.records = append(a.records, appendRecord{kind: recordExemplar, l: l, e: &e})
+	}
+	return ref, err
+}
+
+// AppendHistogram implements storage.Appender.
+func (a *failoverAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	ref, err := a.Appender.AppendHistogram(ref, l, t, h, fh)
+	if err == nil {
+		a.records = append(a.records, appendRecord{kind: recordHistogram, l: l, t: t, h: h, fh: fh})
+	}
+	return ref, err
+}
+
+// UpdateMetadata implements storage.Appender.
+func (a *failoverAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	ref, err := a.Appender.UpdateMetadata(ref, l, m)
+	if err == nil {
+		a.records = append(a.records, appendRecord{kind: recordMetadata, l: l, m: &m})
+	}
+	return ref, err
+}
+
+// AppendCTZeroSample implements storage.Appender.
+func (a *failoverAppender) AppendCTZeroSample(ref storage.SeriesRef, l labels.Labels, t, ct int64) (storage.SeriesRef, error) {
+	ref, err := a.Appender.AppendCTZeroSample(ref, l, t, ct)
+	if err == nil {
+		a.records = append(a.records, appendRecord{kind: recordCTZeroSample, l: l, t: t, ct: ct})
+	}
+	return ref, err
+}
+
+// Commit implements storage.Appender.
+func (a *failoverAppender) Commit() error {
+	err := a.Appender.Commit()
+	a.r.commit(a.records)
+	a.records = nil
+	return err
+}
+
+// Rollback implements storage.Appender.
+func (a *failoverAppender) Rollback() error {
+	a.records = nil
+	return a.Appender.Rollback()
+}
+
+type recordKind int
+
+const (
+	recordSample recordKind = iota
+	recordHistogram
+	recordCTZeroSample
+	recordExemplar
+	recordMetadata
+)
+
+// appendRecord is a call to an appender, which can be repeated on another
+// appender.
+type appendRecord struct {
+	kind recordKind
+	l    labels.Labels
+	t    int64
+	ct   int64
+	v    float64
+	h    *histogram.Histogram
+	fh   *histogram.FloatHistogram
+	e    *exemplar.Exemplar
+	m    *metadata.Metadata
+}
+
+// appendTo repeats the call on app. The series are looked up by their labels,
+// and refs holds the refs of the series appended so far, which exemplars need.
+func (r appendRecord) appendTo(app storage.Appender, refs map[uint64]storage.SeriesRef) error {
+	var (
+		ref storage.SeriesRef
+		err error
+	)
+	switch r.kind {
+	case recordSample:
+		ref, err = app.Append(0, r.l, r.t, r.v)
+	case recordHistogram:
+		ref, err = app.AppendHistogram(0, r.l, r.t, r.h, r.fh)
+	case recordCTZeroSample:
+		ref, err = app.AppendCTZeroSample(0, r.l, r.t, r.ct)
+		if errors.Is(err, storage.ErrOutOfOrderCT) {
+			// The zero sample was already appended for this created timestamp.
+			return nil
+		}
+	case recordExemplar:
+		_, err = app.AppendExemplar(refs[r.l.Hash()], r.l, *r.e)
+		return err
+	case recordMetadata:
+		_, err = app.UpdateMetadata(0, r.l, *r.m)
+		return err
+	}
+	if err == nil {
+		refs[r.l.Hash()] = ref
+	}
+	return err
+}
@@ -0,0 +1,452 @@
+package remotewrite
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestFailoverArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    string
+		errMsg string
+	}{
+		{
+			name: "valid",
+			cfg: `
+				endpoint {
+					name = "primary"
+					url  = "http://primary/api/v1/write"
+				}
+				endpoint {
+					name = "secondary"
+					url  = "http://secondary/api/v1/write"
+				}
+				failover {
+					max_lag = "30s"
+				}
+			`,
+		},
+		{
+			name: "single endpoint",
+			cfg: `
+				endpoint {
+					name = "primary"
+					url  = "http://primary/api/v1/write"
+				}
+				failover { }
+			`,
+			errMsg: "failover requires at least 2 endpoints, got 1",
+		},
+		{
+			name: "missing name",
+			cfg: `
+				endpoint {
+					name = "primary"
+					url  = "http://primary/api/v1/write"
+				}
+				endpoint {
+					url = "http://secondary/api/v1/write"
+				}
+				failover { }
+			`,
+			errMsg: `endpoint "http://secondary/api/v1/write" must have a name when failover is enabled`,
+		},
+		{
+			name: "duplicate name",
+			cfg: `
+				endpoint {
+					name = "primary"
+					url  = "http://primary/api/v1/write"
+				}
+				endpoint {
+					name = "primary"
+					url  = "http://secondary/api/v1/write"
+				}
+				failover { }
+			`,
+			errMsg: `duplicate endpoint name "primary"`,
+		},
+		{
+			name: "invalid max lag",
+			cfg: `
+				endpoint {
+					name = "primary"
+					url  = "http://primary/api/v1/write"
+				}
+				endpoint {
+					name = "secondary"
+					url  = "http://secondary/api/v1/write"
+				}
+				failover {
+					max_lag = "0s"
+				}
+			`,
+			errMsg: "max_lag must be greater than 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.cfg), &args)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestFailover_Check(t *testing.T) {
+	f := newFailover(FailoverOptions{
+		MaxLag:        time.Second,
+		FailoverAfter: time.Minute,
+		FailbackAfter: 5 * time.Minute,
+	}, []string{"primary", "secondary", "tertiary"}, nil)
+	now := time.Now()
+
+	var (
+		healthy   = time.Duration(0)
+		unhealthy = time.Hour
+	)
+
+	// The active endpoint must be unhealthy for failover_after before failing
+	// over, and recovering resets the period.
+	require.False(t, f.Check(now, []time.Duration{unhealthy, healthy, healthy}))
+	require.False(t, f.Check(now.Add(30*time.Second), []time.Duration{healthy, healthy, healthy}))
+	require.False(t, f.Check(now.Add(time.Minute), []time.Duration{unhealthy, healthy, healthy}))
+	require.True(t, f.Check(now.Add(2*time.Minute), []time.Duration{unhealthy, healthy, healthy}))
+	require.Equal(t, 1, f.active)
+
+	// The secondary fails too.
+	now = now.Add(2 * time.Minute)
+	require.False(t, f.Check(now, []time.Duration{unhealthy, unhealthy, healthy}))
+	require.True(t, f.Check(now.Add(time.Minute), []time.Duration{unhealthy, unhealthy, healthy}))
+	require.Equal(t, 2, f.active)
+
+	// There's no endpoint left to fail over to.
+	now = now.Add(time.Minute)
+	require.False(t, f.Check(now, []time.Duration{unhealthy, unhealthy, unhealthy}))
+	require.False(t, f.Check(now.Add(time.Hour), []time.Duration{unhealthy, unhealthy, unhealthy}))
+	require.Equal(t, 2, f.active)
+
+	// Fail back to the first endpoint which is healthy for failback_after.
+	now = now.Add(time.Hour)
+	require.False(t, f.Check(now, []time.Duration{unhealthy, healthy, healthy}))
+	require.False(t, f.Check(now.Add(time.Minute), []time.Duration{healthy, healthy, healthy}))
+	require.True(t, f.Check(now.Add(5*time.Minute), []time.Duration{healthy, healthy, healthy}))
+	require.Equal(t, 1, f.active)
+
+	// The state is kept when the endpoints don't change.
+	require.Same(t, f, newFailover(DefaultFailoverOptions, []string{"primary", "secondary", "tertiary"}, f))
+	require.Equal(t, 0, newFailover(DefaultFailoverOptions, []string{"primary", "secondary"}, f).active)
+}
+
+func TestFailover(t *testing.T) {
+	prevInterval := failoverCheckInterval
+	failoverCheckInterval = 50 * time.Millisecond
+	t.Cleanup(func() { failoverCheckInterval = prevInterval })
+
+	primaryHealthy := atomic.NewBool(true)
+	primary := newFailoverTestServer(t, primaryHealthy)
+	secondary := newFailoverTestServer(t, atomic.NewBool(true))
+
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(fmt.Sprintf(`
+		endpoint {
+			name           = "primary"
+			url            = "%s/api/v1/write"
+			remote_timeout = "100ms"
+
+			queue_config {
+				batch_send_deadline = "50ms"
+				min_backoff         = "10ms"
+				max_backoff         = "50ms"
+			}
+		}
+		endpoint {
+			name           = "secondary"
+			url            = "%s/api/v1/write"
+			remote_timeout = "100ms"
+
+			queue_config {
+				batch_send_deadline = "50ms"
+				min_backoff         = "10ms"
+				max_backoff         = "50ms"
+			}
+		}
+		failover {
+			max_lag        = "500ms"
+			failover_after = "500ms"
+			failback_after = "500ms"
+		}
+	`, primary.URL, secondary.URL)), &args))
+
+	c, err := New(failoverTestOptions(t), args)
+	require.NoError(t, err)
+
+	// The failover block is experimental.
+	c.opts.MinStability = featuregate.StabilityGenerallyAvailable
+	require.ErrorContains(t, c.Update(args), `failover block is at stability level "experimental"`)
+	c.opts.MinStability = featuregate.StabilityExperimental
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+
+	// Keep sending samples, and record them. The samples of the delayed series
+	// are older than the ones of the other series committed with them, so an
+	// endpoint can send the newest samples while the delayed ones are pending.
+	// They start a bit later, since the queues skip the samples older than
+	// when they started.
+	const delay = 500
+	var (
+		writtenMut sync.Mutex
+		written    []failoverTestSample
+		lastTs     int64
+		startTs    = time.Now().Add(time.Second).UnixMilli()
+	)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				writtenMut.Lock()
+				ts := max(time.Now().UnixMilli(), lastTs+1)
+				samples := []failoverTestSample{{"test_metric", ts}}
+				if ts-delay > startTs {
+					samples = append(samples, failoverTestSample{"test_metric_delayed", ts - delay})
+				}
+				app := c.receiver.Appender(ctx)
+				var err error
+				for _, s := range samples {
+					if err == nil {
+						_, err = app.Append(0, labels.FromStrings("__name__", s.name), s.ts, 1)
+					}
+				}
+				if err == nil && app.Commit() == nil {
+					written = append(written, samples...)
+					lastTs = ts
+				}
+				writtenMut.Unlock()
+			}
+		}
+	}()
+	writtenSince := func(i int) []failoverTestSample {
+		writtenMut.Lock()
+		defer writtenMut.Unlock()
+		return slices.Clone(written[i:])
+	}
+
+	isActive := func(name string) func() bool {
+		return func() bool {
+			return testutil.ToFloat64(c.router.activeEndpoint.WithLabelValues(name)) == 1
+		}
+	}
+	receivedAll := func(srv *failoverTestServer, samples []failoverTestSample) func() bool {
+		return func() bool {
+			for _, s := range samples {
+				if !srv.Received(s) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	// The primary is active and the secondary doesn't send anything.
+	require.True(t, isActive("primary")())
+	require.Eventually(t, func() bool { return primary.Count() > 10 }, 10*time.Second, 50*time.Millisecond)
+	require.Eventually(t, func() bool {
+		for _, s := range writtenSince(0) {
+			if s.name == "test_metric_delayed" {
+				return primary.Received(s)
+			}
+		}
+		return false
+	}, 10*time.Second, 50*time.Millisecond)
+	require.Zero(t, secondary.Count())
+
+	// The primary is unhealthy, so the component fails over to the secondary.
+	// The samples written during the outage reach the secondary, including the
+	// ones written before it became active.
+	outageStart := len(writtenSince(0))
+	primaryHealthy.Store(false)
+	require.Eventually(t, isActive("secondary"), 10*time.Second, 50*time.Millisecond)
+	outage := writtenSince(outageStart)
+	require.NotEmpty(t, outage)
+	require.Eventually(t, receivedAll(secondary, outage), 10*time.Second, 50*time.Millisecond)
+
+	// Once the primary recovers, it catches up on the samples it missed and
+	// the component fails back.
+	primaryHealthy.Store(true)
+	require.Eventually(t, isActive("primary"), 10*time.Second, 50*time.Millisecond)
+	require.Eventually(t, receivedAll(primary, writtenSince(0)), 10*time.Second, 50*time.Millisecond)
+	require.Equal(t, 2.0, testutil.ToFloat64(c.router.failoverSwitches))
+}
+
+func TestFailover_Update(t *testing.T) {
+	cfg := `
+		endpoint {
+			name = "primary"
+			url  = "http://primary/api/v1/write"
+		}
+		endpoint {
+			name = "secondary"
+			url  = "http://secondary/api/v1/write"
+		}
+	`
+	var stock, withFailover, renamed Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(cfg), &stock))
+	require.NoError(t, syntax.Unmarshal([]byte(cfg+"failover { }"), &withFailover))
+	require.NoError(t, syntax.Unmarshal([]byte(strings.ReplaceAll(cfg, `"secondary"`, `"backup"`)+"failover { }"), &renamed))
+
+	opts := failoverTestOptions(t)
+	c, err := New(opts, withFailover)
+	require.NoError(t, err)
+	defer c.storage.Close()
+	endpointDirs := func() []string {
+		entries, _ := os.ReadDir(filepath.Join(opts.DataPath, "failover"))
+		var dirs []string
+		for _, e := range entries {
+			dirs = append(dirs, e.Name())
+		}
+		return dirs
+	}
+
+	// The endpoints after the first one have their own storage.
+	require.NotNil(t, c.router.Endpoint("secondary"))
+	require.Equal(t, []string{"endpoint-secondary"}, endpointDirs())
+
+	// The storage of an endpoint which is removed is closed and deleted.
+	require.NoError(t, c.Update(renamed))
+	require.Nil(t, c.router.Endpoint("secondary"))
+	require.NotNil(t, c.router.Endpoint("backup"))
+	require.Equal(t, []string{"endpoint-backup"}, endpointDirs())
+
+	// Failover can be disabled and enabled again.
+	require.NoError(t, c.Update(stock))
+	require.Nil(t, c.router.Endpoint("backup"))
+	require.Empty(t, endpointDirs())
+	require.NoError(t, c.Update(withFailover))
+	require.NotNil(t, c.router.Endpoint("secondary"))
+	require.NoError(t, c.Update(stock))
+}
+
+// failoverTestOptions returns the options of the components created by the
+// failover tests.
+func failoverTestOptions(t *testing.T) component.Options {
+	return component.Options{
+		ID:            "prometheus.remote_write.test",
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus_client.NewRegistry(),
+		DataPath:      t.TempDir(),
+		MinStability:  featuregate.StabilityExperimental,
+		OnStateChange: func(e component.Exports) {},
+		GetServiceData: func(name string) (interface{}, error) {
+			switch name {
+			case labelstore.ServiceName:
+				return labelstore.New(nil, prometheus_client.DefaultRegisterer), nil
+			case livedebugging.ServiceName:
+				return livedebugging.NewLiveDebugging(), nil
+			default:
+				return nil, fmt.Errorf("service %q does not exist", name)
+			}
+		},
+	}
+}
+
+// failoverTestSample is a sample written by TestFailover, identified by the
+// name of its series and its timestamp.
+type failoverTestSample struct {
+	name string
+	ts   int64
+}
+
+// failoverTestServer is a remote_write server which records the samples it
+// receives while healthy, and fails requests otherwise.
+type failoverTestServer struct {
+	*httptest.Server
+
+	mut      sync.Mutex
+	received map[failoverTestSample]struct{}
+}
+
+func newFailoverTestServer(t *testing.T, healthy *atomic.Bool) *failoverTestServer {
+	srv := &failoverTestServer{received: make(map[failoverTestSample]struct{})}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		req, err := remote.DecodeWriteRequest(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		srv.mut.Lock()
+		defer srv.mut.Unlock()
+		for _, ts := range req.Timeseries {
+			var name string
+			for _, l := range ts.Labels {
+				if l.Name == "__name__" {
+					name = l.Value
+				}
+			}
+			for _, s := range ts.Samples {
+				srv.received[failoverTestSample{name, s.Timestamp}] = struct{}{}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Received returns whether the server received sample.
+func (s *failoverTestServer) Received(sample failoverTestSample) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	_, ok := s.received[sample]
+	return ok
+}
+
+// Count returns the number of samples received by the server.
+func (s *failoverTestServer) Count() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return len(s.received)
+}
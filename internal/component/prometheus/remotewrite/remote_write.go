@@ -2,8 +2,9 @@ package remotewrite
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
@@ -17,13 +18,14 @@ import (
 	"github.com/grafana/alloy/internal/runtime/logging/level"
 	"github.com/grafana/alloy/internal/service/labelstore"
 	"github.com/grafana/alloy/internal/service/livedebugging"
-	"github.com/grafana/alloy/internal/static/metrics/wal"
 	"github.com/grafana/alloy/internal/useragent"
+	"github.com/grafana/alloy/internal/util"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/metadata"
-	"github.com/prometheus/prometheus/model/timestamp"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/storage/remote"
 	"go.uber.org/atomic"
@@ -54,10 +56,9 @@ type Component struct {
 	log  log.Logger
 	opts component.Options
 
-	walStore    *wal.Storage
-	remoteStore *remote.Storage
-	storage     storage.Storage
-	exited      atomic.Bool
+	storage *endpointStorage
+	router  *router
+	exited  atomic.Bool
 
 	mut sync.RWMutex
 	cfg Arguments
@@ -78,17 +79,13 @@ func New(o component.Options, c Arguments) (*Component, error) {
 	oldDataPath := filepath.Join(o.DataPath, "wal", o.ID)
 	_ = os.RemoveAll(oldDataPath)
 
-	walLogger := log.With(o.Logger, "subcomponent", "wal")
-	walStorage, err := wal.NewStorage(walLogger, o.Registerer, o.DataPath)
+	// The storage only writes metadata to the WAL once an endpoint which
+	// sends it with Remote Write 2.0 is configured in Update.
+	store, err := newEndpointStorage(o.Logger, o.Registerer, "", o.DataPath)
 	if err != nil {
 		return nil, err
 	}
 
-	remoteLogger := log.With(o.Logger, "subcomponent", "rw")
-	remoteStore := remote.NewStorage(remoteLogger, o.Registerer, startTime, o.DataPath, remoteFlushDeadline, nil, false)
-
-	walStorage.SetNotifier(remoteStore)
-
 	service, err := o.GetServiceData(labelstore.ServiceName)
 	if err != nil {
 		return nil, err
@@ -103,14 +100,29 @@ func New(o component.Options, c Arguments) (*Component, error) {
 	res := &Component{
 		log:                o.Logger,
 		opts:               o,
-		walStore:           walStorage,
-		remoteStore:        remoteStore,
-		storage:            storage.NewFanout(o.Logger, walStorage, remoteStore),
+		storage:            store,
 		debugDataPublisher: debugDataPublisher.(livedebugging.DebugDataPublisher),
 	}
+	res.router = &router{
+		log:   o.Logger,
+		first: store,
+
+		activeEndpoint: prometheus_client.NewGaugeVec(prometheus_client.GaugeOpts{
+			Name: "prometheus_remote_write_failover_active_endpoint",
+			Help: "Set to 1 for the endpoint which is active when failover is enabled, and to 0 for the others.",
+		}, []string{remoteNameLabel}),
+		failoverSwitches: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "prometheus_remote_write_failover_switches_total",
+			Help: "Total number of times the active endpoint changed when failover is enabled.",
+		}),
+	}
+	if o.Registerer != nil {
+		res.router.activeEndpoint = util.MustRegisterOrGet(o.Registerer, res.router.activeEndpoint).(*prometheus_client.GaugeVec)
+		res.router.failoverSwitches = util.MustRegisterOrGet(o.Registerer, res.router.failoverSwitches).(prometheus_client.Counter)
+	}
 	componentID := livedebugging.ComponentID(res.opts.ID)
 	res.receiver = prometheus.NewInterceptor(
-		res.storage,
+		res.router,
 		ls,
 
 		// In the methods below, conversion is needed because remote_writes assume
@@ -136,6 +148,7 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				func() string {
 					return fmt.Sprintf("sample: ts=%d, labels=%s, value=%f", t, l, v)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -164,6 +177,7 @@ func New(o component.Options, c Arguments) (*Component, error) {
 					}
 					return data
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -184,6 +198,28 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				func() string {
 					return fmt.Sprintf("metadata: labels=%s, type=%q, unit=%q, help=%q", l, m.Type, m.Unit, m.Help)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
+			))
+			return globalRef, nextErr
+		}),
+		prometheus.WithCTZeroSampleHook(func(globalRef storage.SeriesRef, l labels.Labels, t, ct int64, next storage.Appender) (storage.SeriesRef, error) {
+			if res.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
+			newRef, nextErr := next.AppendCTZeroSample(storage.SeriesRef(localID), l, t, ct)
+			if localID == 0 && newRef != 0 {
+				ls.GetOrAddLink(res.opts.ID, uint64(newRef), l)
+			}
+			res.debugDataPublisher.PublishIfActive(livedebugging.NewData(
+				componentID,
+				livedebugging.PrometheusMetric,
+				1,
+				func() string {
+					return fmt.Sprintf("created_timestamp: ts=%d, labels=%s, created=%d", t, l, ct)
+				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -204,6 +240,7 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				func() string {
 					return fmt.Sprintf("exemplar: ts=%d, labels=%s, exemplar_labels=%s, value=%f", e.Ts, l, e.Labels, e.Value)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -230,22 +267,27 @@ func (c *Component) Run(ctx context.Context) error {
 		c.exited.Store(true)
 
 		level.Debug(c.log).Log("msg", "closing storage")
-		err := c.storage.Close()
+		err := errors.Join(c.storage.Close(), c.router.Close())
 		level.Debug(c.log).Log("msg", "storage closed")
 		if err != nil {
 			level.Error(c.log).Log("msg", "error when closing storage", "err", err)
 		}
 	}()
 
-	// Track the last timestamp we truncated for to prevent segments from getting
-	// deleted until at least some new data has been sent.
-	var lastTs = int64(math.MinInt64)
+	truncateTimer := time.NewTimer(c.truncateFrequency())
+	defer truncateTimer.Stop()
+	failoverTicker := time.NewTicker(failoverCheckInterval)
+	defer failoverTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-time.After(c.truncateFrequency()):
+		case <-failoverTicker.C:
+			c.router.Check(time.Now())
+		case <-truncateTimer.C:
+			truncateTimer.Reset(c.truncateFrequency())
+
 			// We retrieve the current min/max keepalive time at once, since
 			// retrieving them separately could lead to issues where we have an older
 			// value for min which is now larger than max.
@@ -256,39 +298,12 @@ func (c *Component) Run(ctx context.Context) error {
 			)
 			c.mut.RUnlock()
 
-			// The timestamp ts is used to determine which series are not receiving
-			// samples and may be deleted from the WAL. Their most recent append
-			// timestamp is compared to ts, and if that timestamp is older than ts,
-			// they are considered inactive and may be deleted.
-			//
-			// Subtracting a duration from ts will delay when it will be considered
-			// inactive and scheduled for deletion.
-			ts := c.remoteStore.LowestSentTimestamp() - minWALTime.Milliseconds()
-			if ts < 0 {
-				ts = 0
-			}
-
-			// Network issues can prevent the result of LowestSentTimestamp from
-			// changing. We don't want data in the WAL to grow forever, so we set a cap
-			// on the maximum age data can be. If our ts is older than this cutoff point,
-			// we'll shift it forward to start deleting very stale data.
-			if maxTS := timestamp.FromTime(time.Now().Add(-maxWALTime)); ts < maxTS {
-				ts = maxTS
-			}
-
-			if ts == lastTs {
-				level.Debug(c.log).Log("msg", "not truncating the WAL, remote_write timestamp is unchanged", "ts", ts)
-				continue
-			}
-			lastTs = ts
-
-			level.Debug(c.log).Log("msg", "truncating the WAL", "ts", ts)
-			err := c.walStore.Truncate(ts)
-			if err != nil {
-				// The only issue here is larger disk usage and a greater replay time,
-				// so we'll only log this as a warning.
-				level.Warn(c.log).Log("msg", "could not truncate WAL", "err", err)
-			}
+			c.storage.Truncate(minWALTime, maxWALTime)
+			// When failover is enabled, the endpoints after the first one have
+			// their own WAL.
+			c.router.EachEndpoint(func(e *endpointStorage) {
+				e.Truncate(minWALTime, maxWALTime)
+			})
 		}
 	}
 }
@@ -303,6 +318,12 @@ func (c *Component) truncateFrequency() time.Duration {
 func (c *Component) Update(newConfig component.Arguments) error {
 	cfg := newConfig.(Arguments)
 
+	if cfg.Failover != nil {
+		if err := featuregate.CheckAllowed(featuregate.StabilityExperimental, c.opts.MinStability, "failover block"); err != nil {
+			return err
+		}
+	}
+
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
@@ -318,13 +339,102 @@ func (c *Component) Update(newConfig component.Arguments) error {
 		cfg.Headers[alloyseed.LegacyHeaderName] = uid
 		cfg.Headers[alloyseed.HeaderName] = uid
 	}
-	err = c.remoteStore.ApplyConfig(convertedConfig)
-	if err != nil {
-		return err
+
+	if cfg.Failover == nil {
+		err = c.storage.ApplyConfig(convertedConfig)
+		if err != nil {
+			return err
+		}
+		c.closeEndpoints(c.router.Set(nil, nil, nil))
+	} else {
+		err = c.updateFailover(cfg, convertedConfig)
+		if err != nil {
+			return err
+		}
 	}
 
 	c.cfg = cfg
 	return nil
 }
 
+// updateFailover applies cfg when failover is enabled. The storage of the
+// component sends to the first endpoint, and each of the other endpoints has
+// its own storage. It must be called with c.mut held.
+func (c *Component) updateFailover(cfg Arguments, convertedConfig *config.Config) error {
+	var (
+		endpoints []*endpointStorage
+		created   []*endpointStorage
+		names     = []string{cfg.Endpoints[0].Name}
+	)
+	for i, rwConfig := range convertedConfig.RemoteWriteConfigs[1:] {
+		name := cfg.Endpoints[i+1].Name
+		names = append(names, name)
+
+		e := c.router.Endpoint(name)
+		if e == nil {
+			var err error
+			e, err = newEndpointStorage(log.With(c.log, "endpoint", name), c.opts.Registerer, name, filepath.Join(c.failoverDataPath(), endpointDir(name)))
+			if err != nil {
+				c.closeEndpoints(created)
+				return fmt.Errorf("creating the storage of endpoint %q: %w", name, err)
+			}
+			created = append(created, e)
+		}
+		endpoints = append(endpoints, e)
+
+		endpointConfig := *convertedConfig
+		endpointConfig.RemoteWriteConfigs = []*config.RemoteWriteConfig{rwConfig}
+		if err := e.ApplyConfig(&endpointConfig); err != nil {
+			c.closeEndpoints(created)
+			return err
+		}
+	}
+
+	firstConfig := *convertedConfig
+	firstConfig.RemoteWriteConfigs = convertedConfig.RemoteWriteConfigs[:1]
+	if err := c.storage.ApplyConfig(&firstConfig); err != nil {
+		c.closeEndpoints(created)
+		return err
+	}
+
+	c.closeEndpoints(c.router.Set(cfg.Failover, names, endpoints))
+	return nil
+}
+
+// closeEndpoints closes the storages of endpoints and deletes the directories
+// of the endpoints which aren't used anymore.
+func (c *Component) closeEndpoints(endpoints []*endpointStorage) {
+	for _, e := range endpoints {
+		if err := e.Close(); err != nil {
+			level.Warn(c.log).Log("msg", "error when closing the storage of an endpoint", "endpoint", e.name, "err", err)
+		}
+	}
+
+	used := make(map[string]bool)
+	c.router.EachEndpoint(func(e *endpointStorage) {
+		used[endpointDir(e.name)] = true
+	})
+	entries, err := os.ReadDir(c.failoverDataPath())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !used[entry.Name()] {
+			_ = os.RemoveAll(filepath.Join(c.failoverDataPath(), entry.Name()))
+		}
+	}
+}
+
+// failoverDataPath returns the directory which holds the data of the
+// endpoints after the first one when failover is enabled.
+func (c *Component) failoverDataPath() string {
+	return filepath.Join(c.opts.DataPath, "failover")
+}
+
+// endpointDir returns the name of the directory which holds the data of the
+// endpoint called name when failover is enabled.
+func endpointDir(name string) string {
+	return "endpoint-" + url.PathEscape(name)
+}
+
 func (c *Component) LiveDebugging() {}
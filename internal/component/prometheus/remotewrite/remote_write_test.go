@@ -7,12 +7,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/alloy/internal/component/prometheus/receive_http"
 	"github.com/grafana/alloy/internal/component/prometheus/remotewrite"
 	"github.com/grafana/alloy/internal/runtime/componenttest"
 	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
 	"github.com/grafana/alloy/syntax"
+	"github.com/phayes/freeport"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
 	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/storage/remote"
 	"github.com/stretchr/testify/require"
 )
@@ -180,6 +187,107 @@ func TestUpdate(t *testing.T) {
 	}})
 }
 
+// TestReceiveHTTPCompatibility ensures that every protobuf message which
+// prometheus.remote_write can send is accepted by prometheus.receive_http.
+func TestReceiveHTTPCompatibility(t *testing.T) {
+	tests := []struct {
+		protobufMessage config.RemoteWriteProtoMsg
+		expectMetadata  bool
+	}{
+		// Remote Write 1.0 only sends metadata collected from scrape targets.
+		{protobufMessage: config.RemoteWriteProtoMsgV1, expectMetadata: false},
+		// Remote Write 2.0 sends the metadata from the WAL with every series.
+		{protobufMessage: config.RemoteWriteProtoMsgV2, expectMetadata: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.protobufMessage), func(t *testing.T) {
+			var (
+				samples = make(chan testappender.MetricSample, 100)
+				metas   = make(chan metadata.Metadata, 100)
+			)
+
+			port, err := freeport.GetFreePort()
+			require.NoError(t, err)
+			grpcPort, err := freeport.GetFreePort()
+			require.NoError(t, err)
+
+			var receiveArgs receive_http.Arguments
+			receiveArgs.SetToDefault()
+			receiveArgs.Server.HTTP.ListenAddress = "127.0.0.1"
+			receiveArgs.Server.HTTP.ListenPort = port
+			receiveArgs.Server.GRPC.ListenAddress = "127.0.0.1"
+			receiveArgs.Server.GRPC.ListenPort = grpcPort
+			receiveArgs.ForwardTo = []storage.Appendable{testappender.FuncAppendable{
+				Sample:   func(s testappender.MetricSample) { samples <- s },
+				Metadata: func(_ labels.Labels, m metadata.Metadata) { metas <- m },
+			}}
+
+			receiver, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.receive_http")
+			require.NoError(t, err)
+			go func() {
+				require.NoError(t, receiver.Run(componenttest.TestContext(t), receiveArgs))
+			}()
+			require.NoError(t, receiver.WaitRunning(5*time.Second))
+
+			args := testArgsForConfig(t, fmt.Sprintf(`
+				endpoint {
+					url              = "http://127.0.0.1:%d/api/v1/metrics/write"
+					remote_timeout   = "100ms"
+					protobuf_message = %q
+
+					queue_config {
+						batch_send_deadline = "100ms"
+					}
+				}
+			`, port, tt.protobufMessage))
+			tc, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.remote_write")
+			require.NoError(t, err)
+			go func() {
+				require.NoError(t, tc.Run(componenttest.TestContext(t), args))
+			}()
+			require.NoError(t, tc.WaitRunning(5*time.Second))
+
+			// Use future timestamps since remote_write will ignore any sample
+			// which is earlier than the time when it started.
+			createdTimestamp := time.Now().Add(time.Minute).UnixMilli()
+			sampleTimestamp := createdTimestamp + 1000
+			lbls := labels.FromStrings("__name__", "requests_total", "foo", "bar")
+
+			appender := tc.Exports().(remotewrite.Exports).Receiver.Appender(t.Context())
+			ref, err := appender.AppendCTZeroSample(0, lbls, sampleTimestamp, createdTimestamp)
+			require.NoError(t, err)
+			ref, err = appender.Append(ref, lbls, sampleTimestamp, 12)
+			require.NoError(t, err)
+			_, err = appender.UpdateMetadata(ref, lbls, metadata.Metadata{Type: model.MetricTypeCounter})
+			require.NoError(t, err)
+			require.NoError(t, appender.Commit())
+
+			for _, expect := range []testappender.MetricSample{
+				{Labels: lbls, Timestamp: createdTimestamp, Value: 0},
+				{Labels: lbls, Timestamp: sampleTimestamp, Value: 12},
+			} {
+				select {
+				case <-time.After(time.Minute):
+					require.FailNow(t, "timed out waiting for samples")
+				case actual := <-samples:
+					require.Equal(t, expect, actual)
+				}
+			}
+
+			if !tt.expectMetadata {
+				return
+			}
+			select {
+			case <-time.After(time.Minute):
+				require.FailNow(t, "timed out waiting for metadata")
+			case actual := <-metas:
+				require.Equal(t, model.MetricTypeCounter, actual.Type)
+			}
+		})
+	}
+}
+
 func assertReceived(t *testing.T, writeResult chan *prompb.WriteRequest, expect []prompb.TimeSeries) {
 	select {
 	case <-time.After(time.Minute):
@@ -52,6 +52,12 @@ var (
 		MaxKeepaliveTime:  8 * time.Hour,
 	}
 
+	DefaultFailoverOptions = FailoverOptions{
+		MaxLag:        time.Minute,
+		FailoverAfter: time.Minute,
+		FailbackAfter: 5 * time.Minute,
+	}
+
 	errTooManyAuth = errors.New("at most one of sigv4, azuread, basic_auth, oauth2, bearer_token & bearer_token_file must be configured")
 )
 
@@ -61,6 +67,7 @@ type Arguments struct {
 	ExternalLabels map[string]string  `alloy:"external_labels,attr,optional"`
 	Endpoints      []*EndpointOptions `alloy:"endpoint,block,optional"`
 	WALOptions     WALOptions         `alloy:"wal,block,optional"`
+	Failover       *FailoverOptions   `alloy:"failover,block,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -68,6 +75,30 @@ func (rc *Arguments) SetToDefault() {
 	*rc = DefaultArguments
 }
 
+// Validate implements syntax.Validator.
+func (rc *Arguments) Validate() error {
+	if rc.Failover == nil {
+		return nil
+	}
+
+	if len(rc.Endpoints) < 2 {
+		return fmt.Errorf("failover requires at least 2 endpoints, got %d", len(rc.Endpoints))
+	}
+	// The names identify the endpoints in the metrics used to check their
+	// health, so they must be stable and unique.
+	names := make(map[string]struct{}, len(rc.Endpoints))
+	for _, e := range rc.Endpoints {
+		if e.Name == "" {
+			return fmt.Errorf("endpoint %q must have a name when failover is enabled", e.URL)
+		}
+		if _, ok := names[e.Name]; ok {
+			return fmt.Errorf("duplicate endpoint name %q", e.Name)
+		}
+		names[e.Name] = struct{}{}
+	}
+	return nil
+}
+
 // EndpointOptions describes an individual location for where metrics in the WAL
 // should be delivered to using the remote_write protocol.
 type EndpointOptions struct {
@@ -77,6 +108,7 @@ type EndpointOptions struct {
 	Headers              map[string]string       `alloy:"headers,attr,optional"`
 	SendExemplars        bool                    `alloy:"send_exemplars,attr,optional"`
 	SendNativeHistograms bool                    `alloy:"send_native_histograms,attr,optional"`
+	ProtobufMessage      string                  `alloy:"protobuf_message,attr,optional"`
 	HTTPClientConfig     *types.HTTPClientConfig `alloy:",squash"`
 	QueueOptions         *QueueOptions           `alloy:"queue_config,block,optional"`
 	MetadataOptions      *MetadataOptions        `alloy:"metadata_config,block,optional"`
@@ -90,6 +122,7 @@ func (r *EndpointOptions) SetToDefault() {
 	*r = EndpointOptions{
 		RemoteTimeout:    30 * time.Second,
 		SendExemplars:    true,
+		ProtobufMessage:  string(config.RemoteWriteProtoMsgV1),
 		HTTPClientConfig: types.CloneDefaultHTTPClientConfig(),
 	}
 }
@@ -111,6 +144,10 @@ func (r *EndpointOptions) Validate() error {
 		}
 	}
 
+	if err := config.RemoteWriteProtoMsg(r.ProtobufMessage).Validate(); err != nil {
+		return err
+	}
+
 	if r.SigV4 != nil {
 		if r.AzureAD != nil || isAuthSetInHttpClientConfig(r.HTTPClientConfig) {
 			return errTooManyAuth
@@ -223,6 +260,34 @@ func (o *WALOptions) Validate() error {
 	return nil
 }
 
+// FailoverOptions configures the failover between the endpoints. When it's
+// set, metrics are only sent to one endpoint at a time, in the order of the
+// endpoints.
+type FailoverOptions struct {
+	MaxLag        time.Duration `alloy:"max_lag,attr,optional"`
+	FailoverAfter time.Duration `alloy:"failover_after,attr,optional"`
+	FailbackAfter time.Duration `alloy:"failback_after,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (o *FailoverOptions) SetToDefault() {
+	*o = DefaultFailoverOptions
+}
+
+// Validate implements syntax.Validator.
+func (o *FailoverOptions) Validate() error {
+	switch {
+	case o.MaxLag <= 0:
+		return fmt.Errorf("max_lag must be greater than 0")
+	case o.FailoverAfter < 0:
+		return fmt.Errorf("failover_after must not be negative")
+	case o.FailbackAfter < 0:
+		return fmt.Errorf("failback_after must not be negative")
+	}
+
+	return nil
+}
+
 // Exports are the set of fields exposed by the prometheus.remote_write
 // component.
 type Exports struct {
@@ -243,16 +308,13 @@ func convertConfigs(cfg Arguments) (*config.Config, error) {
 			Name:                 rw.Name,
 			SendExemplars:        rw.SendExemplars,
 			SendNativeHistograms: rw.SendNativeHistograms,
-
-			//TODO: Make this configurable when we upgrade to Prometheus v3?
-			ProtobufMessage: config.RemoteWriteProtoMsgV1,
-
-			WriteRelabelConfigs: alloy_relabel.ComponentToPromRelabelConfigs(rw.WriteRelabelConfigs),
-			HTTPClientConfig:    *rw.HTTPClientConfig.Convert(),
-			QueueConfig:         rw.QueueOptions.toPrometheusType(),
-			MetadataConfig:      rw.MetadataOptions.toPrometheusType(),
-			SigV4Config:         rw.SigV4.toPrometheusType(),
-			AzureADConfig:       rw.AzureAD.toPrometheusType(),
+			ProtobufMessage:      config.RemoteWriteProtoMsg(rw.ProtobufMessage),
+			WriteRelabelConfigs:  alloy_relabel.ComponentToPromRelabelConfigs(rw.WriteRelabelConfigs),
+			HTTPClientConfig:     *rw.HTTPClientConfig.Convert(),
+			QueueConfig:          rw.QueueOptions.toPrometheusType(),
+			MetadataConfig:       rw.MetadataOptions.toPrometheusType(),
+			SigV4Config:          rw.SigV4.toPrometheusType(),
+			AzureADConfig:        rw.AzureAD.toPrometheusType(),
 		})
 	}
 
@@ -0,0 +1,389 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.rules.local",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.rules.local component.
+type Arguments struct {
+	// Where the raw metrics and the results of the rules are forwarded to.
+	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// Rule groups in the Prometheus rule file format, either inline or in a
+	// file.
+	Rules     string `alloy:"rules,attr,optional"`
+	RulesFile string `alloy:"rules_file,attr,optional"`
+
+	EvaluationInterval time.Duration `alloy:"evaluation_interval,attr,optional"`
+	Window             time.Duration `alloy:"window,attr,optional"`
+	MaxSeries          int           `alloy:"max_series,attr,optional"`
+	LookbackDelta      time.Duration `alloy:"lookback_delta,attr,optional"`
+	DropInputs         bool          `alloy:"drop_inputs,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		EvaluationInterval: time.Minute,
+		Window:             10 * time.Minute,
+		MaxSeries:          100_000,
+		LookbackDelta:      5 * time.Minute,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	switch {
+	case args.Rules == "" && args.RulesFile == "":
+		return errors.New("one of rules or rules_file must be set")
+	case args.Rules != "" && args.RulesFile != "":
+		return errors.New("only one of rules or rules_file can be set")
+	case args.EvaluationInterval <= 0:
+		return fmt.Errorf("evaluation_interval must be greater than 0")
+	case args.Window <= 0:
+		return fmt.Errorf("window must be greater than 0")
+	case args.LookbackDelta <= 0:
+		return fmt.Errorf("lookback_delta must be greater than 0")
+	case args.MaxSeries <= 0:
+		return fmt.Errorf("max_series must be greater than 0 and is %d", args.MaxSeries)
+	}
+
+	if args.Rules != "" {
+		rgs, errs := rulefmt.Parse([]byte(args.Rules))
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid rules: %w", errors.Join(errs...))
+		}
+		if err := validateRuleGroups(rgs); err != nil {
+			return fmt.Errorf("invalid rules: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateRuleGroups ensures that only recording rules are used, since the
+// component has nowhere to send alerts to.
+func validateRuleGroups(rgs *rulefmt.RuleGroups) error {
+	for _, g := range rgs.Groups {
+		for _, r := range g.Rules {
+			if r.Alert.Value != "" {
+				return fmt.Errorf("group %q: alerting rule %q isn't supported, only recording rules are", g.Name, r.Alert.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.rules.local
+// component.
+type Exports struct {
+	Receiver storage.Appendable `alloy:"receiver,attr"`
+}
+
+// Component implements the prometheus.rules.local component.
+type Component struct {
+	opts component.Options
+
+	mut  sync.RWMutex
+	args Arguments
+
+	window           *window
+	windowAppendable *prometheus.Interceptor
+	results          *prometheus.Fanout
+	inputs           *prometheus.Fanout
+	manager          *rules.Manager
+	loader           *ruleLoader
+
+	cancel context.CancelFunc
+	exited atomic.Bool
+
+	// lookbackDelta is passed to every query, so that it can change without
+	// recreating the engine.
+	lookbackDelta atomic.Duration
+
+	droppedSamples prometheus_client.Counter
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.rules.local component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	w, err := newWindow(o.Logger, filepath.Join(o.DataPath, "window"), args.Window, args.MaxSeries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create window: %w", err)
+	}
+
+	c := &Component{
+		opts:   o,
+		window: w,
+		loader: &ruleLoader{},
+	}
+
+	c.droppedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "alloy_prometheus_rules_local_window_dropped_samples_total",
+		Help: "Total number of samples which couldn't be added to the window, because they were too old or the window held too many series.",
+	})
+	windowSeries := prometheus_client.NewGaugeFunc(prometheus_client.GaugeOpts{
+		Name: "alloy_prometheus_rules_local_window_series",
+		Help: "Number of series in the window.",
+	}, func() float64 { return float64(w.NumSeries()) })
+	for _, metric := range []prometheus_client.Collector{c.droppedSamples, windowSeries} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	// Both the raw metrics and the results of the rules are added to the
+	// window, so that rules can use the results of previous rules.
+	windowAppendable := prometheus.NewInterceptor(
+		w,
+		ls,
+
+		// The window assigns its own references, so the global ones can't be
+		// passed to it.
+
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if _, err := next.Append(0, l, t, v); err != nil {
+				c.droppedSamples.Inc()
+			}
+			return ref, nil
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if _, err := next.AppendHistogram(0, l, t, h, fh); err != nil {
+				c.droppedSamples.Inc()
+			}
+			return ref, nil
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar, _ storage.Appender) (storage.SeriesRef, error) {
+			return ref, nil
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, _ labels.Labels, _ metadata.Metadata, _ storage.Appender) (storage.SeriesRef, error) {
+			return ref, nil
+		}),
+		prometheus.WithCTZeroSampleHook(func(ref storage.SeriesRef, _ labels.Labels, _, _ int64, _ storage.Appender) (storage.SeriesRef, error) {
+			return ref, nil
+		}),
+	)
+
+	c.windowAppendable = windowAppendable
+	// The children of both fanouts are set in Update, which decides whether
+	// the raw metrics are also forwarded.
+	c.results = prometheus.NewFanout(nil, o.ID, o.Registerer, ls)
+	c.inputs = c.results.WithChildren(nil)
+
+	engine := promql.NewEngine(promql.EngineOpts{
+		Logger:     o.Logger,
+		Reg:        o.Registerer,
+		MaxSamples: 50_000_000,
+		Timeout:    2 * time.Minute,
+		NoStepSubqueryIntervalFn: func(int64) int64 {
+			c.mut.RLock()
+			defer c.mut.RUnlock()
+			return c.args.EvaluationInterval.Milliseconds()
+		},
+		EnableAtModifier:     true,
+		EnableNegativeOffset: true,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.manager = rules.NewManager(&rules.ManagerOptions{
+		Appendable:  c.results,
+		Queryable:   w,
+		QueryFunc:   c.queryFunc(engine),
+		Context:     ctx,
+		Logger:      o.Logger,
+		Registerer:  o.Registerer,
+		GroupLoader: c.loader,
+	})
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.inputs})
+
+	if err := c.Update(args); err != nil {
+		cancel()
+		_ = w.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.exited.Store(true)
+		c.manager.Stop()
+		c.cancel()
+		if err := c.window.Close(); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "error when closing window", "err", err)
+		}
+	}()
+
+	go c.manager.Run()
+
+	for {
+		c.mut.RLock()
+		truncateFrequency, length := c.args.EvaluationInterval, c.args.Window
+		c.mut.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(truncateFrequency):
+			mint := timestamp.FromTime(time.Now().Add(-length))
+			if err := c.window.Truncate(mint); err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to truncate window", "err", err)
+			}
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+
+	identifier := newArgs.RulesFile
+	if identifier == "" {
+		identifier = c.opts.ID
+	}
+	c.loader.Set(identifier, newArgs.Rules)
+	err := c.manager.Update(newArgs.EvaluationInterval, []string{identifier}, labels.EmptyLabels(), "", rules.DefaultEvalIterationFunc)
+	if err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	c.lookbackDelta.Store(newArgs.LookbackDelta)
+	c.window.SetMaxSeries(newArgs.MaxSeries)
+	forward := append([]storage.Appendable{c.windowAppendable}, newArgs.ForwardTo...)
+	c.results.UpdateChildren(forward)
+	if newArgs.DropInputs {
+		c.inputs.UpdateChildren([]storage.Appendable{c.windowAppendable})
+	} else {
+		c.inputs.UpdateChildren(forward)
+	}
+
+	c.args = newArgs
+	return nil
+}
+
+// queryFunc returns the function evaluating the queries of the rules with the
+// engine, using the current lookback delta.
+func (c *Component) queryFunc(engine promql.QueryEngine) rules.QueryFunc {
+	return func(ctx context.Context, qs string, t time.Time) (promql.Vector, error) {
+		opts := promql.NewPrometheusQueryOpts(false, c.lookbackDelta.Load())
+		q, err := engine.NewInstantQuery(ctx, c.window, opts, qs, t)
+		if err != nil {
+			return nil, err
+		}
+		res := q.Exec(ctx)
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		switch v := res.Value.(type) {
+		case promql.Vector:
+			return v, nil
+		case promql.Scalar:
+			return promql.Vector{promql.Sample{T: v.T, F: v.V, Metric: labels.EmptyLabels()}}, nil
+		default:
+			return nil, errors.New("rule result is not a vector or scalar")
+		}
+	}
+}
+
+// ruleLoader loads the rule groups of the component, either from the inline
+// rules or from a rules file.
+type ruleLoader struct {
+	mut        sync.RWMutex
+	identifier string
+	rules      string
+}
+
+var _ rules.GroupLoader = (*ruleLoader)(nil)
+
+// Set changes the inline rules, which are loaded when identifier is passed to
+// Load.
+func (l *ruleLoader) Set(identifier, rules string) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.identifier, l.rules = identifier, rules
+}
+
+// Load implements rules.GroupLoader.
+func (l *ruleLoader) Load(identifier string) (*rulefmt.RuleGroups, []error) {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	var (
+		rgs  *rulefmt.RuleGroups
+		errs []error
+	)
+	if identifier == l.identifier && l.rules != "" {
+		rgs, errs = rulefmt.Parse([]byte(l.rules))
+	} else {
+		rgs, errs = rulefmt.ParseFile(identifier)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if err := validateRuleGroups(rgs); err != nil {
+		return nil, []error{err}
+	}
+	return rgs, nil
+}
+
+// Parse implements rules.GroupLoader.
+func (l *ruleLoader) Parse(query string) (parser.Expr, error) {
+	return parser.ParseExpr(query)
+}
@@ -0,0 +1,214 @@
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+groups:
+  - name: test
+    rules:
+      - record: job:requests:sum
+        expr: sum by (job) (requests_total)
+`
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    string
+		errMsg string
+	}{
+		{
+			name: "inline rules",
+			cfg: `
+				forward_to = []
+				rules      = "groups: [{name: test, rules: [{record: foo, expr: sum(bar)}]}]"
+			`,
+		},
+		{
+			name: "rules file",
+			cfg: `
+				forward_to = []
+				rules_file = "/etc/alloy/rules.yml"
+			`,
+		},
+		{
+			name:   "no rules",
+			cfg:    `forward_to = []`,
+			errMsg: "one of rules or rules_file must be set",
+		},
+		{
+			name: "both rules and rules file",
+			cfg: `
+				forward_to = []
+				rules      = "groups: []"
+				rules_file = "/etc/alloy/rules.yml"
+			`,
+			errMsg: "only one of rules or rules_file can be set",
+		},
+		{
+			name: "invalid expression",
+			cfg: `
+				forward_to = []
+				rules      = "groups: [{name: test, rules: [{record: foo, expr: 'sum('}]}]"
+			`,
+			errMsg: "invalid rules",
+		},
+		{
+			name: "alerting rule",
+			cfg: `
+				forward_to = []
+				rules      = "groups: [{name: test, rules: [{alert: Foo, expr: 'up == 0'}]}]"
+			`,
+			errMsg: `alerting rule "Foo" isn't supported`,
+		},
+		{
+			name: "invalid max series",
+			cfg: `
+				forward_to = []
+				rules      = "groups: []"
+				max_series = 0
+			`,
+			errMsg: "max_series must be greater than 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(tc.cfg), &args)
+			if tc.errMsg != "" {
+				require.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRecordingRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		dropInputs bool
+	}{
+		{name: "keep inputs", dropInputs: false},
+		{name: "drop inputs", dropInputs: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := make(chan testappender.MetricSample, 1000)
+
+			var args Arguments
+			args.SetToDefault()
+			args.Rules = testRules
+			args.EvaluationInterval = 100 * time.Millisecond
+			args.DropInputs = tc.dropInputs
+			args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{Sample: func(s testappender.MetricSample) { received <- s }}}
+
+			ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.rules.local")
+			require.NoError(t, err)
+			go func() {
+				require.NoError(t, ctrl.Run(componenttest.TestContext(t), args))
+			}()
+			require.NoError(t, ctrl.WaitRunning(5*time.Second))
+
+			ts := timestamp.FromTime(time.Now())
+			app := ctrl.Exports().(Exports).Receiver.Appender(t.Context())
+			for i, instance := range []string{"a", "b", "c"} {
+				_, err := app.Append(0, labels.FromStrings("__name__", "requests_total", "job", "api", "instance", instance), ts, float64(i+1))
+				require.NoError(t, err)
+			}
+			require.NoError(t, app.Commit())
+
+			var sawInputs bool
+			timeout := time.After(10 * time.Second)
+			for {
+				select {
+				case s := <-received:
+					if s.Labels.Get("__name__") == "requests_total" {
+						sawInputs = true
+						continue
+					}
+					require.Equal(t, labels.FromStrings("__name__", "job:requests:sum", "job", "api"), s.Labels)
+					require.Equal(t, 6.0, s.Value)
+					require.Equal(t, !tc.dropInputs, sawInputs)
+					return
+				case <-timeout:
+					require.FailNow(t, "timed out waiting for the results of the rules")
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateLookbackDelta(t *testing.T) {
+	received := make(chan testappender.MetricSample, 1000)
+
+	var args Arguments
+	args.SetToDefault()
+	args.Rules = testRules
+	args.EvaluationInterval = 100 * time.Millisecond
+	args.LookbackDelta = 30 * time.Second
+	args.DropInputs = true
+	args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{Sample: func(s testappender.MetricSample) { received <- s }}}
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.rules.local")
+	require.NoError(t, err)
+	go func() {
+		require.NoError(t, ctrl.Run(componenttest.TestContext(t), args))
+	}()
+	require.NoError(t, ctrl.WaitRunning(5*time.Second))
+
+	// The sample is older than the lookback delta, so the rule has no result.
+	ts := timestamp.FromTime(time.Now().Add(-2 * time.Minute))
+	app := ctrl.Exports().(Exports).Receiver.Appender(t.Context())
+	_, err = app.Append(0, labels.FromStrings("__name__", "requests_total", "job", "api"), ts, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	select {
+	case s := <-received:
+		require.FailNow(t, "unexpected result of the rules", "series: %s", s.Labels)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// Once the lookback delta covers the sample, the rule has a result.
+	args.LookbackDelta = 5 * time.Minute
+	require.NoError(t, ctrl.Update(args))
+	select {
+	case s := <-received:
+		require.Equal(t, labels.FromStrings("__name__", "job:requests:sum", "job", "api"), s.Labels)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "timed out waiting for the results of the rules")
+	}
+}
+
+func TestWindowMaxSeries(t *testing.T) {
+	w, err := newWindow(util.TestLogger(t), t.TempDir(), time.Hour, 2)
+	require.NoError(t, err)
+	defer w.Close()
+
+	app := w.Appender(t.Context())
+	for _, instance := range []string{"a", "b", "c"} {
+		_, err = app.Append(0, labels.FromStrings("__name__", "requests_total", "instance", instance), 1000, 1)
+	}
+	require.ErrorIs(t, err, errTooManySeries)
+	require.NoError(t, app.Commit())
+	require.Equal(t, int64(2), w.NumSeries())
+
+	// Series without samples in the window are removed on truncation, which
+	// makes room for new series.
+	require.NoError(t, w.Truncate(2000))
+	require.Equal(t, int64(0), w.NumSeries())
+}
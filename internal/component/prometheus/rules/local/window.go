@@ -0,0 +1,113 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"go.uber.org/atomic"
+)
+
+var errTooManySeries = errors.New("the window already holds max_series series")
+
+// window is a bounded in-memory storage of the most recent samples, which
+// recording rules are evaluated against. It is backed by a TSDB head without
+// a WAL, so its contents don't survive restarts.
+type window struct {
+	head *tsdb.Head
+
+	maxSeries atomic.Int64
+	numSeries atomic.Int64
+}
+
+var (
+	_ storage.Appendable           = (*window)(nil)
+	_ storage.Queryable            = (*window)(nil)
+	_ tsdb.SeriesLifecycleCallback = (*window)(nil)
+)
+
+// newWindow creates a window which keeps up to maxSeries series. Chunks are
+// cut every length, so that truncating the window frees their memory.
+func newWindow(logger log.Logger, dir string, length time.Duration, maxSeries int) (*window, error) {
+	// The head is never replayed, so any chunks left by a previous run are
+	// stale.
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+
+	w := &window{}
+	w.maxSeries.Store(int64(maxSeries))
+
+	opts := tsdb.DefaultHeadOptions()
+	opts.ChunkDirRoot = dir
+	opts.ChunkRange = length.Milliseconds()
+	opts.SeriesCallback = w
+	opts.EnableNativeHistograms.Store(true)
+
+	head, err := tsdb.NewHead(nil, logger, nil, nil, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := head.Init(math.MinInt64); err != nil {
+		return nil, err
+	}
+	w.head = head
+	return w, nil
+}
+
+// SetMaxSeries changes the maximum number of series in the window. Existing
+// series are kept until they are truncated.
+func (w *window) SetMaxSeries(maxSeries int) {
+	w.maxSeries.Store(int64(maxSeries))
+}
+
+// NumSeries returns the number of series in the window.
+func (w *window) NumSeries() int64 {
+	return w.numSeries.Load()
+}
+
+// Appender implements storage.Appendable.
+func (w *window) Appender(ctx context.Context) storage.Appender {
+	return w.head.Appender(ctx)
+}
+
+// Querier implements storage.Queryable.
+func (w *window) Querier(mint, maxt int64) (storage.Querier, error) {
+	return tsdb.NewBlockQuerier(tsdb.NewRangeHead(w.head, mint, maxt), mint, maxt)
+}
+
+// Truncate removes the samples older than mint, and the series which have no
+// samples left. Samples older than mint are rejected afterwards.
+func (w *window) Truncate(mint int64) error {
+	return w.head.Truncate(mint)
+}
+
+// Close releases the resources of the window.
+func (w *window) Close() error {
+	return w.head.Close()
+}
+
+// PreCreation implements tsdb.SeriesLifecycleCallback.
+func (w *window) PreCreation(labels.Labels) error {
+	if w.numSeries.Load() >= w.maxSeries.Load() {
+		return errTooManySeries
+	}
+	return nil
+}
+
+// PostCreation implements tsdb.SeriesLifecycleCallback.
+func (w *window) PostCreation(labels.Labels) {
+	w.numSeries.Inc()
+}
+
+// PostDeletion implements tsdb.SeriesLifecycleCallback.
+func (w *window) PostDeletion(deleted map[chunks.HeadSeriesRef]labels.Labels) {
+	w.numSeries.Sub(int64(len(deleted)))
+}
@@ -111,6 +111,11 @@ type Arguments struct {
 	// More than this label value length post metric-relabeling will cause the
 	// scrape to fail.
 	LabelValueLengthLimit uint `alloy:"label_value_length_limit,attr,optional"`
+	// Indicator whether the limits can be overridden by the labels of each
+	// target.
+	HonorTargetLimits bool `alloy:"honor_target_limits,attr,optional"`
+	// What to do with the samples of a target above its sample limit.
+	SampleLimitAction string `alloy:"sample_limit_action,attr,optional"`
 
 	HTTPClientConfig component_config.HTTPClientConfig `alloy:",squash"`
 
@@ -138,6 +143,7 @@ func (arg *Arguments) SetToDefault() {
 		ScrapeTimeout:            10 * time.Second, // From config.DefaultGlobalConfig
 		ScrapeProtocols:          slices.Clone(defaultScrapeProtocols),
 		ScrapeNativeHistograms:   true,
+		SampleLimitAction:        SampleLimitActionFail,
 	}
 }
 
@@ -158,6 +164,12 @@ func (arg *Arguments) Validate() error {
 		arg.ScrapeProtocols = slices.Clone(defaultNativeHistogramScrapeProtocols)
 	}
 
+	switch arg.SampleLimitAction {
+	case SampleLimitActionFail, SampleLimitActionTruncate:
+	default:
+		return fmt.Errorf("sample_limit_action must be one of %q or %q, got %q", SampleLimitActionFail, SampleLimitActionTruncate, arg.SampleLimitAction)
+	}
+
 	// Validate scrape protocols
 	existing := make(map[string]struct{})
 	for _, p := range arg.ScrapeProtocols {
@@ -197,6 +209,10 @@ type Component struct {
 	args       Arguments
 	scraper    *scrape.Manager
 	appendable *prometheus.Fanout
+	limits     *limitsAppendable
+	// Whether the scrape loops pass the target of the samples to the
+	// appenders, which the limits applied by the component need.
+	targetInContext bool
 
 	dtMutex            sync.Mutex
 	distributedTargets *discovery.DistributedTargets
@@ -241,6 +257,9 @@ func New(o component.Options, args Arguments) (*Component, error) {
 			config_util.WithDialContextFunc(httpData.DialFunc),
 		},
 		EnableNativeHistogramsIngestion: args.ScrapeNativeHistograms,
+		// The target of the samples is needed to apply its limits. The option
+		// can't be changed once the scrape manager is created.
+		PassMetadataInContext: appliesLimits(args),
 	}
 
 	unregisterer := util.WrapWithUnregisterer(o.Registerer)
@@ -270,15 +289,16 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		targetsGauge:        targetsGauge,
 		movedTargetsCounter: movedTargetsCounter,
 		unregisterer:        unregisterer,
+		targetInContext:     scrapeOptions.PassMetadataInContext,
 	}
 
-	interceptor := c.newInterceptor(ls)
+	c.limits = newLimitsAppendable(c.newInterceptor(ls))
 
 	scraper, err := scrape.NewManager(
 		scrapeOptions,
 		o.Logger,
 		func(s string) (go_kit_log.Logger, error) { return logging.NewJSONFileLogger(s) },
-		interceptor,
+		c.limits,
 		unregisterer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create scrape manager: %w", err)
@@ -342,6 +362,11 @@ func (c *Component) Run(ctx context.Context) error {
 				level.Debug(c.opts.Logger).Log("msg", "passed new targets to scrape manager")
 			case <-ctx.Done():
 			}
+
+			// Targets are replaced asynchronously by the scrape manager, so
+			// the state of the targets removed by this reload is only
+			// forgotten by the next one.
+			c.limits.Prune(c.scraper.TargetsActive())
 		}
 	}
 }
@@ -379,13 +404,32 @@ func (c *Component) distributeTargets(
 func (c *Component) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 
+	if newArgs.HonorTargetLimits {
+		if err := featuregate.CheckAllowed(featuregate.StabilityExperimental, c.opts.MinStability, "honor_target_limits argument"); err != nil {
+			return err
+		}
+	}
+	if newArgs.SampleLimitAction == SampleLimitActionTruncate {
+		if err := featuregate.CheckAllowed(featuregate.StabilityExperimental, c.opts.MinStability, "truncate sample_limit_action"); err != nil {
+			return err
+		}
+	}
+
 	c.mut.Lock()
 	defer c.mut.Unlock()
 	c.args = newArgs
 
 	c.appendable.UpdateChildren(newArgs.ForwardTo)
 
-	sc := getPromScrapeConfigs(c.opts.ID, newArgs)
+	limitsArgs := newArgs
+	if appliesLimits(newArgs) && !c.targetInContext {
+		level.Warn(c.opts.Logger).Log("msg", "honor_target_limits and the truncate sample_limit_action take effect once Alloy is restarted, the limits are applied by the scrape loop until then")
+		limitsArgs.HonorTargetLimits = false
+		limitsArgs.SampleLimitAction = SampleLimitActionFail
+	}
+	c.limits.SetArguments(limitsArgs)
+
+	sc := getPromScrapeConfigs(c.opts.ID, limitsArgs)
 	err := c.scraper.ApplyConfig(&config.Config{
 		ScrapeConfigs: []*config.ScrapeConfig{sc},
 	})
@@ -448,6 +492,14 @@ func getPromScrapeConfigs(jobName string, c Arguments) *config.ScrapeConfig {
 	dec.LabelLimit = c.LabelLimit
 	dec.LabelNameLengthLimit = c.LabelNameLengthLimit
 	dec.LabelValueLengthLimit = c.LabelValueLengthLimit
+	// The limits are applied by the component instead of the scrape loop when
+	// they can differ from one target to another.
+	if appliesLimits(c) {
+		dec.SampleLimit = 0
+		dec.LabelLimit = 0
+		dec.LabelNameLengthLimit = 0
+		dec.LabelValueLengthLimit = 0
+	}
 
 	// Scrape protocols
 	scrapeProtocols := make([]config.ScrapeProtocol, 0, len(c.ScrapeProtocols))
@@ -475,10 +527,19 @@ type TargetStatus struct {
 	LastError          string            `alloy:"last_error,attr,optional"`
 	LastScrape         time.Time         `alloy:"last_scrape,attr"`
 	LastScrapeDuration time.Duration     `alloy:"last_scrape_duration,attr,optional"`
+	SampleLimit        int               `alloy:"sample_limit,attr,optional"`
+	LimitStatus        string            `alloy:"limit_status,attr,optional"`
+	TruncatedSamples   int               `alloy:"truncated_samples,attr,optional"`
 }
 
 // BuildTargetStatuses transforms the targets from a scrape manager into our internal status type for debug info.
 func BuildTargetStatuses(targets map[string][]*scrape.Target) []TargetStatus {
+	return buildTargetStatuses(targets, nil)
+}
+
+// buildTargetStatuses is like BuildTargetStatuses, but calls fn, if set, to
+// complete the status of each target.
+func buildTargetStatuses(targets map[string][]*scrape.Target, fn func(st *scrape.Target, status *TargetStatus)) []TargetStatus {
 	var res []TargetStatus
 
 	for job, stt := range targets {
@@ -489,7 +550,7 @@ func BuildTargetStatuses(targets map[string][]*scrape.Target) []TargetStatus {
 			}
 			if st != nil {
 				lb := labels.NewScratchBuilder(0)
-				res = append(res, TargetStatus{
+				status := TargetStatus{
 					JobName:            job,
 					URL:                st.URL().String(),
 					Health:             string(st.Health()),
@@ -497,7 +558,11 @@ func BuildTargetStatuses(targets map[string][]*scrape.Target) []TargetStatus {
 					LastError:          lastError,
 					LastScrape:         st.LastScrape(),
 					LastScrapeDuration: st.LastScrapeDuration(),
-				})
+				}
+				if fn != nil {
+					fn(st, &status)
+				}
+				res = append(res, status)
 			}
 		}
 	}
@@ -507,7 +572,15 @@ func BuildTargetStatuses(targets map[string][]*scrape.Target) []TargetStatus {
 // DebugInfo implements component.DebugComponent
 func (c *Component) DebugInfo() interface{} {
 	return ScraperStatus{
-		TargetStatus: BuildTargetStatuses(c.scraper.TargetsActive()),
+		TargetStatus: buildTargetStatuses(c.scraper.TargetsActive(), func(st *scrape.Target, status *TargetStatus) {
+			// Only set for the targets whose limits are applied by the
+			// component.
+			if limit, ok := c.limits.Status(st); ok {
+				status.SampleLimit = limit.sampleLimit
+				status.LimitStatus = limit.status
+				status.TruncatedSamples = limit.truncatedSamples
+			}
+		}),
 	}
 }
 
@@ -546,6 +619,7 @@ func (c *Component) newInterceptor(ls labelstore.LabelStore) *prometheus.Interce
 				func() string {
 					return fmt.Sprintf("sample: ts=%d, labels=%s, value=%f", t, l, v)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -566,6 +640,7 @@ func (c *Component) newInterceptor(ls labelstore.LabelStore) *prometheus.Interce
 					}
 					return data
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -578,6 +653,7 @@ func (c *Component) newInterceptor(ls labelstore.LabelStore) *prometheus.Interce
 				func() string {
 					return fmt.Sprintf("metadata: labels=%s, type=%q, unit=%q, help=%q", l, m.Type, m.Unit, m.Help)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -590,6 +666,7 @@ func (c *Component) newInterceptor(ls labelstore.LabelStore) *prometheus.Interce
 				func() string {
 					return fmt.Sprintf("exemplar: ts=%d, labels=%s, exemplar_labels=%s, value=%f", e.Ts, l, e.Labels, e.Value)
 				},
+				livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{l} }),
 			))
 			return globalRef, nextErr
 		}),
@@ -0,0 +1,504 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Target labels which override the limits of the component for a single
+// target when honor_target_limits is set.
+const (
+	sampleLimitLabel           = "__sample_limit__"
+	labelLimitLabel            = "__label_limit__"
+	labelNameLengthLimitLabel  = "__label_name_length_limit__"
+	labelValueLengthLimitLabel = "__label_value_length_limit__"
+)
+
+// Supported values of the sample_limit_action argument.
+const (
+	SampleLimitActionFail     = "fail"
+	SampleLimitActionTruncate = "truncate"
+)
+
+// Values of the limit_status field of the debug info of a target.
+const (
+	limitStatusWithinLimit = "within_limit"
+	limitStatusExceeded    = "exceeded"
+	limitStatusTruncated   = "truncated"
+)
+
+// reportMetrics are the metrics generated by the scrape loop for every
+// target. They never count towards the limits.
+var reportMetrics = map[string]struct{}{
+	"up":                                    {},
+	"scrape_duration_seconds":               {},
+	"scrape_samples_scraped":                {},
+	"scrape_samples_post_metric_relabeling": {},
+	"scrape_series_added":                   {},
+	"scrape_timeout_seconds":                {},
+	"scrape_sample_limit":                   {},
+	"scrape_body_size_bytes":                {},
+}
+
+// targetLimits are the limits applied to the samples of a target. A limit of
+// 0 means no limit.
+type targetLimits struct {
+	sampleLimit           int
+	labelLimit            int
+	labelNameLengthLimit  int
+	labelValueLengthLimit int
+}
+
+func (l targetLimits) isZero() bool {
+	return l == targetLimits{}
+}
+
+// limitStatus is the outcome of the latest scrape of a target with a sample
+// limit.
+type limitStatus struct {
+	sampleLimit      int
+	status           string
+	truncatedSamples int
+}
+
+// limitsAppendable applies the sample and label limits of each target,
+// instead of the scrape loop, so that they can be overridden by the labels
+// of the target and so that targets above their sample limit can be
+// truncated rather than failed.
+type limitsAppendable struct {
+	next storage.Appendable
+
+	mut      sync.RWMutex
+	enabled  bool
+	defaults targetLimits
+	honor    bool
+	truncate bool
+
+	statusMut sync.Mutex
+	statuses  map[*scrape.Target]limitStatus
+	// The series of each truncated target which were forwarded by its latest
+	// scrape, so that they can be marked as stale once they're truncated.
+	kept map[*scrape.Target]map[uint64]struct{}
+}
+
+func newLimitsAppendable(next storage.Appendable) *limitsAppendable {
+	return &limitsAppendable{
+		next:     next,
+		statuses: make(map[*scrape.Target]limitStatus),
+		kept:     make(map[*scrape.Target]map[uint64]struct{}),
+	}
+}
+
+// appliesLimits returns whether the limits are applied by the component
+// rather than by the scrape loop.
+func appliesLimits(args Arguments) bool {
+	return args.HonorTargetLimits || args.SampleLimitAction == SampleLimitActionTruncate
+}
+
+// SetArguments updates the default limits and the behaviour of the limits.
+func (a *limitsAppendable) SetArguments(args Arguments) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.enabled = appliesLimits(args)
+	a.defaults = targetLimits{
+		sampleLimit:           int(args.SampleLimit),
+		labelLimit:            int(args.LabelLimit),
+		labelNameLengthLimit:  int(args.LabelNameLengthLimit),
+		labelValueLengthLimit: int(args.LabelValueLengthLimit),
+	}
+	a.honor = args.HonorTargetLimits
+	a.truncate = args.SampleLimitAction == SampleLimitActionTruncate
+}
+
+// Appender implements storage.Appendable.
+func (a *limitsAppendable) Appender(ctx context.Context) storage.Appender {
+	next := a.next.Appender(ctx)
+
+	a.mut.RLock()
+	var (
+		enabled  = a.enabled
+		limits   = a.defaults
+		honor    = a.honor
+		truncate = a.truncate
+	)
+	a.mut.RUnlock()
+
+	target, ok := scrape.TargetFromContext(ctx)
+	if !enabled || !ok {
+		return next
+	}
+	if honor {
+		limits = targetLimitsFromLabels(target, limits)
+	}
+	if limits.isZero() {
+		return next
+	}
+
+	app := &limitsAppender{
+		Appender:   next,
+		appendable: a,
+		target:     target,
+		limits:     limits,
+	}
+	if truncate && limits.sampleLimit > 0 {
+		app.series = make(map[uint64]*bufferedSeries)
+	}
+	return app
+}
+
+// targetLimitsFromLabels returns the limits of the target, overriding the
+// defaults with the values of the limit labels of the target.
+func targetLimitsFromLabels(target *scrape.Target, defaults targetLimits) targetLimits {
+	override := func(name string, limit *int) {
+		if v := target.GetValue(name); v != "" {
+			// Invalid values are ignored so that a single target can't break
+			// the scrape of the other ones.
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				*limit = n
+			}
+		}
+	}
+	limits := defaults
+	override(sampleLimitLabel, &limits.sampleLimit)
+	override(labelLimitLabel, &limits.labelLimit)
+	override(labelNameLengthLimitLabel, &limits.labelNameLengthLimit)
+	override(labelValueLengthLimitLabel, &limits.labelValueLengthLimit)
+	return limits
+}
+
+func (a *limitsAppendable) setStatus(target *scrape.Target, status limitStatus) {
+	a.statusMut.Lock()
+	defer a.statusMut.Unlock()
+	a.statuses[target] = status
+}
+
+// swapKept stores the series forwarded by the latest scrape of the target and
+// returns the ones forwarded by the previous scrape.
+func (a *limitsAppendable) swapKept(target *scrape.Target, kept map[uint64]struct{}) map[uint64]struct{} {
+	a.statusMut.Lock()
+	defer a.statusMut.Unlock()
+	prev := a.kept[target]
+	a.kept[target] = kept
+	return prev
+}
+
+// Status returns the limit status of the latest scrape of the target.
+func (a *limitsAppendable) Status(target *scrape.Target) (limitStatus, bool) {
+	a.statusMut.Lock()
+	defer a.statusMut.Unlock()
+	status, ok := a.statuses[target]
+	return status, ok
+}
+
+// Prune forgets the state of the targets which aren't active anymore.
+func (a *limitsAppendable) Prune(active map[string][]*scrape.Target) {
+	keep := make(map[*scrape.Target]struct{})
+	for _, targets := range active {
+		for _, t := range targets {
+			keep[t] = struct{}{}
+		}
+	}
+
+	a.statusMut.Lock()
+	defer a.statusMut.Unlock()
+	for t := range a.statuses {
+		if _, ok := keep[t]; !ok {
+			delete(a.statuses, t)
+		}
+	}
+	for t := range a.kept {
+		if _, ok := keep[t]; !ok {
+			delete(a.kept, t)
+		}
+	}
+}
+
+// bufferedSeries holds the calls made for a series while a truncating
+// appender waits for the end of the scrape.
+type bufferedSeries struct {
+	labels labels.Labels
+	t      int64
+	ops    []func(app storage.Appender) error
+}
+
+// limitsAppender applies the limits of a target to the samples of a single
+// scrape. If series is set, the samples are buffered until Commit so that
+// the series above the sample limit can be truncated.
+type limitsAppender struct {
+	storage.Appender
+
+	appendable *limitsAppendable
+	target     *scrape.Target
+	limits     targetLimits
+
+	samples int
+	series  map[uint64]*bufferedSeries
+	order   []uint64
+}
+
+// check returns whether the sample is subject to the limits, and an error if
+// it exceeds the label limits or the sample limit.
+func (app *limitsAppender) check(l labels.Labels, stale bool) (bool, error) {
+	if stale {
+		return false, nil
+	}
+	if _, ok := reportMetrics[l.Get(labels.MetricName)]; ok {
+		return false, nil
+	}
+	if err := app.checkLabels(l); err != nil {
+		return true, err
+	}
+
+	// Truncating appenders count the series when the scrape is committed.
+	if app.series == nil && app.limits.sampleLimit > 0 {
+		app.samples++
+		if app.samples > app.limits.sampleLimit {
+			app.appendable.setStatus(app.target, limitStatus{sampleLimit: app.limits.sampleLimit, status: limitStatusExceeded})
+			return true, fmt.Errorf("sample limit exceeded: the target is limited to %d samples", app.limits.sampleLimit)
+		}
+	}
+	return true, nil
+}
+
+func (app *limitsAppender) checkLabels(l labels.Labels) error {
+	if app.limits.labelLimit > 0 && l.Len() > app.limits.labelLimit {
+		return fmt.Errorf("label_limit exceeded (metric: %.50s, number of labels: %d, limit: %d)", l.String(), l.Len(), app.limits.labelLimit)
+	}
+	if app.limits.labelNameLengthLimit == 0 && app.limits.labelValueLengthLimit == 0 {
+		return nil
+	}
+	return l.Validate(func(lbl labels.Label) error {
+		if app.limits.labelNameLengthLimit > 0 && len(lbl.Name) > app.limits.labelNameLengthLimit {
+			return fmt.Errorf("label_name_length_limit exceeded (metric: %.50s, label name: %.50s, length: %d, limit: %d)", l.String(), lbl.Name, len(lbl.Name), app.limits.labelNameLengthLimit)
+		}
+		if app.limits.labelValueLengthLimit > 0 && len(lbl.Value) > app.limits.labelValueLengthLimit {
+			return fmt.Errorf("label_value_length_limit exceeded (metric: %.50s, label name: %.50s, value: %.50q, length: %d, limit: %d)", l.String(), lbl.Name, lbl.Value, len(lbl.Value), app.limits.labelValueLengthLimit)
+		}
+		return nil
+	})
+}
+
+// buffer records op for the series of l. It returns false if the appender
+// doesn't truncate, in which case op must be called immediately.
+func (app *limitsAppender) buffer(l labels.Labels, t int64, op func(app storage.Appender) error) bool {
+	if app.series == nil {
+		return false
+	}
+	hash := l.Hash()
+	s, ok := app.series[hash]
+	if !ok {
+		s = &bufferedSeries{labels: l, t: t}
+		app.series[hash] = s
+		app.order = append(app.order, hash)
+	}
+	s.ops = append(s.ops, op)
+	return true
+}
+
+// Append implements storage.Appender.
+func (app *limitsAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	stale := value.IsStaleNaN(v)
+	limited, err := app.check(l, stale)
+	if err != nil {
+		return 0, err
+	}
+	// The scrape loop reports the limit it applies, which is 0 when the
+	// component applies the limits instead.
+	if !stale && l.Get(labels.MetricName) == "scrape_sample_limit" {
+		v = float64(app.limits.sampleLimit)
+	}
+	if limited && app.buffer(l, t, func(next storage.Appender) error {
+		_, err := next.Append(0, l, t, v)
+		return err
+	}) {
+		return 0, nil
+	}
+	return app.Appender.Append(ref, l, t, v)
+}
+
+// AppendHistogram implements storage.Appender.
+func (app *limitsAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	stale := (h != nil && value.IsStaleNaN(h.Sum)) || (fh != nil && value.IsStaleNaN(fh.Sum))
+	limited, err := app.check(l, stale)
+	if err != nil {
+		return 0, err
+	}
+	if limited && app.buffer(l, t, func(next storage.Appender) error {
+		_, err := next.AppendHistogram(0, l, t, h, fh)
+		return err
+	}) {
+		return 0, nil
+	}
+	return app.Appender.AppendHistogram(ref, l, t, h, fh)
+}
+
+// AppendExemplar implements storage.Appender.
+func (app *limitsAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	if s, ok := app.bufferedSeries(l); ok {
+		s.ops = append(s.ops, func(next storage.Appender) error {
+			_, err := next.AppendExemplar(0, l, e)
+			return err
+		})
+		return 0, nil
+	}
+	return app.Appender.AppendExemplar(ref, l, e)
+}
+
+// UpdateMetadata implements storage.Appender.
+func (app *limitsAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	if s, ok := app.bufferedSeries(l); ok {
+		s.ops = append(s.ops, func(next storage.Appender) error {
+			_, err := next.UpdateMetadata(0, l, m)
+			return err
+		})
+		return 0, nil
+	}
+	return app.Appender.UpdateMetadata(ref, l, m)
+}
+
+// AppendCTZeroSample implements storage.Appender.
+func (app *limitsAppender) AppendCTZeroSample(ref storage.SeriesRef, l labels.Labels, t, ct int64) (storage.SeriesRef, error) {
+	// Created timestamps are appended before the sample of the series.
+	if app.series != nil {
+		if _, ok := reportMetrics[l.Get(labels.MetricName)]; !ok {
+			app.buffer(l, t, func(next storage.Appender) error {
+				_, err := next.AppendCTZeroSample(0, l, t, ct)
+				return err
+			})
+			return 0, nil
+		}
+	}
+	return app.Appender.AppendCTZeroSample(ref, l, t, ct)
+}
+
+func (app *limitsAppender) bufferedSeries(l labels.Labels) (*bufferedSeries, bool) {
+	if app.series == nil {
+		return nil, false
+	}
+	s, ok := app.series[l.Hash()]
+	return s, ok
+}
+
+// Commit implements storage.Appender.
+func (app *limitsAppender) Commit() error {
+	if app.series == nil {
+		if app.limits.sampleLimit > 0 {
+			app.appendable.setStatus(app.target, limitStatus{sampleLimit: app.limits.sampleLimit, status: limitStatusWithinLimit})
+		}
+		return app.Appender.Commit()
+	}
+
+	kept := app.truncate()
+	status := limitStatus{sampleLimit: app.limits.sampleLimit, status: limitStatusWithinLimit}
+	if len(kept) < len(app.series) {
+		status.status = limitStatusTruncated
+		status.truncatedSamples = len(app.series) - len(kept)
+	}
+	app.appendable.setStatus(app.target, status)
+
+	// Series are appended in the order of the scrape, so that the appenders
+	// downstream see the same order as without truncation.
+	for _, hash := range app.order {
+		if _, ok := kept[hash]; !ok {
+			continue
+		}
+		for _, op := range app.series[hash].ops {
+			if err := op(app.Appender); err != nil {
+				_ = app.Appender.Rollback()
+				return err
+			}
+		}
+	}
+
+	// The series which were forwarded by the previous scrape but are now
+	// truncated are marked as stale, since the scrape loop considers them
+	// still exposed. The scrape loop takes care of the series which aren't
+	// exposed anymore.
+	prev := app.appendable.swapKept(app.target, kept)
+	for hash := range prev {
+		s, exposed := app.series[hash]
+		if _, ok := kept[hash]; ok || !exposed {
+			continue
+		}
+		if _, err := app.Appender.Append(0, s.labels, s.t, math.Float64frombits(value.StaleNaN)); err != nil {
+			_ = app.Appender.Rollback()
+			return err
+		}
+	}
+
+	return app.Appender.Commit()
+}
+
+// truncate returns the hashes of the series to keep. Series are kept by
+// group, so that the series of a histogram or a summary are either all kept
+// or all truncated, and the groups are picked in a deterministic order so
+// that the same series are kept from one scrape to the next.
+func (app *limitsAppender) truncate() map[uint64]struct{} {
+	kept := make(map[uint64]struct{}, min(len(app.series), app.limits.sampleLimit))
+	if len(app.series) <= app.limits.sampleLimit {
+		for hash := range app.series {
+			kept[hash] = struct{}{}
+		}
+		return kept
+	}
+
+	groups := make(map[uint64][]uint64)
+	for hash, s := range app.series {
+		key := seriesGroup(s.labels)
+		groups[key] = append(groups[key], hash)
+	}
+	keys := make([]uint64, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		group := groups[key]
+		if len(kept)+len(group) > app.limits.sampleLimit {
+			continue
+		}
+		for _, hash := range group {
+			kept[hash] = struct{}{}
+		}
+	}
+	return kept
+}
+
+// seriesGroup returns the hash of the labels of a series without the
+// suffixes and labels which distinguish the series of a histogram or a
+// summary.
+func seriesGroup(l labels.Labels) uint64 {
+	lb := labels.NewBuilder(l)
+	name := l.Get(labels.MetricName)
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok {
+			name = trimmed
+			break
+		}
+	}
+	lb.Set(labels.MetricName, name)
+	lb.Del(model.BucketLabel, model.QuantileLabel)
+	return lb.Labels().Hash()
+}
+
+// Rollback implements storage.Appender.
+func (app *limitsAppender) Rollback() error {
+	app.series, app.order = nil, nil
+	return app.Appender.Rollback()
+}
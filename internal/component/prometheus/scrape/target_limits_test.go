@@ -0,0 +1,188 @@
+package scrape
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/util/testappender"
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestSampleLimitAction(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`
+	targets             = []
+	forward_to          = []
+	sample_limit_action = "drop"
+	`), &args)
+	require.ErrorContains(t, err, `sample_limit_action must be one of "fail" or "truncate", got "drop"`)
+}
+
+func TestLimitsAppendable_TargetOverrides(t *testing.T) {
+	var received []testappender.MetricSample
+	limits := newLimitsAppendable(testappender.FuncAppendable{
+		Sample: func(s testappender.MetricSample) { received = append(received, s) },
+	})
+
+	var args Arguments
+	args.SetToDefault()
+	args.SampleLimit = 2
+	args.HonorTargetLimits = true
+	limits.SetArguments(args)
+
+	defaultTarget := testTarget()
+	overriddenTarget := testTarget(sampleLimitLabel, "3")
+
+	// The report metrics don't count towards the limit.
+	app := limits.Appender(scrape.ContextWithTarget(t.Context(), defaultTarget))
+	require.NoError(t, appendSeries(app, "a", "b", "up"))
+	require.ErrorContains(t, appendSeries(app, "c"), "sample limit exceeded: the target is limited to 2 samples")
+	require.NoError(t, app.Rollback())
+	status, ok := limits.Status(defaultTarget)
+	require.True(t, ok)
+	require.Equal(t, limitStatus{sampleLimit: 2, status: limitStatusExceeded}, status)
+
+	received = nil
+	app = limits.Appender(scrape.ContextWithTarget(t.Context(), overriddenTarget))
+	require.NoError(t, appendSeries(app, "a", "b", "c", "scrape_sample_limit"))
+	require.NoError(t, app.Commit())
+	status, ok = limits.Status(overriddenTarget)
+	require.True(t, ok)
+	require.Equal(t, limitStatus{sampleLimit: 3, status: limitStatusWithinLimit}, status)
+
+	// The sample limit in effect for the target is reported.
+	i := slices.IndexFunc(received, func(s testappender.MetricSample) bool {
+		return s.Labels.Get("__name__") == "scrape_sample_limit"
+	})
+	require.NotEqual(t, -1, i)
+	require.Equal(t, 3.0, received[i].Value)
+
+	// Labels aren't honored unless honor_target_limits is set.
+	args.HonorTargetLimits = false
+	args.SampleLimitAction = SampleLimitActionTruncate
+	limits.SetArguments(args)
+	app = limits.Appender(scrape.ContextWithTarget(t.Context(), overriddenTarget))
+	require.NoError(t, appendSeries(app, "a", "b", "c"))
+	require.NoError(t, app.Commit())
+	status, _ = limits.Status(overriddenTarget)
+	require.Equal(t, limitStatus{sampleLimit: 2, status: limitStatusTruncated, truncatedSamples: 1}, status)
+}
+
+func TestLimitsAppendable_LabelLimits(t *testing.T) {
+	var received []testappender.MetricSample
+	limits := newLimitsAppendable(testappender.FuncAppendable{
+		Sample: func(s testappender.MetricSample) { received = append(received, s) },
+	})
+
+	var args Arguments
+	args.SetToDefault()
+	args.HonorTargetLimits = true
+	limits.SetArguments(args)
+
+	app := limits.Appender(scrape.ContextWithTarget(t.Context(), testTarget(labelValueLengthLimitLabel, "3")))
+	_, err := app.Append(0, labels.FromStrings("__name__", "metric", "instance", "long-value"), 1000, 1)
+	require.ErrorContains(t, err, "label_value_length_limit exceeded")
+
+	app = limits.Appender(scrape.ContextWithTarget(t.Context(), testTarget(labelLimitLabel, "1")))
+	_, err = app.Append(0, labels.FromStrings("__name__", "metric", "instance", "a"), 1000, 1)
+	require.ErrorContains(t, err, "label_limit exceeded")
+}
+
+func TestLimitsAppendable_Truncate(t *testing.T) {
+	var received []testappender.MetricSample
+	limits := newLimitsAppendable(testappender.FuncAppendable{
+		Sample: func(s testappender.MetricSample) { received = append(received, s) },
+	})
+
+	var args Arguments
+	args.SetToDefault()
+	args.SampleLimit = 4
+	args.SampleLimitAction = SampleLimitActionTruncate
+	limits.SetArguments(args)
+
+	target := testTarget()
+	scrapeTarget := func() {
+		app := limits.Appender(scrape.ContextWithTarget(t.Context(), target))
+		// The series of the histogram are kept or truncated together.
+		require.NoError(t, appendSeries(app, "a", "b", "c", "latency_bucket", "latency_sum", "latency_count", "up"))
+		require.NoError(t, app.Commit())
+	}
+
+	scrapeTarget()
+	kept := sampleNames(received)
+	require.Contains(t, kept, "up")
+	kept = slices.DeleteFunc(kept, func(name string) bool { return name == "up" })
+	require.LessOrEqual(t, len(kept), 4)
+	histogram := 0
+	for _, name := range kept {
+		if strings.HasPrefix(name, "latency_") {
+			histogram++
+		}
+	}
+	require.Contains(t, []int{0, 3}, histogram)
+	status, _ := limits.Status(target)
+	require.Equal(t, limitStatus{sampleLimit: 4, status: limitStatusTruncated, truncatedSamples: 6 - len(kept)}, status)
+
+	// The same series are kept by the next scrape.
+	received = nil
+	scrapeTarget()
+	require.ElementsMatch(t, append(kept, "up"), sampleNames(received))
+
+	// The series which don't fit anymore are marked as stale.
+	args.SampleLimit = 1
+	limits.SetArguments(args)
+	received = nil
+	scrapeTarget()
+	var forwarded, stale []string
+	for _, s := range received {
+		if value.IsStaleNaN(s.Value) {
+			stale = append(stale, s.Labels.Get("__name__"))
+		} else {
+			forwarded = append(forwarded, s.Labels.Get("__name__"))
+		}
+	}
+	require.LessOrEqual(t, len(forwarded), 2)
+	require.Len(t, stale, len(kept)-(len(forwarded)-1))
+	for _, name := range stale {
+		require.Contains(t, kept, name)
+		require.NotContains(t, forwarded, name)
+	}
+}
+
+func testTarget(extraLabels ...string) *scrape.Target {
+	l := labels.FromStrings(append([]string{"__address__", "localhost:9090", "job", "test"}, extraLabels...)...)
+	return scrape.NewTarget(l, l, nil)
+}
+
+// appendSeries appends a sample for each metric name, with a le label for the
+// histogram buckets.
+func appendSeries(app storage.Appender, names ...string) error {
+	for _, name := range names {
+		l := labels.FromStrings("__name__", name, "instance", "localhost:9090")
+		if name == "latency_bucket" {
+			l = labels.FromStrings("__name__", name, "instance", "localhost:9090", "le", "+Inf")
+		}
+		if _, err := app.Append(0, l, 1000, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sampleNames(received []testappender.MetricSample) []string {
+	var names []string
+	for _, s := range received {
+		if !math.IsNaN(s.Value) {
+			names = append(names, s.Labels.Get("__name__"))
+		}
+	}
+	return names
+}
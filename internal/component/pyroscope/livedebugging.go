@@ -0,0 +1,43 @@
+package pyroscope
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// DescribeSamples returns a description of the profiles of samples for live
+// debugging, with their profile types and number of samples. It parses the
+// profiles, so it should only be called when the description is needed.
+func DescribeSamples(samples []*RawSample) string {
+	descriptions := make([]string, 0, len(samples))
+	for _, s := range samples {
+		descriptions = append(descriptions, describeProfile(s.RawProfile))
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// DescribeIncomingProfile returns a description of an ingested profile for
+// live debugging. The profile types and number of samples are only described
+// for pprof profiles.
+func DescribeIncomingProfile(p *IncomingProfile) string {
+	format := "unknown"
+	if p.URL != nil && p.URL.Query().Get("format") != "" {
+		format = p.URL.Query().Get("format")
+	}
+	return fmt.Sprintf("format=%s, content_type=%q, %s", format, strings.Join(p.ContentType, ","), describeProfile(p.RawBody))
+}
+
+func describeProfile(raw []byte) string {
+	p, err := profile.ParseData(raw)
+	if err != nil {
+		return fmt.Sprintf("size=%dB", len(raw))
+	}
+
+	types := make([]string, 0, len(p.SampleType))
+	for _, st := range p.SampleType {
+		types = append(types, st.Type+":"+st.Unit)
+	}
+	return fmt.Sprintf("profile_types=[%s], samples=%d, size=%dB", strings.Join(types, " "), len(p.Sample), len(raw))
+}
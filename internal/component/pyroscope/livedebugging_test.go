@@ -0,0 +1,39 @@
+package pyroscope
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeSamples(t *testing.T) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		Sample: []*profile.Sample{
+			{Value: []int64{1, 10}},
+			{Value: []int64{2, 20}},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+	raw := buf.Bytes()
+
+	require.Equal(t,
+		fmt.Sprintf("profile_types=[samples:count cpu:nanoseconds], samples=2, size=%dB; size=3B", len(raw)),
+		DescribeSamples([]*RawSample{{RawProfile: raw}, {RawProfile: []byte("foo")}}),
+	)
+
+	u, err := url.Parse("http://localhost/ingest?name=app&format=pprof")
+	require.NoError(t, err)
+	require.Equal(t,
+		fmt.Sprintf(`format=pprof, content_type="application/octet-stream", profile_types=[samples:count cpu:nanoseconds], samples=2, size=%dB`, len(raw)),
+		DescribeIncomingProfile(&IncomingProfile{RawBody: raw, ContentType: []string{"application/octet-stream"}, URL: u}),
+	)
+}
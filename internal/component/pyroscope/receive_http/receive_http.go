@@ -21,6 +21,7 @@ import (
 	"github.com/grafana/alloy/internal/component/pyroscope/write"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/livedebugging"
 	"github.com/grafana/alloy/internal/util"
 	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
@@ -66,16 +67,29 @@ type Component struct {
 	uncheckedCollector *util.UncheckedCollector
 	appendables        []pyroscope.Appendable
 	mut                sync.Mutex
+
+	debugDataPublisher livedebugging.DebugDataPublisher
 }
 
+var (
+	_ component.Component     = (*Component)(nil)
+	_ component.LiveDebugging = (*Component)(nil)
+)
+
 func New(opts component.Options, args Arguments) (*Component, error) {
 	uncheckedCollector := util.NewUncheckedCollector(nil)
 	opts.Registerer.MustRegister(uncheckedCollector)
 
+	debugDataPublisher, err := opts.GetServiceData(livedebugging.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Component{
 		opts:               opts,
 		uncheckedCollector: uncheckedCollector,
 		appendables:        args.ForwardTo,
+		debugDataPublisher: debugDataPublisher.(livedebugging.DebugDataPublisher),
 	}
 
 	if err := c.Update(args); err != nil {
@@ -175,6 +189,17 @@ func (c *Component) Push(ctx context.Context, req *connect.Request[pushv1.PushRe
 
 	appendables := c.getAppendables()
 
+	for _, series := range req.Msg.Series {
+		c.publishDebugData(
+			func() labels.Labels {
+				lb := labels.NewBuilder(nil)
+				setLabelBuilderFromAPI(lb, series.Labels)
+				return ensureServiceName(lb.Labels())
+			},
+			func() string { return pyroscope.DescribeSamples(apiToAlloySamples(series.Samples)) },
+		)
+	}
+
 	var wg sync.WaitGroup
 	var errs error
 	var errorMut sync.Mutex
@@ -256,6 +281,17 @@ func (c *Component) handleIngest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	c.publishDebugData(
+		func() labels.Labels { return lbls },
+		func() string {
+			return pyroscope.DescribeIncomingProfile(&pyroscope.IncomingProfile{
+				RawBody:     buf.Bytes(),
+				ContentType: r.Header.Values(pyroscope.HeaderContentType),
+				URL:         r.URL,
+			})
+		},
+	)
+
 	var wg sync.WaitGroup
 	var errs error
 	var errorMut sync.Mutex
@@ -296,6 +332,20 @@ func (c *Component) handleIngest(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// publishDebugData publishes a received profile. The labels and the
+// description of the profile are only computed if live debugging is active.
+func (c *Component) publishDebugData(labelsFunc func() labels.Labels, describe func() string) {
+	c.debugDataPublisher.PublishIfActive(livedebugging.NewData(
+		livedebugging.ComponentID(c.opts.ID),
+		livedebugging.PyroscopeProfile,
+		1,
+		func() string { return fmt.Sprintf("labels=%s, %s", labelsFunc().String(), describe()) },
+		livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{labelsFunc()} }),
+	))
+}
+
+func (c *Component) LiveDebugging() {}
+
 func (c *Component) shutdownServer() {
 	if c.server != nil {
 		c.server.StopAndShutdown()
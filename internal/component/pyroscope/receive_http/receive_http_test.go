@@ -20,6 +20,7 @@ import (
 	"github.com/grafana/alloy/internal/component"
 	fnet "github.com/grafana/alloy/internal/component/common/net"
 	"github.com/grafana/alloy/internal/component/pyroscope"
+	"github.com/grafana/alloy/internal/service/livedebugging"
 	"github.com/grafana/alloy/internal/util"
 	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
@@ -534,6 +535,12 @@ func testOptions(t *testing.T) component.Options {
 		ID:         "pyroscope.receive_http.test",
 		Logger:     util.TestAlloyLogger(t),
 		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			if name == livedebugging.ServiceName {
+				return livedebugging.NewLiveDebugging(), nil
+			}
+			return nil, fmt.Errorf("service not found %s", name)
+		},
 	}
 }
 
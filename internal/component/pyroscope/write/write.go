@@ -26,6 +26,7 @@ import (
 	"github.com/grafana/alloy/internal/component/pyroscope"
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/livedebugging"
 	"github.com/grafana/alloy/internal/useragent"
 	"github.com/grafana/alloy/internal/util"
 	"github.com/grafana/dskit/backoff"
@@ -135,7 +136,10 @@ func New(o component.Options, c Arguments) (*Component, error) {
 	}, nil
 }
 
-var _ component.Component = (*Component)(nil)
+var (
+	_ component.Component     = (*Component)(nil)
+	_ component.LiveDebugging = (*Component)(nil)
+)
 
 // Run implements Component.
 func (c *Component) Run(ctx context.Context) error {
@@ -154,6 +158,8 @@ func (c *Component) Update(newConfig component.Arguments) error {
 	return nil
 }
 
+func (c *Component) LiveDebugging() {}
+
 type fanOutClient struct {
 	// The list of push clients to fan out to.
 	pushClients   []pushv1connect.PusherServiceClient
@@ -161,6 +167,8 @@ type fanOutClient struct {
 	config        Arguments
 	opts          component.Options
 	metrics       *metrics
+
+	debugDataPublisher livedebugging.DebugDataPublisher
 }
 
 // NewFanOut creates a new fan out client that will fan out to all endpoints.
@@ -185,12 +193,17 @@ func NewFanOut(opts component.Options, config Arguments, metrics *metrics) (*fan
 		)
 		ingestClients[endpoint] = httpClient
 	}
+	debugDataPublisher, err := opts.GetServiceData(livedebugging.ServiceName)
+	if err != nil {
+		return nil, err
+	}
 	return &fanOutClient{
-		pushClients:   pushClients,
-		ingestClients: ingestClients,
-		config:        config,
-		opts:          opts,
-		metrics:       metrics,
+		pushClients:        pushClients,
+		ingestClients:      ingestClients,
+		config:             config,
+		opts:               opts,
+		metrics:            metrics,
+		debugDataPublisher: debugDataPublisher.(livedebugging.DebugDataPublisher),
 	}, nil
 }
 
@@ -322,7 +335,17 @@ func (f *fanOutClient) Append(ctx context.Context, lbs labels.Labels, samples []
 	for name, value := range f.config.ExternalLabels {
 		lbsBuilder.Set(name, value)
 	}
-	for _, l := range lbsBuilder.Labels() {
+	finalLabels := lbsBuilder.Labels()
+	f.debugDataPublisher.PublishIfActive(livedebugging.NewData(
+		livedebugging.ComponentID(f.opts.ID),
+		livedebugging.PyroscopeProfile,
+		1,
+		func() string {
+			return fmt.Sprintf("labels=%s, %s", finalLabels.String(), pyroscope.DescribeSamples(samples))
+		},
+		livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{finalLabels} }),
+	))
+	for _, l := range finalLabels {
 		protoLabels = append(protoLabels, &typesv1.LabelPair{
 			Name:  l.Name,
 			Value: l.Value,
@@ -388,6 +411,16 @@ func (f *fanOutClient) AppendIngest(ctx context.Context, profile *pyroscope.Inco
 	}
 	query.Set("name", ls.Normalized())
 
+	f.debugDataPublisher.PublishIfActive(livedebugging.NewData(
+		livedebugging.ComponentID(f.opts.ID),
+		livedebugging.PyroscopeProfile,
+		1,
+		func() string {
+			return fmt.Sprintf("labels=%s, %s", ls.Normalized(), pyroscope.DescribeIncomingProfile(profile))
+		},
+		livedebugging.WithLabels(func() []labels.Labels { return []labels.Labels{labels.FromMap(ls.Labels())} }),
+	))
+
 	// Send to each endpoint concurrently
 	for endpointIdx, endpoint := range f.config.Endpoints {
 		wg.Add(1)
@@ -69,6 +69,7 @@ func toScrapeArguments(scrapeConfig *prom_config.ScrapeConfig, forwardTo []stora
 		LabelLimit:                scrapeConfig.LabelLimit,
 		LabelNameLengthLimit:      scrapeConfig.LabelNameLengthLimit,
 		LabelValueLengthLimit:     scrapeConfig.LabelValueLengthLimit,
+		SampleLimitAction:         scrape.SampleLimitActionFail,
 		HTTPClientConfig:          *common.ToHttpClientConfig(&scrapeConfig.HTTPClientConfig),
 		ExtraMetrics:              false,
 		EnableProtobufNegotiation: false,
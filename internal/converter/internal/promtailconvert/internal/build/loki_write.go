@@ -8,6 +8,7 @@ import (
 	lokiflag "github.com/grafana/loki/v3/pkg/util/flagext"
 
 	"github.com/grafana/alloy/internal/component/common/loki"
+	lokiclient "github.com/grafana/alloy/internal/component/common/loki/client"
 	lokiwrite "github.com/grafana/alloy/internal/component/loki/write"
 	"github.com/grafana/alloy/internal/converter/diag"
 	"github.com/grafana/alloy/internal/converter/internal/common"
@@ -53,6 +54,7 @@ func toLokiWriteArguments(config *client.Config, diags *diag.Diagnostics) *lokiw
 				RemoteTimeout:     config.Timeout,
 				TenantID:          config.TenantID,
 				RetryOnHTTP429:    !config.DropRateLimitedBatches,
+				Protocol:          lokiclient.ProtocolLoki,
 			},
 		},
 		ExternalLabels: convertFlagLabels(config.ExternalLabels),
@@ -3,7 +3,9 @@ package http
 import (
 	"crypto/sha256"
 	"crypto/subtle"
+	"encoding"
 	"errors"
+	"fmt"
 	"net/http"
 	"slices"
 	"strings"
@@ -14,6 +16,7 @@ import (
 
 type AuthArguments struct {
 	Basic  *BasicAuthArguments `alloy:"basic,block,optional"`
+	Users  []UserArguments     `alloy:"user,block,optional"`
 	Filter FilterAuthArguments `alloy:"filter,block,optional"`
 }
 
@@ -22,62 +25,237 @@ type BasicAuthArguments struct {
 	Password alloytypes.Secret `alloy:"password,attr"`
 }
 
+// UserArguments configures a user of the HTTP server along with the
+// credentials used to identify it.
+type UserArguments struct {
+	Name                        string            `alloy:",label"`
+	Role                        Role              `alloy:"role,attr"`
+	Password                    alloytypes.Secret `alloy:"password,attr,optional"`
+	BearerToken                 alloytypes.Secret `alloy:"bearer_token,attr,optional"`
+	ClientCertificateCommonName string            `alloy:"client_certificate_common_name,attr,optional"`
+}
+
 type FilterAuthArguments struct {
 	Paths             []string `alloy:"paths,attr,optional"`
 	AuthMatchingPaths bool     `alloy:"authenticate_matching_paths,attr,optional"`
 }
 
-var _ syntax.Defaulter = (*FilterAuthArguments)(nil)
+var (
+	_ syntax.Defaulter = (*FilterAuthArguments)(nil)
+	_ syntax.Validator = (*AuthArguments)(nil)
+	_ syntax.Validator = (*UserArguments)(nil)
+)
 
 // SetToDefault implements syntax.Defaulter.
 func (f *FilterAuthArguments) SetToDefault() {
 	f.AuthMatchingPaths = true
 }
 
-func (a *AuthArguments) authenticator() authenticator {
+// Validate implements syntax.Validator.
+func (a *AuthArguments) Validate() error {
+	var (
+		names       = make(map[string]struct{})
+		tokens      = make(map[alloytypes.Secret]struct{})
+		commonNames = make(map[string]struct{})
+	)
 	if a.Basic != nil {
-		return routeAuthenticator(a.Filter, basicAuthenticator(a.Basic.Username, string(a.Basic.Password)))
+		names[a.Basic.Username] = struct{}{}
+	}
+
+	for _, u := range a.Users {
+		if _, ok := names[u.Name]; ok {
+			return fmt.Errorf("user %q is defined more than once", u.Name)
+		}
+		names[u.Name] = struct{}{}
+
+		if u.BearerToken != "" {
+			if _, ok := tokens[u.BearerToken]; ok {
+				return fmt.Errorf("user %q: bearer_token is already used by another user", u.Name)
+			}
+			tokens[u.BearerToken] = struct{}{}
+		}
+		if u.ClientCertificateCommonName != "" {
+			if _, ok := commonNames[u.ClientCertificateCommonName]; ok {
+				return fmt.Errorf("user %q: client_certificate_common_name %q is already used by another user", u.Name, u.ClientCertificateCommonName)
+			}
+			commonNames[u.ClientCertificateCommonName] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// Validate implements syntax.Validator.
+func (u *UserArguments) Validate() error {
+	if u.Password == "" && u.BearerToken == "" && u.ClientCertificateCommonName == "" {
+		return fmt.Errorf("user %q must set at least one of password, bearer_token or client_certificate_common_name", u.Name)
 	}
-	// No need to wrap with routeAuthenticator because authentication is not configured.
-	return allowAuthenticator
+	return nil
 }
 
-type authenticator func(w http.ResponseWriter, r *http.Request) error
+// Role is the access level granted to a user. Each role is also granted the
+// permissions of the roles below it.
+type Role int
+
+const (
+	// RoleViewer grants read-only access to the UI and API.
+	RoleViewer Role = iota
+	// RoleDebugger also grants access to live debugging.
+	RoleDebugger
+	// RoleAdmin also grants access to reloading the configuration,
+	// generating support bundles and profiling.
+	RoleAdmin
+)
+
+var (
+	_ encoding.TextUnmarshaler = (*Role)(nil)
+	_ encoding.TextMarshaler   = (Role)(0)
+)
+
+var roles = map[string]Role{
+	"viewer":   RoleViewer,
+	"debugger": RoleDebugger,
+	"admin":    RoleAdmin,
+}
 
-func allowAuthenticator(w http.ResponseWriter, r *http.Request) error {
+// UnmarshalText unmarshals the name of a role.
+func (r *Role) UnmarshalText(text []byte) error {
+	str := string(text)
+
+	role, ok := roles[str]
+	if !ok {
+		return fmt.Errorf("unknown role %q, must be one of viewer, debugger or admin", str)
+	}
+
+	*r = role
 	return nil
 }
 
-func basicAuthenticator(username, password string) authenticator {
+// MarshalText marshals a role to its name.
+func (r Role) MarshalText() ([]byte, error) {
+	for name, role := range roles {
+		if role == r {
+			return []byte(name), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown role %d", r)
+}
+
+// String returns the name of the role.
+func (r Role) String() string {
+	b, err := r.MarshalText()
+	if err != nil {
+		return fmt.Sprintf("Role(%d)", r)
+	}
+	return string(b)
+}
+
+// identity is the authenticated caller of a request.
+type identity struct {
+	user string
+	role Role
+}
+
+func (a *AuthArguments) authenticator() authenticator {
+	if a.Basic == nil && len(a.Users) == 0 {
+		// No need to wrap with routeAuthenticator because authentication is not configured.
+		return allowAuthenticator
+	}
+
+	users := slices.Clone(a.Users)
+	if a.Basic != nil {
+		// The user of the basic block predates roles and is granted full access.
+		users = append(users, UserArguments{
+			Name:     a.Basic.Username,
+			Role:     RoleAdmin,
+			Password: a.Basic.Password,
+		})
+	}
+	return routeAuthenticator(a.Filter, usersAuthenticator(users))
+}
+
+// authenticator identifies the caller of a request. A nil identity with no
+// error means that the request doesn't require authentication.
+type authenticator func(w http.ResponseWriter, r *http.Request) (*identity, error)
+
+func allowAuthenticator(w http.ResponseWriter, r *http.Request) (*identity, error) {
+	return nil, nil
+}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// usersAuthenticator identifies users with, in order of preference, a bearer
+// token, basic authentication or a verified TLS client certificate.
+func usersAuthenticator(users []UserArguments) authenticator {
+	type hashedUser struct {
+		identity
+		username    [sha256.Size]byte
+		password    [sha256.Size]byte
+		bearerToken [sha256.Size]byte
+		hasPassword bool
+		hasToken    bool
+		commonName  string
+	}
+
 	// We hash both expected and incoming data to prevent timing attacks, otherwise
 	// a caller can figure out the length of both password and username.
-	expectedUsername := sha256.Sum256([]byte(username))
-	expectedPassword := sha256.Sum256([]byte(password))
+	hashed := make([]hashedUser, 0, len(users))
+	for _, u := range users {
+		hashed = append(hashed, hashedUser{
+			identity:    identity{user: u.Name, role: u.Role},
+			username:    sha256.Sum256([]byte(u.Name)),
+			password:    sha256.Sum256([]byte(u.Password)),
+			bearerToken: sha256.Sum256([]byte(u.BearerToken)),
+			hasPassword: u.Password != "",
+			hasToken:    u.BearerToken != "",
+			commonName:  u.ClientCertificateCommonName,
+		})
+	}
 
-	return func(w http.ResponseWriter, r *http.Request) error {
-		username, password, ok := r.BasicAuth()
-		if !ok {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			return errors.New("unauthorized")
+	return func(w http.ResponseWriter, r *http.Request) (*identity, error) {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			tokenHash := sha256.Sum256([]byte(token))
+			for _, u := range hashed {
+				if u.hasToken && subtle.ConstantTimeCompare(tokenHash[:], u.bearerToken[:]) == 1 {
+					return &u.identity, nil
+				}
+			}
+			return nil, errUnauthorized
 		}
 
-		usernameHash := sha256.Sum256([]byte(username))
-		passwordHash := sha256.Sum256([]byte(password))
-
-		usernameMatch := subtle.ConstantTimeCompare(usernameHash[:], expectedUsername[:]) == 1
-		passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], expectedPassword[:]) == 1
+		if username, password, ok := r.BasicAuth(); ok {
+			usernameHash := sha256.Sum256([]byte(username))
+			passwordHash := sha256.Sum256([]byte(password))
 
-		if !usernameMatch || !passwordMatch {
+			for _, u := range hashed {
+				usernameMatch := subtle.ConstantTimeCompare(usernameHash[:], u.username[:]) == 1
+				passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], u.password[:]) == 1
+				if u.hasPassword && usernameMatch && passwordMatch {
+					return &u.identity, nil
+				}
+			}
 			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-			return errors.New("unauthorized")
+			return nil, errUnauthorized
+		}
+
+		// Only certificates that have been verified against the client CA can
+		// be used to identify a user.
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+			for _, u := range hashed {
+				if u.commonName != "" && u.commonName == commonName {
+					return &u.identity, nil
+				}
+			}
 		}
 
-		return nil
+		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+		return nil, errUnauthorized
 	}
 }
 
 func routeAuthenticator(filter FilterAuthArguments, auth authenticator) authenticator {
-	return func(w http.ResponseWriter, r *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) (*identity, error) {
 		compare := func(s string) bool { return strings.HasPrefix(r.URL.Path, s) }
 
 		// If AuthMatchingPaths is true we perform authentication on matching paths
@@ -92,6 +270,41 @@ func routeAuthenticator(filter FilterAuthArguments, auth authenticator) authenti
 			}
 		}
 
-		return nil
+		return nil, nil
+	}
+}
+
+// requiredRole returns the role required to make the request r, along with
+// the name of the privileged action it performs. Requests which only require
+// the viewer role aren't privileged and have an empty action.
+//
+// Access is denied by default: only reading from the routes which aren't
+// listed below requires the viewer role. Writing to any route, or accessing
+// the routes of components, which can perform arbitrary actions, requires the
+// admin role.
+//
+// The UI can be served under a custom prefix, so its routes are matched
+// anywhere in the path.
+func requiredRole(r *http.Request) (Role, string) {
+	path := r.URL.Path
+	switch {
+	case path == "/-/reload":
+		return RoleAdmin, "reload"
+	case path == "/-/support":
+		return RoleAdmin, "support_bundle"
+	case strings.HasPrefix(path, "/debug/pprof"):
+		return RoleAdmin, "pprof"
+	case strings.HasPrefix(path, "/api/v0/component/"):
+		// This also covers the components of remote configurations, served
+		// under /api/v0/component/remotecfg.
+		return RoleAdmin, "component"
+	case r.Method != http.MethodGet && r.Method != http.MethodHead:
+		return RoleAdmin, "write"
+	case strings.Contains(path, "/api/v0/web/debug/"):
+		return RoleDebugger, "live_debugging"
+	case strings.Contains(path, "/api/v0/web/graph"):
+		return RoleDebugger, "live_debugging_graph"
+	default:
+		return RoleViewer, ""
 	}
 }
@@ -1,10 +1,14 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/grafana/alloy/syntax"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -68,7 +72,7 @@ func Test_basicAuthenticatorInclude(t *testing.T) {
 				req.SetBasicAuth(tt.username, tt.password)
 			}
 
-			err = auth(w, req)
+			_, err = auth(w, req)
 			if tt.expectError {
 				assert.Error(t, err)
 				assert.Equal(t, `Basic realm="Restricted"`, w.Header().Get("WWW-Authenticate"))
@@ -139,7 +143,7 @@ func Test_basicAuthenticatorExclude(t *testing.T) {
 				req.SetBasicAuth(tt.username, tt.password)
 			}
 
-			err = auth(w, req)
+			_, err = auth(w, req)
 			if tt.expectError {
 				assert.Error(t, err)
 				assert.Equal(t, `Basic realm="Restricted"`, w.Header().Get("WWW-Authenticate"))
@@ -150,3 +154,214 @@ func Test_basicAuthenticatorExclude(t *testing.T) {
 		})
 	}
 }
+
+func Test_usersAuthenticator(t *testing.T) {
+	args := AuthArguments{
+		Basic: &BasicAuthArguments{
+			Username: "legacy",
+			Password: "legacy-password",
+		},
+		Users: []UserArguments{
+			{Name: "viewer", Role: RoleViewer, Password: "viewer-password"},
+			{Name: "debugger", Role: RoleDebugger, BearerToken: "debugger-token"},
+			{Name: "cert", Role: RoleAdmin, ClientCertificateCommonName: "admin.example.com"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		setup        func(r *http.Request)
+		expectError  bool
+		expectedUser string
+		expectedRole Role
+	}{
+		{
+			name:        "no credentials",
+			setup:       func(r *http.Request) {},
+			expectError: true,
+		},
+		{
+			name:         "basic block user is an admin",
+			setup:        func(r *http.Request) { r.SetBasicAuth("legacy", "legacy-password") },
+			expectedUser: "legacy",
+			expectedRole: RoleAdmin,
+		},
+		{
+			name:         "user with password",
+			setup:        func(r *http.Request) { r.SetBasicAuth("viewer", "viewer-password") },
+			expectedUser: "viewer",
+			expectedRole: RoleViewer,
+		},
+		{
+			name:        "user without password",
+			setup:       func(r *http.Request) { r.SetBasicAuth("debugger", "") },
+			expectError: true,
+		},
+		{
+			name:         "bearer token",
+			setup:        func(r *http.Request) { r.Header.Set("Authorization", "Bearer debugger-token") },
+			expectedUser: "debugger",
+			expectedRole: RoleDebugger,
+		},
+		{
+			name:        "invalid bearer token",
+			setup:       func(r *http.Request) { r.Header.Set("Authorization", "Bearer invalid") },
+			expectError: true,
+		},
+		{
+			name: "verified client certificate",
+			setup: func(r *http.Request) {
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+					{Subject: pkix.Name{CommonName: "admin.example.com"}},
+				}}}
+			},
+			expectedUser: "cert",
+			expectedRole: RoleAdmin,
+		},
+		{
+			name: "unverified client certificate",
+			setup: func(r *http.Request) {
+				r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+					{Subject: pkix.Name{CommonName: "admin.example.com"}},
+				}}
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := args.authenticator()
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+			require.NoError(t, err)
+			tt.setup(req)
+
+			id, err := auth(w, req)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, id)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, id)
+			assert.Equal(t, tt.expectedUser, id.user)
+			assert.Equal(t, tt.expectedRole, id.role)
+		})
+	}
+}
+
+func Test_requiredRole(t *testing.T) {
+	tests := []struct {
+		method         string
+		path           string
+		expectedRole   Role
+		expectedAction string
+	}{
+		{method: http.MethodGet, path: "/-/ready", expectedRole: RoleViewer},
+		{method: http.MethodHead, path: "/metrics", expectedRole: RoleViewer},
+		{method: http.MethodGet, path: "/api/v0/web/components", expectedRole: RoleViewer},
+		{method: http.MethodGet, path: "/api/v0/web/debug/loki.process.default", expectedRole: RoleDebugger, expectedAction: "live_debugging"},
+		{method: http.MethodGet, path: "/ui/api/v0/web/graph", expectedRole: RoleDebugger, expectedAction: "live_debugging_graph"},
+		{method: http.MethodGet, path: "/-/reload", expectedRole: RoleAdmin, expectedAction: "reload"},
+		{method: http.MethodPost, path: "/-/reload", expectedRole: RoleAdmin, expectedAction: "reload"},
+		{method: http.MethodGet, path: "/-/support", expectedRole: RoleAdmin, expectedAction: "support_bundle"},
+		{method: http.MethodGet, path: "/debug/pprof/heap", expectedRole: RoleAdmin, expectedAction: "pprof"},
+		{method: http.MethodGet, path: "/api/v0/component/prometheus.exporter.unix.default/metrics", expectedRole: RoleAdmin, expectedAction: "component"},
+		{method: http.MethodPost, path: "/api/v0/component/loki.source.api.default/loki/api/v1/push", expectedRole: RoleAdmin, expectedAction: "component"},
+		{method: http.MethodGet, path: "/api/v0/component/remotecfg/prometheus.exporter.unix.default/metrics", expectedRole: RoleAdmin, expectedAction: "component"},
+		{method: http.MethodPost, path: "/-/ready", expectedRole: RoleAdmin, expectedAction: "write"},
+		{method: http.MethodDelete, path: "/api/v0/web/components", expectedRole: RoleAdmin, expectedAction: "write"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://localhost"+tt.path, nil)
+			role, action := requiredRole(req)
+			assert.Equal(t, tt.expectedRole, role)
+			assert.Equal(t, tt.expectedAction, action)
+		})
+	}
+}
+
+func TestAuthArguments_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			config: `
+				user "alice" {
+					role     = "admin"
+					password = "password"
+				}
+				user "bob" {
+					role         = "viewer"
+					bearer_token = "token"
+				}
+			`,
+		},
+		{
+			name: "unknown role",
+			config: `
+				user "alice" {
+					role     = "root"
+					password = "password"
+				}
+			`,
+			expectedErr: `unknown role "root"`,
+		},
+		{
+			name: "no credentials",
+			config: `
+				user "alice" {
+					role = "admin"
+				}
+			`,
+			expectedErr: `user "alice" must set at least one of password, bearer_token or client_certificate_common_name`,
+		},
+		{
+			name: "conflict with basic user",
+			config: `
+				basic {
+					username = "alice"
+					password = "password"
+				}
+				user "alice" {
+					role     = "viewer"
+					password = "password"
+				}
+			`,
+			expectedErr: `user "alice" is defined more than once`,
+		},
+		{
+			name: "duplicate bearer token",
+			config: `
+				user "alice" {
+					role         = "admin"
+					bearer_token = "token"
+				}
+				user "bob" {
+					role         = "viewer"
+					bearer_token = "token"
+				}
+			`,
+			expectedErr: `user "bob": bearer_token is already used by another user`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args AuthArguments
+			err := syntax.Unmarshal([]byte(tt.config), &args)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
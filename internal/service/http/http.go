@@ -199,14 +199,29 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 	r.Use(func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			s.authenticatorMut.RLock()
-			err := s.authenticator(w, r)
+			id, err := s.authenticator(w, r)
 			s.authenticatorMut.RUnlock()
 			if err != nil {
 				level.Info(s.log).Log("msg", "failed to authenticate request", "path", r.URL.Path, "err", err)
+				if w.Header().Get("WWW-Authenticate") == "" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+				}
 				w.WriteHeader(http.StatusUnauthorized)
 				return
 			}
 
+			// Requests which don't require authentication are granted full
+			// access.
+			role, action := requiredRole(r)
+			if id != nil && id.role < role {
+				level.Info(s.log).Log("msg", "denied request", "path", r.URL.Path, "user", id.user, "role", id.role, "required_role", role)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if action != "" {
+				s.audit(r, id, action)
+			}
+
 			h.ServeHTTP(w, r)
 		})
 	})
@@ -313,6 +328,15 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 	return nil
 }
 
+// audit logs a privileged action performed by the caller of a request.
+func (s *Service) audit(r *http.Request, id *identity, action string) {
+	user, role := "anonymous", ""
+	if id != nil {
+		user, role = id.user, id.role.String()
+	}
+	level.Info(s.log).Log("msg", "audit", "action", action, "user", user, "role", role, "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+}
+
 func (s *Service) generateSupportBundleHandler(host service.Host) func(rw http.ResponseWriter, r *http.Request) {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		s.supportBundleMut.Lock()
@@ -351,8 +375,8 @@ func (s *Service) generateSupportBundleHandler(host service.Host) func(rw http.R
 			s.globalLogger.RemoveTemporaryWriter()
 		}()
 
-		// Get and redact the cached remote config.
-		cachedConfig, err := remoteCfgRedactedCachedConfig(host)
+		// Get and redact the cached remote config of every source.
+		cachedConfigs, err := remoteCfgRedactedCachedConfigs(host)
 		if err != nil {
 			level.Debug(s.log).Log("msg", "failed to get cached remote config", "err", err)
 		}
@@ -361,7 +385,7 @@ func (s *Service) generateSupportBundleHandler(host service.Host) func(rw http.R
 		// secret redaction.
 		sources := redactedSources(s.sources)
 
-		bundle, err := ExportSupportBundle(ctx, s.opts.BundleContext.RuntimeFlags, s.opts.HTTPListenAddr, sources, cachedConfig, s.Data().(Data).DialFunc)
+		bundle, err := ExportSupportBundle(ctx, s.opts.BundleContext.RuntimeFlags, s.opts.HTTPListenAddr, sources, cachedConfigs, s.Data().(Data).DialFunc)
 		if err != nil {
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
@@ -673,13 +697,24 @@ func redactedSources(sources map[string]*ast.File) map[string][]byte {
 	return printedSources
 }
 
-func remoteCfgRedactedCachedConfig(host service.Host) ([]byte, error) {
+// remoteCfgRedactedCachedConfigs returns the redacted cached configuration
+// of every remotecfg source, keyed by source name.
+func remoteCfgRedactedCachedConfigs(host service.Host) (map[string][]byte, error) {
 	svc, ok := host.GetService(remotecfg.ServiceName)
 	if !ok {
 		return nil, fmt.Errorf("failed to get the remotecfg service")
 	}
 
-	return printFileRedacted(svc.(*remotecfg.Service).GetCachedAstFile())
+	files := svc.(*remotecfg.Service).GetCachedAstFiles()
+	configs := make(map[string][]byte, len(files))
+	for name, f := range files {
+		b, err := printFileRedacted(f)
+		if err != nil {
+			return nil, err
+		}
+		configs[name] = b
+	}
+	return configs, nil
 }
 
 func printFileRedacted(f *ast.File) ([]byte, error) {
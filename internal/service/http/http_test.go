@@ -215,6 +215,67 @@ func TestAuth(t *testing.T) {
 	})
 }
 
+func TestAuthRoles(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+
+	env, err := newTestEnvironment(t)
+	require.NoError(t, err)
+	require.NoError(t, env.ApplyConfig(`
+		auth {
+			user "viewer" {
+				role     = "viewer"
+				password = "password"
+			}
+			user "admin" {
+				role         = "admin"
+				bearer_token = "token"
+			}
+		}
+	`))
+
+	go func() {
+		require.NoError(t, env.Run(ctx))
+	}()
+
+	requestMethod := func(t require.TestingT, method, path string, cfg config.HTTPClientConfig) *http.Response {
+		cli, err := config.NewClientFromConfig(cfg, "test")
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", env.ListenAddr(), path), nil)
+		require.NoError(t, err)
+
+		resp, err := cli.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+	request := func(t require.TestingT, path string, cfg config.HTTPClientConfig) int {
+		return requestMethod(t, http.MethodGet, path, cfg).StatusCode
+	}
+
+	viewer := config.HTTPClientConfig{BasicAuth: &config.BasicAuth{Username: "viewer", Password: "password"}}
+	admin := config.HTTPClientConfig{BearerToken: "token"}
+
+	util.Eventually(t, func(t require.TestingT) {
+		require.Equal(t, http.StatusUnauthorized, request(t, "/-/ready", config.HTTPClientConfig{}))
+		require.Equal(t, http.StatusOK, request(t, "/-/ready", viewer))
+		require.Equal(t, http.StatusForbidden, request(t, "/-/reload", viewer))
+		require.Equal(t, http.StatusOK, request(t, "/-/ready", admin))
+		require.Equal(t, http.StatusOK, request(t, "/-/reload", admin))
+
+		// Writes and component routes are denied to viewers.
+		require.Equal(t, http.StatusForbidden, requestMethod(t, http.MethodPost, "/-/ready", viewer).StatusCode)
+		require.Equal(t, http.StatusForbidden, requestMethod(t, http.MethodPost, "/api/v0/component/loki.source.api.default/push", viewer).StatusCode)
+		require.Equal(t, http.StatusForbidden, request(t, "/api/v0/component/remotecfg/prometheus.exporter.self.default/metrics", viewer))
+		require.NotEqual(t, http.StatusForbidden, request(t, "/api/v0/component/remotecfg/prometheus.exporter.self.default/metrics", admin))
+
+		// An invalid bearer token is challenged like missing credentials.
+		resp := requestMethod(t, http.MethodGet, "/-/ready", config.HTTPClientConfig{BearerToken: "invalid"})
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Equal(t, `Basic realm="Restricted"`, resp.Header.Get("WWW-Authenticate"))
+	})
+}
+
 func Test_Toggle_Auth(t *testing.T) {
 	ctx := componenttest.TestContext(t)
 
@@ -37,7 +37,7 @@ type Bundle struct {
 	runtimeFlags         []byte
 	environmentVariables []byte
 	sources              map[string][]byte
-	remoteCfg            []byte
+	remoteCfg            map[string][]byte
 	heapBuf              *bytes.Buffer
 	goroutineBuf         *bytes.Buffer
 	blockBuf             *bytes.Buffer
@@ -54,7 +54,7 @@ type Metadata struct {
 }
 
 // ExportSupportBundle gathers the information required for the support bundle.
-func ExportSupportBundle(ctx context.Context, runtimeFlags []string, srvAddress string, sources map[string][]byte, remoteCfg []byte, dialContext server.DialContextFunc) (*Bundle, error) {
+func ExportSupportBundle(ctx context.Context, runtimeFlags []string, srvAddress string, sources map[string][]byte, remoteCfg map[string][]byte, dialContext server.DialContextFunc) (*Bundle, error) {
 	var httpClient http.Client
 	httpClient.Transport = &http.Transport{DialContext: dialContext}
 
@@ -227,8 +227,17 @@ func ServeSupportBundle(rw http.ResponseWriter, b *Bundle, logsBuf *bytes.Buffer
 		"pprof/block.pprof":              b.blockBuf.Bytes(),
 	}
 
-	if len(b.remoteCfg) > 0 {
-		zipStructure["sources/remote-config/remote.alloy"] = b.remoteCfg
+	// The configuration of the primary remotecfg source is stored as
+	// remote.alloy, and the one of each named source after its name.
+	for name, cfg := range b.remoteCfg {
+		if len(cfg) == 0 {
+			continue
+		}
+		fn := "remote.alloy"
+		if name != "" {
+			fn = "remote-" + filepath.Base(name) + ".alloy"
+		}
+		zipStructure["sources/remote-config/"+fn] = cfg
 	}
 
 	for p, s := range b.sources {
@@ -1,5 +1,7 @@
 package livedebugging
 
+import "github.com/prometheus/prometheus/model/labels"
+
 type DataType string
 
 const (
@@ -9,6 +11,7 @@ const (
 	OtelMetric       DataType = "otel_metric"
 	OtelLog          DataType = "otel_log"
 	OtelTrace        DataType = "otel_trace"
+	PyroscopeProfile DataType = "pyroscope_profile"
 )
 
 type DataOption func(Data) Data
@@ -20,6 +23,27 @@ func WithTargetComponentIDs(ids []string) DataOption {
 	}
 }
 
+// WithLabels sets the function returning the label sets carried by the data,
+// such as the labels of a metric or a log line, or the attributes of
+// OpenTelemetry records. The label sets are used to evaluate filters and are
+// only computed when a consumer uses one.
+func WithLabels(labelsFunc func() []labels.Labels) DataOption {
+	return func(d Data) Data {
+		d.LabelsFunc = labelsFunc
+		return d
+	}
+}
+
+// AsSnapshot marks the data as a snapshot of the full state of the component,
+// such as all the targets exported by a discovery component, rather than a
+// single item. Consecutive snapshots can be diffed by consumers.
+func AsSnapshot() DataOption {
+	return func(d Data) Data {
+		d.Snapshot = true
+		return d
+	}
+}
+
 type Data struct {
 	// ID of the component that created the data.
 	ComponentID ComponentID
@@ -33,6 +57,12 @@ type Data struct {
 	Count uint64
 	// The data string is passed as a function to only compute the string if needed.
 	DataFunc func() string
+	// The label sets are passed as a function to only compute them if a filter is used.
+	// Data without labels never matches a filter.
+	LabelsFunc func() []labels.Labels
+	// Snapshot is true if the data represents the full state of the component.
+	// The LabelsFunc of a snapshot returns one label set per item of the state.
+	Snapshot bool
 }
 
 func NewData(componentID ComponentID, dataType DataType, count uint64, dataFunc func() string, opts ...DataOption) Data {
@@ -0,0 +1,129 @@
+package livedebugging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// TargetDiffer turns consecutive snapshots of targets into the list of
+// targets added, removed or changed between them. It keeps the last snapshot
+// it has seen, so a TargetDiffer must be used by a single consumer.
+//
+// Targets are identified by their labels which aren't prefixed with __meta_,
+// a target is changed when only its __meta_ labels differ.
+type TargetDiffer struct {
+	filter *Filter
+
+	mut  sync.Mutex
+	prev map[string]labels.Labels
+}
+
+// NewTargetDiffer creates a TargetDiffer. Only the targets matching filter are
+// compared, a nil filter compares all the targets.
+func NewTargetDiffer(filter *Filter) *TargetDiffer {
+	return &TargetDiffer{filter: filter}
+}
+
+// Diff returns data describing the changes between the targets of data and
+// the targets of the previous snapshot. The first snapshot is reported as all
+// its targets being added. It returns false if no target changed.
+//
+// data must be a snapshot with labels.
+func (d *TargetDiffer) Diff(data Data) (Data, bool) {
+	current := make(map[string]labels.Labels)
+	if data.LabelsFunc != nil {
+		for _, lbls := range data.LabelsFunc() {
+			if d.filter != nil && !d.filter.matchesLabels(lbls) {
+				continue
+			}
+			current[targetKey(lbls)] = lbls
+		}
+	}
+
+	d.mut.Lock()
+	prev := d.prev
+	d.prev = current
+	d.mut.Unlock()
+
+	var diff targetDiff
+	for key, lbls := range current {
+		old, ok := prev[key]
+		switch {
+		case !ok:
+			diff.added = append(diff.added, lbls)
+		case !labels.Equal(old, lbls):
+			diff.changed = append(diff.changed, [2]labels.Labels{old, lbls})
+		}
+	}
+	for key, lbls := range prev {
+		if _, ok := current[key]; !ok {
+			diff.removed = append(diff.removed, lbls)
+		}
+	}
+
+	if diff.len() == 0 {
+		return Data{}, false
+	}
+
+	return NewData(
+		data.ComponentID,
+		data.Type,
+		uint64(diff.len()),
+		diff.String,
+		WithTargetComponentIDs(data.TargetComponentIDs),
+		WithLabels(diff.labels),
+	), true
+}
+
+func targetKey(lbls labels.Labels) string {
+	b := labels.NewBuilder(lbls)
+	lbls.Range(func(l labels.Label) {
+		if strings.HasPrefix(l.Name, "__meta_") {
+			b.Del(l.Name)
+		}
+	})
+	return b.Labels().String()
+}
+
+type targetDiff struct {
+	added   []labels.Labels
+	removed []labels.Labels
+	changed [][2]labels.Labels
+}
+
+func (d targetDiff) len() int {
+	return len(d.added) + len(d.removed) + len(d.changed)
+}
+
+// labels returns the label sets of the added, removed and the new version of
+// the changed targets.
+func (d targetDiff) labels() []labels.Labels {
+	res := make([]labels.Labels, 0, d.len())
+	res = append(res, d.added...)
+	res = append(res, d.removed...)
+	for _, c := range d.changed {
+		res = append(res, c[1])
+	}
+	return res
+}
+
+// String renders one target per line, prefixed with + when added, - when
+// removed and ~ when changed. Lines are sorted to keep the output stable.
+func (d targetDiff) String() string {
+	lines := make([]string, 0, d.len())
+	for _, lbls := range d.added {
+		lines = append(lines, "+ "+lbls.String())
+	}
+	for _, lbls := range d.removed {
+		lines = append(lines, "- "+lbls.String())
+	}
+	for _, c := range d.changed {
+		lines = append(lines, fmt.Sprintf("~ %s => %s", c[0], c[1]))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
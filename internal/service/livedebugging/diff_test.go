@@ -0,0 +1,59 @@
+package livedebugging
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetDiffer(t *testing.T) {
+	snapshot := func(targets ...labels.Labels) Data {
+		return NewData("discovery.fake.default", Target, uint64(len(targets)), func() string { return "" },
+			WithLabels(func() []labels.Labels { return targets }), AsSnapshot())
+	}
+
+	var (
+		a        = labels.FromStrings("__address__", "a:80", "__meta_pod", "pod-a")
+		aChanged = labels.FromStrings("__address__", "a:80", "__meta_pod", "pod-a2")
+		b        = labels.FromStrings("__address__", "b:80")
+		c        = labels.FromStrings("__address__", "c:80", "job", "api")
+	)
+
+	differ := NewTargetDiffer(nil)
+
+	diff, ok := differ.Diff(snapshot(a, b))
+	require.True(t, ok)
+	require.Equal(t, uint64(2), diff.Count)
+	require.Equal(t, "+ {__address__=\"a:80\", __meta_pod=\"pod-a\"}\n+ {__address__=\"b:80\"}", diff.DataFunc())
+
+	_, ok = differ.Diff(snapshot(b, a))
+	require.False(t, ok, "identical snapshots must not produce a diff")
+
+	diff, ok = differ.Diff(snapshot(aChanged, c))
+	require.True(t, ok)
+	require.Equal(t, uint64(3), diff.Count)
+	require.Equal(t, "+ {__address__=\"c:80\", job=\"api\"}\n"+
+		"- {__address__=\"b:80\"}\n"+
+		"~ {__address__=\"a:80\", __meta_pod=\"pod-a\"} => {__address__=\"a:80\", __meta_pod=\"pod-a2\"}", diff.DataFunc())
+	require.Equal(t, Target, diff.Type)
+	require.Len(t, diff.LabelsFunc(), 3)
+}
+
+func TestTargetDifferFilter(t *testing.T) {
+	snapshot := func(targets ...labels.Labels) Data {
+		return NewData("discovery.fake.default", Target, uint64(len(targets)), func() string { return "" },
+			WithLabels(func() []labels.Labels { return targets }), AsSnapshot())
+	}
+
+	filter, err := ParseFilter(`{job="api"}`)
+	require.NoError(t, err)
+	differ := NewTargetDiffer(filter)
+
+	diff, ok := differ.Diff(snapshot(labels.FromStrings("__address__", "a:80", "job", "api"), labels.FromStrings("__address__", "b:80")))
+	require.True(t, ok)
+	require.Equal(t, "+ {__address__=\"a:80\", job=\"api\"}", diff.DataFunc())
+
+	_, ok = differ.Diff(snapshot(labels.FromStrings("__address__", "a:80", "job", "api")))
+	require.False(t, ok, "changes to targets not matching the filter must be ignored")
+}
@@ -0,0 +1,86 @@
+package livedebugging
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"golang.org/x/time/rate"
+)
+
+// Filter selects the data sent to a live debugging consumer based on the
+// labels of the data.
+type Filter struct {
+	matchers []*labels.Matcher
+}
+
+// ParseFilter parses a filter expression written as a Prometheus series
+// selector, for example {job="api", level=~"error|warn"}. Names which aren't
+// valid Prometheus label names, such as OpenTelemetry attributes, must be
+// quoted: {"service.name"="checkout"}.
+//
+// An empty expression returns a nil Filter, which matches all data.
+func ParseFilter(expr string) (*Filter, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	matchers, err := parser.ParseMetricSelector(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return &Filter{matchers: matchers}, nil
+}
+
+// Matches returns true if at least one label set of the data matches all the
+// matchers of the filter.
+func (f *Filter) Matches(data Data) bool {
+	if f == nil {
+		return true
+	}
+	if data.LabelsFunc == nil {
+		return false
+	}
+
+	for _, lbls := range data.LabelsFunc() {
+		if f.matchesLabels(lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) matchesLabels(lbls labels.Labels) bool {
+	for _, m := range f.matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sampler reduces the amount of data sent to a live debugging consumer. Data
+// is first sampled with a fixed probability, then rate limited.
+type Sampler struct {
+	probability float64
+	limiter     *rate.Limiter
+}
+
+// NewSampler creates a Sampler keeping data with the given probability,
+// between 0 and 1, and keeping at most maxRate items per second. A maxRate of
+// 0 disables rate limiting.
+func NewSampler(probability float64, maxRate float64) *Sampler {
+	s := &Sampler{probability: probability}
+	if maxRate > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(maxRate), max(1, int(maxRate)))
+	}
+	return s
+}
+
+// Sample returns true if the next data should be sent to the consumer.
+func (s *Sampler) Sample() bool {
+	if s.probability < 1 && rand.Float64() >= s.probability {
+		return false
+	}
+	return s.limiter == nil || s.limiter.Allow()
+}
@@ -0,0 +1,130 @@
+package livedebugging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	withLabels := func(lbls ...labels.Labels) Data {
+		return NewData("fake.liveDebugging", PrometheusMetric, 1, func() string { return "" },
+			WithLabels(func() []labels.Labels { return lbls }))
+	}
+
+	tests := []struct {
+		name    string
+		filter  string
+		data    Data
+		matches bool
+	}{
+		{
+			name:    "empty filter matches everything",
+			filter:  "",
+			data:    NewData("fake.liveDebugging", PrometheusMetric, 1, func() string { return "" }),
+			matches: true,
+		},
+		{
+			name:    "data without labels",
+			filter:  `{job="api"}`,
+			data:    NewData("fake.liveDebugging", PrometheusMetric, 1, func() string { return "" }),
+			matches: false,
+		},
+		{
+			name:    "equal matcher",
+			filter:  `{job="api"}`,
+			data:    withLabels(labels.FromStrings("job", "api", "instance", "a")),
+			matches: true,
+		},
+		{
+			name:    "all matchers must match",
+			filter:  `{job="api", instance!="a"}`,
+			data:    withLabels(labels.FromStrings("job", "api", "instance", "a")),
+			matches: false,
+		},
+		{
+			name:    "metric name",
+			filter:  `up{job=~"api|web"}`,
+			data:    withLabels(labels.FromStrings("__name__", "up", "job", "web")),
+			matches: true,
+		},
+		{
+			name:    "any label set can match",
+			filter:  `{level="error"}`,
+			data:    withLabels(labels.FromStrings("level", "info"), labels.FromStrings("level", "error")),
+			matches: true,
+		},
+		{
+			name:    "quoted attribute name",
+			filter:  `{"service.name"="checkout"}`,
+			data:    withLabels(labels.FromStrings("service.name", "checkout")),
+			matches: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseFilter(tt.filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.matches, filter.Matches(tt.data))
+		})
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	_, err := ParseFilter(`{job=}`)
+	require.ErrorContains(t, err, "invalid filter")
+}
+
+func TestSampler(t *testing.T) {
+	count := func(s *Sampler) int {
+		n := 0
+		for range 1000 {
+			if s.Sample() {
+				n++
+			}
+		}
+		return n
+	}
+
+	require.Equal(t, 1000, count(NewSampler(1, 0)))
+	require.Equal(t, 0, count(NewSampler(0, 0)))
+	// The burst of the rate limiter is the rate, the test runs in well under a second.
+	require.Equal(t, 10, count(NewSampler(1, 10)))
+}
+
+func TestRecords(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := NewData("fake.liveDebugging", LokiLog, 2, func() string { return "test data" }, WithTargetComponentIDs([]string{"component1"}))
+
+	var buf bytes.Buffer
+	for range 2 {
+		b, err := json.Marshal(NewRecord(data, ts))
+		require.NoError(t, err)
+		buf.Write(append(b, '\n'))
+	}
+	buf.WriteString("\n")
+
+	var records []Record
+	require.NoError(t, ReadRecords(&buf, func(r Record) error {
+		records = append(records, r)
+		return nil
+	}))
+
+	expected := Record{
+		Timestamp:          ts,
+		ComponentID:        "fake.liveDebugging",
+		TargetComponentIDs: []string{"component1"},
+		Type:               LokiLog,
+		Count:              2,
+		Data:               "test data",
+	}
+	require.Equal(t, []Record{expected, expected}, records)
+
+	err := ReadRecords(bytes.NewBufferString("{}\nnot json\n"), func(r Record) error { return nil })
+	require.ErrorContains(t, err, "invalid record on line 2")
+}
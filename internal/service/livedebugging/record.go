@@ -0,0 +1,62 @@
+package livedebugging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Record is the serialized form of a Data, used to export a live debugging
+// session. A session is stored as newline-delimited JSON (NDJSON), with one
+// Record per line.
+type Record struct {
+	Timestamp          time.Time `json:"timestamp"`
+	ComponentID        string    `json:"componentID"`
+	TargetComponentIDs []string  `json:"targetComponentIDs,omitempty"`
+	Type               DataType  `json:"type"`
+	Count              uint64    `json:"count"`
+	Data               string    `json:"data"`
+}
+
+// NewRecord creates a Record from data received at the given time. The data
+// string is computed immediately.
+func NewRecord(data Data, ts time.Time) Record {
+	return Record{
+		Timestamp:          ts,
+		ComponentID:        string(data.ComponentID),
+		TargetComponentIDs: data.TargetComponentIDs,
+		Type:               data.Type,
+		Count:              data.Count,
+		Data:               data.DataFunc(),
+	}
+}
+
+// maxRecordSize is the maximum size of a single line when reading records.
+const maxRecordSize = 16 * 1024 * 1024
+
+// ReadRecords reads the NDJSON records from r and calls fn for each of them,
+// in order. Empty lines are ignored. It stops at the first error returned by
+// fn.
+func ReadRecords(r io.Reader, fn func(Record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,73 @@
+package remotecfg
+
+import (
+	"strings"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/service"
+)
+
+// sourcesHost is a [service.Host] giving access to the components of the
+// isolated controllers of every source.
+//
+// The components of the primary source are looked up by their local ID, as
+// they are in its own controller. The components of the named sources are
+// looked up with the ID of their controller as module ID, for example
+// "remotecfg/team/loki.process.default".
+type sourcesHost struct {
+	service.Host // The primary source's host.
+
+	// Hosts of the named sources, keyed by the ID of their controller and
+	// ordered by source name.
+	ids     []string
+	sources map[string]service.Host
+}
+
+var _ service.Host = (*sourcesHost)(nil)
+
+// GetComponent implements [service.Host].
+func (h *sourcesHost) GetComponent(id component.ID, opts component.InfoOptions) (*component.Info, error) {
+	host, moduleID := h.resolve(id.ModuleID)
+	return host.GetComponent(component.ID{ModuleID: moduleID, LocalID: id.LocalID}, opts)
+}
+
+// ListComponents implements [service.Host]. Listing the root module returns
+// the components of every source.
+func (h *sourcesHost) ListComponents(moduleID string, opts component.InfoOptions) ([]*component.Info, error) {
+	if moduleID != "" {
+		host, moduleID := h.resolve(moduleID)
+		return host.ListComponents(moduleID, opts)
+	}
+
+	infos, err := h.Host.ListComponents("", opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range h.ids {
+		sourceInfos, err := h.sources[id].ListComponents("", opts)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, sourceInfos...)
+	}
+	return infos, nil
+}
+
+// resolve returns the host of the source owning a module, and the ID of the
+// module within that host.
+func (h *sourcesHost) resolve(moduleID string) (service.Host, string) {
+	if moduleID == ServiceName {
+		return h.Host, ""
+	}
+	for _, id := range h.ids {
+		if moduleID == id {
+			return h.sources[id], ""
+		}
+		// Modules created by the components of a source are registered with
+		// their full ID in its controller.
+		if strings.HasPrefix(moduleID, id+"/") {
+			return h.sources[id], moduleID
+		}
+	}
+	return h.Host, moduleID
+}
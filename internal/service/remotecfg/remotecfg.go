@@ -9,24 +9,21 @@ import (
 	"math"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/go-kit/log"
-	collectorv1 "github.com/grafana/alloy-remote-config/api/gen/proto/go/collector/v1"
 	"github.com/grafana/alloy-remote-config/api/gen/proto/go/collector/v1/collectorv1connect"
 	"github.com/grafana/alloy/internal/alloyseed"
 	"github.com/grafana/alloy/internal/build"
 	"github.com/grafana/alloy/internal/component/common/config"
 	"github.com/grafana/alloy/internal/featuregate"
-	alloy_runtime "github.com/grafana/alloy/internal/runtime"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
 	"github.com/grafana/alloy/internal/service"
-	"github.com/grafana/alloy/internal/util/jitter"
 	"github.com/grafana/alloy/syntax"
 	"github.com/grafana/alloy/syntax/ast"
 	"github.com/prometheus/client_golang/prometheus"
@@ -46,49 +43,53 @@ const baseJitter = 100 * time.Millisecond
 // slightly less than MaxInt to avoid overflowing
 const disablePollingFrequency = math.MaxInt64 - baseJitter
 
+// longPollInterval is the delay between two consecutive long-poll requests.
+// It has to be larger than baseJitter for the ticker to be valid.
+const longPollInterval = 2 * baseJitter
+
+// longPollGracePeriod is added to the long-poll timeout when waiting for the
+// API to respond, so that the server has a chance to reply before the
+// request is canceled on the client side.
+const longPollGracePeriod = 10 * time.Second
+
+// longPollTimeoutHeader is sent with GetConfig requests in long-poll mode.
+// It tells the API for how long it may hold the request open while waiting
+// for a configuration change.
+const longPollTimeoutHeader = "X-Alloy-Long-Poll-Timeout"
+
 var errNotModified = errors.New("config not modified since last fetch")
 
 // Service implements a service for remote configuration.
-// The default value of ch is nil; this means it will block forever if the
-// remotecfg service is not configured. In addition, we're keeping track of
-// the ticker so we can avoid leaking goroutines.
-// The datapath field is where the service looks for the local cache location.
-// It is defined as a hash of the Arguments field.
+// The service fetches configuration from one or more sources. The primary
+// source is configured by the top-level arguments, while additional named
+// sources are configured with `source` blocks. Each source is loaded in its
+// own isolated controller, with independent caching and polling settings.
 type Service struct {
 	opts Options
-	args Arguments
-
-	ctrl service.Controller
-
-	mut                  sync.RWMutex
-	asClient             collectorv1connect.CollectorServiceClient
-	clientFactory        func(args Arguments) (collectorv1connect.CollectorServiceClient, error)
-	ticker               *jitter.Ticker
-	updateTickerChan     chan struct{}
-	pollFrequency        time.Duration
-	dataPath             string
-	lastLoadedConfigHash string
-	systemAttrs          map[string]string
-	attrs                map[string]string
-	metrics              *metrics
-
-	// This is the hash received from the API. It is used to determine if
-	// the configuration has changed since the last fetch
-	remoteHash string
-
-	// This is the AST file parsed from the configuration. This is used
-	// for the support bundle
-	astFile *ast.File
+
+	mut           sync.RWMutex
+	args          Arguments
+	host          service.Host
+	runCtx        context.Context
+	clientFactory func(args SourceArguments) (collectorv1connect.CollectorServiceClient, error)
+	systemAttrs   map[string]string
+	metrics       *metrics
+	wg            sync.WaitGroup
+
+	// The primary source always exists, even when it's not configured, so
+	// that the Host of its controller can be exposed with Data.
+	primary *source
+	sources map[string]*source
 }
 
 type metrics struct {
-	lastLoadSuccess      prometheus.Gauge
-	lastFetchNotModified prometheus.Gauge
-	totalFailures        prometheus.Counter
+	lastLoadSuccess      *prometheus.GaugeVec
+	lastFetchNotModified *prometheus.GaugeVec
+	totalFailures        *prometheus.CounterVec
 	configHash           *prometheus.GaugeVec
-	lastFetchSuccessTime prometheus.Gauge
-	totalAttempts        prometheus.Counter
-	getConfigTime        prometheus.Histogram
+	lastFetchSuccessTime *prometheus.GaugeVec
+	totalAttempts        *prometheus.CounterVec
+	getConfigTime        *prometheus.HistogramVec
 }
 
 // ServiceName defines the name used for the remotecfg service.
@@ -113,7 +114,10 @@ type Arguments struct {
 	Name             string                   `alloy:"name,attr,optional"`
 	Attributes       map[string]string        `alloy:"attributes,attr,optional"`
 	PollFrequency    time.Duration            `alloy:"poll_frequency,attr,optional"`
+	LongPoll         bool                     `alloy:"long_poll,attr,optional"`
+	LongPollTimeout  time.Duration            `alloy:"long_poll_timeout,attr,optional"`
 	HTTPClientConfig *config.HTTPClientConfig `alloy:",squash"`
+	Sources          []SourceArguments        `alloy:"source,block,optional"`
 }
 
 // GetDefaultArguments populates the default values for the Arguments struct.
@@ -122,6 +126,7 @@ func GetDefaultArguments() Arguments {
 		ID:               alloyseed.Get().UID,
 		Attributes:       make(map[string]string),
 		PollFrequency:    1 * time.Minute,
+		LongPollTimeout:  5 * time.Minute,
 		HTTPClientConfig: config.CloneDefaultHTTPClientConfig(),
 	}
 }
@@ -133,14 +138,19 @@ func (a *Arguments) SetToDefault() {
 
 // Validate implements syntax.Validator.
 func (a *Arguments) Validate() error {
-	if a.PollFrequency < 10*time.Second {
-		return fmt.Errorf("poll_frequency must be at least \"10s\", got %q", a.PollFrequency)
+	if err := validatePolling(a.PollFrequency, a.LongPoll, a.LongPollTimeout); err != nil {
+		return err
+	}
+	if err := validateAttributes(a.Attributes); err != nil {
+		return err
 	}
 
-	for k := range a.Attributes {
-		if strings.HasPrefix(k, reservedAttributeNamespace+namespaceDelimiter) {
-			return fmt.Errorf("%q is a reserved namespace for remotecfg attribute keys", reservedAttributeNamespace)
+	seen := make(map[string]struct{}, len(a.Sources))
+	for _, src := range a.Sources {
+		if _, ok := seen[src.Name]; ok {
+			return fmt.Errorf("source %q is defined more than once", src.Name)
 		}
+		seen[src.Name] = struct{}{}
 	}
 
 	// We must explicitly Validate because HTTPClientConfig is squashed and it
@@ -152,15 +162,53 @@ func (a *Arguments) Validate() error {
 	return nil
 }
 
-// Hash marshals the Arguments and returns a hash representation.
+func validatePolling(pollFrequency time.Duration, longPoll bool, longPollTimeout time.Duration) error {
+	if pollFrequency < 10*time.Second {
+		return fmt.Errorf("poll_frequency must be at least \"10s\", got %q", pollFrequency)
+	}
+
+	if longPoll && longPollTimeout < time.Second {
+		return fmt.Errorf("long_poll_timeout must be at least \"1s\", got %q", longPollTimeout)
+	}
+	return nil
+}
+
+func validateAttributes(attrs map[string]string) error {
+	for k := range attrs {
+		if strings.HasPrefix(k, reservedAttributeNamespace+namespaceDelimiter) {
+			return fmt.Errorf("%q is a reserved namespace for remotecfg attribute keys", reservedAttributeNamespace)
+		}
+	}
+	return nil
+}
+
+// Hash marshals the Arguments and returns a hash representation. Sources
+// are excluded, so that adding or removing a source doesn't change the
+// location of the primary source's cache.
 func (a *Arguments) Hash() (string, error) {
-	b, err := syntax.Marshal(a)
+	primary := *a
+	primary.Sources = nil
+
+	b, err := syntax.Marshal(&primary)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal arguments: %w", err)
 	}
 	return getHash(b), nil
 }
 
+// primarySource returns the settings of the source configured by the
+// top-level arguments.
+func (a *Arguments) primarySource() SourceArguments {
+	return SourceArguments{
+		URL:              a.URL,
+		Attributes:       a.Attributes,
+		PollFrequency:    a.PollFrequency,
+		LongPoll:         a.LongPoll,
+		LongPollTimeout:  a.LongPollTimeout,
+		HTTPClientConfig: a.HTTPClientConfig,
+	}
+}
+
 // New returns a new instance of the remotecfg service.
 func New(opts Options) (*Service, error) {
 	basePath := filepath.Join(opts.StoragePath, ServiceName)
@@ -169,12 +217,11 @@ func New(opts Options) (*Service, error) {
 		return nil, err
 	}
 
-	return &Service{
-		opts:             opts,
-		systemAttrs:      getSystemAttributes(),
-		updateTickerChan: make(chan struct{}, 1),
-		pollFrequency:    disablePollingFrequency,
-		clientFactory: func(args Arguments) (collectorv1connect.CollectorServiceClient, error) {
+	s := &Service{
+		opts:        opts,
+		systemAttrs: getSystemAttributes(),
+		sources:     make(map[string]*source),
+		clientFactory: func(args SourceArguments) (collectorv1connect.CollectorServiceClient, error) {
 			httpClient, err := commonconfig.NewClientFromConfig(*args.HTTPClientConfig.Convert(), "remoteconfig")
 			if err != nil {
 				return nil, err
@@ -185,7 +232,9 @@ func New(opts Options) (*Service, error) {
 				connect.WithHTTPGet(),
 			), nil
 		},
-	}, nil
+	}
+	s.primary = newSource(s, "")
+	return s, nil
 }
 
 func getSystemAttributes() map[string]string {
@@ -203,63 +252,109 @@ func (s *Service) registerMetrics() {
 				Name: "remotecfg_hash",
 				Help: "Hash of the currently active remote configuration.",
 			},
-			[]string{"hash"},
+			[]string{"source", "hash"},
 		),
-		lastLoadSuccess: prom.NewGauge(
+		lastLoadSuccess: prom.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "remotecfg_last_load_successful",
 				Help: "Remote config loaded successfully",
 			},
+			[]string{"source"},
 		),
-		lastFetchNotModified: prom.NewGauge(
+		lastFetchNotModified: prom.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "remotecfg_last_load_not_modified",
 				Help: "Remote config not modified since last fetch",
 			},
+			[]string{"source"},
 		),
-		totalFailures: prom.NewCounter(
+		totalFailures: prom.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "remotecfg_load_failures_total",
 				Help: "Remote configuration load failures",
 			},
+			[]string{"source"},
 		),
-		totalAttempts: prom.NewCounter(
+		totalAttempts: prom.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "remotecfg_load_attempts_total",
 				Help: "Attempts to load remote configuration",
 			},
+			[]string{"source"},
 		),
-		lastFetchSuccessTime: prom.NewGauge(
+		lastFetchSuccessTime: prom.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "remotecfg_last_load_success_timestamp_seconds",
 				Help: "Timestamp of the last successful remote configuration load",
 			},
+			[]string{"source"},
 		),
-		getConfigTime: prom.NewHistogram(
+		getConfigTime: prom.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name: "remotecfg_request_duration_seconds",
 				Help: "Duration of remote configuration requests.",
 			},
+			[]string{"source"},
 		),
 	}
 	s.metrics = mets
 }
 
+func (s *Service) getMetrics() *metrics {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.metrics
+}
+
+// deleteMetrics removes the series of a source that has been removed.
+func (s *Service) deleteMetrics(name string) {
+	m := s.getMetrics()
+	if m == nil {
+		return
+	}
+
+	labels := prometheus.Labels{"source": name}
+	m.configHash.DeletePartialMatch(labels)
+	m.lastLoadSuccess.Delete(labels)
+	m.lastFetchNotModified.Delete(labels)
+	m.totalFailures.Delete(labels)
+	m.totalAttempts.Delete(labels)
+	m.lastFetchSuccessTime.Delete(labels)
+	m.getConfigTime.Delete(labels)
+}
+
 // Data returns an instance of [Data]. Calls to Data are cachable by the
 // caller.
 // Data must only be called after Run.
 func (s *Service) Data() any {
-	if s.ctrl == nil {
-		return Data{Host: nil}
+	primary := s.primary.getHost()
+	if primary == nil {
+		return Data{}
 	}
-	host := s.ctrl.(alloy_runtime.ServiceController).GetHost()
+
+	s.mut.RLock()
+	names := slices.Sorted(maps.Keys(s.sources))
+	host := &sourcesHost{Host: primary, sources: make(map[string]service.Host, len(names))}
+	for _, name := range names {
+		sourceHost := s.sources[name].getHost()
+		if sourceHost == nil {
+			continue
+		}
+		id := ServiceName + "/" + name
+		host.ids = append(host.ids, id)
+		host.sources[id] = sourceHost
+	}
+	s.mut.RUnlock()
+
 	return Data{Host: host}
 }
 
 // Data includes information associated with the HTTP service.
 type Data struct {
-	// Host exposes the Host of the isolated controller that is created by the
-	// remotecfg service.
+	// Host exposes the components of the isolated controllers that are
+	// created by the remotecfg service for every source. The components of
+	// the named sources have the ID of their controller, remotecfg/<name>, as
+	// module ID.
 	Host service.Host
 }
 
@@ -278,289 +373,200 @@ var _ service.Service = (*Service)(nil)
 // Run implements [service.Service] and starts the remotecfg service. It will
 // run until the provided context is canceled or there is a fatal error.
 func (s *Service) Run(ctx context.Context, host service.Host) error {
-	s.ctrl = host.NewController(ServiceName)
+	ctx, cancel := context.WithCancel(ctx)
+	// Wait for the named sources to exit once they have been canceled.
+	defer s.wg.Wait()
+	defer cancel()
+
+	s.mut.Lock()
+	s.host = host
+	s.runCtx = ctx
+	sources := slices.Collect(maps.Values(s.sources))
+	s.mut.Unlock()
 
-	s.fetch()
-	err := s.registerCollector()
+	s.primary.start(ctx, host.NewController(ServiceName))
+	err := s.primary.registerCollector()
 	if err != nil && err != errNoopClient {
 		return err
 	}
 
-	s.ticker = jitter.NewTicker(s.pollFrequency, baseJitter)
+	for _, src := range sources {
+		s.startSource(ctx, host, src)
+	}
 
-	defer func() {
-		s.ticker.Stop()
-		s.ticker = nil
-	}()
+	s.primary.run(ctx)
+	return nil
+}
 
-	// Run the service's own controller.
+// startSource runs a named source in the background until either ctx is
+// canceled or the source is removed.
+func (s *Service) startSource(ctx context.Context, host service.Host, src *source) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	src.mut.Lock()
+	src.cancel = cancel
+	src.done = done
+	src.mut.Unlock()
+
+	s.wg.Add(1)
 	go func() {
-		s.ctrl.Run(ctx)
-	}()
+		defer s.wg.Done()
+		defer close(done)
 
-	for {
-		select {
-		case <-s.ticker.C:
-			err := s.fetchRemote()
-			if err != nil && err != errNoopClient {
-				level.Error(s.opts.Logger).Log("msg", "failed to fetch remote configuration from the API", "err", err)
-			}
-		case <-s.updateTickerChan:
-			s.ticker.Reset(s.pollFrequency)
-		case <-ctx.Done():
-			return nil
+		src.start(ctx, host.NewController(ServiceName+"/"+src.name))
+		if err := src.registerCollector(); err != nil && err != errNoopClient {
+			level.Error(src.logger).Log("msg", "failed to register collector", "err", err)
 		}
-	}
+		src.run(ctx)
+	}()
 }
 
 // Update implements [service.Service] and applies settings.
 func (s *Service) Update(newConfig any) error {
 	newArgs := newConfig.(Arguments)
-	s.mut.Lock()
-
-	// We either never set the block on the first place, or recently removed
-	// it. Make sure we stop everything gracefully before returning.
-	if newArgs.URL == "" {
-		s.setPollFrequency(disablePollingFrequency)
-		s.asClient = noopClient{}
-		s.args.HTTPClientConfig = config.CloneDefaultHTTPClientConfig()
-		s.mut.Unlock()
 
-		s.setLastLoadedCfgHash("")
-		return nil
+	s.mut.Lock()
+	if s.metrics == nil && (newArgs.URL != "" || len(newArgs.Sources) > 0) {
+		s.registerMetrics()
 	}
 
-	hash, err := newArgs.Hash()
-	if err != nil {
-		s.mut.Unlock()
-		return err
+	var (
+		updated = make(map[*source]SourceArguments, len(newArgs.Sources))
+		added   []*source
+		removed []*source
+	)
+	for _, srcArgs := range newArgs.Sources {
+		src, ok := s.sources[srcArgs.Name]
+		if !ok {
+			src = newSource(s, srcArgs.Name)
+			s.sources[srcArgs.Name] = src
+			added = append(added, src)
+		}
+		updated[src] = srcArgs
 	}
-	s.dataPath = filepath.Join(s.opts.StoragePath, ServiceName, hash)
-
-	s.setPollFrequency(newArgs.PollFrequency)
-	// Update the HTTP client last since it might fail.
-	if !reflect.DeepEqual(s.args.HTTPClientConfig, newArgs.HTTPClientConfig) {
-		client, err := s.clientFactory(newArgs)
-		if err != nil {
-			s.mut.Unlock()
-			return err
+	for name, src := range s.sources {
+		if _, ok := updated[src]; !ok {
+			delete(s.sources, name)
+			removed = append(removed, src)
 		}
-		s.asClient = client
 	}
-	// Combine the new attributes on top of the system attributes
-	s.attrs = maps.Clone(s.systemAttrs)
-	maps.Copy(s.attrs, newArgs.Attributes)
-
-	// Update the args as the last step to avoid polluting any comparisons
 	s.args = newArgs
-	err = s.registerCollector()
-	if err != nil {
-		s.mut.Unlock()
-		return err
-	}
-
-	if s.metrics == nil {
-		s.registerMetrics()
-	}
+	host, runCtx := s.host, s.runCtx
 	s.mut.Unlock()
 
-	// If we've already called Run, then immediately trigger an API call with
-	// the updated Arguments, and/or fall back to the updated cache location.
-	if s.ctrl != nil && s.ctrl.Ready() {
-		s.fetch()
-	}
-
-	return nil
-}
-
-// GetCachedAstFile returns the AST file that was parsed from the configuration.
-func (s *Service) GetCachedAstFile() *ast.File {
-	s.mut.RLock()
-	defer s.mut.RUnlock()
-	return s.astFile
-}
-
-// fetch attempts to read configuration from the API and the local cache
-// and then parse/load their contents in order of preference.
-func (s *Service) fetch() {
-	if err := s.fetchRemote(); err != nil {
-		level.Error(s.opts.Logger).Log("msg", "failed to fetch remote config", "err", err)
-		s.fetchLocal()
+	for _, src := range removed {
+		src.stop()
 	}
-}
 
-func (s *Service) registerCollector() error {
-	req := connect.NewRequest(&collectorv1.RegisterCollectorRequest{
-		Id:              s.args.ID,
-		LocalAttributes: s.attrs,
-		Name:            s.args.Name,
-	})
-	client := s.asClient
-
-	_, err := client.RegisterCollector(context.Background(), req)
+	hash, err := newArgs.Hash()
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-func (s *Service) fetchRemote() error {
-	if !s.isEnabled() {
-		return nil
-	}
-
-	level.Debug(s.opts.Logger).Log("msg", "fetching remote configuration")
-
-	b, err := s.getAPIConfig()
-	s.metrics.totalAttempts.Add(1)
-
-	if err == nil {
-		s.metrics.lastLoadSuccess.Set(1)
-		s.metrics.lastFetchSuccessTime.SetToCurrentTime()
-	} else if err != errNotModified {
-		s.metrics.totalFailures.Add(1)
-		s.metrics.lastLoadSuccess.Set(0)
+	dataPath := filepath.Join(s.opts.StoragePath, ServiceName, hash)
+	err = s.primary.update(newArgs.primarySource(), newArgs.ID, newArgs.Name, dataPath)
+	if err != nil {
 		return err
 	}
 
-	if err == errNotModified {
-		level.Debug(s.opts.Logger).Log("msg", "skipping over API response since it has not been modified since last fetch")
-		s.metrics.lastFetchNotModified.Set(1)
-		return nil
-	} else {
-		s.metrics.lastFetchNotModified.Set(0)
-	}
-
-	// API returned the same configuration as the last one we loaded, no need to reload.
-	newConfigHash := getHash(b)
-	if s.getLastLoadedCfgHash() == newConfigHash {
-		level.Debug(s.opts.Logger).Log("msg", "skipping over API response since it matched the last loaded one")
-		return nil
+	for src, srcArgs := range updated {
+		hash, err := srcArgs.Hash()
+		if err != nil {
+			return err
+		}
+		basePath := filepath.Join(s.opts.StoragePath, ServiceName, "sources", src.name)
+		if err := os.MkdirAll(basePath, 0750); err != nil {
+			return err
+		}
+		err = src.update(srcArgs, newArgs.ID, newArgs.Name, filepath.Join(basePath, hash))
+		if err != nil {
+			return fmt.Errorf("failed to update source %q: %w", src.name, err)
+		}
 	}
 
-	err = s.parseAndLoad(b)
-	if err != nil {
-		return err
+	// Sources added after Run has been called must be started here.
+	if host != nil {
+		for _, src := range added {
+			s.startSource(runCtx, host, src)
+		}
 	}
 
-	// If successful, flush to disk and keep a copy.
-	s.setCachedConfig(b)
 	return nil
 }
 
-func (s *Service) fetchLocal() {
-	b, err := s.getCachedConfig()
-	if err != nil {
-		level.Error(s.opts.Logger).Log("msg", "failed to read from cache", "err", err)
-		return
-	}
+// GetCachedAstFile returns the AST file that was parsed from the
+// configuration of the primary source.
+func (s *Service) GetCachedAstFile() *ast.File {
+	return s.primary.getCachedAstFile()
+}
 
-	err = s.parseAndLoad(b)
-	if err != nil {
-		level.Error(s.opts.Logger).Log("msg", "failed to load from cache", "err", err)
+// GetCachedAstFiles returns the AST files that were parsed from the
+// configuration of every source, keyed by source name. The primary source
+// has an empty name.
+func (s *Service) GetCachedAstFiles() map[string]*ast.File {
+	files := make(map[string]*ast.File)
+	if f := s.primary.getCachedAstFile(); f != nil {
+		files[""] = f
 	}
-}
 
-func (s *Service) getAPIConfig() ([]byte, error) {
 	s.mut.RLock()
-	req := connect.NewRequest(&collectorv1.GetConfigRequest{
-		Id:              s.args.ID,
-		LocalAttributes: s.attrs,
-		Hash:            s.remoteHash,
-	})
-	client := s.asClient
-	s.mut.RUnlock()
-
-	start := time.Now()
-	gcr, err := client.GetConfig(context.Background(), req)
-	if err != nil {
-		return nil, err
-	}
-	s.metrics.getConfigTime.Observe(time.Since(start).Seconds())
-	if gcr.Msg.NotModified {
-		return nil, errNotModified
-	}
-	if gcr.Msg.Hash != "" {
-		s.mut.Lock()
-		s.remoteHash = gcr.Msg.Hash
-		s.mut.Unlock()
+	defer s.mut.RUnlock()
+	for name, src := range s.sources {
+		if f := src.getCachedAstFile(); f != nil {
+			files[name] = f
+		}
 	}
-	return []byte(gcr.Msg.GetContent()), nil
+	return files
 }
 
-func (s *Service) getCachedConfig() ([]byte, error) {
-	s.mut.RLock()
-	p := s.dataPath
-	s.mut.RUnlock()
-
-	return os.ReadFile(p)
+// GetStatus returns the status of the primary source.
+func (s *Service) GetStatus() Status {
+	return s.primary.getStatus()
 }
 
-func (s *Service) setCachedConfig(b []byte) {
-	s.mut.RLock()
-	p := s.dataPath
-	s.mut.RUnlock()
-
-	err := os.WriteFile(p, b, 0750)
-	if err != nil {
-		level.Error(s.opts.Logger).Log("msg", "failed to flush remote configuration contents the on-disk cache", "err", err)
-	}
+// SourceInfo describes a source of remote configuration.
+type SourceInfo struct {
+	// Name of the source. The primary source has an empty name.
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Status Status `json:"status"`
 }
 
-func (s *Service) parseAndLoad(b []byte) error {
+// Sources returns information about the configured sources, starting with
+// the primary source if it's enabled and followed by the named sources
+// ordered by name.
+func (s *Service) Sources() []SourceInfo {
 	s.mut.RLock()
-	ctrl := s.ctrl
+	sources := slices.SortedFunc(maps.Values(s.sources), func(a, b *source) int {
+		return strings.Compare(a.name, b.name)
+	})
 	s.mut.RUnlock()
 
-	if len(b) == 0 {
-		return nil
+	if s.primary.isEnabled() {
+		sources = append([]*source{s.primary}, sources...)
 	}
-	s.setLastLoadedCfgHash(getHash(b))
-	file, err := ctrl.LoadSource(b, nil, s.opts.ConfigPath)
-	if err != nil {
-		return err
-	}
-
-	s.setAstFile(file)
-	return nil
-}
-
-func (s *Service) getLastLoadedCfgHash() string {
-	s.mut.RLock()
-	defer s.mut.RUnlock()
-
-	return s.lastLoadedConfigHash
-}
 
-func (s *Service) setAstFile(f *ast.File) {
-	s.mut.Lock()
-	defer s.mut.Unlock()
-	s.astFile = f
+	infos := make([]SourceInfo, 0, len(sources))
+	for _, src := range sources {
+		infos = append(infos, src.info())
+	}
+	return infos
 }
 
-func (s *Service) setLastLoadedCfgHash(h string) {
-	s.mut.Lock()
-	defer s.mut.Unlock()
-	if s.metrics != nil {
-		s.metrics.configHash.Reset()
-		s.metrics.configHash.WithLabelValues(h).Set(1)
+// SourceHost returns the Host of the isolated controller of the named
+// source. The primary source is looked up with an empty name.
+func (s *Service) SourceHost(name string) (service.Host, bool) {
+	if name == "" {
+		host := s.primary.getHost()
+		return host, host != nil
 	}
-	s.lastLoadedConfigHash = h
-}
 
-func (s *Service) isEnabled() bool {
 	s.mut.RLock()
-	defer s.mut.RUnlock()
-	return s.args.URL != "" && s.asClient != nil
-}
-
-func (s *Service) setPollFrequency(t time.Duration) {
-	s.pollFrequency = t
-	select {
-	// If the channel is full it means there's already an update triggered
-	// or Run is not running. In both cases, we don't need to trigger another
-	// update or block.
-	case s.updateTickerChan <- struct{}{}:
-	default:
+	src, ok := s.sources[name]
+	s.mut.RUnlock()
+	if !ok {
+		return nil, false
 	}
+
+	host := src.getHost()
+	return host, host != nil
 }
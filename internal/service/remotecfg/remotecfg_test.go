@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
 	"testing"
@@ -25,6 +27,7 @@ import (
 	"github.com/grafana/alloy/syntax"
 	"github.com/grafana/alloy/syntax/ast"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -51,7 +54,7 @@ func TestOnDiskCache(t *testing.T) {
 	client.getConfigFunc = buildGetConfigHandler("unparseable config", "", false)
 
 	// Write the cache contents, and run the service.
-	err := os.WriteFile(env.svc.dataPath, []byte(cacheContents), 0644)
+	err := os.WriteFile(env.svc.primary.dataPath, []byte(cacheContents), 0644)
 	require.NoError(t, err)
 
 	// Run the service.
@@ -65,7 +68,7 @@ func TestOnDiskCache(t *testing.T) {
 	// As the API response was unparseable, verify that the service has loaded
 	// the on-disk cache contents.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		b, err := env.svc.getCachedConfig()
+		b, err := env.svc.primary.getCachedConfig()
 		assert.NoError(c, err)
 		assert.Equal(c, cacheContents, string(b))
 	}, time.Second, 10*time.Millisecond)
@@ -109,7 +112,7 @@ func TestGoodBadGood(t *testing.T) {
 	// As the API response was successful, verify that the service has loaded
 	// the valid response.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		assert.Equal(c, getHash([]byte(cfgGood)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfgGood)), env.svc.primary.getLastLoadedCfgHash())
 	}, time.Second, 10*time.Millisecond)
 
 	// Update the response returned by the API to an invalid configuration.
@@ -121,13 +124,13 @@ func TestGoodBadGood(t *testing.T) {
 	// loaded and flushed on disk, but also recorded the "bad" hash saved for
 	// comparison.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		b, err := env.svc.getCachedConfig()
+		b, err := env.svc.primary.getCachedConfig()
 		assert.NoError(c, err)
 		assert.Equal(c, cfgGood, string(b))
 	}, 1*time.Second, 10*time.Millisecond)
 
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		assert.Equal(c, getHash([]byte(cfgBad)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfgBad)), env.svc.primary.getLastLoadedCfgHash())
 	}, 1*time.Second, 10*time.Millisecond)
 
 	// Update the response returned by the API to the previous "good"
@@ -138,7 +141,7 @@ func TestGoodBadGood(t *testing.T) {
 
 	// Verify that the service has updated the hash.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		assert.Equal(c, getHash([]byte(cfgGood)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfgGood)), env.svc.primary.getLastLoadedCfgHash())
 	}, 1*time.Second, 10*time.Millisecond)
 
 	cancel()
@@ -180,7 +183,7 @@ func TestAPIResponse(t *testing.T) {
 	// As the API response was successful, verify that the service has loaded
 	// the valid response.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		assert.Equal(c, getHash([]byte(cfg1)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfg1)), env.svc.primary.getLastLoadedCfgHash())
 	}, time.Second, 10*time.Millisecond)
 
 	// Update the response returned by the API.
@@ -190,7 +193,7 @@ func TestAPIResponse(t *testing.T) {
 
 	// Verify that the service has loaded the updated response.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		assert.Equal(c, getHash([]byte(cfg2)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfg2)), env.svc.primary.getLastLoadedCfgHash())
 	}, 1*time.Second, 10*time.Millisecond)
 
 	cancel()
@@ -231,7 +234,7 @@ func TestAPIResponseNotModified(t *testing.T) {
 	// As the API response was successful, verify that the service has loaded
 	// the valid response.
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
-		assert.Equal(c, getHash([]byte(cfg1)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfg1)), env.svc.primary.getLastLoadedCfgHash())
 	}, time.Second, 10*time.Millisecond)
 
 	// Update the response returned by the API.
@@ -245,13 +248,392 @@ func TestAPIResponseNotModified(t *testing.T) {
 	require.EventuallyWithT(t, func(c *assert.CollectT) {
 		// Ensure that getConfig has been called again since changing the response.
 		assert.Greater(c, client.getConfigCalls.Load(), calls)
-		assert.Equal(c, getHash([]byte(cfg1)), env.svc.getLastLoadedCfgHash())
+		assert.Equal(c, getHash([]byte(cfg1)), env.svc.primary.getLastLoadedCfgHash())
 	}, 1*time.Second, 10*time.Millisecond)
 
 	cancel()
 	wg.Wait()
 }
 
+func TestStatusReporting(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	url := "https://example.com/"
+	cfgGood := `loki.process "default" { forward_to = [] }`
+	cfgBad := `unparseable config`
+
+	client := &collectorClient{}
+
+	var (
+		attrsMut  sync.Mutex
+		lastAttrs map[string]string
+	)
+	getLastAttrs := func() map[string]string {
+		attrsMut.Lock()
+		defer attrsMut.Unlock()
+		return lastAttrs
+	}
+	buildHandler := func(in string) func(context.Context, *connect.Request[collectorv1.GetConfigRequest]) (*connect.Response[collectorv1.GetConfigResponse], error) {
+		return func(ctx context.Context, req *connect.Request[collectorv1.GetConfigRequest]) (*connect.Response[collectorv1.GetConfigResponse], error) {
+			attrsMut.Lock()
+			lastAttrs = req.Msg.LocalAttributes
+			attrsMut.Unlock()
+			return buildGetConfigHandler(in, "", false)(ctx, req)
+		}
+	}
+
+	var registerCalled atomic.Bool
+	client.mut.Lock()
+	client.getConfigFunc = buildHandler(cfgGood)
+	client.registerCollectorFunc = buildRegisterCollectorFunc(&registerCalled)
+	client.mut.Unlock()
+
+	env := newTestEnvironment(t, client)
+	require.NoError(t, env.ApplyConfig(fmt.Sprintf(`
+		url            = "%s"
+		poll_frequency = "10s"
+	`, url)))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, env.Run(ctx))
+	}()
+
+	// Once the good configuration has been loaded, the following requests
+	// report its hash and the health of its components.
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		attrs := getLastAttrs()
+		assert.Equal(c, string(LoadStatusOK), attrs[attrConfigStatus])
+		assert.Equal(c, getHash([]byte(cfgGood)), attrs[attrConfigHash])
+		assert.NotContains(c, attrs, attrConfigError)
+		assert.Equal(c, "1", attrs[attrComponentsHealthy])
+		assert.Equal(c, "0", attrs[attrComponentsUnhealthy])
+		assert.Contains(c, attrs, reservedAttributeNamespace+namespaceDelimiter+"os")
+	}, time.Second, 10*time.Millisecond)
+
+	// A bad configuration is reported as an error, but the effective hash is
+	// still the one of the running configuration.
+	client.mut.Lock()
+	client.getConfigFunc = buildHandler(cfgBad)
+	client.mut.Unlock()
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		attrs := getLastAttrs()
+		assert.Equal(c, string(LoadStatusError), attrs[attrConfigStatus])
+		assert.Equal(c, getHash([]byte(cfgGood)), attrs[attrConfigHash])
+		assert.NotEmpty(c, attrs[attrConfigError])
+	}, time.Second, 10*time.Millisecond)
+
+	status := env.svc.GetStatus()
+	require.Equal(t, LoadStatusError, status.LastStatus)
+	require.Equal(t, getHash([]byte(cfgGood)), status.EffectiveHash)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestLongPoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cfg1 := `loki.process "default" { forward_to = [] }`
+	cfg2 := `loki.process "updated" { forward_to = [] }`
+
+	server := newLongPollServer(cfg1)
+	path, handler := collectorv1connect.NewCollectorServiceHandler(server)
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	env := newTestEnvironment(t, nil)
+	env.svc.clientFactory = func(args SourceArguments) (collectorv1connect.CollectorServiceClient, error) {
+		return collectorv1connect.NewCollectorServiceClient(srv.Client(), args.URL, connect.WithHTTPGet()), nil
+	}
+	// The polling frequency is large enough that only long-polling can pick
+	// up the change within the test's deadline.
+	require.NoError(t, env.ApplyConfig(fmt.Sprintf(`
+		url               = "%s"
+		poll_frequency    = "1h"
+		long_poll         = true
+		long_poll_timeout = "30s"
+	`, srv.URL)))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, env.Run(ctx))
+	}()
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, getHash([]byte(cfg1)), env.svc.primary.getLastLoadedCfgHash())
+	}, time.Second, 10*time.Millisecond)
+
+	// Wait for the service to block on the next request before updating the
+	// configuration.
+	require.Eventually(t, func() bool { return server.waiting.Load() }, time.Second, 10*time.Millisecond)
+	server.setContent(cfg2)
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, getHash([]byte(cfg2)), env.svc.primary.getLastLoadedCfgHash())
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The effective configuration hash is reported with the next request.
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, getHash([]byte(cfg2)), server.getLastAttributes()[attrConfigHash])
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Canceling the context must not wait for the pending long-poll request.
+	cancel()
+	wg.Wait()
+}
+
+func TestLongPollIgnoredByAPI(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cfg := `loki.process "default" { forward_to = [] }`
+
+	// The API answers right away instead of holding the request open.
+	client := &collectorClient{}
+	var registerCalled atomic.Bool
+	client.mut.Lock()
+	client.getConfigFunc = buildGetConfigHandler(cfg, getHash([]byte(cfg)), false)
+	client.registerCollectorFunc = buildRegisterCollectorFunc(&registerCalled)
+	client.mut.Unlock()
+
+	env := newTestEnvironment(t, client)
+	require.NoError(t, env.ApplyConfig(`
+		url               = "https://example.com/"
+		poll_frequency    = "1h"
+		long_poll         = true
+		long_poll_timeout = "30s"
+	`))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, env.Run(ctx))
+	}()
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		assert.Equal(c, getHash([]byte(cfg)), env.svc.primary.getLastLoadedCfgHash())
+	}, time.Second, 10*time.Millisecond)
+
+	// After a request which returned without waiting, the service falls back
+	// to the polling frequency instead of sending requests in a loop.
+	time.Sleep(time.Second)
+	require.LessOrEqual(t, client.getConfigCalls.Load(), int32(3))
+
+	cancel()
+	wg.Wait()
+}
+
+func TestMultipleSources(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cfgBaseline := `loki.process "baseline" { forward_to = [] }`
+	cfgTeam := `loki.process "team" { forward_to = [] }`
+
+	var (
+		registerCalled atomic.Bool
+		teamAttrs      sync.Map
+	)
+	baselineClient := &collectorClient{
+		getConfigFunc:         buildGetConfigHandler(cfgBaseline, "", false),
+		registerCollectorFunc: buildRegisterCollectorFunc(&registerCalled),
+	}
+	teamClient := &collectorClient{
+		getConfigFunc: func(ctx context.Context, req *connect.Request[collectorv1.GetConfigRequest]) (*connect.Response[collectorv1.GetConfigResponse], error) {
+			for k, v := range req.Msg.LocalAttributes {
+				teamAttrs.Store(k, v)
+			}
+			return buildGetConfigHandler(cfgTeam, "", false)(ctx, req)
+		},
+		registerCollectorFunc: buildRegisterCollectorFunc(&registerCalled),
+	}
+
+	env := newTestEnvironment(t, nil)
+	env.svc.clientFactory = func(args SourceArguments) (collectorv1connect.CollectorServiceClient, error) {
+		if args.Name == "team" {
+			return teamClient, nil
+		}
+		return baselineClient, nil
+	}
+	require.NoError(t, env.ApplyConfig(`
+		url            = "https://example.com/baseline"
+		poll_frequency = "10s"
+
+		source "team" {
+			url            = "https://example.com/team"
+			poll_frequency = "10s"
+			attributes     = {"team" = "a"}
+		}
+	`))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, env.Run(ctx))
+	}()
+
+	// Each source is loaded independently.
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		sources := env.svc.Sources()
+		if !assert.Len(c, sources, 2) {
+			return
+		}
+		assert.Equal(c, "", sources[0].Name)
+		assert.Equal(c, getHash([]byte(cfgBaseline)), sources[0].Status.EffectiveHash)
+		assert.Equal(c, "team", sources[1].Name)
+		assert.Equal(c, "https://example.com/team", sources[1].URL)
+		assert.Equal(c, LoadStatusOK, sources[1].Status.LastStatus)
+		assert.Equal(c, getHash([]byte(cfgTeam)), sources[1].Status.EffectiveHash)
+	}, time.Second, 10*time.Millisecond)
+
+	// The source name is reported along with its own attributes.
+	source, _ := teamAttrs.Load(attrSource)
+	require.Equal(t, "team", source)
+	team, _ := teamAttrs.Load("team")
+	require.Equal(t, "a", team)
+
+	// Each source runs its components in its own controller.
+	host, ok := env.svc.SourceHost("team")
+	require.True(t, ok)
+	infos, err := host.ListComponents("", component.InfoOptions{})
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.Equal(t, "loki.process.team", infos[0].ID.LocalID)
+
+	// The components of every source are exposed through the service data,
+	// the ones of the named sources with the ID of their controller.
+	dataHost := env.svc.Data().(Data).Host
+	infos, err = dataHost.ListComponents("", component.InfoOptions{})
+	require.NoError(t, err)
+	var ids []string
+	for _, info := range infos {
+		ids = append(ids, info.ID.String())
+	}
+	require.ElementsMatch(t, []string{"remotecfg/loki.process.baseline", "remotecfg/team/loki.process.team"}, ids)
+	info, err := dataHost.GetComponent(component.ParseID("remotecfg/team/loki.process.team"), component.InfoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "loki.process.team", info.ID.LocalID)
+	_, err = dataHost.GetComponent(component.ParseID("loki.process.baseline"), component.InfoOptions{})
+	require.NoError(t, err)
+	_, err = dataHost.GetComponent(component.ParseID("loki.process.team"), component.InfoOptions{})
+	require.ErrorIs(t, err, component.ErrComponentNotFound)
+
+	// The cached configuration of every source is available.
+	files := env.svc.GetCachedAstFiles()
+	require.Len(t, files, 2)
+	require.Contains(t, files, "team")
+
+	// The on-disk cache of each source is kept separately.
+	b, err := env.svc.sources["team"].getCachedConfig()
+	require.NoError(t, err)
+	require.Equal(t, cfgTeam, string(b))
+	b, err = env.svc.primary.getCachedConfig()
+	require.NoError(t, err)
+	require.Equal(t, cfgBaseline, string(b))
+
+	// Removing a source stops it.
+	require.NoError(t, env.ApplyConfig(`
+		url            = "https://example.com/baseline"
+		poll_frequency = "10s"
+	`))
+	require.Len(t, env.svc.Sources(), 1)
+	_, ok = env.svc.SourceHost("team")
+	require.False(t, ok)
+	// Its metrics are removed once it has exited, so only the ones of the
+	// primary source are left.
+	require.Equal(t, 1, testutil.CollectAndCount(env.svc.getMetrics().totalAttempts))
+
+	cancel()
+	wg.Wait()
+}
+
+func TestSourceValidation(t *testing.T) {
+	var args Arguments
+	err := syntax.Unmarshal([]byte(`
+		source "team" {
+			url = "https://example.com/a"
+		}
+		source "team" {
+			url = "https://example.com/b"
+		}
+	`), &args)
+	require.ErrorContains(t, err, `source "team" is defined more than once`)
+}
+
+// longPollServer is a minimal implementation of the collector API which holds
+// GetConfig requests until the configuration changes or the long-poll timeout
+// requested by the client expires.
+type longPollServer struct {
+	collectorv1connect.UnimplementedCollectorServiceHandler
+
+	waiting atomic.Bool
+
+	mut       sync.Mutex
+	content   string
+	changed   chan struct{}
+	lastAttrs map[string]string
+}
+
+func newLongPollServer(content string) *longPollServer {
+	return &longPollServer{
+		content: content,
+		changed: make(chan struct{}),
+	}
+}
+
+func (s *longPollServer) setContent(content string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.content = content
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *longPollServer) getLastAttributes() map[string]string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.lastAttrs
+}
+
+func (s *longPollServer) GetConfig(ctx context.Context, req *connect.Request[collectorv1.GetConfigRequest]) (*connect.Response[collectorv1.GetConfigResponse], error) {
+	s.mut.Lock()
+	s.lastAttrs = req.Msg.LocalAttributes
+	content, changed := s.content, s.changed
+	s.mut.Unlock()
+
+	if hash := getHash([]byte(content)); req.Msg.Hash != hash {
+		return connect.NewResponse(&collectorv1.GetConfigResponse{Content: content, Hash: hash}), nil
+	}
+
+	timeout, err := time.ParseDuration(req.Header().Get(longPollTimeoutHeader))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	s.waiting.Store(true)
+	defer s.waiting.Store(false)
+
+	select {
+	case <-changed:
+		return s.GetConfig(ctx, req)
+	case <-time.After(timeout):
+		return connect.NewResponse(&collectorv1.GetConfigResponse{NotModified: true}), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *longPollServer) RegisterCollector(_ context.Context, req *connect.Request[collectorv1.RegisterCollectorRequest]) (*connect.Response[collectorv1.RegisterCollectorResponse], error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.lastAttrs = req.Msg.LocalAttributes
+	return connect.NewResponse(&collectorv1.RegisterCollectorResponse{}), nil
+}
+
 func buildGetConfigHandler(in string, hash string, notModified bool) func(context.Context, *connect.Request[collectorv1.GetConfigRequest]) (*connect.Response[collectorv1.GetConfigResponse], error) {
 	return func(context.Context, *connect.Request[collectorv1.GetConfigRequest]) (*connect.Response[collectorv1.GetConfigResponse], error) {
 		rsp := &connect.Response[collectorv1.GetConfigResponse]{
@@ -284,7 +666,7 @@ func newTestEnvironment(t *testing.T, client *collectorClient) *testEnvironment
 		Logger:      util.TestLogger(t),
 		StoragePath: t.TempDir(),
 	})
-	svc.clientFactory = func(_ Arguments) (collectorv1connect.CollectorServiceClient, error) {
+	svc.clientFactory = func(_ SourceArguments) (collectorv1connect.CollectorServiceClient, error) {
 		return client, nil
 	}
 	require.NoError(t, err)
@@ -304,6 +686,9 @@ func (env *testEnvironment) ApplyConfig(config string) error {
 	// considerably; let's artificially lower it after the initial validation
 	// has taken place.
 	args.PollFrequency /= 100
+	for i := range args.Sources {
+		args.Sources[i].PollFrequency /= 100
+	}
 	return env.svc.Update(args)
 }
 
@@ -332,7 +717,7 @@ func (fakeHost) GetService(_ string) (service.Service, bool)     { return nil, f
 func (f fakeHost) NewController(id string) service.Controller {
 	logger, _ := logging.New(io.Discard, logging.DefaultOptions)
 	ctrl := alloy_runtime.New(alloy_runtime.Options{
-		ControllerID:    ServiceName,
+		ControllerID:    id,
 		Logger:          logger,
 		Tracer:          nil,
 		DataPath:        "",
@@ -391,4 +776,5 @@ func (sc serviceController) LoadSource(b []byte, args map[string]any, configPath
 	}
 	return source.SourceFiles()[""], sc.f.LoadSource(source, args, configPath)
 }
-func (sc serviceController) Ready() bool { return sc.f.Ready() }
+func (sc serviceController) Ready() bool           { return sc.f.Ready() }
+func (sc serviceController) GetHost() service.Host { return sc.f }
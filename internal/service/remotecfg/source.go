@@ -0,0 +1,530 @@
+package remotecfg
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log"
+	collectorv1 "github.com/grafana/alloy-remote-config/api/gen/proto/go/collector/v1"
+	"github.com/grafana/alloy-remote-config/api/gen/proto/go/collector/v1/collectorv1connect"
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service"
+	"github.com/grafana/alloy/internal/util/jitter"
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/ast"
+)
+
+// SourceArguments holds the settings of a single remote configuration source.
+type SourceArguments struct {
+	Name             string                   `alloy:",label"`
+	URL              string                   `alloy:"url,attr"`
+	Attributes       map[string]string        `alloy:"attributes,attr,optional"`
+	PollFrequency    time.Duration            `alloy:"poll_frequency,attr,optional"`
+	LongPoll         bool                     `alloy:"long_poll,attr,optional"`
+	LongPollTimeout  time.Duration            `alloy:"long_poll_timeout,attr,optional"`
+	HTTPClientConfig *config.HTTPClientConfig `alloy:",squash"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *SourceArguments) SetToDefault() {
+	*a = SourceArguments{
+		Attributes:       make(map[string]string),
+		PollFrequency:    1 * time.Minute,
+		LongPollTimeout:  5 * time.Minute,
+		HTTPClientConfig: config.CloneDefaultHTTPClientConfig(),
+	}
+}
+
+// Validate implements syntax.Validator.
+func (a *SourceArguments) Validate() error {
+	if err := validatePolling(a.PollFrequency, a.LongPoll, a.LongPollTimeout); err != nil {
+		return err
+	}
+	if err := validateAttributes(a.Attributes); err != nil {
+		return err
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it
+	// won't run otherwise
+	if a.HTTPClientConfig != nil {
+		return a.HTTPClientConfig.Validate()
+	}
+
+	return nil
+}
+
+// Hash marshals the SourceArguments and returns a hash representation.
+func (a *SourceArguments) Hash() (string, error) {
+	b, err := syntax.Marshal(a)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	return getHash(b), nil
+}
+
+// source fetches and loads the configuration of a single remote
+// configuration source into its own isolated controller.
+type source struct {
+	name   string
+	svc    *Service
+	logger log.Logger
+
+	mut                  sync.RWMutex
+	args                 SourceArguments
+	collectorID          string
+	collectorName        string
+	ctrl                 service.Controller
+	cancel               context.CancelFunc
+	done                 chan struct{} // Closed once a named source has exited.
+	asClient             collectorv1connect.CollectorServiceClient
+	updateTickerChan     chan struct{}
+	pollFrequency        time.Duration
+	dataPath             string
+	lastLoadedConfigHash string
+	attrs                map[string]string
+
+	// This is the hash received from the API. It is used to determine if
+	// the configuration has changed since the last fetch
+	remoteHash string
+
+	// Set when the last long-poll request was answered well before the
+	// requested timeout without a configuration change, meaning the API
+	// doesn't hold requests open.
+	longPollIgnored bool
+
+	// This is the AST file parsed from the configuration. This is used
+	// for the support bundle
+	astFile *ast.File
+
+	// Outcome of the last load attempt, reported back to the API.
+	status Status
+}
+
+func newSource(svc *Service, name string) *source {
+	logger := svc.opts.Logger
+	if name != "" {
+		logger = log.With(logger, "source", name)
+	}
+
+	return &source{
+		name:             name,
+		svc:              svc,
+		logger:           logger,
+		updateTickerChan: make(chan struct{}, 1),
+		pollFrequency:    disablePollingFrequency,
+		status:           Status{LastStatus: LoadStatusUnknown},
+	}
+}
+
+// update applies new settings to the source. dataPath is the location of the
+// source's on-disk cache.
+func (s *source) update(newArgs SourceArguments, collectorID, collectorName, dataPath string) error {
+	s.mut.Lock()
+
+	// We either never set the block on the first place, or recently removed
+	// it. Make sure we stop everything gracefully before returning.
+	if newArgs.URL == "" {
+		s.setPollFrequency(disablePollingFrequency)
+		s.asClient = noopClient{}
+		s.args.HTTPClientConfig = config.CloneDefaultHTTPClientConfig()
+		s.mut.Unlock()
+
+		s.setLastLoadedCfgHash("")
+		return nil
+	}
+
+	s.dataPath = dataPath
+
+	s.setPollFrequency(newArgs.PollFrequency)
+	// Update the HTTP client last since it might fail.
+	_, disabled := s.asClient.(noopClient)
+	if disabled || s.asClient == nil || s.args.URL != newArgs.URL || !reflect.DeepEqual(s.args.HTTPClientConfig, newArgs.HTTPClientConfig) {
+		client, err := s.svc.clientFactory(newArgs)
+		if err != nil {
+			s.mut.Unlock()
+			return err
+		}
+		s.asClient = client
+	}
+	// Combine the new attributes on top of the system attributes
+	s.attrs = maps.Clone(s.svc.systemAttrs)
+	if s.name != "" {
+		s.attrs[attrSource] = s.name
+	}
+	maps.Copy(s.attrs, newArgs.Attributes)
+
+	// Update the args as the last step to avoid polluting any comparisons
+	s.args = newArgs
+	s.collectorID = collectorID
+	s.collectorName = collectorName
+	ctrl := s.ctrl
+	s.mut.Unlock()
+
+	err := s.registerCollector()
+	if err != nil {
+		return err
+	}
+
+	// If we've already started, then immediately trigger an API call with the
+	// updated Arguments, and/or fall back to the updated cache location.
+	if ctrl != nil && ctrl.Ready() {
+		s.fetch(context.Background())
+	}
+
+	return nil
+}
+
+// start sets the controller of the source and loads its initial
+// configuration, either from the API or from the on-disk cache.
+func (s *source) start(ctx context.Context, ctrl service.Controller) {
+	s.mut.Lock()
+	s.ctrl = ctrl
+	s.mut.Unlock()
+
+	s.fetch(ctx)
+}
+
+// run polls the API for configuration updates and runs the source's
+// controller until the provided context is canceled.
+func (s *source) run(ctx context.Context) {
+	s.mut.RLock()
+	ticker := jitter.NewTicker(s.pollFrequency, baseJitter)
+	ctrl := s.ctrl
+	s.mut.RUnlock()
+
+	defer ticker.Stop()
+
+	// Run the source's own controller, and wait for it to exit before
+	// returning.
+	ctrlDone := make(chan struct{})
+	go func() {
+		defer close(ctrlDone)
+		ctrl.Run(ctx)
+	}()
+	defer func() { <-ctrlDone }()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := s.fetchRemote(ctx)
+			if err != nil && err != errNoopClient {
+				level.Error(s.logger).Log("msg", "failed to fetch remote configuration from the API", "err", err)
+			}
+			// In long-poll mode the next request is issued right away, unless
+			// the last one failed or the API answered without waiting for a
+			// change; then we back off to the regular polling frequency.
+			if s.isLongPolling() {
+				if err != nil || s.isLongPollIgnored() {
+					ticker.Reset(s.getPollFrequency())
+				} else {
+					ticker.Reset(longPollInterval)
+				}
+			}
+		case <-s.updateTickerChan:
+			if s.isLongPolling() {
+				ticker.Reset(longPollInterval)
+			} else {
+				ticker.Reset(s.getPollFrequency())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stop stops the source's controller and polling loop, and removes its
+// metrics once they have exited, so that they can't report them again.
+func (s *source) stop() {
+	s.mut.Lock()
+	cancel, done := s.cancel, s.done
+	s.mut.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	s.svc.deleteMetrics(s.name)
+}
+
+// getCachedAstFile returns the AST file that was parsed from the configuration.
+func (s *source) getCachedAstFile() *ast.File {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.astFile
+}
+
+func (s *source) info() SourceInfo {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return SourceInfo{
+		Name:   s.name,
+		URL:    s.args.URL,
+		Status: s.status,
+	}
+}
+
+// getHost returns the Host of the source's controller, or nil if it hasn't
+// been started yet.
+func (s *source) getHost() service.Host {
+	s.mut.RLock()
+	ctrl := s.ctrl
+	s.mut.RUnlock()
+
+	if h, ok := ctrl.(interface{ GetHost() service.Host }); ok {
+		return h.GetHost()
+	}
+	return nil
+}
+
+// fetch attempts to read configuration from the API and the local cache
+// and then parse/load their contents in order of preference.
+func (s *source) fetch(ctx context.Context) {
+	if err := s.fetchRemote(ctx); err != nil {
+		level.Error(s.logger).Log("msg", "failed to fetch remote config", "err", err)
+		s.fetchLocal()
+	}
+}
+
+func (s *source) registerCollector() error {
+	attrs := s.requestAttributes()
+
+	s.mut.RLock()
+	req := connect.NewRequest(&collectorv1.RegisterCollectorRequest{
+		Id:              s.collectorID,
+		LocalAttributes: attrs,
+		Name:            s.collectorName,
+	})
+	client := s.asClient
+	s.mut.RUnlock()
+
+	_, err := client.RegisterCollector(context.Background(), req)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *source) fetchRemote(ctx context.Context) error {
+	if !s.isEnabled() {
+		return nil
+	}
+
+	level.Debug(s.logger).Log("msg", "fetching remote configuration")
+
+	metrics := s.svc.getMetrics()
+	b, err := s.getAPIConfig(ctx)
+	metrics.totalAttempts.WithLabelValues(s.name).Add(1)
+
+	if err == nil {
+		metrics.lastLoadSuccess.WithLabelValues(s.name).Set(1)
+		metrics.lastFetchSuccessTime.WithLabelValues(s.name).SetToCurrentTime()
+	} else if err != errNotModified {
+		metrics.totalFailures.WithLabelValues(s.name).Add(1)
+		metrics.lastLoadSuccess.WithLabelValues(s.name).Set(0)
+		return err
+	}
+
+	if err == errNotModified {
+		level.Debug(s.logger).Log("msg", "skipping over API response since it has not been modified since last fetch")
+		metrics.lastFetchNotModified.WithLabelValues(s.name).Set(1)
+		return nil
+	} else {
+		metrics.lastFetchNotModified.WithLabelValues(s.name).Set(0)
+	}
+
+	// API returned the same configuration as the last one we loaded, no need to reload.
+	newConfigHash := getHash(b)
+	if s.getLastLoadedCfgHash() == newConfigHash {
+		level.Debug(s.logger).Log("msg", "skipping over API response since it matched the last loaded one")
+		return nil
+	}
+
+	err = s.parseAndLoad(b)
+	s.reportStatus()
+	if err != nil {
+		return err
+	}
+
+	// If successful, flush to disk and keep a copy.
+	s.setCachedConfig(b)
+	return nil
+}
+
+// reportStatus sends the outcome of the last load attempt to the API without
+// waiting for the next poll.
+func (s *source) reportStatus() {
+	if err := s.registerCollector(); err != nil && err != errNoopClient {
+		level.Warn(s.logger).Log("msg", "failed to report remote configuration status to the API", "err", err)
+	}
+}
+
+func (s *source) fetchLocal() {
+	b, err := s.getCachedConfig()
+	if err != nil {
+		level.Error(s.logger).Log("msg", "failed to read from cache", "err", err)
+		return
+	}
+
+	err = s.parseAndLoad(b)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "failed to load from cache", "err", err)
+	}
+}
+
+func (s *source) getAPIConfig(ctx context.Context) ([]byte, error) {
+	attrs := s.requestAttributes()
+
+	s.mut.RLock()
+	req := connect.NewRequest(&collectorv1.GetConfigRequest{
+		Id:              s.collectorID,
+		LocalAttributes: attrs,
+		Hash:            s.remoteHash,
+	})
+	client := s.asClient
+	reqHash := s.remoteHash
+	longPoll, longPollTimeout := s.args.LongPoll, s.args.LongPollTimeout
+	s.mut.RUnlock()
+
+	if longPoll {
+		req.Header().Set(longPollTimeoutHeader, longPollTimeout.String())
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, longPollTimeout+longPollGracePeriod)
+		defer cancel()
+	}
+
+	start := time.Now()
+	gcr, err := client.GetConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	s.svc.getMetrics().getConfigTime.WithLabelValues(s.name).Observe(elapsed.Seconds())
+
+	if longPoll {
+		unchanged := gcr.Msg.NotModified || (reqHash != "" && gcr.Msg.Hash == reqHash)
+		ignored := unchanged && elapsed < longPollTimeout/2
+		if ignored {
+			level.Warn(s.logger).Log("msg", "API answered a long-poll request without waiting for a configuration change, falling back to poll_frequency", "elapsed", elapsed)
+		}
+		s.mut.Lock()
+		s.longPollIgnored = ignored
+		s.mut.Unlock()
+	}
+
+	if gcr.Msg.NotModified {
+		return nil, errNotModified
+	}
+	if gcr.Msg.Hash != "" {
+		s.mut.Lock()
+		s.remoteHash = gcr.Msg.Hash
+		s.mut.Unlock()
+	}
+	return []byte(gcr.Msg.GetContent()), nil
+}
+
+func (s *source) getCachedConfig() ([]byte, error) {
+	s.mut.RLock()
+	p := s.dataPath
+	s.mut.RUnlock()
+
+	return os.ReadFile(p)
+}
+
+func (s *source) setCachedConfig(b []byte) {
+	s.mut.RLock()
+	p := s.dataPath
+	s.mut.RUnlock()
+
+	err := os.WriteFile(p, b, 0750)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "failed to flush remote configuration contents the on-disk cache", "err", err)
+	}
+}
+
+func (s *source) parseAndLoad(b []byte) error {
+	s.mut.RLock()
+	ctrl := s.ctrl
+	s.mut.RUnlock()
+
+	if len(b) == 0 {
+		return nil
+	}
+	hash := getHash(b)
+	s.setLastLoadedCfgHash(hash)
+	file, err := ctrl.LoadSource(b, nil, s.svc.opts.ConfigPath)
+	s.setLoadStatus(hash, err)
+	if err != nil {
+		return err
+	}
+
+	s.setAstFile(file)
+	return nil
+}
+
+func (s *source) getLastLoadedCfgHash() string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.lastLoadedConfigHash
+}
+
+func (s *source) setAstFile(f *ast.File) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.astFile = f
+}
+
+func (s *source) setLastLoadedCfgHash(h string) {
+	metrics := s.svc.getMetrics()
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if metrics != nil {
+		metrics.configHash.DeletePartialMatch(map[string]string{"source": s.name})
+		metrics.configHash.WithLabelValues(s.name, h).Set(1)
+	}
+	s.lastLoadedConfigHash = h
+}
+
+func (s *source) isEnabled() bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.args.URL != "" && s.asClient != nil
+}
+
+func (s *source) isLongPolling() bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.pollFrequency != disablePollingFrequency && s.args.LongPoll
+}
+
+func (s *source) isLongPollIgnored() bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.longPollIgnored
+}
+
+func (s *source) getPollFrequency() time.Duration {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.pollFrequency
+}
+
+func (s *source) setPollFrequency(t time.Duration) {
+	s.pollFrequency = t
+	select {
+	// If the channel is full it means there's already an update triggered
+	// or run is not running. In both cases, we don't need to trigger another
+	// update or block.
+	case s.updateTickerChan <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,124 @@
+package remotecfg
+
+import (
+	"maps"
+	"strconv"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/service"
+)
+
+// Reserved attribute keys used to report the status of the remote
+// configuration back to the API. They are sent along the system attributes
+// with every request.
+const (
+	attrSource              = reservedAttributeNamespace + namespaceDelimiter + "source"
+	attrConfigHash          = reservedAttributeNamespace + namespaceDelimiter + "config_hash"
+	attrConfigStatus        = reservedAttributeNamespace + namespaceDelimiter + "config_status"
+	attrConfigError         = reservedAttributeNamespace + namespaceDelimiter + "config_error"
+	attrComponentsHealthy   = reservedAttributeNamespace + namespaceDelimiter + "components_healthy"
+	attrComponentsUnhealthy = reservedAttributeNamespace + namespaceDelimiter + "components_unhealthy"
+	attrComponentsUnknown   = reservedAttributeNamespace + namespaceDelimiter + "components_unknown"
+	attrComponentsExited    = reservedAttributeNamespace + namespaceDelimiter + "components_exited"
+)
+
+// maxReportedErrorLength caps the size of the load error reported to the API
+// so that a large diagnostic doesn't bloat every request.
+const maxReportedErrorLength = 1024
+
+// LoadStatus is the state of the most recent attempt to load a remote
+// configuration.
+type LoadStatus string
+
+const (
+	LoadStatusUnknown LoadStatus = "unknown" // No configuration has been loaded yet.
+	LoadStatusOK      LoadStatus = "ok"      // The last configuration loaded successfully.
+	LoadStatusError   LoadStatus = "error"   // The last configuration failed to load.
+)
+
+// Status describes the remote configuration currently applied by the
+// service, along with the outcome of the last load attempt.
+type Status struct {
+	// Hash of the configuration that is currently running. If the last load
+	// failed, this is the hash of the last configuration that loaded
+	// successfully.
+	EffectiveHash string     `json:"effectiveHash"`
+	LastStatus    LoadStatus `json:"lastStatus"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// setLoadStatus records the outcome of loading the configuration identified
+// by hash.
+func (s *source) setLoadStatus(hash string, err error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if err != nil {
+		s.status.LastStatus = LoadStatusError
+		s.status.LastError = err.Error()
+		return
+	}
+	s.status.EffectiveHash = hash
+	s.status.LastStatus = LoadStatusOK
+	s.status.LastError = ""
+}
+
+func (s *source) getStatus() Status {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.status
+}
+
+// requestAttributes returns the attributes to send to the API, composed of
+// the user-provided and system attributes along with the current status of
+// the remote configuration and of the components it runs.
+func (s *source) requestAttributes() map[string]string {
+	s.mut.RLock()
+	attrs := maps.Clone(s.attrs)
+	status := s.status
+	ctrl := s.ctrl
+	s.mut.RUnlock()
+
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+
+	attrs[attrConfigStatus] = string(status.LastStatus)
+	if status.EffectiveHash != "" {
+		attrs[attrConfigHash] = status.EffectiveHash
+	}
+	if status.LastError != "" {
+		errMsg := status.LastError
+		if len(errMsg) > maxReportedErrorLength {
+			errMsg = errMsg[:maxReportedErrorLength]
+		}
+		attrs[attrConfigError] = errMsg
+	}
+
+	if h, ok := ctrl.(interface{ GetHost() service.Host }); ok {
+		maps.Copy(attrs, healthAttributes(h.GetHost()))
+	}
+	return attrs
+}
+
+// healthAttributes summarizes the health of the components running in host.
+func healthAttributes(host service.Host) map[string]string {
+	if host == nil {
+		return nil
+	}
+	infos, err := host.ListComponents("", component.InfoOptions{GetHealth: true})
+	if err != nil {
+		return nil
+	}
+
+	counts := make(map[component.HealthType]int)
+	for _, info := range infos {
+		counts[info.Health.Health]++
+	}
+	return map[string]string{
+		attrComponentsHealthy:   strconv.Itoa(counts[component.HealthTypeHealthy]),
+		attrComponentsUnhealthy: strconv.Itoa(counts[component.HealthTypeUnhealthy]),
+		attrComponentsUnknown:   strconv.Itoa(counts[component.HealthTypeUnknown]),
+		attrComponentsExited:    strconv.Itoa(counts[component.HealthTypeExited]),
+	}
+}
@@ -1,6 +1,12 @@
 package waltools
 
 import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/tsdb/record"
 	"github.com/prometheus/prometheus/tsdb/wlog"
 )
@@ -65,3 +71,117 @@ func collectCardinality(r *wlog.Reader, job, instance string, cardinality map[st
 
 	return r.Err()
 }
+
+// LabelCardinality represents a label by name, the number of unique values it
+// has and the number of series it's used in.
+type LabelCardinality struct {
+	Label  string
+	Values int
+	Series int
+}
+
+// CardinalityReport holds the metrics and labels with the highest
+// cardinality in a WAL.
+type CardinalityReport struct {
+	// Series is the total number of unique series matching the selector.
+	Series int
+
+	// Metrics holds the metrics with the most series, in descending order.
+	Metrics []Cardinality
+
+	// Labels holds the labels with the most unique values, in descending
+	// order.
+	Labels []LabelCardinality
+}
+
+// FindTopCardinality searches the WAL and returns the n metrics with the most
+// series and the n labels with the most unique values, across all series
+// matching the given label selector. Series defined more than once in the WAL
+// are only counted once.
+func FindTopCardinality(walDir string, selectorStr string, n int) (CardinalityReport, error) {
+	w, err := wlog.Open(nil, walDir)
+	if err != nil {
+		return CardinalityReport{}, err
+	}
+	defer w.Close()
+
+	matchers, err := parser.ParseMetricSelector(selectorStr)
+	if err != nil {
+		return CardinalityReport{}, err
+	}
+	selector := labels.Selector(matchers)
+
+	var (
+		seen         = make(map[uint64]struct{})
+		metricSeries = make(map[string]int)
+		labelSeries  = make(map[string]int)
+		labelValues  = make(map[string]map[string]struct{})
+	)
+
+	err = walIterate(w, func(r *wlog.Reader) error {
+		var dec record.Decoder
+
+		for r.Next() {
+			rec := r.Record()
+			if dec.Type(rec) != record.Series {
+				continue
+			}
+
+			series, err := dec.Series(rec, nil)
+			if err != nil {
+				return err
+			}
+			for _, s := range series {
+				if !selector.Matches(s.Labels) {
+					continue
+				}
+				hash := s.Labels.Hash()
+				if _, ok := seen[hash]; ok {
+					continue
+				}
+				seen[hash] = struct{}{}
+
+				metricSeries[s.Labels.Get(labels.MetricName)]++
+				s.Labels.Range(func(l labels.Label) {
+					if l.Name == labels.MetricName {
+						return
+					}
+					labelSeries[l.Name]++
+					values, ok := labelValues[l.Name]
+					if !ok {
+						values = make(map[string]struct{})
+						labelValues[l.Name] = values
+					}
+					values[l.Value] = struct{}{}
+				})
+			}
+		}
+
+		return r.Err()
+	})
+	if err != nil {
+		return CardinalityReport{}, err
+	}
+
+	report := CardinalityReport{Series: len(seen)}
+	for metric, series := range metricSeries {
+		report.Metrics = append(report.Metrics, Cardinality{Metric: metric, Instances: series})
+	}
+	slices.SortFunc(report.Metrics, func(a, b Cardinality) int {
+		return cmp.Or(cmp.Compare(b.Instances, a.Instances), strings.Compare(a.Metric, b.Metric))
+	})
+	for label, values := range labelValues {
+		report.Labels = append(report.Labels, LabelCardinality{Label: label, Values: len(values), Series: labelSeries[label]})
+	}
+	slices.SortFunc(report.Labels, func(a, b LabelCardinality) int {
+		return cmp.Or(cmp.Compare(b.Values, a.Values), strings.Compare(a.Label, b.Label))
+	})
+
+	if len(report.Metrics) > n {
+		report.Metrics = report.Metrics[:n]
+	}
+	if len(report.Labels) > n {
+		report.Labels = report.Labels[:n]
+	}
+	return report, nil
+}
@@ -30,3 +30,27 @@ func TestCardinality(t *testing.T) {
 		{Metric: "metric_9", Instances: 2},
 	}, cardinality)
 }
+
+func TestTopCardinality(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	report, err := FindTopCardinality(walDir, "{}", 2)
+	require.NoError(t, err)
+	require.Equal(t, CardinalityReport{
+		Series: 20, // The duplicate of metric_1 is only counted once.
+		Metrics: []Cardinality{
+			{Metric: "metric_0", Instances: 2},
+			{Metric: "metric_1", Instances: 2},
+		},
+		Labels: []LabelCardinality{
+			{Label: "initial", Values: 2, Series: 20},
+			{Label: "instance", Values: 1, Series: 20},
+		},
+	}, report)
+
+	report, err = FindTopCardinality(walDir, `{initial="yes"}`, 10)
+	require.NoError(t, err)
+	require.Equal(t, 10, report.Series)
+	require.Len(t, report.Metrics, 10)
+	require.Equal(t, LabelCardinality{Label: "initial", Values: 1, Series: 10}, report.Labels[0])
+}
@@ -0,0 +1,230 @@
+package waltools
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+)
+
+// Sample is a single float sample of a series.
+type Sample struct {
+	Timestamp int64
+	Value     float64
+}
+
+// SeriesSamples holds the samples found in the WAL for a series.
+type SeriesSamples struct {
+	Labels  labels.Labels
+	Samples []Sample
+}
+
+// DumpSamples searches the WAL and returns the float samples of series
+// matching the given label selector, with timestamps between from and to
+// inclusive. A zero from or to leaves that side of the range open. Stale
+// markers aren't returned.
+//
+// Series are returned sorted by metric name and then by labels, and their
+// samples are sorted by timestamp.
+func DumpSamples(walDir string, selectorStr string, from, to time.Time) ([]*SeriesSamples, error) {
+	bySeries := make(map[uint64]*SeriesSamples)
+	err := iterateSamples(walDir, selectorStr, from, to, func(lbls labels.Labels, s record.RefSample) error {
+		if value.IsStaleNaN(s.V) {
+			return nil
+		}
+		hash := lbls.Hash()
+		series, ok := bySeries[hash]
+		if !ok {
+			series = &SeriesSamples{Labels: lbls}
+			bySeries[hash] = series
+		}
+		series.Samples = append(series.Samples, Sample{Timestamp: s.T, Value: s.V})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*SeriesSamples, 0, len(bySeries))
+	for _, series := range bySeries {
+		slices.SortStableFunc(series.Samples, func(a, b Sample) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		res = append(res, series)
+	}
+	slices.SortFunc(res, func(a, b *SeriesSamples) int {
+		return cmp.Or(
+			strings.Compare(a.Labels.Get(labels.MetricName), b.Labels.Get(labels.MetricName)),
+			labels.Compare(a.Labels, b.Labels),
+		)
+	})
+	return res, nil
+}
+
+// iterateSamples calls f for every float sample in the WAL which belongs to a
+// series matching the label selector and has a timestamp between from and to
+// inclusive, in the order they were written to the WAL.
+func iterateSamples(walDir string, selectorStr string, from, to time.Time, f func(labels.Labels, record.RefSample) error) error {
+	w, err := wlog.Open(nil, walDir)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	matchers, err := parser.ParseMetricSelector(selectorStr)
+	if err != nil {
+		return err
+	}
+	selector := labels.Selector(matchers)
+
+	minTS, maxTS := int64(math.MinInt64), int64(math.MaxInt64)
+	if !from.IsZero() {
+		minTS = timestamp.FromTime(from)
+	}
+	if !to.IsZero() {
+		maxTS = timestamp.FromTime(to)
+	}
+
+	labelsByRef := make(map[chunks.HeadSeriesRef]labels.Labels)
+
+	return walIterate(w, func(r *wlog.Reader) error {
+		var dec record.Decoder
+
+		for r.Next() {
+			rec := r.Record()
+
+			switch dec.Type(rec) {
+			case record.Series:
+				series, err := dec.Series(rec, nil)
+				if err != nil {
+					return err
+				}
+				for _, s := range series {
+					if selector.Matches(s.Labels) {
+						labelsByRef[s.Ref] = s.Labels.Copy()
+					}
+				}
+			case record.Samples:
+				samples, err := dec.Samples(rec, nil)
+				if err != nil {
+					return err
+				}
+				for _, s := range samples {
+					lbls, ok := labelsByRef[s.Ref]
+					if !ok || s.T < minTS || s.T > maxTS {
+						continue
+					}
+					if err := f(lbls, s); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return r.Err()
+	})
+}
+
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// WriteOpenMetrics writes series to w in the OpenMetrics text format. The
+// type of every metric is unknown since the WAL doesn't record it.
+func WriteOpenMetrics(w io.Writer, series []*SeriesSamples) error {
+	bw := bufio.NewWriter(w)
+
+	var family string
+	for i, s := range series {
+		name := s.Labels.Get(labels.MetricName)
+		if i == 0 || name != family {
+			family = name
+			fmt.Fprintf(bw, "# TYPE %s unknown\n", name)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(name)
+		first := true
+		s.Labels.Range(func(l labels.Label) {
+			if l.Name == labels.MetricName {
+				return
+			}
+			if first {
+				sb.WriteByte('{')
+				first = false
+			} else {
+				sb.WriteByte(',')
+			}
+			fmt.Fprintf(&sb, `%s="%s"`, l.Name, openMetricsEscaper.Replace(l.Value))
+		})
+		if !first {
+			sb.WriteByte('}')
+		}
+		seriesName := sb.String()
+
+		for _, sample := range s.Samples {
+			fmt.Fprintf(bw, "%s %s %s\n", seriesName, formatValue(sample.Value), formatTimestamp(sample.Timestamp))
+		}
+	}
+
+	fmt.Fprint(bw, "# EOF\n")
+	return bw.Flush()
+}
+
+// WriteJSON writes series to w as JSON, with one object per line for each
+// series. Samples are written as pairs of a timestamp in seconds and a value
+// formatted as a string, like in the Prometheus HTTP API.
+func WriteJSON(w io.Writer, series []*SeriesSamples) error {
+	type jsonSeries struct {
+		Labels  map[string]string `json:"labels"`
+		Samples [][2]any          `json:"samples"`
+	}
+
+	enc := json.NewEncoder(w)
+	for _, s := range series {
+		out := jsonSeries{
+			Labels:  s.Labels.Map(),
+			Samples: make([][2]any, 0, len(s.Samples)),
+		}
+		for _, sample := range s.Samples {
+			out.Samples = append(out.Samples, [2]any{
+				json.Number(formatTimestamp(sample.Timestamp)),
+				formatValue(sample.Value),
+			})
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTimestamp formats a timestamp in milliseconds as seconds.
+func formatTimestamp(ts int64) string {
+	return strconv.FormatFloat(float64(ts)/1000, 'f', -1, 64)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,58 @@
+package waltools
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpSamples(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	series, err := DumpSamples(walDir, `{__name__="metric_1"}`, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, []*SeriesSamples{
+		{
+			Labels:  labels.FromStrings("__name__", "metric_1", "job", "test-job", "instance", "test-instance", "initial", "no"),
+			Samples: []Sample{{Timestamp: 4, Value: 1}},
+		},
+		{
+			Labels:  labels.FromStrings("__name__", "metric_1", "job", "test-job", "instance", "test-instance", "initial", "yes"),
+			Samples: []Sample{{Timestamp: 3, Value: 1}},
+		},
+	}, series)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenMetrics(&buf, series))
+	require.Equal(t, `# TYPE metric_1 unknown
+metric_1{initial="no",instance="test-instance",job="test-job"} 1 0.004
+metric_1{initial="yes",instance="test-instance",job="test-job"} 1 0.003
+# EOF
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteJSON(&buf, series))
+	require.Equal(t, `{"labels":{"__name__":"metric_1","initial":"no","instance":"test-instance","job":"test-job"},"samples":[[0.004,"1"]]}
+{"labels":{"__name__":"metric_1","initial":"yes","instance":"test-instance","job":"test-job"},"samples":[[0.003,"1"]]}
+`, buf.String())
+}
+
+func TestDumpSamples_TimeRange(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	series, err := DumpSamples(walDir, "{}", time.UnixMilli(5), time.UnixMilli(6))
+	require.NoError(t, err)
+	require.Equal(t, []*SeriesSamples{
+		{
+			Labels:  labels.FromStrings("__name__", "metric_2", "job", "test-job", "instance", "test-instance", "initial", "no"),
+			Samples: []Sample{{Timestamp: 6, Value: 1}},
+		},
+		{
+			Labels:  labels.FromStrings("__name__", "metric_2", "job", "test-job", "instance", "test-instance", "initial", "yes"),
+			Samples: []Sample{{Timestamp: 5, Value: 1}},
+		},
+	}, series)
+}
@@ -0,0 +1,161 @@
+package waltools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/backoff"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"golang.org/x/time/rate"
+)
+
+// ReplayOptions configures which samples Replay sends and how.
+type ReplayOptions struct {
+	// Selector is the label selector of the series to send.
+	Selector string
+
+	// From and To limit the samples to send to a range of timestamps. A zero
+	// value leaves that side of the range open.
+	From, To time.Time
+
+	// URL is the remote_write endpoint to send samples to.
+	URL string
+
+	// Headers are extra headers to send with every request.
+	Headers map[string]string
+
+	// Timeout is the timeout of each request.
+	Timeout time.Duration
+
+	// BatchSize is the maximum number of samples sent in a request.
+	BatchSize int
+
+	// SamplesPerSecond limits how many samples are sent per second. Zero
+	// disables the limit.
+	SamplesPerSecond float64
+
+	// MaxRetries is how many times a request which failed with a recoverable
+	// error is retried.
+	MaxRetries int
+}
+
+// ReplayStats holds statistics on the samples sent by Replay.
+type ReplayStats struct {
+	Samples  int
+	Requests int
+}
+
+// Replay reads the float samples of the series matching opts.Selector from
+// the WAL and sends them to a remote_write endpoint, in the order they were
+// written to the WAL.
+func Replay(ctx context.Context, walDir string, opts ReplayOptions) (ReplayStats, error) {
+	var stats ReplayStats
+
+	if opts.BatchSize <= 0 {
+		return stats, errors.New("batch size must be greater than 0")
+	}
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return stats, fmt.Errorf("invalid URL: %w", err)
+	}
+	client, err := remote.NewWriteClient("replay", &remote.ClientConfig{
+		URL:              &config_util.URL{URL: u},
+		Timeout:          model.Duration(opts.Timeout),
+		HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+		Headers:          opts.Headers,
+		RetryOnRateLimit: true,
+		WriteProtoMsg:    config.RemoteWriteProtoMsgV1,
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	limit := rate.Inf
+	if opts.SamplesPerSecond > 0 {
+		limit = rate.Limit(opts.SamplesPerSecond)
+	}
+	limiter := rate.NewLimiter(limit, opts.BatchSize)
+
+	var (
+		batch       prompb.WriteRequest
+		batchSize   int
+		seriesIndex = make(map[uint64]int)
+		sendBatch   = func() error {
+			if batchSize == 0 {
+				return nil
+			}
+			if err := limiter.WaitN(ctx, batchSize); err != nil {
+				return err
+			}
+			if err := storeWithRetries(ctx, client, &batch, opts.MaxRetries); err != nil {
+				return err
+			}
+			stats.Samples += batchSize
+			stats.Requests++
+
+			batch.Timeseries = batch.Timeseries[:0]
+			batchSize = 0
+			clear(seriesIndex)
+			return nil
+		}
+	)
+
+	err = iterateSamples(walDir, opts.Selector, opts.From, opts.To, func(lbls labels.Labels, s record.RefSample) error {
+		hash := lbls.Hash()
+		i, ok := seriesIndex[hash]
+		if !ok {
+			i = len(batch.Timeseries)
+			seriesIndex[hash] = i
+			batch.Timeseries = append(batch.Timeseries, prompb.TimeSeries{Labels: prompb.FromLabels(lbls, nil)})
+		}
+		batch.Timeseries[i].Samples = append(batch.Timeseries[i].Samples, prompb.Sample{Timestamp: s.T, Value: s.V})
+		batchSize++
+
+		if batchSize >= opts.BatchSize {
+			return sendBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, sendBatch()
+}
+
+func storeWithRetries(ctx context.Context, client remote.WriteClient, req *prompb.WriteRequest, maxRetries int) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	compressed := snappy.Encode(nil, data)
+
+	b := backoff.New(ctx, backoff.Config{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+		MaxRetries: maxRetries + 1,
+	})
+	for b.Ongoing() {
+		_, err = client.Store(ctx, compressed, b.NumRetries())
+		if err == nil {
+			return nil
+		}
+		if !errors.As(err, &remote.RecoverableError{}) {
+			return err
+		}
+		b.Wait()
+	}
+	if err == nil {
+		return b.Err()
+	}
+	return fmt.Errorf("%w: %w", b.Err(), err)
+}
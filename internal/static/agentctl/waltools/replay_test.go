@@ -0,0 +1,92 @@
+package waltools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	var (
+		mut      sync.Mutex
+		requests int
+		received []prompb.TimeSeries
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+
+		requests++
+		// The first request fails with a recoverable error and is retried.
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		require.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+		req, err := remote.DecodeWriteRequest(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		received = append(received, req.Timeseries...)
+	}))
+	defer srv.Close()
+
+	stats, err := Replay(t.Context(), walDir, ReplayOptions{
+		Selector:   `{__name__=~"metric_[12]"}`,
+		From:       time.UnixMilli(4),
+		URL:        srv.URL,
+		Headers:    map[string]string{"X-Scope-OrgID": "tenant"},
+		Timeout:    time.Second,
+		BatchSize:  2,
+		MaxRetries: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ReplayStats{Samples: 3, Requests: 2}, stats)
+	require.Equal(t, 3, requests)
+
+	series := func(name, initial string, ts int64) prompb.TimeSeries {
+		return prompb.TimeSeries{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: name},
+				{Name: "initial", Value: initial},
+				{Name: "instance", Value: "test-instance"},
+				{Name: "job", Value: "test-job"},
+			},
+			Samples: []prompb.Sample{{Timestamp: ts, Value: 1}},
+		}
+	}
+	require.Equal(t, []prompb.TimeSeries{
+		series("metric_1", "no", 4),
+		series("metric_2", "yes", 5),
+		series("metric_2", "no", 6),
+	}, received)
+}
+
+func TestReplay_NonRecoverableError(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	stats, err := Replay(t.Context(), walDir, ReplayOptions{
+		Selector:   "{}",
+		URL:        srv.URL,
+		Timeout:    time.Second,
+		BatchSize:  10,
+		MaxRetries: 5,
+	})
+	require.ErrorContains(t, err, "out of order sample")
+	require.Equal(t, ReplayStats{}, stats)
+}
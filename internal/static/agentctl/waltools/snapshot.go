@@ -0,0 +1,121 @@
+package waltools
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+)
+
+// snapshotAttempts is how many times Snapshot tries to snapshot a WAL whose
+// files are removed while they're being snapshotted.
+const snapshotAttempts = 5
+
+// Snapshot creates a snapshot of the latest checkpoint and the segments of the
+// WAL in walDir, so that it can be read while a running process still writes
+// to and truncates it. The snapshot is created in a new temporary directory
+// which is returned along with a function to remove it.
+//
+// Files are hard linked into the snapshot when possible and copied otherwise.
+// The last segment is always copied since it may still be appended to, so it
+// may end with a partially written record, which readers ignore.
+func Snapshot(walDir string) (string, func(), error) {
+	var lastErr error
+	for range snapshotAttempts {
+		dir, err := os.MkdirTemp("", "alloy-wal-snapshot-")
+		if err != nil {
+			return "", nil, err
+		}
+		cleanup := func() { _ = os.RemoveAll(dir) }
+
+		err = snapshot(walDir, dir)
+		if err == nil {
+			return dir, cleanup, nil
+		}
+		cleanup()
+
+		// Files may be removed by a truncation of the WAL while they're
+		// snapshotted. The next attempt will see the new checkpoint.
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", nil, err
+		}
+		lastErr = err
+	}
+	return "", nil, fmt.Errorf("WAL kept changing while it was snapshotted: %w", lastErr)
+}
+
+func snapshot(walDir, dst string) error {
+	checkpoint, checkpointIdx, err := wlog.LastCheckpoint(walDir)
+	if err != nil && err != record.ErrNotFound {
+		return err
+	}
+
+	startIdx, last, err := wlog.Segments(walDir)
+	if err != nil {
+		return err
+	}
+
+	if checkpoint != "" {
+		checkpointDst := filepath.Join(dst, filepath.Base(checkpoint))
+		if err := os.Mkdir(checkpointDst, 0o700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(checkpoint)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := linkOrCopy(filepath.Join(checkpoint, e.Name()), filepath.Join(checkpointDst, e.Name())); err != nil {
+				return err
+			}
+		}
+		startIdx = checkpointIdx + 1
+	}
+
+	// Segments are only removed once a newer checkpoint is created, so a
+	// segment missing here means the WAL was truncated since the checkpoint
+	// was snapshotted.
+	for i := max(startIdx, 0); i <= last; i++ {
+		segmentSrc, segmentDst := wlog.SegmentName(walDir, i), wlog.SegmentName(dst, i)
+		if i == last {
+			err = copyFile(segmentSrc, segmentDst)
+		} else {
+			err = linkOrCopy(segmentSrc, segmentDst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	} else if errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return copyFile(src, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
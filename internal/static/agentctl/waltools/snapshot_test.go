@@ -0,0 +1,47 @@
+package waltools
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	snapshot, cleanup, err := Snapshot(walDir)
+	require.NoError(t, err)
+
+	expect, err := CalculateStats(walDir)
+	require.NoError(t, err)
+	actual, err := CalculateStats(snapshot)
+	require.NoError(t, err)
+
+	// Segments before the checkpoint aren't part of the snapshot.
+	require.Equal(t, expect.CheckpointNumber+1, actual.FirstSegment)
+	actual.FirstSegment = expect.FirstSegment
+	require.Equal(t, expect, actual)
+
+	cleanup()
+	require.NoDirExists(t, snapshot)
+}
+
+func TestWALIterate_IncompleteRecord(t *testing.T) {
+	walDir := setupTestWAL(t)
+
+	// Write the start of a record to the last segment, as if it was being
+	// written while the WAL is read.
+	_, last, err := wlog.Segments(walDir)
+	require.NoError(t, err)
+	f, err := os.OpenFile(wlog.SegmentName(walDir, last), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 0, 100, 0, 0, 0, 0, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	stats, err := CalculateStats(walDir)
+	require.NoError(t, err)
+	require.Equal(t, 20, stats.Samples())
+}
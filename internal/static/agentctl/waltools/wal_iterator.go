@@ -1,12 +1,18 @@
 package waltools
 
 import (
+	"errors"
+	"io"
+
 	"github.com/prometheus/prometheus/tsdb/record"
 	"github.com/prometheus/prometheus/tsdb/wlog"
 )
 
 // walIterate iterates over the latest checkpoint in the provided WAL and all
 // of the segments in the WAL and calls f for each of them.
+//
+// The last segment may still be written to, so an incomplete record at its
+// end isn't treated as an error.
 func walIterate(w *wlog.WL, f func(r *wlog.Reader) error) error {
 	checkpoint, checkpointIdx, err := wlog.LastCheckpoint(w.Dir())
 	if err != nil && err != record.ErrNotFound {
@@ -39,6 +45,9 @@ func walIterate(w *wlog.WL, f func(r *wlog.Reader) error) error {
 		}
 		sr := wlog.NewSegmentBufReader(s)
 		err = f(wlog.NewReader(sr))
+		if err != nil && i == last && isIncompleteRecord(s, err) {
+			err = nil
+		}
 		_ = sr.Close()
 		if err != nil {
 			return err
@@ -47,3 +56,28 @@ func walIterate(w *wlog.WL, f func(r *wlog.Reader) error) error {
 
 	return nil
 }
+
+// walPageSize is the size of the pages of WAL segments.
+const walPageSize = 32 * 1024
+
+// isIncompleteRecord returns true if err was caused by reading a record at
+// the end of s which was only partially written.
+func isIncompleteRecord(s *wlog.Segment, err error) bool {
+	var cerr *wlog.CorruptionErr
+	if !errors.As(err, &cerr) {
+		return false
+	}
+	// wlog doesn't export the error for a torn record, so it's matched by its
+	// message.
+	if errors.Is(cerr.Err, io.ErrUnexpectedEOF) || cerr.Err.Error() == "last record is torn" {
+		return true
+	}
+
+	// Readers pad the last page of a segment with zeros, so a partially
+	// written record in it can fail in other ways.
+	info, statErr := s.Stat()
+	if statErr != nil {
+		return false
+	}
+	return cerr.Offset >= info.Size()/walPageSize*walPageSize
+}
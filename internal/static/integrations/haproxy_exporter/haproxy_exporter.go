@@ -0,0 +1,351 @@
+// Package haproxy_exporter collects metrics from the CSV statistics of an
+// HAProxy server, like https://github.com/prometheus/haproxy_exporter.
+//
+// It's only used by the prometheus.exporter.haproxy component and isn't
+// registered as a static mode integration.
+package haproxy_exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultConfig holds the default settings for the haproxy integration.
+var DefaultConfig = Config{
+	ScrapeURI: "http://localhost/;csv",
+	Timeout:   5 * time.Second,
+}
+
+// Config controls the haproxy integration.
+type Config struct {
+	// ScrapeURI is either the URL of the CSV statistics page, or the path of
+	// the stats socket prefixed with unix:.
+	ScrapeURI string        `yaml:"scrape_uri,omitempty"`
+	Insecure  bool          `yaml:"insecure,omitempty"`
+	Timeout   time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration this config is for.
+func (c *Config) Name() string {
+	return "haproxy"
+}
+
+// InstanceKey returns the host of the HAProxy server, or the path of its
+// stats socket.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "unix" {
+		return u.Opaque + u.Path, nil
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts the config into an integration instance.
+func (c *Config) NewIntegration(logger log.Logger) (integrations.Integration, error) {
+	return New(logger, c)
+}
+
+// New creates a new haproxy integration. The integration scrapes metrics from
+// the CSV statistics of an HAProxy server.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	fetch, err := newFetcher(c)
+	if err != nil {
+		return nil, err
+	}
+	col := &collector{logger: logger, fetch: fetch}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
+
+// newFetcher returns a function which fetches the CSV statistics from the
+// scrape URI of c.
+func newFetcher(c *Config) (func(context.Context) (io.ReadCloser, error), error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return nil, fmt.Errorf("scrape_uri is invalid: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.Insecure}
+		client := &http.Client{Transport: transport, Timeout: c.Timeout}
+
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ScrapeURI, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+			}
+			return resp.Body, nil
+		}, nil
+
+	case "unix":
+		path := u.Opaque + u.Path
+		return func(ctx context.Context) (io.ReadCloser, error) {
+			dialer := net.Dialer{Timeout: c.Timeout}
+			conn, err := dialer.DialContext(ctx, "unix", path)
+			if err != nil {
+				return nil, err
+			}
+			if c.Timeout > 0 {
+				_ = conn.SetDeadline(time.Now().Add(c.Timeout))
+			}
+			if _, err := conn.Write([]byte("show stat\n")); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("scrape_uri is invalid: unsupported scheme %q", u.Scheme)
+	}
+}
+
+const namespace = "haproxy"
+
+// Values of the type field of the statistics.
+const (
+	typeFrontend = "0"
+	typeBackend  = "1"
+	typeServer   = "2"
+)
+
+var upDesc = prometheus.NewDesc(namespace+"_up", "Whether the last scrape of HAProxy was successful.", nil, nil)
+
+// metric maps a field of the statistics to a metric.
+type metric struct {
+	field     string
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+var (
+	frontendLabels = []string{"frontend"}
+	backendLabels  = []string{"backend"}
+	serverLabels   = []string{"backend", "server"}
+
+	frontendMetrics = []metric{
+		{"scur", newDesc("frontend", "current_sessions", "Current number of active sessions.", frontendLabels), prometheus.GaugeValue},
+		{"smax", newDesc("frontend", "max_sessions", "Maximum observed number of active sessions.", frontendLabels), prometheus.GaugeValue},
+		{"slim", newDesc("frontend", "limit_sessions", "Configured session limit.", frontendLabels), prometheus.GaugeValue},
+		{"stot", newDesc("frontend", "sessions_total", "Total number of sessions.", frontendLabels), prometheus.CounterValue},
+		{"bin", newDesc("frontend", "bytes_in_total", "Current total of incoming bytes.", frontendLabels), prometheus.CounterValue},
+		{"bout", newDesc("frontend", "bytes_out_total", "Current total of outgoing bytes.", frontendLabels), prometheus.CounterValue},
+		{"dreq", newDesc("frontend", "requests_denied_total", "Total of requests denied for security.", frontendLabels), prometheus.CounterValue},
+		{"ereq", newDesc("frontend", "request_errors_total", "Total of request errors.", frontendLabels), prometheus.CounterValue},
+		{"req_tot", newDesc("frontend", "http_requests_total", "Total HTTP requests.", frontendLabels), prometheus.CounterValue},
+	}
+
+	backendMetrics = []metric{
+		{"qcur", newDesc("backend", "current_queue", "Current number of queued requests not assigned to any server.", backendLabels), prometheus.GaugeValue},
+		{"scur", newDesc("backend", "current_sessions", "Current number of active sessions.", backendLabels), prometheus.GaugeValue},
+		{"smax", newDesc("backend", "max_sessions", "Maximum observed number of active sessions.", backendLabels), prometheus.GaugeValue},
+		{"stot", newDesc("backend", "sessions_total", "Total number of sessions.", backendLabels), prometheus.CounterValue},
+		{"bin", newDesc("backend", "bytes_in_total", "Current total of incoming bytes.", backendLabels), prometheus.CounterValue},
+		{"bout", newDesc("backend", "bytes_out_total", "Current total of outgoing bytes.", backendLabels), prometheus.CounterValue},
+		{"econ", newDesc("backend", "connection_errors_total", "Total of connection errors.", backendLabels), prometheus.CounterValue},
+		{"eresp", newDesc("backend", "response_errors_total", "Total of response errors.", backendLabels), prometheus.CounterValue},
+		{"weight", newDesc("backend", "weight", "Total weight of the servers in the backend.", backendLabels), prometheus.GaugeValue},
+	}
+
+	serverMetrics = []metric{
+		{"qcur", newDesc("server", "current_queue", "Current number of queued requests assigned to this server.", serverLabels), prometheus.GaugeValue},
+		{"scur", newDesc("server", "current_sessions", "Current number of active sessions.", serverLabels), prometheus.GaugeValue},
+		{"smax", newDesc("server", "max_sessions", "Maximum observed number of active sessions.", serverLabels), prometheus.GaugeValue},
+		{"stot", newDesc("server", "sessions_total", "Total number of sessions.", serverLabels), prometheus.CounterValue},
+		{"bin", newDesc("server", "bytes_in_total", "Current total of incoming bytes.", serverLabels), prometheus.CounterValue},
+		{"bout", newDesc("server", "bytes_out_total", "Current total of outgoing bytes.", serverLabels), prometheus.CounterValue},
+		{"econ", newDesc("server", "connection_errors_total", "Total of connection errors.", serverLabels), prometheus.CounterValue},
+		{"eresp", newDesc("server", "response_errors_total", "Total of response errors.", serverLabels), prometheus.CounterValue},
+		{"chkfail", newDesc("server", "check_failures_total", "Total number of failed health checks.", serverLabels), prometheus.CounterValue},
+		{"downtime", newDesc("server", "downtime_seconds_total", "Total downtime in seconds.", serverLabels), prometheus.CounterValue},
+		{"weight", newDesc("server", "weight", "Current weight of the server.", serverLabels), prometheus.GaugeValue},
+	}
+
+	backendUpDesc = newDesc("backend", "up", "Current health status of the backend (1 = UP, 0 = DOWN).", backendLabels)
+	serverUpDesc  = newDesc("server", "up", "Current health status of the server (1 = UP, 0 = DOWN).", serverLabels)
+
+	frontendResponsesDesc = newDesc("frontend", "http_responses_total", "Total of HTTP responses.", append(frontendLabels, "code"))
+	backendResponsesDesc  = newDesc("backend", "http_responses_total", "Total of HTTP responses.", append(backendLabels, "code"))
+	serverResponsesDesc   = newDesc("server", "http_responses_total", "Total of HTTP responses.", append(serverLabels, "code"))
+)
+
+// responseFields maps the fields of the HTTP responses counters to the value
+// of their code label.
+var responseFields = []struct{ field, code string }{
+	{"hrsp_1xx", "1xx"},
+	{"hrsp_2xx", "2xx"},
+	{"hrsp_3xx", "3xx"},
+	{"hrsp_4xx", "4xx"},
+	{"hrsp_5xx", "5xx"},
+	{"hrsp_other", "other"},
+}
+
+func newDesc(subsystem, name, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+type collector struct {
+	logger log.Logger
+	fetch  func(context.Context) (io.ReadCloser, error)
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	for _, metrics := range [][]metric{frontendMetrics, backendMetrics, serverMetrics} {
+		for _, m := range metrics {
+			ch <- m.desc
+		}
+	}
+	ch <- backendUpDesc
+	ch <- serverUpDesc
+	ch <- frontendResponsesDesc
+	ch <- backendResponsesDesc
+	ch <- serverResponsesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	rows, err := c.scrape(context.Background())
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to scrape HAProxy statistics", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	for _, row := range rows {
+		var (
+			proxy  = row["pxname"]
+			server = row["svname"]
+		)
+
+		switch row["type"] {
+		case typeFrontend:
+			c.collectRow(ch, row, frontendMetrics, frontendResponsesDesc, proxy)
+		case typeBackend:
+			ch <- prometheus.MustNewConstMetric(backendUpDesc, prometheus.GaugeValue, parseStatus(row["status"]), proxy)
+			c.collectRow(ch, row, backendMetrics, backendResponsesDesc, proxy)
+		case typeServer:
+			ch <- prometheus.MustNewConstMetric(serverUpDesc, prometheus.GaugeValue, parseStatus(row["status"]), proxy, server)
+			c.collectRow(ch, row, serverMetrics, serverResponsesDesc, proxy, server)
+		}
+	}
+}
+
+func (c *collector) collectRow(ch chan<- prometheus.Metric, row map[string]string, metrics []metric, responsesDesc *prometheus.Desc, labels ...string) {
+	for _, m := range metrics {
+		v, ok := parseValue(row[m.field])
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, v, labels...)
+	}
+	for _, r := range responseFields {
+		v, ok := parseValue(row[r.field])
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(responsesDesc, prometheus.CounterValue, v, append(labels, r.code)...)
+	}
+}
+
+func (c *collector) scrape(ctx context.Context) ([]map[string]string, error) {
+	body, err := c.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return parseCSV(body)
+}
+
+// parseCSV parses the CSV statistics of HAProxy into a map of field names to
+// values for each row.
+func parseCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty statistics")
+	} else if err != nil {
+		return nil, err
+	}
+	if len(header) == 0 || !strings.HasPrefix(header[0], "#") {
+		return nil, errors.New("statistics don't start with a header")
+	}
+	header[0] = strings.TrimSpace(strings.TrimPrefix(header[0], "#"))
+
+	var rows []map[string]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(header))
+		for i, field := range header {
+			if i < len(record) {
+				row[field] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseValue(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// parseStatus returns 1 if status reports that a backend or server is up, and
+// 0 otherwise. Servers without health checks are considered up.
+func parseStatus(status string) float64 {
+	switch {
+	case strings.HasPrefix(status, "UP"), status == "OPEN", status == "no check":
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,149 @@
+package haproxy_exporter
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testStats = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,
+http-in,FRONTEND,,,12,30,2000,1500,102400,204800,3,0,4,,,,,OPEN,,,,,,,,,1,2,0,,,,0,5,0,20,,,,0,1400,50,40,10,0,,5,20,1500,
+app,web1,0,0,5,12,,700,40000,80000,,0,,1,2,0,0,UP,1,1,0,3,1,100,60,,1,3,1,,700,,2,2,,10,L7OK,200,1,0,650,20,25,5,0,0,,,,
+app,web2,0,0,0,10,,600,30000,70000,,0,,0,1,0,0,DOWN,1,1,0,7,2,10,120,,1,3,2,,600,,2,0,,8,L4CON,,0,0,580,15,3,2,0,0,,,,
+app,BACKEND,2,4,5,22,200,1300,70000,150000,0,0,,1,3,0,0,UP,2,2,0,,1,100,0,,1,3,0,,1300,,1,2,,18,,,,0,1230,35,28,7,0,,,,,
+`
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/;csv", r.URL.Path)
+		_, _ = w.Write([]byte(testStats))
+	}))
+	defer srv.Close()
+
+	col := newTestCollector(t, srv.URL+"/;csv")
+
+	expect := `
+# HELP haproxy_backend_current_queue Current number of queued requests not assigned to any server.
+# TYPE haproxy_backend_current_queue gauge
+haproxy_backend_current_queue{backend="app"} 2
+# HELP haproxy_backend_up Current health status of the backend (1 = UP, 0 = DOWN).
+# TYPE haproxy_backend_up gauge
+haproxy_backend_up{backend="app"} 1
+# HELP haproxy_frontend_current_sessions Current number of active sessions.
+# TYPE haproxy_frontend_current_sessions gauge
+haproxy_frontend_current_sessions{frontend="http-in"} 12
+# HELP haproxy_frontend_http_responses_total Total of HTTP responses.
+# TYPE haproxy_frontend_http_responses_total counter
+haproxy_frontend_http_responses_total{code="1xx",frontend="http-in"} 0
+haproxy_frontend_http_responses_total{code="2xx",frontend="http-in"} 1400
+haproxy_frontend_http_responses_total{code="3xx",frontend="http-in"} 50
+haproxy_frontend_http_responses_total{code="4xx",frontend="http-in"} 40
+haproxy_frontend_http_responses_total{code="5xx",frontend="http-in"} 10
+haproxy_frontend_http_responses_total{code="other",frontend="http-in"} 0
+# HELP haproxy_server_check_failures_total Total number of failed health checks.
+# TYPE haproxy_server_check_failures_total counter
+haproxy_server_check_failures_total{backend="app",server="web1"} 3
+haproxy_server_check_failures_total{backend="app",server="web2"} 7
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="app",server="web1"} 1
+haproxy_server_up{backend="app",server="web2"} 0
+# HELP haproxy_up Whether the last scrape of HAProxy was successful.
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect),
+		"haproxy_up",
+		"haproxy_frontend_current_sessions",
+		"haproxy_frontend_http_responses_total",
+		"haproxy_backend_up",
+		"haproxy_backend_current_queue",
+		"haproxy_server_up",
+		"haproxy_server_check_failures_total",
+	))
+
+	// Empty fields, like the session limit of servers, aren't reported.
+	require.Equal(t, 1, testutil.CollectAndCount(col, "haproxy_frontend_limit_sessions"))
+	require.Equal(t, 0, testutil.CollectAndCount(col, "haproxy_server_limit_sessions"))
+}
+
+func TestCollector_Socket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "haproxy.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, len("show stat\n"))
+			if _, err := conn.Read(buf); err == nil && string(buf) == "show stat\n" {
+				_, _ = conn.Write([]byte(testStats))
+			}
+			conn.Close()
+		}
+	}()
+
+	col := newTestCollector(t, "unix:"+path)
+
+	expect := `
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="app",server="web1"} 1
+haproxy_server_up{backend="app",server="web2"} 0
+# HELP haproxy_up Whether the last scrape of HAProxy was successful.
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect), "haproxy_up", "haproxy_server_up"))
+}
+
+func TestCollector_Down(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	col := newTestCollector(t, srv.URL+"/;csv")
+
+	expect := `
+# HELP haproxy_up Whether the last scrape of HAProxy was successful.
+# TYPE haproxy_up gauge
+haproxy_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestParseCSV_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"pxname,svname\nhttp-in,FRONTEND\n",
+	} {
+		_, err := parseCSV(strings.NewReader(input))
+		require.Error(t, err, input)
+	}
+}
+
+func TestNew_InvalidScrapeURI(t *testing.T) {
+	_, err := New(log.NewNopLogger(), &Config{ScrapeURI: "ftp://localhost/;csv"})
+	require.ErrorContains(t, err, "scrape_uri is invalid")
+}
+
+func newTestCollector(t *testing.T, scrapeURI string) prometheus.Collector {
+	t.Helper()
+	fetch, err := newFetcher(&Config{ScrapeURI: scrapeURI, Timeout: time.Second})
+	require.NoError(t, err)
+	return &collector{logger: log.NewNopLogger(), fetch: fetch}
+}
@@ -0,0 +1,260 @@
+// Package nats_exporter collects metrics from the monitoring endpoints of a
+// NATS server, like https://github.com/nats-io/prometheus-nats-exporter.
+//
+// It's only used by the prometheus.exporter.nats component and isn't
+// registered as a static mode integration.
+package nats_exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultConfig holds the default settings for the nats integration.
+var DefaultConfig = Config{
+	URL:     "http://localhost:8222",
+	Timeout: 5 * time.Second,
+}
+
+// Config controls the nats integration.
+type Config struct {
+	URL      string        `yaml:"url,omitempty"`
+	Insecure bool          `yaml:"insecure,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+
+	// JetStream enables collecting metrics from the /jsz endpoint.
+	JetStream bool `yaml:"jetstream,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration this config is for.
+func (c *Config) Name() string {
+	return "nats"
+}
+
+// InstanceKey returns the host of the NATS monitoring endpoints.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", err
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts the config into an integration instance.
+func (c *Config) NewIntegration(logger log.Logger) (integrations.Integration, error) {
+	return New(logger, c)
+}
+
+// New creates a new nats integration. The integration scrapes metrics from the
+// monitoring endpoints of a NATS server.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(logger, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
+
+func newCollector(logger log.Logger, c *Config) (*collector, error) {
+	u, err := url.ParseRequestURI(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("url is invalid: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("url is invalid: unsupported scheme %q", u.Scheme)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.Insecure}
+
+	return &collector{
+		logger:    logger,
+		url:       strings.TrimSuffix(c.URL, "/"),
+		client:    &http.Client{Transport: transport, Timeout: c.Timeout},
+		jetStream: c.JetStream,
+	}, nil
+}
+
+const namespace = "nats"
+
+var (
+	upDesc         = prometheus.NewDesc(namespace+"_up", "Whether the last scrape of the NATS monitoring endpoints was successful.", nil, nil)
+	serverInfoDesc = prometheus.NewDesc(namespace+"_server_info", "Information about the NATS server.", []string{"server_id", "server_name", "version"}, nil)
+
+	serverStartTimeDesc        = newServerDesc("start_time_seconds", "Start time of the server since the Unix epoch in seconds.")
+	serverConnectionsDesc      = newServerDesc("connections", "Number of current client connections.")
+	serverTotalConnectionsDesc = newServerDesc("connections_total", "Total number of client connections.")
+	serverRoutesDesc           = newServerDesc("routes", "Number of routes to other servers of the cluster.")
+	serverRemotesDesc          = newServerDesc("remotes", "Number of remote servers of the cluster.")
+	serverLeafNodesDesc        = newServerDesc("leafnodes", "Number of leaf node connections.")
+	serverSubscriptionsDesc    = newServerDesc("subscriptions", "Number of subscriptions.")
+	serverInMsgsDesc           = newServerDesc("in_msgs_total", "Total number of messages received.")
+	serverOutMsgsDesc          = newServerDesc("out_msgs_total", "Total number of messages sent.")
+	serverInBytesDesc          = newServerDesc("in_bytes_total", "Total number of bytes received.")
+	serverOutBytesDesc         = newServerDesc("out_bytes_total", "Total number of bytes sent.")
+	serverSlowConsumersDesc    = newServerDesc("slow_consumers_total", "Total number of slow consumers.")
+	serverMemDesc              = newServerDesc("mem_bytes", "Resident memory of the server.")
+	serverCPUDesc              = newServerDesc("cpu_percent", "CPU usage of the server in percent.")
+
+	jetStreamStreamsDesc   = newJetStreamDesc("streams", "Number of streams.")
+	jetStreamConsumersDesc = newJetStreamDesc("consumers", "Number of consumers.")
+	jetStreamMessagesDesc  = newJetStreamDesc("messages", "Number of messages stored in streams.")
+	jetStreamBytesDesc     = newJetStreamDesc("bytes", "Number of bytes stored in streams.")
+	jetStreamMemoryDesc    = newJetStreamDesc("memory_bytes", "Memory used by streams.")
+	jetStreamStorageDesc   = newJetStreamDesc("storage_bytes", "Storage used by streams.")
+)
+
+func newServerDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "server", name), help, []string{"server_id"}, nil)
+}
+
+func newJetStreamDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "jetstream", name), help, []string{"server_id"}, nil)
+}
+
+// varz holds the fields of the /varz endpoint which are collected.
+type varz struct {
+	ServerID         string    `json:"server_id"`
+	ServerName       string    `json:"server_name"`
+	Version          string    `json:"version"`
+	Start            time.Time `json:"start"`
+	Connections      float64   `json:"connections"`
+	TotalConnections float64   `json:"total_connections"`
+	Routes           float64   `json:"routes"`
+	Remotes          float64   `json:"remotes"`
+	LeafNodes        float64   `json:"leafnodes"`
+	Subscriptions    float64   `json:"subscriptions"`
+	InMsgs           float64   `json:"in_msgs"`
+	OutMsgs          float64   `json:"out_msgs"`
+	InBytes          float64   `json:"in_bytes"`
+	OutBytes         float64   `json:"out_bytes"`
+	SlowConsumers    float64   `json:"slow_consumers"`
+	Mem              float64   `json:"mem"`
+	CPU              float64   `json:"cpu"`
+}
+
+// jsz holds the fields of the /jsz endpoint which are collected.
+type jsz struct {
+	ServerID  string  `json:"server_id"`
+	Streams   float64 `json:"streams"`
+	Consumers float64 `json:"consumers"`
+	Messages  float64 `json:"messages"`
+	Bytes     float64 `json:"bytes"`
+	Memory    float64 `json:"memory"`
+	Storage   float64 `json:"storage"`
+}
+
+type collector struct {
+	logger    log.Logger
+	url       string
+	client    *http.Client
+	jetStream bool
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc, serverInfoDesc,
+		serverStartTimeDesc, serverConnectionsDesc, serverTotalConnectionsDesc,
+		serverRoutesDesc, serverRemotesDesc, serverLeafNodesDesc, serverSubscriptionsDesc,
+		serverInMsgsDesc, serverOutMsgsDesc, serverInBytesDesc, serverOutBytesDesc,
+		serverSlowConsumersDesc, serverMemDesc, serverCPUDesc,
+	} {
+		ch <- desc
+	}
+	if c.jetStream {
+		for _, desc := range []*prometheus.Desc{
+			jetStreamStreamsDesc, jetStreamConsumersDesc, jetStreamMessagesDesc,
+			jetStreamBytesDesc, jetStreamMemoryDesc, jetStreamStorageDesc,
+		} {
+			ch <- desc
+		}
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+
+	var v varz
+	if err := c.get(ctx, "/varz", &v); err != nil {
+		level.Error(c.logger).Log("msg", "failed to scrape NATS monitoring endpoint", "path", "/varz", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+
+	var j jsz
+	if c.jetStream {
+		if err := c.get(ctx, "/jsz", &j); err != nil {
+			level.Error(c.logger).Log("msg", "failed to scrape NATS monitoring endpoint", "path", "/jsz", "err", err)
+			ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+			return
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	id := v.ServerID
+	ch <- prometheus.MustNewConstMetric(serverInfoDesc, prometheus.GaugeValue, 1, id, v.ServerName, v.Version)
+	if !v.Start.IsZero() {
+		ch <- prometheus.MustNewConstMetric(serverStartTimeDesc, prometheus.GaugeValue, float64(v.Start.UnixNano())/1e9, id)
+	}
+	ch <- prometheus.MustNewConstMetric(serverConnectionsDesc, prometheus.GaugeValue, v.Connections, id)
+	ch <- prometheus.MustNewConstMetric(serverTotalConnectionsDesc, prometheus.CounterValue, v.TotalConnections, id)
+	ch <- prometheus.MustNewConstMetric(serverRoutesDesc, prometheus.GaugeValue, v.Routes, id)
+	ch <- prometheus.MustNewConstMetric(serverRemotesDesc, prometheus.GaugeValue, v.Remotes, id)
+	ch <- prometheus.MustNewConstMetric(serverLeafNodesDesc, prometheus.GaugeValue, v.LeafNodes, id)
+	ch <- prometheus.MustNewConstMetric(serverSubscriptionsDesc, prometheus.GaugeValue, v.Subscriptions, id)
+	ch <- prometheus.MustNewConstMetric(serverInMsgsDesc, prometheus.CounterValue, v.InMsgs, id)
+	ch <- prometheus.MustNewConstMetric(serverOutMsgsDesc, prometheus.CounterValue, v.OutMsgs, id)
+	ch <- prometheus.MustNewConstMetric(serverInBytesDesc, prometheus.CounterValue, v.InBytes, id)
+	ch <- prometheus.MustNewConstMetric(serverOutBytesDesc, prometheus.CounterValue, v.OutBytes, id)
+	ch <- prometheus.MustNewConstMetric(serverSlowConsumersDesc, prometheus.CounterValue, v.SlowConsumers, id)
+	ch <- prometheus.MustNewConstMetric(serverMemDesc, prometheus.GaugeValue, v.Mem, id)
+	ch <- prometheus.MustNewConstMetric(serverCPUDesc, prometheus.GaugeValue, v.CPU, id)
+
+	if c.jetStream {
+		ch <- prometheus.MustNewConstMetric(jetStreamStreamsDesc, prometheus.GaugeValue, j.Streams, id)
+		ch <- prometheus.MustNewConstMetric(jetStreamConsumersDesc, prometheus.GaugeValue, j.Consumers, id)
+		ch <- prometheus.MustNewConstMetric(jetStreamMessagesDesc, prometheus.GaugeValue, j.Messages, id)
+		ch <- prometheus.MustNewConstMetric(jetStreamBytesDesc, prometheus.GaugeValue, j.Bytes, id)
+		ch <- prometheus.MustNewConstMetric(jetStreamMemoryDesc, prometheus.GaugeValue, j.Memory, id)
+		ch <- prometheus.MustNewConstMetric(jetStreamStorageDesc, prometheus.GaugeValue, j.Storage, id)
+	}
+}
+
+// get fetches path from the monitoring endpoints and decodes the JSON
+// response into dst.
+func (c *collector) get(ctx context.Context, path string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
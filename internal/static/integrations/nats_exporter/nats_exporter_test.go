@@ -0,0 +1,130 @@
+package nats_exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testVarz = `{
+		"server_id": "NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV",
+		"server_name": "nats-0",
+		"version": "2.10.22",
+		"start": "2024-10-01T12:00:00Z",
+		"connections": 12,
+		"total_connections": 340,
+		"routes": 2,
+		"remotes": 2,
+		"leafnodes": 1,
+		"subscriptions": 57,
+		"in_msgs": 100000,
+		"out_msgs": 250000,
+		"in_bytes": 5000000,
+		"out_bytes": 12500000,
+		"slow_consumers": 3,
+		"mem": 26214400,
+		"cpu": 1.5
+	}`
+	testJsz = `{
+		"server_id": "NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV",
+		"streams": 4,
+		"consumers": 9,
+		"messages": 1200,
+		"bytes": 65536,
+		"memory": 1024,
+		"storage": 64512
+	}`
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/varz":
+			_, _ = w.Write([]byte(testVarz))
+		case "/jsz":
+			_, _ = w.Write([]byte(testJsz))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCollector(t *testing.T) {
+	srv := newTestServer(t)
+
+	col, err := newCollector(log.NewNopLogger(), &Config{URL: srv.URL, Timeout: time.Second, JetStream: true})
+	require.NoError(t, err)
+
+	expect := `
+# HELP nats_jetstream_messages Number of messages stored in streams.
+# TYPE nats_jetstream_messages gauge
+nats_jetstream_messages{server_id="NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV"} 1200
+# HELP nats_jetstream_streams Number of streams.
+# TYPE nats_jetstream_streams gauge
+nats_jetstream_streams{server_id="NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV"} 4
+# HELP nats_server_connections Number of current client connections.
+# TYPE nats_server_connections gauge
+nats_server_connections{server_id="NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV"} 12
+# HELP nats_server_in_msgs_total Total number of messages received.
+# TYPE nats_server_in_msgs_total counter
+nats_server_in_msgs_total{server_id="NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV"} 100000
+# HELP nats_server_info Information about the NATS server.
+# TYPE nats_server_info gauge
+nats_server_info{server_id="NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV",server_name="nats-0",version="2.10.22"} 1
+# HELP nats_server_start_time_seconds Start time of the server since the Unix epoch in seconds.
+# TYPE nats_server_start_time_seconds gauge
+nats_server_start_time_seconds{server_id="NDJWE4SOUJOJT2TY5Y2YQEOAHGAK5VIGXTGKWJSFHVCII4ITI3LBHBUV"} 1.7277840e+09
+# HELP nats_up Whether the last scrape of the NATS monitoring endpoints was successful.
+# TYPE nats_up gauge
+nats_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect),
+		"nats_up",
+		"nats_server_info",
+		"nats_server_start_time_seconds",
+		"nats_server_connections",
+		"nats_server_in_msgs_total",
+		"nats_jetstream_streams",
+		"nats_jetstream_messages",
+	))
+}
+
+func TestCollector_JetStreamDisabled(t *testing.T) {
+	srv := newTestServer(t)
+
+	col, err := newCollector(log.NewNopLogger(), &Config{URL: srv.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, testutil.CollectAndCount(col, "nats_server_connections"))
+	require.Equal(t, 0, testutil.CollectAndCount(col, "nats_jetstream_streams"))
+}
+
+func TestCollector_Down(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	col, err := newCollector(log.NewNopLogger(), &Config{URL: srv.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	expect := `
+# HELP nats_up Whether the last scrape of the NATS monitoring endpoints was successful.
+# TYPE nats_up gauge
+nats_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	_, err := New(log.NewNopLogger(), &Config{URL: "nats://localhost:4222"})
+	require.ErrorContains(t, err, "url is invalid")
+}
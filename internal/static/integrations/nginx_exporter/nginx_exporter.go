@@ -0,0 +1,78 @@
+// Package nginx_exporter embeds https://github.com/nginx/nginx-prometheus-exporter
+//
+// It's only used by the prometheus.exporter.nginx component and isn't
+// registered as a static mode integration.
+package nginx_exporter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging"
+	"github.com/grafana/alloy/internal/static/integrations"
+	"github.com/nginx/nginx-prometheus-exporter/client"
+	"github.com/nginx/nginx-prometheus-exporter/collector"
+)
+
+// DefaultConfig holds the default settings for the nginx integration.
+var DefaultConfig = Config{
+	ScrapeURI: "http://localhost:8080/stub_status",
+	Timeout:   5 * time.Second,
+}
+
+// Config controls the nginx integration.
+type Config struct {
+	ScrapeURI string        `yaml:"scrape_uri,omitempty"`
+	Insecure  bool          `yaml:"insecure,omitempty"`
+	Timeout   time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration this config is for.
+func (c *Config) Name() string {
+	return "nginx"
+}
+
+// InstanceKey returns the host of the NGINX server.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return "", err
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts the config into an integration instance.
+func (c *Config) NewIntegration(logger log.Logger) (integrations.Integration, error) {
+	return New(logger, c)
+}
+
+// New creates a new nginx integration. The integration scrapes metrics from
+// the stub_status page of an NGINX server.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	if _, err := url.ParseRequestURI(c.ScrapeURI); err != nil {
+		return nil, fmt.Errorf("scrape_uri is invalid: %w", err)
+	}
+
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(newCollector(logger, c))), nil
+}
+
+func newCollector(logger log.Logger, c *Config) *collector.NginxCollector {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.Insecure}
+
+	nginxClient := client.NewNginxClient(&http.Client{Transport: transport, Timeout: c.Timeout}, c.ScrapeURI)
+	return collector.NewNginxCollector(nginxClient, "nginx", nil, slog.New(logging.NewSlogGoKitHandler(logger)))
+}
@@ -0,0 +1,84 @@
+package nginx_exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testStubStatus = `Active connections: 291
+server accepts handled requests
+ 16630948 16630946 31070465
+Reading: 6 Writing: 179 Waiting: 106
+`
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/stub_status", r.URL.Path)
+		_, _ = w.Write([]byte(testStubStatus))
+	}))
+	defer srv.Close()
+
+	col := newTestCollector(t, srv.URL+"/stub_status")
+
+	expect := `
+# HELP nginx_connections_accepted Accepted client connections
+# TYPE nginx_connections_accepted counter
+nginx_connections_accepted 1.6630948e+07
+# HELP nginx_connections_active Active client connections
+# TYPE nginx_connections_active gauge
+nginx_connections_active 291
+# HELP nginx_connections_handled Handled client connections
+# TYPE nginx_connections_handled counter
+nginx_connections_handled 1.6630946e+07
+# HELP nginx_connections_reading Connections where NGINX is reading the request header
+# TYPE nginx_connections_reading gauge
+nginx_connections_reading 6
+# HELP nginx_connections_waiting Idle client connections
+# TYPE nginx_connections_waiting gauge
+nginx_connections_waiting 106
+# HELP nginx_connections_writing Connections where NGINX is writing the response back to the client
+# TYPE nginx_connections_writing gauge
+nginx_connections_writing 179
+# HELP nginx_http_requests_total Total http requests
+# TYPE nginx_http_requests_total counter
+nginx_http_requests_total 3.1070465e+07
+# HELP nginx_up Status of the last metric scrape
+# TYPE nginx_up gauge
+nginx_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestCollector_Down(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	col := newTestCollector(t, srv.URL+"/stub_status")
+
+	expect := `
+# HELP nginx_up Status of the last metric scrape
+# TYPE nginx_up gauge
+nginx_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestNew_InvalidScrapeURI(t *testing.T) {
+	_, err := New(log.NewNopLogger(), &Config{ScrapeURI: "localhost"})
+	require.ErrorContains(t, err, "scrape_uri is invalid")
+}
+
+func newTestCollector(t *testing.T, scrapeURI string) prometheus.Collector {
+	t.Helper()
+	return newCollector(log.NewNopLogger(), &Config{ScrapeURI: scrapeURI, Timeout: time.Second})
+}
@@ -0,0 +1,233 @@
+package rabbitmq_exporter
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	rabbithole "github.com/michaelklishin/rabbit-hole/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "rabbitmq"
+
+// The names and labels of the metrics are the ones of
+// https://github.com/kbudde/rabbitmq_exporter, so that its dashboards and
+// alerts can be reused.
+var (
+	overviewLabels = []string{"cluster"}
+	nodeLabels     = []string{"cluster", "node", "self"}
+	queueLabels    = []string{"cluster", "vhost", "queue", "durable", "policy", "self"}
+	exchangeLabels = []string{"cluster", "vhost", "exchange"}
+
+	upDesc          = newDesc("up", "Was the last scrape of rabbitmq successful.", []string{"cluster", "node"})
+	versionInfoDesc = newDesc("version_info", "A metric with a constant '1' value labeled by rabbitmq version, erlang version, node, cluster.", []string{"rabbitmq", "erlang", "node", "cluster"})
+
+	channelsDesc                     = newDesc("channels", "Number of channels.", overviewLabels)
+	connectionsDesc                  = newDesc("connections", "Number of connections.", overviewLabels)
+	consumersDesc                    = newDesc("consumers", "Number of message consumers.", overviewLabels)
+	queuesDesc                       = newDesc("queues", "Number of queues in use.", overviewLabels)
+	exchangesDesc                    = newDesc("exchanges", "Number of exchanges in use.", overviewLabels)
+	queueMessagesGlobalDesc          = newDesc("queue_messages_global", "Number ready and unacknowledged messages in cluster.", overviewLabels)
+	queueMessagesReadyGlobalDesc     = newDesc("queue_messages_ready_global", "Number of messages ready to be delivered to clients.", overviewLabels)
+	queueMessagesUnackedGlobalDesc   = newDesc("queue_messages_unacknowledged_global", "Number of messages delivered to clients but not yet acknowledged.", overviewLabels)
+	nodeRunningDesc                  = newDesc("running", "number of running nodes", nodeLabels)
+	nodeMemUsedDesc                  = newDesc("node_mem_used", "Memory used in bytes", nodeLabels)
+	nodeMemLimitDesc                 = newDesc("node_mem_limit", "Point at which the memory alarm will go off", nodeLabels)
+	nodeMemAlarmDesc                 = newDesc("node_mem_alarm", "Whether the memory alarm has gone off", nodeLabels)
+	nodeDiskFreeDesc                 = newDesc("node_disk_free", "Disk free space in bytes.", nodeLabels)
+	nodeDiskFreeLimitDesc            = newDesc("node_disk_free_limit", "Point at which the disk alarm will go off.", nodeLabels)
+	nodeDiskFreeAlarmDesc            = newDesc("node_disk_free_alarm", "Whether the disk alarm has gone off.", nodeLabels)
+	nodeFdUsedDesc                   = newDesc("fd_used", "Used File descriptors", nodeLabels)
+	nodeFdAvailableDesc              = newDesc("fd_available", "File descriptors available", nodeLabels)
+	nodeSocketsUsedDesc              = newDesc("sockets_used", "File descriptors used as sockets.", nodeLabels)
+	nodeSocketsAvailableDesc         = newDesc("sockets_available", "File descriptors available for use as sockets", nodeLabels)
+	queueMessagesDesc                = newDesc("queue_messages", "Sum of ready and unacknowledged messages (queue depth).", queueLabels)
+	queueMessagesReadyDesc           = newDesc("queue_messages_ready", "Number of messages ready to be delivered to clients.", queueLabels)
+	queueMessagesUnackedDesc         = newDesc("queue_messages_unacknowledged", "Number of messages delivered to clients but not yet acknowledged.", queueLabels)
+	queueConsumersDesc               = newDesc("queue_consumers", "Number of consumers.", queueLabels)
+	queueMemoryDesc                  = newDesc("queue_memory", "Bytes of memory consumed by the Erlang process associated with the queue, including stack, heap and internal structures.", queueLabels)
+	queueMessagesPublishedDesc       = newDesc("queue_messages_published_total", "Count of messages published.", queueLabels)
+	queueMessagesDeliveredDesc       = newDesc("queue_messages_delivered_total", "Count of messages delivered in acknowledgement mode to consumers.", queueLabels)
+	queueMessagesAckDesc             = newDesc("queue_messages_ack_total", "Count of messages delivered in acknowledgement mode in response to basic.get.", queueLabels)
+	queueMessagesRedeliveredDesc     = newDesc("queue_messages_redelivered_total", "Count of subset of messages in deliver_get which had the redelivered flag set.", queueLabels)
+	exchangeMessagesPublishedInDesc  = newDesc("exchange_messages_published_in_total", "Count of messages published in to an exchange, i.e. not taking account of routing.", exchangeLabels)
+	exchangeMessagesPublishedOutDesc = newDesc("exchange_messages_published_out_total", "Count of messages published out of an exchange, i.e. taking account of routing.", exchangeLabels)
+)
+
+func newDesc(name, help string, labels []string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+}
+
+// collector collects metrics from the management API of a RabbitMQ server
+// on each scrape.
+type collector struct {
+	logger        log.Logger
+	client        *rabbithole.Client
+	includeQueues *regexp.Regexp
+	excludeQueues *regexp.Regexp
+}
+
+var _ prometheus.Collector = (*collector)(nil)
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc, versionInfoDesc,
+		channelsDesc, connectionsDesc, consumersDesc, queuesDesc, exchangesDesc,
+		queueMessagesGlobalDesc, queueMessagesReadyGlobalDesc, queueMessagesUnackedGlobalDesc,
+		nodeRunningDesc, nodeMemUsedDesc, nodeMemLimitDesc, nodeMemAlarmDesc,
+		nodeDiskFreeDesc, nodeDiskFreeLimitDesc, nodeDiskFreeAlarmDesc,
+		nodeFdUsedDesc, nodeFdAvailableDesc, nodeSocketsUsedDesc, nodeSocketsAvailableDesc,
+		queueMessagesDesc, queueMessagesReadyDesc, queueMessagesUnackedDesc, queueConsumersDesc, queueMemoryDesc,
+		queueMessagesPublishedDesc, queueMessagesDeliveredDesc, queueMessagesAckDesc, queueMessagesRedeliveredDesc,
+		exchangeMessagesPublishedInDesc, exchangeMessagesPublishedOutDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	overview, err := c.client.Overview()
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to get the overview", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0, "", "")
+		return
+	}
+	clusterName, err := c.client.GetClusterName()
+	if err != nil {
+		level.Error(c.logger).Log("msg", "failed to get the cluster name", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0, "", overview.Node)
+		return
+	}
+	cluster := clusterName.Name
+
+	ch <- prometheus.MustNewConstMetric(versionInfoDesc, prometheus.GaugeValue, 1, overview.RabbitMQVersion, overview.ErlangVersion, overview.Node, cluster)
+	for desc, v := range map[*prometheus.Desc]int{
+		channelsDesc:                   overview.ObjectTotals.Channels,
+		connectionsDesc:                overview.ObjectTotals.Connections,
+		consumersDesc:                  overview.ObjectTotals.Consumers,
+		queuesDesc:                     overview.ObjectTotals.Queues,
+		exchangesDesc:                  overview.ObjectTotals.Exchanges,
+		queueMessagesGlobalDesc:        overview.QueueTotals.Messages,
+		queueMessagesReadyGlobalDesc:   overview.QueueTotals.MessagesReady,
+		queueMessagesUnackedGlobalDesc: overview.QueueTotals.MessagesUnacknowledged,
+	} {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), cluster)
+	}
+
+	up := 1.0
+	if err := c.collectNodes(ch, cluster, overview.Node); err != nil {
+		level.Error(c.logger).Log("msg", "failed to list the nodes", "err", err)
+		up = 0
+	}
+	if err := c.collectQueues(ch, cluster, overview.Node); err != nil {
+		level.Error(c.logger).Log("msg", "failed to list the queues", "err", err)
+		up = 0
+	}
+	if err := c.collectExchanges(ch, cluster); err != nil {
+		level.Error(c.logger).Log("msg", "failed to list the exchanges", "err", err)
+		up = 0
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, cluster, overview.Node)
+}
+
+func (c *collector) collectNodes(ch chan<- prometheus.Metric, cluster, self string) error {
+	nodes, err := c.client.ListNodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		labels := []string{cluster, n.Name, isSelf(n.Name, self)}
+		ch <- prometheus.MustNewConstMetric(nodeRunningDesc, prometheus.GaugeValue, boolToFloat(n.IsRunning), labels...)
+		if !n.IsRunning {
+			continue
+		}
+		for desc, v := range map[*prometheus.Desc]float64{
+			nodeMemUsedDesc:          float64(n.MemUsed),
+			nodeMemLimitDesc:         float64(n.MemLimit),
+			nodeMemAlarmDesc:         boolToFloat(n.MemAlarm),
+			nodeDiskFreeDesc:         float64(n.DiskFree),
+			nodeDiskFreeLimitDesc:    float64(n.DiskFreeLimit),
+			nodeDiskFreeAlarmDesc:    boolToFloat(n.DiskFreeAlarm),
+			nodeFdUsedDesc:           float64(n.FdUsed),
+			nodeFdAvailableDesc:      float64(n.FdTotal),
+			nodeSocketsUsedDesc:      float64(n.SocketsUsed),
+			nodeSocketsAvailableDesc: float64(n.SocketsTotal),
+		} {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectQueues(ch chan<- prometheus.Metric, cluster, self string) error {
+	queues, err := c.client.ListQueues()
+	if err != nil {
+		return err
+	}
+	for _, q := range queues {
+		if !c.includeQueues.MatchString(q.Name) || c.excludeQueues.MatchString(q.Name) {
+			continue
+		}
+		labels := []string{cluster, q.Vhost, q.Name, strconv.FormatBool(q.Durable), q.Policy, isSelf(q.Node, self)}
+		for desc, v := range map[*prometheus.Desc]float64{
+			queueMessagesDesc:        float64(q.Messages),
+			queueMessagesReadyDesc:   float64(q.MessagesReady),
+			queueMessagesUnackedDesc: float64(q.MessagesUnacknowledged),
+			queueConsumersDesc:       float64(q.Consumers),
+			queueMemoryDesc:          float64(q.Memory),
+		} {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+		}
+
+		var stats rabbithole.MessageStats
+		if q.MessageStats != nil {
+			stats = *q.MessageStats
+		}
+		for desc, v := range map[*prometheus.Desc]int64{
+			queueMessagesPublishedDesc:   stats.Publish,
+			queueMessagesDeliveredDesc:   stats.Deliver,
+			queueMessagesAckDesc:         stats.Ack,
+			queueMessagesRedeliveredDesc: stats.Redeliver,
+		} {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectExchanges(ch chan<- prometheus.Metric, cluster string) error {
+	exchanges, err := c.client.ListExchanges()
+	if err != nil {
+		return err
+	}
+	for _, e := range exchanges {
+		// Exchanges which never routed a message have no statistics.
+		if e.MessageStats == nil {
+			continue
+		}
+		labels := []string{cluster, e.Vhost, e.Name}
+		ch <- prometheus.MustNewConstMetric(exchangeMessagesPublishedInDesc, prometheus.CounterValue, float64(e.MessageStats.PublishIn), labels...)
+		ch <- prometheus.MustNewConstMetric(exchangeMessagesPublishedOutDesc, prometheus.CounterValue, float64(e.MessageStats.PublishOut), labels...)
+	}
+	return nil
+}
+
+// isSelf returns the value of the self label, which is "1" for the node the
+// management API was requested from.
+func isSelf(node, self string) string {
+	if node == self {
+		return "1"
+	}
+	return "0"
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
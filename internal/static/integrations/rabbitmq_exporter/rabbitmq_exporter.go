@@ -0,0 +1,117 @@
+// Package rabbitmq_exporter collects metrics from the management API of a
+// RabbitMQ server with github.com/michaelklishin/rabbit-hole, like
+// https://github.com/kbudde/rabbitmq_exporter, whose exporter can't be
+// imported.
+//
+// It's only used by the prometheus.exporter.rabbitmq component and isn't
+// registered as a static mode integration.
+package rabbitmq_exporter
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/static/integrations"
+	rabbithole "github.com/michaelklishin/rabbit-hole/v2"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the rabbitmq integration.
+var DefaultConfig = Config{
+	URL:           "http://localhost:15672",
+	Username:      "guest",
+	Password:      "guest",
+	Timeout:       10 * time.Second,
+	IncludeQueues: ".*",
+	ExcludeQueues: "^$",
+}
+
+// Config controls the rabbitmq integration.
+type Config struct {
+	URL      string             `yaml:"url,omitempty"`
+	Username string             `yaml:"username,omitempty"`
+	Password config_util.Secret `yaml:"password,omitempty"`
+	Insecure bool               `yaml:"insecure,omitempty"`
+	Timeout  time.Duration      `yaml:"timeout,omitempty"`
+
+	// IncludeQueues and ExcludeQueues are regular expressions matched against
+	// the names of the queues to collect metrics for.
+	IncludeQueues string `yaml:"include_queues,omitempty"`
+	ExcludeQueues string `yaml:"exclude_queues,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration this config is for.
+func (c *Config) Name() string {
+	return "rabbitmq"
+}
+
+// InstanceKey returns the host of the RabbitMQ management API.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", err
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts the config into an integration instance.
+func (c *Config) NewIntegration(logger log.Logger) (integrations.Integration, error) {
+	return New(logger, c)
+}
+
+// New creates a new rabbitmq integration. The integration scrapes metrics from
+// the management API of a RabbitMQ server.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(logger, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
+
+func newCollector(logger log.Logger, c *Config) (*collector, error) {
+	u, err := url.ParseRequestURI(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("url is invalid: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("url is invalid: unsupported scheme %q", u.Scheme)
+	}
+	include, err := regexp.Compile(c.IncludeQueues)
+	if err != nil {
+		return nil, fmt.Errorf("include_queues is invalid: %w", err)
+	}
+	exclude, err := regexp.Compile(c.ExcludeQueues)
+	if err != nil {
+		return nil, fmt.Errorf("exclude_queues is invalid: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: c.Insecure}
+
+	client, err := rabbithole.NewTLSClient(strings.TrimSuffix(c.URL, "/"), c.Username, string(c.Password), transport)
+	if err != nil {
+		return nil, fmt.Errorf("url is invalid: %w", err)
+	}
+	client.SetTimeout(c.Timeout)
+
+	return &collector{
+		logger:        logger,
+		client:        client,
+		includeQueues: include,
+		excludeQueues: exclude,
+	}, nil
+}
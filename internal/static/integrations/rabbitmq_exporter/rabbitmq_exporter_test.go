@@ -0,0 +1,152 @@
+package rabbitmq_exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var testResponses = map[string]string{
+	"/api/overview": `{
+		"node": "rabbit@a", "rabbitmq_version": "4.0.5", "erlang_version": "27.2",
+		"object_totals": {"channels": 4, "connections": 2, "consumers": 3, "exchanges": 8, "queues": 3},
+		"queue_totals": {"messages": 15, "messages_ready": 12, "messages_unacknowledged": 3},
+		"message_stats": {"publish": 1200, "deliver_get": 1185, "ack": 1180}
+	}`,
+	"/api/cluster-name/": `{"name": "shop"}`,
+	"/api/exchanges": `[
+		{"name": "orders", "vhost": "/", "message_stats": {"publish_in": 1000, "publish_out": 1000}}
+	]`,
+	"/api/nodes": `[
+		{"name": "rabbit@a", "running": true, "mem_used": 104857600, "mem_limit": 838860800, "mem_alarm": false,
+		 "disk_free": 5368709120, "disk_free_limit": 50000000, "disk_free_alarm": false,
+		 "fd_used": 40, "fd_total": 1024, "sockets_used": 2, "sockets_total": 829},
+		{"name": "rabbit@b", "running": false}
+	]`,
+	"/api/queues": `[
+		{"name": "orders", "vhost": "/", "durable": true, "policy": "", "node": "rabbit@a",
+		 "messages": 10, "messages_ready": 8, "messages_unacknowledged": 2,
+		 "consumers": 2, "memory": 55000, "message_stats": {"publish": 1000, "deliver_get": 990}},
+		{"name": "events", "vhost": "shop", "durable": true, "policy": "", "node": "rabbit@b",
+		 "messages": 5, "messages_ready": 4, "messages_unacknowledged": 1,
+		 "consumers": 1, "memory": 21000},
+		{"name": "amq.gen-tmp", "vhost": "/", "durable": false, "policy": "", "node": "rabbit@a",
+		 "messages": 0, "messages_ready": 0, "messages_unacknowledged": 0,
+		 "consumers": 0, "memory": 10000}
+	]`,
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "monitoring" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		resp, ok := testResponses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCollector(t *testing.T) {
+	srv := newTestServer(t)
+
+	col, err := newCollector(log.NewNopLogger(), &Config{
+		URL:           srv.URL,
+		Username:      "monitoring",
+		Password:      "secret",
+		Timeout:       time.Second,
+		IncludeQueues: ".*",
+		ExcludeQueues: "^amq\\.",
+	})
+	require.NoError(t, err)
+
+	expect := `
+# HELP rabbitmq_exchange_messages_published_in_total Count of messages published in to an exchange, i.e. not taking account of routing.
+# TYPE rabbitmq_exchange_messages_published_in_total counter
+rabbitmq_exchange_messages_published_in_total{cluster="shop",exchange="orders",vhost="/"} 1000
+# HELP rabbitmq_node_mem_used Memory used in bytes
+# TYPE rabbitmq_node_mem_used gauge
+rabbitmq_node_mem_used{cluster="shop",node="rabbit@a",self="1"} 1.048576e+08
+# HELP rabbitmq_queue_messages Sum of ready and unacknowledged messages (queue depth).
+# TYPE rabbitmq_queue_messages gauge
+rabbitmq_queue_messages{cluster="shop",durable="true",policy="",queue="events",self="0",vhost="shop"} 5
+rabbitmq_queue_messages{cluster="shop",durable="true",policy="",queue="orders",self="1",vhost="/"} 10
+# HELP rabbitmq_queue_messages_published_total Count of messages published.
+# TYPE rabbitmq_queue_messages_published_total counter
+rabbitmq_queue_messages_published_total{cluster="shop",durable="true",policy="",queue="events",self="0",vhost="shop"} 0
+rabbitmq_queue_messages_published_total{cluster="shop",durable="true",policy="",queue="orders",self="1",vhost="/"} 1000
+# HELP rabbitmq_queues Number of queues in use.
+# TYPE rabbitmq_queues gauge
+rabbitmq_queues{cluster="shop"} 3
+# HELP rabbitmq_running number of running nodes
+# TYPE rabbitmq_running gauge
+rabbitmq_running{cluster="shop",node="rabbit@a",self="1"} 1
+rabbitmq_running{cluster="shop",node="rabbit@b",self="0"} 0
+# HELP rabbitmq_up Was the last scrape of rabbitmq successful.
+# TYPE rabbitmq_up gauge
+rabbitmq_up{cluster="shop",node="rabbit@a"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect),
+		"rabbitmq_up",
+		"rabbitmq_queues",
+		"rabbitmq_exchange_messages_published_in_total",
+		"rabbitmq_running",
+		"rabbitmq_node_mem_used",
+		"rabbitmq_queue_messages",
+		"rabbitmq_queue_messages_published_total",
+	))
+}
+
+func TestCollector_Unauthorized(t *testing.T) {
+	srv := newTestServer(t)
+
+	col, err := newCollector(log.NewNopLogger(), &Config{
+		URL:           srv.URL,
+		Username:      "guest",
+		Password:      "guest",
+		Timeout:       time.Second,
+		IncludeQueues: ".*",
+		ExcludeQueues: "^$",
+	})
+	require.NoError(t, err)
+
+	expect := `
+# HELP rabbitmq_up Was the last scrape of rabbitmq successful.
+# TYPE rabbitmq_up gauge
+rabbitmq_up{cluster="",node=""} 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect), "rabbitmq_up"))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	tt := []struct {
+		name   string
+		modify func(c *Config)
+		err    string
+	}{
+		{"url", func(c *Config) { c.URL = "localhost:15672" }, "url is invalid"},
+		{"include_queues", func(c *Config) { c.IncludeQueues = "(" }, "include_queues is invalid"},
+		{"exclude_queues", func(c *Config) { c.ExcludeQueues = "(" }, "exclude_queues is invalid"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig
+			tc.modify(&c)
+			_, err := New(log.NewNopLogger(), &c)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -142,6 +142,10 @@ type Storage struct {
 	metrics *storageMetrics
 
 	notifier wlog.WriteNotified
+
+	// metadataRecords is whether the metadata of the series is written to the
+	// WAL.
+	metadataRecords atomic.Bool
 }
 
 // NewStorage makes a new Storage.
@@ -345,8 +349,8 @@ func (w *Storage) loadWAL(r *wlog.Reader, multiRef map[chunks.HeadSeriesRef]chun
 					return
 				}
 				decoded <- floatHistograms
-			case record.Tombstones, record.Exemplars:
-				// We don't care about decoding tombstones or exemplars
+			case record.Tombstones, record.Exemplars, record.Metadata:
+				// We don't care about decoding tombstones, exemplars or metadata
 				// TODO: If decide to decode exemplars, we should make sure to prepopulate
 				// stripeSeries.exemplars in the next block by using setLatestExemplar.
 				continue
@@ -464,6 +468,13 @@ func (w *Storage) SetNotifier(n wlog.WriteNotified) {
 	w.notifier = n
 }
 
+// SetMetadataRecords sets whether the metadata of the series is written to the
+// WAL. Only Remote Write 2.0 queues read metadata records, so they're not
+// written by default.
+func (w *Storage) SetMetadataRecords(enabled bool) {
+	w.metadataRecords.Store(enabled)
+}
+
 // Directory returns the path where the WAL storage is held.
 func (w *Storage) Directory() string {
 	return w.path
@@ -660,6 +671,7 @@ type appender struct {
 	pendingExamplars       []record.RefExemplar
 	pendingHistograms      []record.RefHistogramSample
 	pendingFloatHistograms []record.RefFloatHistogramSample
+	pendingMetadata        []record.RefMetadata
 
 	// Pointers to the series referenced by each element of pendingSamples.
 	// Series lock is not held on elements.
@@ -672,6 +684,10 @@ type appender struct {
 	// Pointers to the series referenced by each element of pendingFloatHistograms.
 	// Series lock is not held on elements.
 	floatHistogramSeries []*memSeries
+
+	// Pointers to the series referenced by each element of pendingMetadata.
+	// Series lock is not held on elements.
+	metadataSeries []*memSeries
 }
 
 var _ storage.Appender = (*appender)(nil)
@@ -855,14 +871,60 @@ func (a *appender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int
 	return storage.SeriesRef(series.ref), nil
 }
 
-func (a *appender) AppendCTZeroSample(_ storage.SeriesRef, _ labels.Labels, _ int64, _ int64) (storage.SeriesRef, error) {
-	// TODO(ptodev): implement this later
-	return 0, nil
+func (a *appender) AppendCTZeroSample(ref storage.SeriesRef, l labels.Labels, t int64, ct int64) (storage.SeriesRef, error) {
+	if ct >= t {
+		return 0, fmt.Errorf("CT is newer or the same as sample's timestamp, ignoring")
+	}
+
+	series := a.w.series.GetByID(chunks.HeadSeriesRef(ref))
+	if series == nil {
+		series = a.w.series.GetByHash(l.Hash(), l)
+	}
+	if series != nil {
+		series.Lock()
+		isOOO := ct <= series.lastTs
+		series.Unlock()
+
+		// Long living counters share the same created timestamp, so the zero
+		// sample only needs to be written once.
+		if isOOO {
+			return storage.SeriesRef(series.ref), storage.ErrOutOfOrderCT
+		}
+		ref = storage.SeriesRef(series.ref)
+	}
+
+	return a.Append(ref, l, ct, 0)
 }
 
-func (a *appender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
-	// TODO(rfratto): implement pushing metadata to WAL
-	return 0, nil
+func (a *appender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	if !a.w.metadataRecords.Load() {
+		return 0, nil
+	}
+
+	series := a.w.series.GetByID(chunks.HeadSeriesRef(ref))
+	if series == nil {
+		series = a.w.series.GetByHash(l.Hash(), l)
+	}
+	if series == nil {
+		return 0, fmt.Errorf("unknown series when trying to add metadata with ref %d and labels %s", ref, l)
+	}
+
+	series.Lock()
+	hasNewMetadata := series.meta == nil || *series.meta != m
+	series.Unlock()
+
+	if hasNewMetadata {
+		// NOTE: always modify pendingMetadata and metadataSeries together.
+		a.pendingMetadata = append(a.pendingMetadata, record.RefMetadata{
+			Ref:  series.ref,
+			Type: record.GetMetricType(m.Type),
+			Unit: m.Unit,
+			Help: m.Help,
+		})
+		a.metadataSeries = append(a.metadataSeries, series)
+	}
+
+	return storage.SeriesRef(series.ref), nil
 }
 
 // Commit submits the collected samples and purges the batch.
@@ -902,6 +964,16 @@ func (a *appender) log() error {
 		buf = buf[:0]
 	}
 
+	// Metadata should be logged before samples, so that remote write knows the
+	// metadata of a series by the time it sends its samples.
+	if len(a.pendingMetadata) > 0 {
+		buf = encoder.Metadata(a.pendingMetadata, buf)
+		if err := a.w.wal.Log(buf); err != nil {
+			return err
+		}
+		buf = buf[:0]
+	}
+
 	if len(a.pendingSamples) > 0 {
 		buf = encoder.Samples(a.pendingSamples, buf)
 		if err := a.w.wal.Log(buf); err != nil {
@@ -957,6 +1029,12 @@ func (a *appender) log() error {
 			a.w.metrics.totalOutOfOrderSamples.Inc()
 		}
 	}
+	for i, m := range a.pendingMetadata {
+		series = a.metadataSeries[i]
+		series.Lock()
+		series.meta = &metadata.Metadata{Type: record.ToMetricType(m.Type), Unit: m.Unit, Help: m.Help}
+		series.Unlock()
+	}
 
 	return nil
 }
@@ -968,9 +1046,11 @@ func (a *appender) clearData() {
 	a.pendingHistograms = a.pendingHistograms[:0]
 	a.pendingFloatHistograms = a.pendingFloatHistograms[:0]
 	a.pendingExamplars = a.pendingExamplars[:0]
+	a.pendingMetadata = a.pendingMetadata[:0]
 	a.sampleSeries = a.sampleSeries[:0]
 	a.histogramSeries = a.histogramSeries[:0]
 	a.floatHistogramSeries = a.floatHistogramSeries[:0]
+	a.metadataSeries = a.metadataSeries[:0]
 }
 
 func (a *appender) Rollback() error {
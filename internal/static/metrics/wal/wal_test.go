@@ -10,8 +10,10 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/grafana/alloy/internal/util"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
@@ -443,6 +445,97 @@ func TestGlobalReferenceID_Normal(t *testing.T) {
 	require.True(t, ref3 == 2)
 }
 
+func TestStorage_Metadata(t *testing.T) {
+	walDir := t.TempDir()
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	lbls := labels.FromStrings("__name__", "requests_total")
+	meta := metadata.Metadata{Type: model.MetricTypeCounter, Unit: "requests", Help: "Total requests."}
+
+	// Metadata isn't written until metadata records are enabled.
+	app := s.Appender(t.Context())
+	ref, err := app.Append(0, lbls, 5, 1)
+	require.NoError(t, err)
+	_, err = app.UpdateMetadata(ref, lbls, meta)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	s.SetMetadataRecords(true)
+
+	app = s.Appender(t.Context())
+	_, err = app.UpdateMetadata(0, labels.FromStrings("__name__", "unknown"), meta)
+	require.Error(t, err, "should reject unknown series")
+
+	_, err = app.Append(ref, lbls, 10, 1)
+	require.NoError(t, err)
+	_, err = app.UpdateMetadata(ref, lbls, meta)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// Unchanged metadata must not be logged again.
+	app = s.Appender(t.Context())
+	_, err = app.Append(ref, lbls, 20, 2)
+	require.NoError(t, err)
+	_, err = app.UpdateMetadata(ref, lbls, meta)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	var collector walDataCollector
+	replayer := walReplayer{w: &collector}
+	require.NoError(t, replayer.Replay(s.wal.Dir()))
+
+	require.Equal(t, []record.RefMetadata{{
+		Ref:  chunks.HeadSeriesRef(ref),
+		Type: record.GetMetricType(model.MetricTypeCounter),
+		Unit: "requests",
+		Help: "Total requests.",
+	}}, collector.metadata)
+}
+
+func TestStorage_CTZeroSample(t *testing.T) {
+	walDir := t.TempDir()
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	lbls := labels.FromStrings("__name__", "requests_total")
+
+	app := s.Appender(t.Context())
+	_, err = app.AppendCTZeroSample(0, lbls, 100, 100)
+	require.Error(t, err, "should reject created timestamp not older than the sample")
+
+	ref, err := app.AppendCTZeroSample(0, lbls, 100, 50)
+	require.NoError(t, err)
+	_, err = app.Append(ref, lbls, 100, 5)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	app = s.Appender(t.Context())
+	_, err = app.AppendCTZeroSample(ref, lbls, 200, 50)
+	require.ErrorIs(t, err, storage.ErrOutOfOrderCT, "should only write the zero sample once")
+	_, err = app.Append(ref, lbls, 200, 10)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	var collector walDataCollector
+	replayer := walReplayer{w: &collector}
+	require.NoError(t, replayer.Replay(s.wal.Dir()))
+
+	require.Equal(t, []record.RefSample{
+		{Ref: chunks.HeadSeriesRef(ref), T: 50, V: 0},
+		{Ref: chunks.HeadSeriesRef(ref), T: 100, V: 5},
+		{Ref: chunks.HeadSeriesRef(ref), T: 200, V: 10},
+	}, collector.samples)
+}
+
 func TestDBAllowOOOSamples(t *testing.T) {
 	walDir := t.TempDir()
 
@@ -0,0 +1,69 @@
+package testappender
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// FuncAppendable is a storage.Appendable which calls its functions with the
+// data appended to it, as soon as it's appended and without waiting for a
+// commit. Data without a function is ignored.
+//
+// It's useful to check the data forwarded by a component, for example by
+// sending the samples to a channel:
+//
+//	received := make(chan testappender.MetricSample, 100)
+//	args.ForwardTo = []storage.Appendable{testappender.FuncAppendable{
+//		Sample: func(s testappender.MetricSample) { received <- s },
+//	}}
+type FuncAppendable struct {
+	// Sample is called with every float sample.
+	Sample func(MetricSample)
+	// Metadata is called with every metadata update.
+	Metadata func(labels.Labels, metadata.Metadata)
+}
+
+var _ storage.Appendable = FuncAppendable{}
+
+// Appender implements storage.Appendable.
+func (f FuncAppendable) Appender(_ context.Context) storage.Appender {
+	return funcAppender{f}
+}
+
+type funcAppender struct {
+	f FuncAppendable
+}
+
+func (a funcAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	if a.f.Sample != nil {
+		a.f.Sample(MetricSample{Timestamp: t, Value: v, Labels: l})
+	}
+	return ref, nil
+}
+
+func (a funcAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	if a.f.Metadata != nil {
+		a.f.Metadata(l, m)
+	}
+	return ref, nil
+}
+
+func (funcAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (funcAppender) AppendHistogram(ref storage.SeriesRef, _ labels.Labels, _ int64, _ *histogram.Histogram, _ *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (funcAppender) AppendCTZeroSample(ref storage.SeriesRef, _ labels.Labels, _, _ int64) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (funcAppender) Commit() error   { return nil }
+func (funcAppender) Rollback() error { return nil }
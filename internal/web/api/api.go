@@ -6,8 +6,8 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"path"
 	"strconv"
@@ -53,6 +53,9 @@ func (a *AlloyAPI) RegisterRoutes(urlPrefix string, r *mux.Router) {
 	r.Handle(path.Join(urlPrefix, "/components/{id:.+}"), httputil.CompressionHandler{Handler: getComponentHandler(a.alloy)})
 	r.Handle(path.Join(urlPrefix, "/remotecfg/components/{id:.+}"), httputil.CompressionHandler{Handler: getComponentHandlerRemoteCfg(a.alloy)})
 
+	r.Handle(path.Join(urlPrefix, "/remotecfg/sources"), httputil.CompressionHandler{Handler: listRemoteCfgSourcesHandler(a.alloy)})
+	r.Handle(path.Join(urlPrefix, "/remotecfg/sources/{source}/components"), httputil.CompressionHandler{Handler: listComponentsHandlerRemoteCfgSource(a.alloy)})
+
 	r.Handle(path.Join(urlPrefix, "/peers"), httputil.CompressionHandler{Handler: getClusteringPeersHandler(a.alloy)})
 	r.Handle(path.Join(urlPrefix, "/debug/{id:.+}"), liveDebugging(a.alloy, a.CallbackManager, a.logger))
 
@@ -90,6 +93,40 @@ func listComponentsHandlerRemoteCfg(host service.Host) http.HandlerFunc {
 	}
 }
 
+func listComponentsHandlerRemoteCfgSource(host service.Host) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, found := host.GetService(remotecfg.ServiceName)
+		if !found {
+			http.Error(w, "remote config service not available", http.StatusInternalServerError)
+			return
+		}
+
+		sourceHost, ok := svc.(*remotecfg.Service).SourceHost(mux.Vars(r)["source"])
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		listComponentsHandlerInternal(sourceHost, w, r)
+	}
+}
+
+func listRemoteCfgSourcesHandler(host service.Host) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		svc, found := host.GetService(remotecfg.ServiceName)
+		if !found {
+			http.Error(w, "remote config service not available", http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(svc.(*remotecfg.Service).Sources())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
 func listComponentsHandlerInternal(host service.Host, w http.ResponseWriter, r *http.Request) {
 	// moduleID is set from the /modules/{moduleID:.+}/components route above
 	// but not from the /components route.
@@ -283,6 +320,7 @@ func liveDebugging(h service.Host, callbackManager livedebugging.CallbackManager
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		componentID := livedebugging.ComponentID(vars["id"])
+		query := r.URL.Query()
 
 		host, err := resolveServiceHost(h, string(componentID))
 		if err != nil {
@@ -290,10 +328,49 @@ func liveDebugging(h service.Host, callbackManager livedebugging.CallbackManager
 			return
 		}
 
-		dataCh := make(chan string, 1000)
-		ctx := r.Context()
+		filter, err := livedebugging.ParseFilter(query.Get("filter"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		sampleProb := setSampleProb(w, r.URL.Query().Get("sampleProb"))
+		sampleProb, err := parseSampleProb(query.Get("sampleProb"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		maxRate, err := parseMaxRate(query.Get("rate"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sampler := livedebugging.NewSampler(sampleProb, maxRate)
+
+		// In target diff mode, snapshots of targets are replaced by the
+		// targets which changed since the previous snapshot.
+		var differ *livedebugging.TargetDiffer
+		if query.Get("targetDiff") == "true" {
+			differ = livedebugging.NewTargetDiffer(filter)
+		}
+
+		format := query.Get("format")
+		switch format {
+		case "", formatText:
+			format = formatText
+		case formatNDJSON:
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			if query.Get("download") == "true" {
+				filename := fmt.Sprintf("%s-%s.ndjson", strings.ReplaceAll(string(componentID), "/", "_"), time.Now().UTC().Format("20060102T150405Z"))
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			}
+		default:
+			http.Error(w, fmt.Sprintf("Invalid format %q: must be %q or %q", format, formatText, formatNDJSON), http.StatusBadRequest)
+			return
+		}
+
+		dataCh := make(chan livedebugging.Record, 1000)
+		ctx := r.Context()
 
 		id := livedebugging.CallbackID(uuid.New().String())
 
@@ -303,12 +380,18 @@ func liveDebugging(h service.Host, callbackManager livedebugging.CallbackManager
 			case <-ctx.Done():
 				return
 			default:
-				if sampleProb < 1 && rand.Float64() > sampleProb {
+				// Diffs aren't sampled because dropping one would hide changes.
+				if differ != nil && data.Snapshot {
+					var changed bool
+					if data, changed = differ.Diff(data); !changed {
+						return
+					}
+				} else if !filter.Matches(data) || !sampler.Sample() {
 					return
 				}
 				// Avoid blocking the channel when the channel is full
 				select {
-				case dataCh <- data.DataFunc():
+				case dataCh <- livedebugging.NewRecord(data, time.Now()):
 				default:
 					if !droppedData {
 						level.Warn(logger).Log("msg", "data throughput is very high, not all debugging data can be sent the live debugging stream")
@@ -333,12 +416,20 @@ func liveDebugging(h service.Host, callbackManager livedebugging.CallbackManager
 
 		for {
 			select {
-			case data := <-dataCh:
-				var builder strings.Builder
-				builder.WriteString(data)
-				// |;| delimiter is added at the end of every chunk
-				builder.WriteString("|;|")
-				_, writeErr := w.Write([]byte(builder.String()))
+			case record := <-dataCh:
+				var chunk []byte
+				if format == formatNDJSON {
+					chunk, err = json.Marshal(record)
+					if err != nil {
+						level.Error(logger).Log("msg", "failed to encode live debugging record", "err", err)
+						continue
+					}
+					chunk = append(chunk, '\n')
+				} else {
+					// |;| delimiter is added at the end of every chunk
+					chunk = []byte(record.Data + "|;|")
+				}
+				_, writeErr := w.Write(chunk)
 				if writeErr != nil {
 					return
 				}
@@ -351,6 +442,14 @@ func liveDebugging(h service.Host, callbackManager livedebugging.CallbackManager
 	}
 }
 
+// Formats of the live debugging stream.
+const (
+	// formatText streams the data strings, delimited by |;|.
+	formatText = "text"
+	// formatNDJSON streams livedebugging.Record values as newline-delimited JSON.
+	formatNDJSON = "ndjson"
+)
+
 func resolveServiceHost(host service.Host, id string) (service.Host, error) {
 	if strings.HasPrefix(id, "remotecfg/") {
 		remoteCfgHost, err := getRemoteCfgHost(host)
@@ -362,17 +461,27 @@ func resolveServiceHost(host service.Host, id string) (service.Host, error) {
 	return host, nil
 }
 
-func setSampleProb(w http.ResponseWriter, sampleProbParam string) (sampleProb float64) {
-	sampleProb = 1.0
-	if sampleProbParam != "" {
-		var err error
-		sampleProb, err = strconv.ParseFloat(sampleProbParam, 64)
-		if err != nil || sampleProb < 0 || sampleProb > 1 {
-			http.Error(w, "Invalid sample probability", http.StatusBadRequest)
-			return 1.0
-		}
+func parseSampleProb(sampleProbParam string) (float64, error) {
+	if sampleProbParam == "" {
+		return 1.0, nil
+	}
+	sampleProb, err := strconv.ParseFloat(sampleProbParam, 64)
+	if err != nil || sampleProb < 0 || sampleProb > 1 {
+		return 0, errors.New("invalid sample probability: must be a number between 0 and 1")
+	}
+	return sampleProb, nil
+}
+
+// rate is the maximum number of items sent per second. 0 means unlimited.
+func parseMaxRate(rateParam string) (float64, error) {
+	if rateParam == "" {
+		return 0, nil
+	}
+	maxRate, err := strconv.ParseFloat(rateParam, 64)
+	if err != nil || maxRate < 0 {
+		return 0, errors.New("invalid rate: must be a positive number of items per second")
 	}
-	return sampleProb
+	return maxRate, nil
 }
 
 // window is expected to be in seconds, between 1 and 60.
@@ -16,8 +16,9 @@ interface ComponentListProps {
 
 const TABLEHEADERS = ['Health', 'ID'];
 
-// overrideModuleID is a workaround for the remote config page because the remotecfg component has the moduleID of its controller,
-// it should not be fetched as a module.
+// overrideModuleID is a workaround for the remote config page because the components of the primary remotecfg source have
+// the moduleID of its controller, it should not be fetched as a module. The components of the named sources keep the
+// moduleID of their controller, which is used to look them up.
 const ComponentList = ({ components, overrideModuleID, useRemotecfg, handleSorting }: ComponentListProps) => {
   const tableStyles = { width: '130px' };
   const urlPrefix = useRemotecfg ? '/remotecfg' : '';
@@ -26,7 +27,7 @@ const ComponentList = ({ components, overrideModuleID, useRemotecfg, handleSorti
    */
   const renderTableData = () => {
     return components.map(({ health, localID: id, moduleID }) => (
-      <tr key={id} style={{ lineHeight: '2.5' }}>
+      <tr key={moduleID + '/' + id} style={{ lineHeight: '2.5' }}>
         <td>
           <HealthLabel health={health.state} />
         </td>
@@ -36,7 +37,11 @@ const ComponentList = ({ components, overrideModuleID, useRemotecfg, handleSorti
             to={
               urlPrefix +
               '/component/' +
-              (overrideModuleID !== undefined ? overrideModuleID : moduleID ? moduleID + '/' : '') +
+              (overrideModuleID !== undefined && moduleID === 'remotecfg'
+                ? overrideModuleID
+                : moduleID
+                ? moduleID + '/'
+                : '') +
               id
             }
             className={styles.viewButton}
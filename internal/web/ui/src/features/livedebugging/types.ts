@@ -0,0 +1,12 @@
+/**
+ * A single item of a live debugging stream, as sent by the API in the NDJSON
+ * format. A recorded session is a list of records, one per line.
+ */
+export interface LiveDebuggingRecord {
+  timestamp: string;
+  componentID: string;
+  targetComponentIDs?: string[];
+  type: string;
+  count: number;
+  data: string;
+}
@@ -0,0 +1,48 @@
+import Table from '../clustering/Table';
+
+import { SourceInfo } from './types';
+
+import styles from './SourceList.module.css';
+
+interface SourceListProps {
+  sources: SourceInfo[];
+}
+
+const TABLEHEADERS = ['Source', 'URL', 'Last Load', 'Effective Hash', 'Error'];
+
+const SourceList = ({ sources }: SourceListProps) => {
+  const tableStyles = { width: '130px' };
+
+  /**
+   * Custom renderer for table data
+   */
+  const renderTableData = () => {
+    return sources.map(({ name, url, status }) => (
+      <tr key={name} style={{ lineHeight: '2.5' }}>
+        <td>
+          <span className={styles.idName}>{name === '' ? '(primary)' : name}</span>
+        </td>
+        <td>
+          <span className={styles.idName}>{url}</span>
+        </td>
+        <td>
+          <span className={styles.idName}>{status.lastStatus}</span>
+        </td>
+        <td>
+          <span className={styles.idName}>{status.effectiveHash}</span>
+        </td>
+        <td>
+          <span className={styles.idName}>{status.lastError}</span>
+        </td>
+      </tr>
+    ));
+  };
+
+  return (
+    <div className={styles.list}>
+      <Table tableHeaders={TABLEHEADERS} renderTableData={renderTableData} style={tableStyles} />
+    </div>
+  );
+};
+
+export default SourceList;
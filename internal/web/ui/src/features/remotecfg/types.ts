@@ -0,0 +1,30 @@
+/**
+ * SourceInfo describes a source of remote configuration.
+ */
+export interface SourceInfo {
+  /**
+   * Name of the source. The primary source has an empty name.
+   */
+  name: string;
+
+  url: string;
+
+  status: SourceStatus;
+}
+
+export interface SourceStatus {
+  /**
+   * Hash of the configuration currently running for the source.
+   */
+  effectiveHash: string;
+
+  /**
+   * Outcome of the last load attempt.
+   */
+  lastStatus: 'unknown' | 'ok' | 'error';
+
+  /**
+   * Error of the last load attempt, if it failed.
+   */
+  lastError?: string;
+}
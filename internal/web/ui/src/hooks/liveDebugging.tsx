@@ -1,10 +1,15 @@
 import { useEffect, useState } from 'react';
 
+import { LiveDebuggingRecord } from '../features/livedebugging/types';
+
 export const useLiveDebugging = (
   componentID: string,
   enabled: boolean,
   sampleProb: number,
-  setData: React.Dispatch<React.SetStateAction<string[]>>
+  selector: string,
+  rate: number,
+  targetDiff: boolean,
+  setData: React.Dispatch<React.SetStateAction<LiveDebuggingRecord[]>>
 ) => {
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState('');
@@ -20,9 +25,21 @@ export const useLiveDebugging = (
       }
 
       setLoading(true);
+      setError('');
+
+      const params = new URLSearchParams({ format: 'ndjson', sampleProb: String(sampleProb) });
+      if (selector !== '') {
+        params.set('filter', selector);
+      }
+      if (rate > 0) {
+        params.set('rate', String(rate));
+      }
+      if (targetDiff) {
+        params.set('targetDiff', 'true');
+      }
 
       try {
-        const response = await fetch(`./api/v0/web/debug/${componentID}?sampleProb=${sampleProb}`, {
+        const response = await fetch(`./api/v0/web/debug/${componentID}?${params.toString()}`, {
           signal: abortController.signal,
           cache: 'no-cache',
           credentials: 'same-origin',
@@ -35,6 +52,7 @@ export const useLiveDebugging = (
 
         const reader = response.body.getReader();
         const decoder = new TextDecoder();
+        let buffer = '';
 
         while (enabled) {
           const { value, done } = await reader.read();
@@ -42,20 +60,21 @@ export const useLiveDebugging = (
             break;
           }
 
-          const decodedChunk = decoder.decode(value, { stream: true });
+          // A chunk can end in the middle of a record, the incomplete line is kept for the next chunk.
+          buffer += decoder.decode(value, { stream: true });
+          const lines = buffer.split('\n');
+          buffer = lines.pop() ?? '';
 
-          setData((prevValue) => {
-            const newValue = decodedChunk.split('|;|');
-            newValue.pop(); // last element is empty because of the split, we discard it
-
-            if (newValue.length > maxLines) {
-              console.warn(
-                'Received %s lines but the buffer has a maximum of %s. Some lines will be dropped.',
-                newValue.length,
-                maxLines
-              );
-            }
+          const newValue = lines.filter((line) => line !== '').map((line) => JSON.parse(line) as LiveDebuggingRecord);
+          if (newValue.length > maxLines) {
+            console.warn(
+              'Received %s lines but the buffer has a maximum of %s. Some lines will be dropped.',
+              newValue.length,
+              maxLines
+            );
+          }
 
+          setData((prevValue) => {
             let dataArr = prevValue.concat(newValue);
             if (dataArr.length > maxLines) {
               dataArr = dataArr.slice(-maxLines); // truncate the array to keep the last {maxLines} lines
@@ -77,7 +96,7 @@ export const useLiveDebugging = (
     return () => {
       abortController.abort();
     };
-  }, [componentID, enabled, sampleProb, setData]);
+  }, [componentID, enabled, sampleProb, selector, rate, targetDiff, setData]);
 
   return { loading, error };
 };
@@ -0,0 +1,28 @@
+import { useEffect, useState } from 'react';
+
+import { SourceInfo } from '../features/remotecfg/types';
+
+/**
+ * useRemoteSources retrieves the list of remote configuration sources from
+ * the API.
+ */
+export const useRemoteSources = (): SourceInfo[] => {
+  const [sources, setSources] = useState<SourceInfo[]>([]);
+
+  useEffect(function () {
+    const worker = async () => {
+      const infoPath = './api/v0/web/remotecfg/sources';
+
+      // Request is relative to the <base> tag inside of <head>.
+      const resp = await fetch(infoPath, {
+        cache: 'no-cache',
+        credentials: 'same-origin',
+      });
+      setSources(await resp.json());
+    };
+
+    worker().catch(console.error);
+  }, []);
+
+  return sources;
+};
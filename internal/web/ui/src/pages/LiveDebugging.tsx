@@ -1,12 +1,13 @@
 import { useState } from 'react';
 import { useParams } from 'react-router-dom';
 import AutoScroll from '@brianmcallister/react-auto-scroll';
-import { faBroom, faBug, faCopy, faRoad, faStop } from '@fortawesome/free-solid-svg-icons';
+import { faBroom, faBug, faCodeCompare, faCopy, faDownload, faRoad, faStop } from '@fortawesome/free-solid-svg-icons';
 import { FontAwesomeIcon } from '@fortawesome/react-fontawesome';
 
 import { Field, Input, Slider } from '@grafana/ui';
 
 import Page from '../features/layout/Page';
+import { LiveDebuggingRecord } from '../features/livedebugging/types';
 import { useLiveDebugging } from '../hooks/liveDebugging';
 
 import styles from './LiveDebugging.module.css';
@@ -14,13 +15,28 @@ import styles from './LiveDebugging.module.css';
 function PageLiveDebugging() {
   const { '*': componentID } = useParams();
   const [enabled, setEnabled] = useState(true);
-  const [data, setData] = useState<string[]>([]);
+  const [data, setData] = useState<LiveDebuggingRecord[]>([]);
   const [sampleProb, setSampleProb] = useState(1);
   const [sliderProb, setSliderProb] = useState(100);
   const [filterValue, setFilterValue] = useState('');
-  const { loading, error } = useLiveDebugging(String(componentID), enabled, sampleProb, setData);
+  const [selector, setSelector] = useState('');
+  const [rate, setRate] = useState(0);
+  const [targetDiff, setTargetDiff] = useState(false);
+  const { loading, error } = useLiveDebugging(
+    String(componentID),
+    enabled,
+    sampleProb,
+    selector,
+    rate,
+    targetDiff,
+    setData
+  );
+
+  // Discovery components publish snapshots of their targets which can be
+  // streamed as diffs between updates.
+  const isDiscovery = /(^|\/)discovery\./.test(String(componentID));
 
-  const filteredData = data.filter((n) => n.toLowerCase().includes(filterValue.toLowerCase()));
+  const filteredData = data.filter((n) => n.data.toLowerCase().includes(filterValue.toLowerCase()));
 
   function toggleEnableButton() {
     if (enabled) {
@@ -54,7 +70,7 @@ function PageLiveDebugging() {
   }
 
   async function copyDataToClipboard(): Promise<void> {
-    const dataToCopy = filteredData.join('\n');
+    const dataToCopy = filteredData.map((n) => n.data).join('\n');
 
     try {
       await navigator.clipboard.writeText(dataToCopy);
@@ -63,6 +79,19 @@ function PageLiveDebugging() {
     }
   }
 
+  // The records are downloaded as NDJSON so that the session can be replayed
+  // with the `alloy debug replay` command.
+  function downloadData() {
+    const ndjson = filteredData.map((n) => JSON.stringify(n) + '\n').join('');
+    const url = URL.createObjectURL(new Blob([ndjson], { type: 'application/x-ndjson' }));
+
+    const link = document.createElement('a');
+    link.href = url;
+    link.download = `${String(componentID).replaceAll('/', '_')}-${new Date().toISOString()}.ndjson`;
+    link.click();
+    URL.revokeObjectURL(url);
+  }
+
   const samplingControl = (
     <div className={styles.slider}>
       <span className={styles.sliderLabel}>Sample rate</span>
@@ -88,10 +117,56 @@ function PageLiveDebugging() {
     </Field>
   );
 
+  // The selector and the rate are applied by the server, the stream is
+  // restarted when they change.
+  function handleSelectorChange(event: React.FocusEvent<HTMLInputElement>) {
+    setSelector(event.target.value.trim());
+  }
+
+  function handleRateChange(event: React.FocusEvent<HTMLInputElement>) {
+    const value = Number(event.target.value);
+    setRate(Number.isFinite(value) && value > 0 ? value : 0);
+  }
+
+  function applyOnEnter(event: React.KeyboardEvent<HTMLInputElement>) {
+    if (event.key === 'Enter') {
+      event.currentTarget.blur();
+    }
+  }
+
+  const selectorControl = (
+    <Field className={styles.filter}>
+      <Input placeholder='Selector, e.g. {job="api"}' onBlur={handleSelectorChange} onKeyDown={applyOnEnter} />
+    </Field>
+  );
+
+  const rateControl = (
+    <Field className={styles.rate}>
+      <Input type="number" min={0} placeholder="Max/s" onBlur={handleRateChange} onKeyDown={applyOnEnter} />
+    </Field>
+  );
+
+  const targetDiffControl = isDiscovery && (
+    <div className={styles.debugLink}>
+      <button
+        className={targetDiff ? styles.diffButtonActive : styles.diffButton}
+        onClick={() => {
+          setData([]);
+          setTargetDiff(!targetDiff);
+        }}
+      >
+        <FontAwesomeIcon icon={faCodeCompare} /> {targetDiff ? 'Snapshots' : 'Diff'}
+      </button>
+    </div>
+  );
+
   const controls = (
     <>
+      {selectorControl}
       {filterControl}
+      {rateControl}
       {samplingControl}
+      {targetDiffControl}
       {toggleEnableButton()}
       <div className={styles.debugLink}>
         <button className={styles.clearButton} onClick={() => setData([])}>
@@ -103,6 +178,11 @@ function PageLiveDebugging() {
           <FontAwesomeIcon icon={faCopy} /> Copy
         </button>
       </div>
+      <div className={styles.debugLink}>
+        <button className={styles.downloadButton} onClick={downloadData}>
+          <FontAwesomeIcon icon={faDownload} /> Download
+        </button>
+      </div>
     </>
   );
 
@@ -113,7 +193,7 @@ function PageLiveDebugging() {
       <AutoScroll className={styles.autoScroll} height={document.body.scrollHeight - 260}>
         {filteredData.map((msg, index) => (
           <div className={styles.logLine} key={index}>
-            {msg}
+            {msg.data}
           </div>
         ))}
       </AutoScroll>
@@ -3,7 +3,9 @@ import { faCubes } from '@fortawesome/free-solid-svg-icons';
 import ComponentList from '../features/component/ComponentList';
 import { ComponentInfo, SortOrder } from '../features/component/types';
 import Page from '../features/layout/Page';
+import SourceList from '../features/remotecfg/SourceList';
 import { useComponentInfo } from '../hooks/componentInfo';
+import { useRemoteSources } from '../hooks/remoteSources';
 
 const fieldMappings: { [key: string]: (comp: ComponentInfo) => string | undefined } = {
   Health: (comp) => comp.health?.state?.toString(),
@@ -18,6 +20,7 @@ function getSortValue(component: ComponentInfo, field: string): string | undefin
 
 function PageRemoteComponentList() {
   const [components, setComponents] = useComponentInfo('', true);
+  const sources = useRemoteSources();
 
   // TODO: make this sorting logic reusable
   const handleSorting = (sortField: string, sortOrder: SortOrder): void => {
@@ -38,6 +41,7 @@ function PageRemoteComponentList() {
 
   return (
     <Page name="Remote Configuration" desc="List of remote configuration pipelines" icon={faCubes}>
+      <SourceList sources={sources} />
       <ComponentList overrideModuleID={''} components={components} useRemotecfg={true} handleSorting={handleSorting} />
     </Page>
   );